@@ -9,6 +9,117 @@ const (
 	AnnotationsAnnotation = "cloud-hypervisor-provider.ironcore.dev/annotations"
 )
 
+const (
+	IgnitionTransportAnnotation = "cloud-hypervisor-provider.ironcore.dev/ignition-transport"
+
+	CloudInitUserDataAnnotation = "cloud-hypervisor-provider.ironcore.dev/cloud-init-user-data"
+	CloudInitMetaDataAnnotation = "cloud-hypervisor-provider.ironcore.dev/cloud-init-meta-data"
+
+	// OpaqueUserDataAnnotation is data the provider passes to the guest as is, for integrations the IRI
+	// spec does not model.
+	OpaqueUserDataAnnotation = "cloud-hypervisor-provider.ironcore.dev/opaque-user-data"
+
+	// DNSServersAnnotation and DNSSearchAnnotation are comma separated DNS servers and search domains of the
+	// guest, overriding the ones configured for the provider.
+	DNSServersAnnotation = "cloud-hypervisor-provider.ironcore.dev/dns-servers"
+	DNSSearchAnnotation  = "cloud-hypervisor-provider.ironcore.dev/dns-search"
+
+	// SSHAuthorizedKeysAnnotation are SSH public keys, one per line, that are merged into the ignition or
+	// cloud-init data of the machine when it is created.
+	SSHAuthorizedKeysAnnotation = "cloud-hypervisor-provider.ironcore.dev/ssh-authorized-keys"
+	// SSHUserAnnotation is the user the SSH keys are authorized for via ignition.
+	SSHUserAnnotation = "cloud-hypervisor-provider.ironcore.dev/ssh-user"
+
+	// VolumeFilesystemsAnnotation is a JSON object of the names of empty local disks to the FilesystemSpec
+	// created on them, e.g. {"scratch":{"type":"ext4","label":"scratch"}}.
+	VolumeFilesystemsAnnotation = "cloud-hypervisor-provider.ironcore.dev/volume-filesystems"
+
+	// NetworkInterfaceFirewallAttribute is a network interface attribute with the JSON FirewallSpec of the
+	// network interface, e.g. {"ingress":[{"protocol":"TCP","port":22}]}.
+	NetworkInterfaceFirewallAttribute = "cloud-hypervisor-provider.ironcore.dev/firewall"
+
+	// SerialModeAnnotation and ConsoleModeAnnotation select where the serial port and the virtio console of the
+	// guest are written to (off, tty, file or pty), overriding the ones of the machine class.
+	SerialModeAnnotation  = "cloud-hypervisor-provider.ironcore.dev/serial-mode"
+	ConsoleModeAnnotation = "cloud-hypervisor-provider.ironcore.dev/console-mode"
+
+	// DryRunAnnotation set to true on CreateMachine validates the machine, resolves its class and checks the
+	// resources of the host and the quotas, and returns the machine without storing it.
+	DryRunAnnotation = "cloud-hypervisor-provider.ironcore.dev/dry-run"
+
+	// MemoryVolumesAnnotation are the comma separated names of empty local disks stored in host memory.
+	MemoryVolumesAnnotation = "cloud-hypervisor-provider.ironcore.dev/memory-volumes"
+
+	// RecreateRequestedAnnotation marks a machine whose VM is deleted and created again on the next
+	// reconciliation. It is removed once the VM was deleted.
+	RecreateRequestedAnnotation = "cloud-hypervisor-provider.ironcore.dev/recreate-requested"
+
+	// RebootRequestedAnnotation marks a machine whose VM is rebooted on the next reconciliation. It is
+	// removed once the VM was rebooted. Set as IRI annotation, a changed value requests another reboot.
+	RebootRequestedAnnotation = "cloud-hypervisor-provider.ironcore.dev/reboot-requested"
+
+	// NetworkInterfaceIPsAnnotation is set on the IRI metadata of machines to the addresses of their network
+	// interfaces, a JSON object of NetworkInterfaceIPs keyed by network interface name.
+	NetworkInterfaceIPsAnnotation = "cloud-hypervisor-provider.ironcore.dev/network-interface-ips"
+
+	// CloneSourceAnnotation marks a machine cloned from the machine named by its value. It is removed once the
+	// disks of the source were copied.
+	CloneSourceAnnotation = "cloud-hypervisor-provider.ironcore.dev/clone-source"
+	// ClonedFromLabel is the IRI label of a cloned machine, its value is the id of the source machine.
+	ClonedFromLabel = "cloud-hypervisor-provider.ironcore.dev/cloned-from"
+
+	// ImportedVMUUIDAnnotation marks a machine created for a VM that was imported, its value is the platform
+	// uuid of the VM, which may be empty or differ from the id of the machine.
+	ImportedVMUUIDAnnotation = "cloud-hypervisor-provider.ironcore.dev/imported-vm-uuid"
+	// ImportedLabel is the IRI label of a machine created for an imported VM.
+	ImportedLabel = "cloud-hypervisor-provider.ironcore.dev/imported"
+
+	// PowerFilterKey selects machines by the name of their IRI power, e.g. POWER_ON, in the label selector of a
+	// machine filter. Like the ClassLabel, it is matched against the machine instead of its IRI labels.
+	PowerFilterKey = "cloud-hypervisor-provider.ironcore.dev/power"
+	// StateFilterKey selects machines by the name of their IRI state, e.g. MACHINE_RUNNING, in the label selector
+	// of a machine filter.
+	StateFilterKey = "cloud-hypervisor-provider.ironcore.dev/state"
+)
+
+// NetworkInterfaceIPs are the addresses of a network interface reported in the NetworkInterfaceIPsAnnotation.
+type NetworkInterfaceIPs struct {
+	IPs       []string `json:"ips,omitempty"`
+	PublicIPs []string `json:"publicIPs,omitempty"`
+}
+
+const (
+	// MigrationDestinationAnnotation is the url of the migration api of the provider the machine's VM is
+	// migrated to.
+	MigrationDestinationAnnotation = "cloud-hypervisor-provider.ironcore.dev/migration-destination"
+	// MigrationSourceAnnotation marks a machine whose VM is migrated from another host, named by its value.
+	MigrationSourceAnnotation = "cloud-hypervisor-provider.ironcore.dev/migration-source"
+	// MigrationStateAnnotation is the MigrationState of a migrating machine, on the source and the destination.
+	MigrationStateAnnotation = "cloud-hypervisor-provider.ironcore.dev/migration-state"
+	// MigrationReceiverAnnotation is the url the cloud-hypervisor instance on the destination receives the VM on.
+	MigrationReceiverAnnotation = "cloud-hypervisor-provider.ironcore.dev/migration-receiver"
+	// MigrationMessageAnnotation is the reason of a failed migration.
+	MigrationMessageAnnotation = "cloud-hypervisor-provider.ironcore.dev/migration-message"
+)
+
+const (
+	// VMMUpgradeAnnotation requests to move the VM of the machine to a free cloud-hypervisor instance of a newer
+	// version, its value is the UpgradeMethod. It is removed once the VM was moved or the upgrade failed.
+	VMMUpgradeAnnotation = "cloud-hypervisor-provider.ironcore.dev/vmm-upgrade"
+	// VMMUpgradeTargetAnnotation is the api socket the VM is moved to while it is upgraded, so that an upgrade
+	// interrupted by a restart of the provider is completed instead of the VM being created again.
+	VMMUpgradeTargetAnnotation = "cloud-hypervisor-provider.ironcore.dev/vmm-upgrade-target"
+)
+
+const (
+	// ExportRefAnnotation is the reference of the image the root disk of the machine is exported to.
+	ExportRefAnnotation = "cloud-hypervisor-provider.ironcore.dev/export-ref"
+	// ExportStateAnnotation is the ExportState of the export of the root disk.
+	ExportStateAnnotation = "cloud-hypervisor-provider.ironcore.dev/export-state"
+	// ExportMessageAnnotation is the digest of the pushed manifest or the reason of a failed export.
+	ExportMessageAnnotation = "cloud-hypervisor-provider.ironcore.dev/export-message"
+)
+
 const (
 	ManagerLabel = "cloud-hypervisor-provider.ironcore.dev/manager"
 	ClassLabel   = "cloud-hypervisor-provider.ironcore.dev/class"
@@ -17,3 +128,7 @@ const (
 const (
 	MachineManager = "cloud-hypervisor-provider"
 )
+
+// EventsResourceVersionKey is the gRPC metadata key of the resource version of the events. ListEvents reports the
+// current one in its response header, a request carrying the last one it got waits until newer events are recorded.
+const EventsResourceVersionKey = "cloud-hypervisor-provider-events-resource-version"
@@ -4,6 +4,10 @@
 package api
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	apiutils "github.com/ironcore-dev/provider-utils/apiutils/api"
@@ -25,7 +29,46 @@ type MachineSpec struct {
 	Cpu         int64 `json:"cpuMillis"`
 	MemoryBytes int64 `json:"memoryBytes"`
 
-	Ignition []byte `json:"ignition"`
+	Ignition          []byte            `json:"ignition"`
+	IgnitionTransport IgnitionTransport `json:"ignitionTransport,omitempty"`
+
+	CloudInit *CloudInitSpec `json:"cloudInit,omitempty"`
+
+	// Hostname is the hostname of the guest, derived from the machine name.
+	Hostname string `json:"hostname,omitempty"`
+	// DNS is the DNS configuration of the guest.
+	DNS *DNSSpec `json:"dns,omitempty"`
+
+	// OpaqueUserData is passed to the guest via the config drive if the machine has one, otherwise via OEM
+	// strings, and via the metadata service.
+	OpaqueUserData []byte `json:"opaqueUserData,omitempty"`
+
+	Landlock bool `json:"landlock,omitempty"`
+
+	CpuTopology *CpuTopology `json:"cpuTopology,omitempty"`
+	CpuFeatures *CpuFeatures `json:"cpuFeatures,omitempty"`
+
+	Clock GuestClock `json:"clock,omitempty"`
+
+	// FreePageReporting lets the guest return its free memory to the host via the balloon device.
+	FreePageReporting bool `json:"freePageReporting,omitempty"`
+
+	// SharedMemory maps the guest memory shared, which vhost-user devices require. If nil, the memory is shared
+	// if the machine has volumes connected via vhost-user when its VM is created.
+	SharedMemory *bool `json:"sharedMemory,omitempty"`
+
+	MemoryBacking *MemoryBacking `json:"memoryBacking,omitempty"`
+
+	// VMConfigOverrides are set in the cloud-hypervisor config of the VM, by dot separated path of the field.
+	VMConfigOverrides map[string]string `json:"vmConfigOverrides,omitempty"`
+
+	// SerialMode is where the serial port of the guest is written to, a file in the machine directory if empty.
+	SerialMode ConsoleMode `json:"serialMode,omitempty"`
+	// ConsoleMode is where the virtio console of the guest is written to, the guest has none if empty.
+	ConsoleMode ConsoleMode `json:"consoleMode,omitempty"`
+
+	// VMMRequirements restrict the cloud-hypervisor instances the machine is placed on, any if nil.
+	VMMRequirements *VMMRequirements `json:"vmmRequirements,omitempty"`
 
 	Volumes           []*VolumeSpec           `json:"volumes"`
 	NetworkInterfaces []*NetworkInterfaceSpec `json:"networkInterfaces"`
@@ -38,6 +81,237 @@ type MachineStatus struct {
 	NetworkInterfaceStatus []NetworkInterfaceStatus `json:"networkInterfaceStatus"`
 	State                  MachineState             `json:"state"`
 	ImageRef               string                   `json:"imageRef"`
+	ConfigDrive            *ConfigDriveStatus       `json:"configDrive,omitempty"`
+	Utilization            *UtilizationStatus       `json:"utilization,omitempty"`
+	Conditions             []MachineCondition       `json:"conditions,omitempty"`
+	// BootedAt is when the VM last entered Running, it is unset while the VM does not run.
+	BootedAt *time.Time `json:"bootedAt,omitempty"`
+	// BootDuration is the time from the creation of the machine until its VM first entered Running.
+	BootDuration time.Duration `json:"bootDuration,omitempty"`
+	// PCISegments are the PCI segments reserved for the network interfaces of the machine, by interface name.
+	// Reservations outlive their interface, so an interface attached again with the same name gets its segment back.
+	PCISegments map[string]int16 `json:"pciSegments,omitempty"`
+	// DiskOrder are the names of the volumes in the order they were plugged into the VM, first the ones of its
+	// creation, then the hot-plugged ones. A recreated VM gets its disks in this order.
+	DiskOrder []string `json:"diskOrder,omitempty"`
+}
+
+type MachineConditionType string
+
+const (
+	// MachineConditionRequiresRestart is present if the VM differs from the machine spec in a way that only a
+	// recreation of the VM resolves.
+	MachineConditionRequiresRestart MachineConditionType = "RequiresRestart"
+	// MachineConditionImageUpdatePending is present if the image of the machine changed while its VM runs. The
+	// root disk is provisioned from the new image once the VM stopped.
+	MachineConditionImageUpdatePending MachineConditionType = "ImageUpdatePending"
+	// MachineConditionSocketUnavailable is present while no cloud-hypervisor instance is free to run the VM of
+	// the machine.
+	MachineConditionSocketUnavailable MachineConditionType = "SocketUnavailable"
+	// MachineConditionImagePullBackOff is present while the boot image of the machine fails to pull. The pull is
+	// retried with backoff.
+	MachineConditionImagePullBackOff MachineConditionType = "ImagePullBackOff"
+	// MachineConditionClassDeprecated is present while the class of the machine is deprecated or was removed. The
+	// machine keeps running, but no new machines of the class are admitted.
+	MachineConditionClassDeprecated MachineConditionType = "ClassDeprecated"
+)
+
+// MachineCondition is a condition that currently applies to the machine. Conditions that do not apply are
+// removed.
+type MachineCondition struct {
+	Type               MachineConditionType `json:"type"`
+	Reason             string               `json:"reason,omitempty"`
+	Message            string               `json:"message,omitempty"`
+	LastTransitionTime time.Time            `json:"lastTransitionTime"`
+}
+
+// HasCondition reports whether the condition of the type is present.
+func (s *MachineStatus) HasCondition(conditionType MachineConditionType) bool {
+	return slices.ContainsFunc(s.Conditions, func(condition MachineCondition) bool {
+		return condition.Type == conditionType
+	})
+}
+
+// GetCondition returns the condition of the type or nil if it is not present.
+func (s *MachineStatus) GetCondition(conditionType MachineConditionType) *MachineCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition, keeping its transition time if it is already present.
+func (s *MachineStatus) SetCondition(condition MachineCondition) {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == condition.Type {
+			condition.LastTransitionTime = s.Conditions[i].LastTransitionTime
+			s.Conditions[i] = condition
+			return
+		}
+	}
+	s.Conditions = append(s.Conditions, condition)
+}
+
+// RemoveCondition removes the condition of the type if present.
+func (s *MachineStatus) RemoveCondition(conditionType MachineConditionType) {
+	s.Conditions = slices.DeleteFunc(s.Conditions, func(condition MachineCondition) bool {
+		return condition.Type == conditionType
+	})
+}
+
+// UtilizationStatus is the resource usage of the VM, sampled from its cloud-hypervisor process.
+type UtilizationStatus struct {
+	// CpuMillis is the cpu time used per second since the previous sample.
+	CpuMillis   int64 `json:"cpuMillis"`
+	MemoryBytes int64 `json:"memoryBytes"`
+	// ReclaimedMemoryBytes is the memory of the machine the guest returned to the host by free page reporting.
+	ReclaimedMemoryBytes int64     `json:"reclaimedMemoryBytes,omitempty"`
+	SampledAt            time.Time `json:"sampledAt"`
+}
+
+// MemoryBacking places the guest memory elsewhere than on anonymous memory of the host.
+type MemoryBacking struct {
+	// HugepageSizeBytes backs the memory with hugepages of the size.
+	HugepageSizeBytes int64 `json:"hugepageSizeBytes,omitempty"`
+	// File backs the memory with a file. If it is a directory, e.g. a hugetlbfs or DAX mount, an unnamed file
+	// is created in it.
+	File string `json:"file,omitempty"`
+}
+
+// VMMRequirements are what a cloud-hypervisor instance has to report on ping to be chosen for a machine.
+type VMMRequirements struct {
+	// MinVersion is the oldest version of the instance, e.g. v49.0.
+	MinVersion string `json:"minVersion,omitempty"`
+	// Features are the build features of the instance, e.g. sev_snp.
+	Features []string `json:"features,omitempty"`
+}
+
+// GuestClock is the clock the guest is expected to synchronize its time with.
+// ConsoleMode is where the output of a serial port or virtio console of a guest goes.
+type ConsoleMode string
+
+const (
+	// ConsoleModeOff leaves the guest without the device.
+	ConsoleModeOff ConsoleMode = "off"
+	// ConsoleModeTty writes the output to the journal of the cloud-hypervisor instance.
+	ConsoleModeTty ConsoleMode = "tty"
+	// ConsoleModeFile writes the output to a file in the machine directory.
+	ConsoleModeFile ConsoleMode = "file"
+	// ConsoleModePty connects the device to a pseudo terminal on the host for interactive use.
+	ConsoleModePty ConsoleMode = "pty"
+)
+
+// ParseConsoleMode parses a console mode, ignoring its case.
+func ParseConsoleMode(value string) (ConsoleMode, error) {
+	switch mode := ConsoleMode(strings.ToLower(value)); mode {
+	case ConsoleModeOff, ConsoleModeTty, ConsoleModeFile, ConsoleModePty:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid console mode %q, must be one of off, tty, file or pty", value)
+	}
+}
+
+type GuestClock string
+
+const (
+	// GuestClockKVM is the kvmclock every guest has.
+	GuestClockKVM GuestClock = "kvm"
+	// GuestClockPTP is the host clock read by the guest via the KVM PTP hypercall.
+	GuestClockPTP GuestClock = "ptp"
+)
+
+// CpuTopology is the cpu topology presented to the guest. Its sockets, cores per socket and threads per core
+// multiply to the cpus of the machine.
+type CpuTopology struct {
+	Sockets        int `json:"sockets"`
+	CoresPerSocket int `json:"coresPerSocket"`
+	ThreadsPerCore int `json:"threadsPerCore"`
+}
+
+func (t CpuTopology) Cpus() int {
+	return t.Sockets * t.CoresPerSocket * t.ThreadsPerCore
+}
+
+func (t CpuTopology) String() string {
+	return fmt.Sprintf("%dx%dx%d", t.Sockets, t.CoresPerSocket, t.ThreadsPerCore)
+}
+
+// ParseCpuTopology parses a topology formatted as <sockets>x<cores per socket>x<threads per core>.
+func ParseCpuTopology(value string) (*CpuTopology, error) {
+	parts := strings.Split(value, "x")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid cpu topology %q: expected <sockets>x<cores>x<threads>", value)
+	}
+
+	var counts [3]int
+	for i, part := range parts {
+		count, err := strconv.Atoi(part)
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("invalid cpu topology %q: %q is no positive number", value, part)
+		}
+		counts[i] = count
+	}
+
+	return &CpuTopology{
+		Sockets:        counts[0],
+		CoresPerSocket: counts[1],
+		ThreadsPerCore: counts[2],
+	}, nil
+}
+
+// CpuFeatures are the optional cpu features of the guest.
+type CpuFeatures struct {
+	// AMX enables the advanced matrix extensions.
+	AMX bool `json:"amx,omitempty"`
+	// KvmHyperV enlightens Windows guests.
+	KvmHyperV bool `json:"kvmHyperV,omitempty"`
+	// MaxPhysBits limits the physical address bits of the guest, so that it can be migrated to hosts with
+	// fewer bits.
+	MaxPhysBits int `json:"maxPhysBits,omitempty"`
+	// SgxEpcBytes is the size of the SGX enclave page cache of the guest.
+	SgxEpcBytes int64 `json:"sgxEpcBytes,omitempty"`
+}
+
+type ConfigDriveStatus struct {
+	Path   string            `json:"path"`
+	Format ConfigDriveFormat `json:"format"`
+}
+
+type ConfigDriveFormat string
+
+const (
+	ConfigDriveFormatOpenStack ConfigDriveFormat = "openstack"
+	ConfigDriveFormatNoCloud   ConfigDriveFormat = "nocloud"
+)
+
+type CloudInitSpec struct {
+	UserData []byte `json:"userData,omitempty"`
+	MetaData []byte `json:"metaData,omitempty"`
+}
+
+type DNSSpec struct {
+	Servers []string `json:"servers,omitempty"`
+	Search  []string `json:"search,omitempty"`
+}
+
+type IgnitionTransport string
+
+const (
+	IgnitionTransportOEMStrings  IgnitionTransport = "oem-strings"
+	IgnitionTransportConfigDrive IgnitionTransport = "config-drive"
+)
+
+// ParseIgnitionTransport parses an ignition transport.
+func ParseIgnitionTransport(value string) (IgnitionTransport, error) {
+	switch transport := IgnitionTransport(value); transport {
+	case IgnitionTransportOEMStrings, IgnitionTransportConfigDrive:
+		return transport, nil
+	default:
+		return "", fmt.Errorf("unsupported ignition transport %q, must be one of %s or %s", value,
+			IgnitionTransportOEMStrings, IgnitionTransportConfigDrive)
+	}
 }
 
 type MachineState string
@@ -50,6 +324,40 @@ const (
 	MachineStateTerminated  MachineState = "Terminated"
 )
 
+type MigrationState string
+
+const (
+	// MigrationStatePending is set while the destination prepares the machine.
+	MigrationStatePending MigrationState = "Pending"
+	// MigrationStateReady is set once the destination listens for the VM.
+	MigrationStateReady MigrationState = "Ready"
+	// MigrationStateCompleted is set once the VM runs on the destination.
+	MigrationStateCompleted MigrationState = "Completed"
+	// MigrationStateFailed is set if the migration failed. The VM keeps running on the source.
+	MigrationStateFailed MigrationState = "Failed"
+)
+
+// UpgradeMethod is how the VM of a machine is moved to a cloud-hypervisor instance of a newer version.
+type UpgradeMethod string
+
+const (
+	// UpgradeMethodMigration live migrates the VM over a unix socket, the guest is only paused briefly.
+	UpgradeMethodMigration UpgradeMethod = "migration"
+	// UpgradeMethodSnapshot pauses the VM, snapshots it and restores the snapshot on the new instance.
+	UpgradeMethodSnapshot UpgradeMethod = "snapshot"
+)
+
+type ExportState string
+
+const (
+	// ExportStatePending is set while the root disk is exported.
+	ExportStatePending ExportState = "Pending"
+	// ExportStateCompleted is set once the image was pushed.
+	ExportStateCompleted ExportState = "Completed"
+	// ExportStateFailed is set if the export failed.
+	ExportStateFailed ExportState = "Failed"
+)
+
 type PowerState int32
 
 const (
@@ -63,6 +371,13 @@ type VolumeSpec struct {
 	LocalDisk  *LocalDiskSpec    `json:"LocalDisk,omitempty"`
 	Connection *VolumeConnection `json:"cephDisk,omitempty"`
 	DeletedAt  *time.Time        `json:"deletedAt,omitempty"`
+	// Imported marks a disk of an imported VM. No plugin provides it, it is only removed from the VM.
+	Imported bool `json:"imported,omitempty"`
+}
+
+// RequiresSharedMemory reports whether the volume is connected via vhost-user.
+func (v *VolumeSpec) RequiresSharedMemory() bool {
+	return v.Connection != nil || (v.LocalDisk != nil && v.LocalDisk.Medium == StorageMediumCeph)
 }
 
 type VolumeStatus struct {
@@ -72,11 +387,75 @@ type VolumeStatus struct {
 	Handle string      `json:"handle,omitempty"`
 	State  VolumeState `json:"state,omitempty"`
 	Size   int64       `json:"size,omitempty"`
+	// Image is the image the disk was provisioned from.
+	Image string `json:"image,omitempty"`
+
+	Stats *VolumeStats `json:"stats,omitempty"`
+	// Health is the result of the last probe of the attached volume, nil if it was not probed since it was
+	// attached.
+	Health *VolumeHealth `json:"health,omitempty"`
+}
+
+// VolumeHealth is whether the attached volume is served as the guest expects.
+type VolumeHealth struct {
+	State   VolumeHealthState `json:"state"`
+	Message string            `json:"message,omitempty"`
+	// Since is when the volume entered the state.
+	Since time.Time `json:"since"`
+}
+
+type VolumeHealthState string
+
+const (
+	VolumeHealthStateHealthy VolumeHealthState = "Healthy"
+	// VolumeHealthStateDegraded volumes are served, but IO of the guest fails.
+	VolumeHealthStateDegraded VolumeHealthState = "Degraded"
+	// VolumeHealthStateUnavailable volumes are not served anymore, they are mounted and attached again.
+	VolumeHealthStateUnavailable VolumeHealthState = "Unavailable"
+)
+
+// VolumeStats is the IO of a volume since it was attached. The latencies are averages in microseconds.
+type VolumeStats struct {
+	ReadBytes          int64 `json:"readBytes"`
+	ReadOps            int64 `json:"readOps"`
+	ReadLatencyMicros  int64 `json:"readLatencyMicros,omitempty"`
+	WriteBytes         int64 `json:"writeBytes"`
+	WriteOps           int64 `json:"writeOps"`
+	WriteLatencyMicros int64 `json:"writeLatencyMicros,omitempty"`
 }
 
 type LocalDiskSpec struct {
 	Size  int64   `json:"size"`
 	Image *string `json:"image"`
+	// Filesystem is created on an empty disk when the disk is created.
+	Filesystem *FilesystemSpec `json:"filesystem,omitempty"`
+	// Medium is the medium the disk is stored on, the default medium of the host if empty.
+	Medium StorageMedium `json:"medium,omitempty"`
+}
+
+type StorageMedium string
+
+const (
+	// StorageMediumMemory stores the disk in host memory, on a tmpfs. Its content is lost when the host
+	// reboots.
+	StorageMediumMemory StorageMedium = "Memory"
+	// StorageMediumCeph stores a disk provisioned from an image as a clone of an RBD snapshot of the image.
+	StorageMediumCeph StorageMedium = "Ceph"
+)
+
+type FilesystemType string
+
+const (
+	FilesystemTypeExt4 FilesystemType = "ext4"
+	FilesystemTypeXFS  FilesystemType = "xfs"
+)
+
+// FilesystemSpec is a filesystem created with mkfs.<type>. Options are passed to mkfs as they are, they are
+// restricted to options that do not read from the host.
+type FilesystemSpec struct {
+	Type    FilesystemType `json:"type"`
+	Label   string         `json:"label,omitempty"`
+	Options []string       `json:"options,omitempty"`
 }
 
 type VolumeConnection struct {
@@ -107,7 +486,63 @@ type NetworkInterfaceSpec struct {
 	NetworkId  string            `json:"networkId"`
 	Ips        []string          `json:"ips"`
 	Attributes map[string]string `json:"attributes"`
-	DeletedAt  *time.Time        `json:"deletedAt,omitempty"`
+	// Firewall restricts the traffic of the network interface, if set.
+	Firewall  *FirewallSpec `json:"firewall,omitempty"`
+	DeletedAt *time.Time    `json:"deletedAt,omitempty"`
+	// Imported marks a network interface of an imported VM. The plugin does not set it up, it is only removed
+	// from the VM.
+	Imported bool `json:"imported,omitempty"`
+}
+
+type FirewallPolicyType string
+
+const (
+	FirewallPolicyTypeIngress FirewallPolicyType = "Ingress"
+	FirewallPolicyTypeEgress  FirewallPolicyType = "Egress"
+)
+
+// FirewallSpec follows the semantics of network policies: traffic in a direction listed in PolicyTypes is
+// dropped unless a rule of the direction allows it. Replies to allowed traffic are always allowed. PolicyTypes
+// defaults to Ingress, and Egress if there are egress rules.
+type FirewallSpec struct {
+	PolicyTypes []FirewallPolicyType `json:"policyTypes,omitempty"`
+	Ingress     []FirewallRule       `json:"ingress,omitempty"`
+	Egress      []FirewallRule       `json:"egress,omitempty"`
+}
+
+type FirewallProtocol string
+
+const (
+	FirewallProtocolTCP  FirewallProtocol = "TCP"
+	FirewallProtocolUDP  FirewallProtocol = "UDP"
+	FirewallProtocolSCTP FirewallProtocol = "SCTP"
+	FirewallProtocolICMP FirewallProtocol = "ICMP"
+)
+
+// FirewallRule allows traffic from (ingress) or to (egress) the CIDRs, any address if empty. Port and EndPort
+// are the guest port range for ingress and the remote one for egress, any port if zero. Rules without protocol
+// match any protocol and must not have ports.
+type FirewallRule struct {
+	Protocol FirewallProtocol `json:"protocol,omitempty"`
+	Port     int32            `json:"port,omitempty"`
+	EndPort  int32            `json:"endPort,omitempty"`
+	CIDRs    []string         `json:"cidrs,omitempty"`
+}
+
+// Restricts returns whether traffic of the direction is dropped unless a rule allows it.
+func (f *FirewallSpec) Restricts(policyType FirewallPolicyType) bool {
+	return slices.Contains(f.EffectivePolicyTypes(), policyType)
+}
+
+// EffectivePolicyTypes returns the PolicyTypes, or their default.
+func (f *FirewallSpec) EffectivePolicyTypes() []FirewallPolicyType {
+	if len(f.PolicyTypes) > 0 {
+		return f.PolicyTypes
+	}
+	if len(f.Egress) > 0 {
+		return []FirewallPolicyType{FirewallPolicyTypeIngress, FirewallPolicyTypeEgress}
+	}
+	return []FirewallPolicyType{FirewallPolicyTypeIngress}
 }
 
 type NetworkInterfaceStatus struct {
@@ -116,6 +551,24 @@ type NetworkInterfaceStatus struct {
 	State  NetworkInterfaceState `json:"state"`
 	Type   NetworkInterfaceType  `json:"type,omitempty"`
 	Path   string                `json:"path,omitempty"`
+	// MAC is the guest MAC address of tap network interfaces. If empty, cloud-hypervisor picks a random one.
+	MAC string `json:"mac,omitempty"`
+	// IPs are the addresses of the guest on the network interface.
+	IPs []string `json:"ips,omitempty"`
+	// PublicIPs are the public addresses the network interface is reachable at, e.g. assigned by apinet.
+	PublicIPs []string `json:"publicIPs,omitempty"`
+	// PCISegment is the PCI segment the network interface is plugged into, 0 if it shares the default segment.
+	PCISegment int16 `json:"pciSegment,omitempty"`
+
+	Stats *NetworkInterfaceStats `json:"stats,omitempty"`
+}
+
+// NetworkInterfaceStats is the traffic of a network interface as seen by the guest.
+type NetworkInterfaceStats struct {
+	RxBytes   int64 `json:"rxBytes"`
+	RxPackets int64 `json:"rxPackets"`
+	TxBytes   int64 `json:"txBytes"`
+	TxPackets int64 `json:"txPackets"`
 }
 
 type NetworkInterfaceState string
@@ -0,0 +1,346 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/admin"
+	"github.com/spf13/cobra"
+)
+
+func requeueCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "requeue <machine-id>",
+		Short: "Reconcile a machine immediately, clearing its error backoff.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.adminAction(cmd.Context(), args[0], "requeue"); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "machine %s requeued\n", args[0])
+			return nil
+		},
+	}
+}
+
+func recreateCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "recreate <machine-id>",
+		Short: "Delete the VM of a machine and create it again, e.g. after manual intervention.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.adminAction(cmd.Context(), args[0], "recreate"); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "recreation of the vm of machine %s requested\n", args[0])
+			return nil
+		},
+	}
+}
+
+func rebootCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reboot <machine-id>",
+		Short: "Reboot the VM of a machine, keeping its hot-plugged devices.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.adminAction(cmd.Context(), args[0], "reboot"); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "reboot of machine %s requested\n", args[0])
+			return nil
+		},
+	}
+}
+
+func migrateCommand(opts *Options) *cobra.Command {
+	var destination string
+
+	cmd := &cobra.Command{
+		Use:   "migrate <machine-id>",
+		Short: "Live migrate the VM of a machine to the provider on another host.",
+		Long: "Live migrate the VM of a machine to the provider on another host. The destination is the url of " +
+			"its migration api. An empty destination cancels a failed migration.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := fmt.Sprintf("/v1/machines/%s/migrate", url.PathEscape(args[0]))
+			if err := opts.adminRequest(cmd.Context(), http.MethodPost, path, map[string]string{
+				"destination": destination,
+			}, http.StatusAccepted, nil); err != nil {
+				return err
+			}
+			if destination == "" {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "migration of machine %s cancelled\n", args[0])
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "migration of machine %s to %s requested\n", args[0], destination)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&destination, "to", "", "URL of the migration api of the destination provider.")
+	return cmd
+}
+
+func upgradeCommand(opts *Options) *cobra.Command {
+	var (
+		method     string
+		minVersion string
+		cancel     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade [<machine-id>]",
+		Short: "Move VMs to free cloud-hypervisor instances of a newer version.",
+		Long: "Move the VM of a machine to a free cloud-hypervisor instance of a newer version, or with " +
+			"--min-version the VMs of all machines on older instances. Running VMs are live migrated or snapshotted " +
+			"and restored, other VMs are created again on the new instance.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				if minVersion == "" {
+					return fmt.Errorf("must specify a machine id or --min-version")
+				}
+				var resp admin.UpgradesResponse
+				if err := opts.adminRequest(cmd.Context(), http.MethodPost, "/v1/upgrades", admin.UpgradesRequest{
+					MinVersion: minVersion,
+					Method:     api.UpgradeMethod(method),
+				}, http.StatusAccepted, &resp); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "upgrade of %d machines requested\n", len(resp.Machines))
+				for _, id := range resp.Machines {
+					_, _ = fmt.Fprintln(cmd.OutOrStdout(), id)
+				}
+				return nil
+			}
+
+			path := fmt.Sprintf("/v1/machines/%s/upgrade", url.PathEscape(args[0]))
+			if err := opts.adminRequest(cmd.Context(), http.MethodPost, path, admin.UpgradeRequest{
+				Method: api.UpgradeMethod(method),
+				Cancel: cancel,
+			}, http.StatusAccepted, nil); err != nil {
+				return err
+			}
+			if cancel {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "upgrade of machine %s cancelled\n", args[0])
+				return nil
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "upgrade of machine %s requested\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&method, "method", string(api.UpgradeMethodMigration),
+		fmt.Sprintf("How running VMs are moved, %s or %s.", api.UpgradeMethodMigration, api.UpgradeMethodSnapshot))
+	cmd.Flags().StringVar(&minVersion, "min-version", "",
+		"Upgrade all machines on instances older than the version, e.g. v49.0.")
+	cmd.Flags().BoolVar(&cancel, "cancel", false, "Cancel the upgrade of the machine while it waits for an instance.")
+	return cmd
+}
+
+func exportCommand(opts *Options) *cobra.Command {
+	var ref string
+
+	cmd := &cobra.Command{
+		Use:   "export <machine-id>",
+		Short: "Push the root disk of a machine as image to a registry.",
+		Long: "Push the root disk of a machine as image to a registry. A running VM is paused while its disk is " +
+			"read. The export runs in the background, its state is shown in the annotations of the machine.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := fmt.Sprintf("/v1/machines/%s/export", url.PathEscape(args[0]))
+			if err := opts.adminRequest(cmd.Context(), http.MethodPost, path, admin.ExportRequest{
+				Ref: ref,
+			}, http.StatusAccepted, nil); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "export of machine %s to %s requested\n", args[0], ref)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&ref, "to", "", "Reference of the image, e.g. registry.example.com/images/web:v2.")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func cloneCommand(opts *Options) *cobra.Command {
+	var powerOn bool
+
+	cmd := &cobra.Command{
+		Use:   "clone <machine-id>",
+		Short: "Create a machine with copies of the local disks of a powered off machine.",
+		Long: "Create a machine with copies of the local disks of a powered off machine, e.g. of a template. The " +
+			"clone gets no network interfaces and is labeled with the id of its source.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp admin.CloneResponse
+			path := fmt.Sprintf("/v1/machines/%s/clone", url.PathEscape(args[0]))
+			if err := opts.adminRequest(cmd.Context(), http.MethodPost, path, admin.CloneRequest{
+				PowerOn: powerOn,
+			}, http.StatusCreated, &resp); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "machine %s cloned as %s\n", args[0], resp.ID)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&powerOn, "power-on", false, "Start the clone once its disks are copied.")
+	return cmd
+}
+
+func importCommand(opts *Options) *cobra.Command {
+	var class string
+
+	cmd := &cobra.Command{
+		Use:   "import <api-socket>",
+		Short: "Create a machine for a VM on a cloud-hypervisor instance of the provider that belongs to no machine.",
+		Long: "Create a machine for a VM on a cloud-hypervisor instance of the provider that belongs to no machine, " +
+			"e.g. a VM started by hand. The disks and network interfaces of the VM are kept, but not managed.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp admin.ImportResponse
+			if err := opts.adminRequest(cmd.Context(), http.MethodPost, "/v1/imports", admin.ImportRequest{
+				Socket: args[0],
+				Class:  class,
+			}, http.StatusCreated, &resp); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "vm on %s imported as machine %s\n", args[0], resp.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&class, "class", "", "Machine class, defaults to the class of the size of the VM.")
+	return cmd
+}
+
+func consoleCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "console <machine-id>",
+		Short: "Print the serial console output of a machine kept by the provider.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var output admin.ConsoleOutput
+			path := fmt.Sprintf("/v1/machines/%s/console", url.PathEscape(args[0]))
+			if err := opts.adminRequest(cmd.Context(), http.MethodGet, path, nil, http.StatusOK, &output); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(cmd.OutOrStdout(), output.Output)
+			return nil
+		},
+	}
+}
+
+func vmConfigCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "vm-config <machine-id>",
+		Short: "Print the live config of the VM of a machine and the config the provider computes for it.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var config admin.VMConfig
+			path := fmt.Sprintf("/v1/machines/%s/vm-config", url.PathEscape(args[0]))
+			if err := opts.adminRequest(cmd.Context(), http.MethodGet, path, nil, http.StatusOK, &config); err != nil {
+				return err
+			}
+
+			// The configs are nested too deeply for a table.
+			printOpts := *opts
+			if printOpts.Output == OutputTable {
+				printOpts.Output = OutputYAML
+			}
+			return printOpts.print(cmd.OutOrStdout(), config, nil)
+		},
+	}
+}
+
+// vmmCalls are the read-only calls of the cloud-hypervisor api the admin api proxies.
+var vmmCalls = []string{"vm.info", "vmm.ping", "vm.counters"}
+
+func vmmCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:       fmt.Sprintf("vmm <machine-id> <%s>", strings.Join(vmmCalls, "|")),
+		Short:     "Print the result of a read-only call of the cloud-hypervisor api of a machine.",
+		Args:      cobra.ExactArgs(2),
+		ValidArgs: vmmCalls,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(vmmCalls, args[1]) {
+				return fmt.Errorf("unsupported call %q, must be one of %s", args[1], strings.Join(vmmCalls, ", "))
+			}
+
+			var result any
+			path := fmt.Sprintf("/v1/machines/%s/vmm/%s", url.PathEscape(args[0]), args[1])
+			if err := opts.adminRequest(cmd.Context(), http.MethodGet, path, nil, http.StatusOK, &result); err != nil {
+				return err
+			}
+
+			// The results are nested too deeply for a table.
+			printOpts := *opts
+			if printOpts.Output == OutputTable {
+				printOpts.Output = OutputYAML
+			}
+			return printOpts.print(cmd.OutOrStdout(), result, nil)
+		},
+	}
+}
+
+func (o *Options) adminAction(ctx context.Context, machineID, action string) error {
+	path := fmt.Sprintf("/v1/machines/%s/%s", url.PathEscape(machineID), action)
+	return o.adminRequest(ctx, http.MethodPost, path, nil, http.StatusAccepted, nil)
+}
+
+// adminRequest calls the admin api with body encoded as json and decodes the response into out if not nil.
+func (o *Options) adminRequest(ctx context.Context, method, path string, body any, expectedStatus int, out any) error {
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", o.AdminSocket)
+			},
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost"+path, reqBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call admin api: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != expectedStatus {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("admin api returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode admin api response: %w", err)
+		}
+	}
+	return nil
+}
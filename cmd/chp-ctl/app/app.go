@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	hostutils "github.com/ironcore-dev/provider-utils/storeutils/host"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+type Options struct {
+	MachineStoreDir             string
+	CloudHypervisorSocketsPaths []string
+	Address                     string
+	AdminSocket                 string
+	Output                      string
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(
+		&o.MachineStoreDir,
+		"provider-machine-store-dir",
+		"/var/lib/chp/store",
+		"Path to the directory of the machine store.",
+	)
+	fs.StringSliceVar(
+		&o.CloudHypervisorSocketsPaths,
+		"cloud-hypervisor-sockets-path",
+		[]string{"/run/chp/ch/"},
+		"Paths to the cloud-hypervisor management sockets.",
+	)
+	fs.StringVar(
+		&o.Address,
+		"address",
+		"/run/chp/iri-machinebroker.sock",
+		"Address of the provider's iri socket.",
+	)
+	fs.StringVar(
+		&o.AdminSocket,
+		"admin-socket",
+		"/run/chp/admin.sock",
+		"Unix socket of the provider's admin api.",
+	)
+	fs.StringVarP(
+		&o.Output,
+		"output",
+		"o",
+		OutputTable,
+		fmt.Sprintf("Output format (%s, %s, %s).", OutputTable, OutputJSON, OutputYAML),
+	)
+}
+
+func Command() *cobra.Command {
+	var opts Options
+
+	cmd := &cobra.Command{
+		Use:          "chp-ctl",
+		Short:        "Inspect the state of the cloud-hypervisor-provider on this host.",
+		SilenceUsage: true,
+	}
+
+	opts.AddFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(
+		machinesCommand(&opts),
+		describeCommand(&opts),
+		socketsCommand(&opts),
+		eventsCommand(&opts),
+		requeueCommand(&opts),
+		recreateCommand(&opts),
+		consoleCommand(&opts),
+		vmConfigCommand(&opts),
+		vmmCommand(&opts),
+		rebootCommand(&opts),
+		migrateCommand(&opts),
+		upgradeCommand(&opts),
+		cloneCommand(&opts),
+		exportCommand(&opts),
+		importCommand(&opts),
+		maintenanceCommand(&opts),
+		portForwardCommand(&opts),
+	)
+
+	return cmd
+}
+
+func (o *Options) machineStore() (*hostutils.Store[*api.Machine], error) {
+	machineStore, err := hostutils.NewStore[*api.Machine](hostutils.Options[*api.Machine]{
+		Dir:     o.MachineStoreDir,
+		NewFunc: func() *api.Machine { return &api.Machine{} },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open machine store: %w", err)
+	}
+	return machineStore, nil
+}
+
+// print writes v as json or yaml, or calls table for the table output.
+func (o *Options) print(w io.Writer, v any, table func(w *tabwriter.Writer)) error {
+	switch o.Output {
+	case OutputTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q", o.Output)
+	}
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	irievent "github.com/ironcore-dev/ironcore/iri/apis/event/v1alpha1"
+	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// eventsPollInterval is how often --watch lists the events of a provider that cannot wait for new ones.
+const eventsPollInterval = 2 * time.Second
+
+func eventsCommand(opts *Options) *cobra.Command {
+	var (
+		machineID string
+		watch     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "List the recent events recorded by the provider.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if watch {
+				return watchEvents(cmd.Context(), opts, cmd.OutOrStdout(), machineID)
+			}
+
+			events, err := listEvents(cmd.Context(), opts.Address, machineID)
+			if err != nil {
+				return err
+			}
+			return opts.printEvents(cmd.OutOrStdout(), events, true)
+		},
+	}
+
+	cmd.Flags().StringVar(&machineID, "machine", "", "Only list events of the machine with this id.")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep printing new events until interrupted.")
+
+	return cmd
+}
+
+func (o *Options) printEvents(w io.Writer, events []*irievent.Event, header bool) error {
+	return o.print(w, events, func(w *tabwriter.Writer) {
+		if header {
+			_, _ = fmt.Fprintln(w, "AGE\tMACHINE\tTYPE\tREASON\tMESSAGE")
+		}
+		for _, evt := range events {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				duration.HumanDuration(time.Since(time.Unix(evt.Spec.EventTime, 0))),
+				evt.Spec.InvolvedObjectMeta.GetId(),
+				evt.Spec.Type,
+				evt.Spec.Reason,
+				evt.Spec.Message,
+			)
+		}
+	})
+}
+
+func dialProvider(address string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to provider: %w", err)
+	}
+	return conn, nil
+}
+
+// listEvents lists the events via the iri socket of the provider, since they are only kept in its memory.
+func listEvents(ctx context.Context, address, machineID string) ([]*irievent.Event, error) {
+	conn, err := dialProvider(address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	events, _, err := fetchEvents(ctx, iri.NewMachineRuntimeClient(conn), machineID, "")
+	return events, err
+}
+
+// watchEvents prints the events and then the ones recorded later. The provider holds each request until newer
+// events than the resource version of the previous response are recorded.
+func watchEvents(ctx context.Context, opts *Options, w io.Writer, machineID string) error {
+	conn, err := dialProvider(opts.Address)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	client := iri.NewMachineRuntimeClient(conn)
+
+	var (
+		resourceVersion string
+		seen            = map[string]struct{}{}
+		header          = true
+	)
+	for {
+		// The provider answers within 30s, even if no event was recorded.
+		reqCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		events, version, err := fetchEvents(reqCtx, client, machineID, resourceVersion)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var added []*irievent.Event
+		current := make(map[string]struct{}, len(events))
+		for _, evt := range events {
+			key := eventKey(evt)
+			current[key] = struct{}{}
+			if _, ok := seen[key]; !ok {
+				added = append(added, evt)
+			}
+		}
+		seen = current
+		if len(added) > 0 || header {
+			if err := opts.printEvents(w, added, header); err != nil {
+				return err
+			}
+			header = false
+		}
+
+		if version == "" {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(eventsPollInterval):
+			}
+		}
+		resourceVersion = version
+	}
+}
+
+func eventKey(evt *irievent.Event) string {
+	return fmt.Sprintf("%d/%s/%s/%s/%s", evt.Spec.EventTime, evt.Spec.InvolvedObjectMeta.GetId(), evt.Spec.Type,
+		evt.Spec.Reason, evt.Spec.Message)
+}
+
+// fetchEvents lists the events of the machine, or of all machines if machineID is empty, sorted by time. With a
+// resource version, the provider waits for newer events. It returns the resource version of the listed events,
+// which is empty if the provider does not report one.
+func fetchEvents(
+	ctx context.Context,
+	client iri.MachineRuntimeClient,
+	machineID, resourceVersion string,
+) ([]*irievent.Event, string, error) {
+	if resourceVersion != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, api.EventsResourceVersionKey, resourceVersion)
+	}
+
+	var header metadata.MD
+	resp, err := client.ListEvents(ctx, &iri.ListEventsRequest{}, grpc.Header(&header))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list events: %w", err)
+	}
+
+	var events []*irievent.Event
+	for _, evt := range resp.Events {
+		if machineID != "" && evt.Spec.InvolvedObjectMeta.GetId() != machineID {
+			continue
+		}
+		events = append(events, evt)
+	}
+	slices.SortFunc(events, func(a, b *irievent.Event) int {
+		return int(a.Spec.EventTime - b.Spec.EventTime)
+	})
+
+	var version string
+	if values := header.Get(api.EventsResourceVersionKey); len(values) > 0 {
+		version = values[0]
+	}
+	return events, version, nil
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	irievent "github.com/ironcore-dev/ironcore/iri/apis/event/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/utils/ptr"
+)
+
+const redacted = "<redacted>"
+
+func machinesCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:     "machines",
+		Aliases: []string{"machine", "m"},
+		Short:   "List the machines of the machine store.",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			machineStore, err := opts.machineStore()
+			if err != nil {
+				return err
+			}
+
+			machines, err := machineStore.List(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to list machines: %w", err)
+			}
+			slices.SortFunc(machines, func(a, b *api.Machine) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+			for _, machine := range machines {
+				redactSecrets(machine)
+			}
+
+			return opts.print(cmd.OutOrStdout(), machines, func(w *tabwriter.Writer) {
+				_, _ = fmt.Fprintln(w, "ID\tCLASS\tPOWER\tSTATE\tSOCKET\tVOLUMES\tNICS\tAGE")
+				for _, machine := range machines {
+					_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+						machine.ID,
+						machine.Labels[api.ClassLabel],
+						powerString(machine.Spec.Power),
+						stateString(machine),
+						ptr.Deref(machine.Spec.ApiSocketPath, "<none>"),
+						volumeSummary(machine),
+						nicSummary(machine),
+						duration.HumanDuration(time.Since(machine.CreatedAt)),
+					)
+				}
+			})
+		},
+	}
+}
+
+type machineDescription struct {
+	Machine *api.Machine      `json:"machine"`
+	VM      *client.VmInfo    `json:"vm,omitempty"`
+	VMError string            `json:"vmError,omitempty"`
+	Events  []*irievent.Event `json:"events,omitempty"`
+}
+
+func describeCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe <machine-id>",
+		Short: "Show a machine including its volumes, nics, vm and recent events.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			machineStore, err := opts.machineStore()
+			if err != nil {
+				return err
+			}
+
+			machine, err := machineStore.Get(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("failed to get machine: %w", err)
+			}
+			redactSecrets(machine)
+
+			desc := machineDescription{Machine: machine}
+			if socket := ptr.Deref(machine.Spec.ApiSocketPath, ""); socket != "" {
+				desc.VM, err = getVM(ctx, socket)
+				if err != nil {
+					desc.VMError = err.Error()
+				}
+			}
+
+			// Events are only kept in memory by the provider, so they are not available if it is down.
+			desc.Events, err = listEvents(ctx, opts.Address, machine.ID)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+
+			return opts.print(cmd.OutOrStdout(), desc, func(w *tabwriter.Writer) {
+				printDescription(w, desc)
+			})
+		},
+	}
+}
+
+func getVM(ctx context.Context, socket string) (*client.VmInfo, error) {
+	apiClient, err := vmm.NewUnixSocketClient(socket)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := apiClient.GetVmInfoWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status(), strings.TrimSpace(string(resp.Body)))
+	}
+	return resp.JSON200, nil
+}
+
+func printDescription(w *tabwriter.Writer, desc machineDescription) {
+	machine := desc.Machine
+
+	_, _ = fmt.Fprintf(w, "ID:\t%s\n", machine.ID)
+	_, _ = fmt.Fprintf(w, "Class:\t%s\n", machine.Labels[api.ClassLabel])
+	_, _ = fmt.Fprintf(w, "Created:\t%s\n", machine.CreatedAt.Format(time.RFC3339))
+	if machine.DeletedAt != nil {
+		_, _ = fmt.Fprintf(w, "Deleted:\t%s\n", machine.DeletedAt.Format(time.RFC3339))
+	}
+	_, _ = fmt.Fprintf(w, "Finalizers:\t%s\n", strings.Join(machine.Finalizers, ","))
+	_, _ = fmt.Fprintf(w, "Power:\t%s\n", powerString(machine.Spec.Power))
+	_, _ = fmt.Fprintf(w, "State:\t%s\n", stateString(machine))
+	if bootedAt := machine.Status.BootedAt; bootedAt != nil {
+		_, _ = fmt.Fprintf(w, "Booted:\t%s (up %s)\n", bootedAt.Format(time.RFC3339),
+			duration.HumanDuration(time.Since(*bootedAt)))
+	}
+	if machine.Status.BootDuration != 0 {
+		_, _ = fmt.Fprintf(w, "Boot Duration:\t%s\n", machine.Status.BootDuration.Round(time.Millisecond))
+	}
+	_, _ = fmt.Fprintf(w, "Resources:\t%d cpu, %d bytes memory\n", machine.Spec.Cpu, machine.Spec.MemoryBytes)
+	if utilization := machine.Status.Utilization; utilization != nil {
+		_, _ = fmt.Fprintf(w, "Utilization:\t%dm cpu, %d bytes memory (%s ago)\n", utilization.CpuMillis,
+			utilization.MemoryBytes, duration.HumanDuration(time.Since(utilization.SampledAt)))
+		if utilization.ReclaimedMemoryBytes != 0 {
+			_, _ = fmt.Fprintf(w, "Reclaimed:\t%d bytes memory\n", utilization.ReclaimedMemoryBytes)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "Image:\t%s\n", ptr.Deref(api.HasBootImage(machine), "<none>"))
+	_, _ = fmt.Fprintf(w, "Socket:\t%s\n", ptr.Deref(machine.Spec.ApiSocketPath, "<none>"))
+
+	switch {
+	case desc.VM != nil:
+		_, _ = fmt.Fprintf(w, "VM:\t%s\n", desc.VM.State)
+	case desc.VMError != "":
+		_, _ = fmt.Fprintf(w, "VM:\t<error: %s>\n", desc.VMError)
+	}
+	for _, condition := range machine.Status.Conditions {
+		_, _ = fmt.Fprintf(w, "Condition:\t%s: %s (since %s)\n",
+			condition.Type, condition.Message, condition.LastTransitionTime.Format(time.RFC3339))
+	}
+
+	_, _ = fmt.Fprintln(w, "\nVolumes:")
+	_, _ = fmt.Fprintln(w, "  NAME\tTYPE\tSTATE\tSIZE\tPATH\tREAD BYTES\tWRITE BYTES")
+	for _, status := range machine.Status.VolumeStatus {
+		read, written := "-", "-"
+		if status.Stats != nil {
+			read, written = strconv.FormatInt(status.Stats.ReadBytes, 10), strconv.FormatInt(status.Stats.WriteBytes, 10)
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			status.Name, status.Type, status.State, status.Size, status.Path, read, written)
+	}
+
+	_, _ = fmt.Fprintln(w, "\nNetwork Interfaces:")
+	_, _ = fmt.Fprintln(w, "  NAME\tTYPE\tSTATE\tPATH\tRX BYTES\tTX BYTES")
+	for _, status := range machine.Status.NetworkInterfaceStatus {
+		rx, tx := "-", "-"
+		if status.Stats != nil {
+			rx, tx = strconv.FormatInt(status.Stats.RxBytes, 10), strconv.FormatInt(status.Stats.TxBytes, 10)
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", status.Name, status.Type, status.State, status.Path, rx, tx)
+	}
+
+	_, _ = fmt.Fprintln(w, "\nEvents:")
+	_, _ = fmt.Fprintln(w, "  AGE\tTYPE\tREASON\tMESSAGE")
+	for _, evt := range desc.Events {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n",
+			duration.HumanDuration(time.Since(time.Unix(evt.Spec.EventTime, 0))),
+			evt.Spec.Type,
+			evt.Spec.Reason,
+			evt.Spec.Message,
+		)
+	}
+}
+
+func powerString(power api.PowerState) string {
+	switch power {
+	case api.PowerStatePowerOn:
+		return "On"
+	case api.PowerStatePowerOff:
+		return "Off"
+	default:
+		return fmt.Sprintf("Unknown(%d)", power)
+	}
+}
+
+func stateString(machine *api.Machine) string {
+	if machine.DeletedAt != nil {
+		return "Deleting"
+	}
+	if machine.Status.State == "" {
+		return string(api.MachineStatePending)
+	}
+	return string(machine.Status.State)
+}
+
+func volumeSummary(machine *api.Machine) string {
+	ready := 0
+	for _, status := range machine.Status.VolumeStatus {
+		if status.State == api.VolumeStateAttached {
+			ready++
+		}
+	}
+	return fmt.Sprintf("%d/%d", ready, len(machine.Spec.Volumes))
+}
+
+func nicSummary(machine *api.Machine) string {
+	ready := 0
+	for _, status := range machine.Status.NetworkInterfaceStatus {
+		if status.State == api.NetworkInterfaceStateAttached {
+			ready++
+		}
+	}
+	return fmt.Sprintf("%d/%d", ready, len(machine.Spec.NetworkInterfaces))
+}
+
+// redactSecrets removes the volume secrets, which must not end up in a terminal or a ticket.
+func redactSecrets(machine *api.Machine) {
+	for _, vol := range machine.Spec.Volumes {
+		if vol.Connection == nil {
+			continue
+		}
+		for key := range vol.Connection.SecretData {
+			vol.Connection.SecretData[key] = []byte(redacted)
+		}
+		for key := range vol.Connection.EncryptionData {
+			vol.Connection.EncryptionData[key] = []byte(redacted)
+		}
+	}
+}
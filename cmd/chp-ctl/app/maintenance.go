@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/admin"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	"github.com/spf13/cobra"
+)
+
+func maintenanceCommand(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Show or switch the maintenance mode of the host.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var status admin.MaintenanceStatus
+			if err := opts.adminRequest(cmd.Context(), http.MethodGet, "/v1/maintenance", nil, http.StatusOK, &status); err != nil {
+				return err
+			}
+			return opts.printMaintenanceStatus(cmd, &status)
+		},
+	}
+
+	var evacuation string
+	enable := &cobra.Command{
+		Use:   "enable",
+		Short: "Stop admitting new machines and evacuate the existing ones.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return opts.setMaintenance(cmd, admin.MaintenanceRequest{
+				Enabled:    true,
+				Evacuation: maintenance.Evacuation(evacuation),
+			})
+		},
+	}
+	enable.Flags().StringVar(&evacuation, "evacuation", string(maintenance.EvacuationNone),
+		fmt.Sprintf("How machines are evacuated (%s, %s, %s).",
+			maintenance.EvacuationNone, maintenance.EvacuationShutdown, maintenance.EvacuationSnapshot))
+
+	disable := &cobra.Command{
+		Use:   "disable",
+		Short: "Admit machines again and restore evacuated ones.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return opts.setMaintenance(cmd, admin.MaintenanceRequest{Enabled: false})
+		},
+	}
+
+	cmd.AddCommand(enable, disable)
+	return cmd
+}
+
+func (o *Options) setMaintenance(cmd *cobra.Command, req admin.MaintenanceRequest) error {
+	var status admin.MaintenanceStatus
+	if err := o.adminRequest(cmd.Context(), http.MethodPut, "/v1/maintenance", req, http.StatusOK, &status); err != nil {
+		return err
+	}
+	return o.printMaintenanceStatus(cmd, &status)
+}
+
+func (o *Options) printMaintenanceStatus(cmd *cobra.Command, status *admin.MaintenanceStatus) error {
+	return o.print(cmd.OutOrStdout(), status, func(w *tabwriter.Writer) {
+		_, _ = fmt.Fprintf(w, "Enabled:\t%t\n", status.Enabled)
+		if !status.Enabled {
+			return
+		}
+		_, _ = fmt.Fprintf(w, "Evacuation:\t%s\n", status.Evacuation)
+		_, _ = fmt.Fprintf(w, "Since:\t%s\n", status.Since.Format(time.RFC3339))
+		_, _ = fmt.Fprintf(w, "Drained:\t%t\n", status.Drained)
+		if len(status.Undrained) > 0 {
+			_, _ = fmt.Fprintf(w, "Undrained:\t%s\n", strings.Join(status.Undrained, ", "))
+		}
+	})
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vsock"
+	"github.com/spf13/cobra"
+	"k8s.io/utils/ptr"
+)
+
+func portForwardCommand(opts *Options) *cobra.Command {
+	var listen string
+
+	cmd := &cobra.Command{
+		Use:   "port-forward <machine-id> <guest-port>",
+		Short: "Forward a local tcp port or unix socket to a vsock port of a guest, e.g. to debug isolated machines.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			port, err := strconv.ParseUint(args[1], 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid guest port %q: %w", args[1], err)
+			}
+
+			socketPath, err := opts.vsockSocket(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			if listen == "" {
+				listen = fmt.Sprintf("tcp:127.0.0.1:%d", port)
+			}
+			network, address, ok := strings.Cut(listen, ":")
+			if !ok || (network != "tcp" && network != "unix") {
+				return fmt.Errorf("invalid listen address %q: expected tcp:<host:port> or unix:<path>", listen)
+			}
+
+			l, err := net.Listen(network, address)
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			go func() {
+				<-ctx.Done()
+				_ = l.Close()
+			}()
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Forwarding %s to port %d of machine %s\n", l.Addr(), port, args[0])
+			return forward(ctx, l, socketPath, uint32(port), cmd.ErrOrStderr())
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&listen,
+		"listen",
+		"",
+		"Address to listen on, tcp:<host:port> or unix:<path>. Defaults to tcp:127.0.0.1:<guest-port>.",
+	)
+
+	return cmd
+}
+
+// vsockSocket returns the unix socket the vsock of the VM of the machine is proxied through.
+func (o *Options) vsockSocket(ctx context.Context, machineID string) (string, error) {
+	machineStore, err := o.machineStore()
+	if err != nil {
+		return "", err
+	}
+	machine, err := machineStore.Get(ctx, machineID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get machine: %w", err)
+	}
+
+	socket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	if socket == "" {
+		return "", fmt.Errorf("machine %s has no VM", machineID)
+	}
+	vm, err := getVM(ctx, socket)
+	if err != nil {
+		return "", fmt.Errorf("failed to get VM: %w", err)
+	}
+	if vm.Config.Vsock == nil {
+		return "", fmt.Errorf("VM of machine %s has no vsock device, it is enabled by --metadata-vsock-port", machineID)
+	}
+	return vm.Config.Vsock.Socket, nil
+}
+
+// forward copies the connections accepted by l to the port of the guest until l is closed. A unix socket is
+// removed when l is closed.
+func forward(ctx context.Context, l net.Listener, socketPath string, port uint32, errOut io.Writer) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		go func() {
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			guestConn, err := vsock.Dial(ctx, socketPath, port)
+			if err != nil {
+				_, _ = fmt.Fprintf(errOut, "%v\n", err)
+				return
+			}
+			defer func() {
+				_ = guestConn.Close()
+			}()
+
+			done := make(chan struct{}, 2)
+			for _, pipe := range [][2]net.Conn{{guestConn, conn}, {conn, guestConn}} {
+				go func() {
+					_, _ = io.Copy(pipe[0], pipe[1])
+					closeWrite(pipe[0])
+					done <- struct{}{}
+				}()
+			}
+			<-done
+			<-done
+		}()
+	}
+}
+
+// closeWrite signals the end of the stream to the other side, which may still respond.
+func closeWrite(conn net.Conn) {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = c.CloseWrite()
+		return
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"github.com/spf13/cobra"
+	"k8s.io/utils/ptr"
+)
+
+type socketInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Pid     int64  `json:"pid,omitempty"`
+	VMState string `json:"vmState,omitempty"`
+	Machine string `json:"machine,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func socketsCommand(opts *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:     "sockets",
+		Aliases: []string{"socket", "s"},
+		Short:   "List the cloud-hypervisor sockets, their state and the machines assigned to them.",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			machineStore, err := opts.machineStore()
+			if err != nil {
+				return err
+			}
+
+			machines, err := machineStore.List(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list machines: %w", err)
+			}
+			assignments := map[string]string{}
+			for _, machine := range machines {
+				if socket := ptr.Deref(machine.Spec.ApiSocketPath, ""); socket != "" {
+					assignments[socket] = machine.ID
+				}
+			}
+
+			var sockets []socketInfo
+			for _, dir := range opts.CloudHypervisorSocketsPaths {
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					return fmt.Errorf("failed to read cloud-hypervisor sockets dir: %w", err)
+				}
+
+				for _, entry := range entries {
+					if entry.IsDir() || filepath.Ext(entry.Name()) != ".sock" {
+						continue
+					}
+
+					socket := filepath.Join(dir, entry.Name())
+					info := inspectSocket(ctx, socket)
+					info.Machine = assignments[socket]
+					delete(assignments, socket)
+					sockets = append(sockets, info)
+				}
+			}
+
+			// Machines assigned to sockets that do not exist anymore.
+			for socket, machineID := range assignments {
+				sockets = append(sockets, socketInfo{Path: socket, Machine: machineID, Error: "socket not found"})
+			}
+
+			return opts.print(cmd.OutOrStdout(), sockets, func(w *tabwriter.Writer) {
+				_, _ = fmt.Fprintln(w, "SOCKET\tVERSION\tPID\tVM\tMACHINE\tERROR")
+				for _, s := range sockets {
+					_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", s.Path, s.Version, s.Pid, s.VMState, s.Machine, s.Error)
+				}
+			})
+		},
+	}
+}
+
+func inspectSocket(ctx context.Context, socket string) socketInfo {
+	info := socketInfo{Path: socket}
+
+	apiClient, err := vmm.NewUnixSocketClient(socket)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ping, err := apiClient.GetVmmPingWithResponse(ctx)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	if ping.JSON200 != nil {
+		info.Version = ping.JSON200.Version
+		info.Pid = ptr.Deref(ping.JSON200.Pid, 0)
+	}
+
+	vm, err := apiClient.GetVmInfoWithResponse(ctx)
+	switch {
+	case err != nil:
+		info.Error = err.Error()
+	case vm.JSON200 != nil:
+		info.VMState = string(vm.JSON200.State)
+	case strings.Contains(string(vm.Body), "VM is not created"):
+		info.VMState = "<none>"
+	default:
+		info.Error = fmt.Sprintf("unexpected status %s: %s", vm.Status(), strings.TrimSpace(string(vm.Body)))
+	}
+	return info
+}
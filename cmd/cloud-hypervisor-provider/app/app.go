@@ -9,57 +9,190 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/configdrive"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/console"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/controllers"
-	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/health"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/machinelog"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/metadata"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/migration"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/oci"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/networkinterface/options"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume/ceph"
-	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume/localdisk"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/quota"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/raw"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/server"
-	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/strategy"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/stats"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
-	"github.com/ironcore-dev/ironcore-image/oci/remote"
-	ocistore "github.com/ironcore-dev/ironcore-image/oci/store"
 	"github.com/ironcore-dev/ironcore/broker/common"
 	commongrpc "github.com/ironcore-dev/ironcore/broker/common/grpc"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
-	"github.com/ironcore-dev/provider-utils/eventutils/event"
-	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
-	ocihostutils "github.com/ironcore-dev/provider-utils/ociutils/host"
-	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
-	hostutils "github.com/ironcore-dev/provider-utils/storeutils/host"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
-	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
+const (
+	ImageCacheBackendLocal      = "local"
+	ImageCacheBackendContainerd = "containerd"
+)
+
+const (
+	EventSinkKubernetes = "kubernetes"
+	EventSinkWebhook    = "webhook"
+)
+
+// maxNICPCISegments leaves the default segment out of the 16 PCI segments cloud-hypervisor supports.
+const maxNICPCISegments = 15
+
 type Options struct {
-	Address string
+	ConfigFile string
+	// config is the loaded config file, nil without config file.
+	config *config
+
+	Address            string
+	MetricsBindAddress string
+	HealthBindAddress  string
+	AdminSocket        string
 
 	RootDir         string
 	MachineStoreDir string
 
 	MachineClasses MachineClassOptions
 
-	CloudHypervisorSocketsPath  string
-	CloudHypervisorFirmwarePath string
+	PrefetchImages []string
+
+	ImageCacheBackend    string
+	ContainerdAddress    string
+	ContainerdNamespace  string
+	ContainerdContentDir string
+
+	RawImplementation string
+	MemoryDiskDir     string
+
+	VMMMode          string
+	FakeVMMInstances int
+	DetachVms        bool
+	DeleteForeignVms bool
+	VMInfoCacheTTL   time.Duration
+	VMMAPIRateLimit  float64
+	VMMAPIBurst      int
+
+	ShutdownGracePeriod time.Duration
+	Workers             int
+	ResyncInterval      time.Duration
+	DrainTimeout        time.Duration
+
+	StatsInterval        time.Duration
+	VolumeHealthInterval time.Duration
+	VolumeGCInterval     time.Duration
+
+	ConsoleScrollbackSize int
+
+	MachineLogVerbosity  int
+	MachineLogMaxSize    int64
+	MachineLogMaxBackups int
+
+	EventSinks          []string
+	EventSinkKubeconfig string
+	EventSinkWebhookURL string
+
+	Maintenance           bool
+	MaintenanceEvacuation string
+
+	MigrationBindAddress      string
+	MigrationAdvertiseAddress string
+	MigrationTLS              migration.TLSOptions
+
+	CloudHypervisorSocketsPaths     []string
+	SocketSelection                 string
+	CloudHypervisorNUMANodes        map[string]int
+	CloudHypervisorFirmwarePath     string
+	CloudHypervisorMinVersion       string
+	CloudHypervisorRequiredFeatures []string
+
+	QMPSocketPath     string
+	CephProvider      string
+	StorageDaemonPath string
+
+	CephClonePool     string
+	CephCloneMonitors []string
+	CephCloneUser     string
+	CephCloneKeyFile  string
+
+	IgnitionTransport   string
+	IgnitionCompression bool
+	ConfigDriveISOTool  string
+
+	MetadataVsockPort        uint32
+	MetadataLinkLocalAddress string
+
+	NICPCISegments int16
+
+	GuestDNSServers []string
+	GuestDNSSearch  []string
+
+	SystemReserved map[string]string
+
+	QuotaLabel string
+	Quotas     QuotaOptions
+
+	OEMStringLabels      []string
+	OEMStringAnnotations []string
+
+	CgroupRoot string
+
+	ChownMachineDirs   bool
+	SELinuxFileContext string
 
-	QMPSocketPath string
+	Landlock      bool
+	LandlockRules []string
+
+	VMConfigOverrides         map[string]string
+	VMConfigOverrideAllowlist []string
 
 	NicPlugin *options.Options
 }
 
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(
+		&o.ConfigFile,
+		configFlag,
+		"",
+		"YAML file setting options by their flag name. Options given on the command line take precedence. "+
+			"Changes of the machine classes and the maintenance are applied while running.",
+	)
 	fs.StringVar(&o.Address, "address", "/run/chp/iri-machinebroker.sock", "Address to listen on.")
+	fs.StringVar(
+		&o.MetricsBindAddress,
+		"metrics-bind-address",
+		"0",
+		"Address the metrics endpoint binds to. Use 0 to disable serving metrics.",
+	)
+	fs.StringVar(
+		&o.HealthBindAddress,
+		"health-probe-bind-address",
+		"0",
+		"Address the /healthz and /readyz endpoints bind to. Use 0 to disable serving them.",
+	)
+	fs.StringVar(
+		&o.AdminSocket,
+		"admin-socket",
+		"/run/chp/admin.sock",
+		"Unix socket of the admin api used by chp-ctl. The admin api is disabled if empty.",
+	)
 
 	fs.StringVar(
 		&o.RootDir,
@@ -81,12 +214,246 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"/run/chp/qmp/sock",
 		"Path to the qmp socket.",
 	)
+	fs.StringVar(
+		&o.CephProvider,
+		"ceph-provider",
+		string(ceph.ProviderTypeQMP),
+		fmt.Sprintf("Provider of the ceph volumes: %s connects to a running qemu-storage-daemon, %s starts it if "+
+			"it is not running.", ceph.ProviderTypeQMP, ceph.ProviderTypeQemuStorage),
+	)
+	fs.StringVar(
+		&o.StorageDaemonPath,
+		"qemu-storage-daemon-path",
+		"/usr/local/bin/qemu-storage-daemon",
+		"Path to the qemu-storage-daemon binary started by the qemu-storage ceph provider.",
+	)
 
 	fs.StringVar(
-		&o.CloudHypervisorSocketsPath,
+		&o.CephClonePool,
+		"ceph-clone-pool",
+		"",
+		"Ceph pool the root disks of classes with root-disk-medium=ceph are cloned in. "+
+			"Ceph root disks are not supported if empty.",
+	)
+	fs.StringSliceVar(
+		&o.CephCloneMonitors,
+		"ceph-clone-monitors",
+		nil,
+		"Monitors (host:port) of the ceph cluster of the clone pool.",
+	)
+	fs.StringVar(
+		&o.CephCloneUser,
+		"ceph-clone-user",
+		"",
+		"Ceph user managing the images of the clone pool.",
+	)
+	fs.StringVar(
+		&o.CephCloneKeyFile,
+		"ceph-clone-key-file",
+		"",
+		"Path to the file containing the key of the ceph clone user.",
+	)
+
+	fs.StringVar(
+		&o.VMMMode,
+		"vmm-mode",
+		vmm.ModeReal,
+		fmt.Sprintf("Backend to run VMs with. Use %q to simulate cloud-hypervisor in memory, e.g. for tests on hosts without KVM.",
+			vmm.ModeFake),
+	)
+
+	fs.IntVar(
+		&o.FakeVMMInstances,
+		"fake-vmm-instances",
+		vmm.DefaultFakeInstances,
+		"Number of simulated cloud-hypervisor instances if --vmm-mode=fake.",
+	)
+
+	fs.BoolVar(
+		&o.DetachVms,
+		"detach-vms",
+		false,
+		"Adopt the VMs running on the cloud-hypervisor instances on startup by matching their uuid to the "+
+			"stored machines, instead of relying on the recorded api sockets.",
+	)
+	fs.BoolVar(
+		&o.DeleteForeignVms,
+		"delete-foreign-vms",
+		false,
+		"Delete a VM found on the api socket of a machine if its uuid belongs to no stored machine. "+
+			"Otherwise the socket is left to the VM and the machine moves to a new socket.",
+	)
+
+	fs.DurationVar(
+		&o.VMInfoCacheTTL,
+		"vm-info-cache-ttl",
+		vmm.DefaultVMInfoCacheTTL,
+		"Time the VM info of a cloud-hypervisor instance is reused by the reconciliations, unless a call changes "+
+			"the VM or the instance reports an event. Changes made by the guest itself, e.g. a shutdown, are "+
+			"noticed after it at the latest if the instance has no event monitor. Disabled if 0.",
+	)
+	fs.Float64Var(
+		&o.VMMAPIRateLimit,
+		"vmm-api-rate-limit",
+		vmm.DefaultAPIRateLimit,
+		"Calls per second the provider makes to the api of each cloud-hypervisor instance at most, the calls "+
+			"beyond wait for their turn. Disabled if 0.",
+	)
+	fs.IntVar(
+		&o.VMMAPIBurst,
+		"vmm-api-burst",
+		vmm.DefaultAPIBurst,
+		"Calls the provider makes to the api of a cloud-hypervisor instance at once before the rate limit applies.",
+	)
+
+	fs.DurationVar(
+		&o.ShutdownGracePeriod,
+		"shutdown-grace-period",
+		controllers.DefaultShutdownGracePeriod,
+		"Time a guest gets to shut down after the shutdown deadline of its machine before the VM is powered off.",
+	)
+	fs.IntVar(
+		&o.Workers,
+		"workers",
+		controllers.DefaultWorkers,
+		"Number of machines reconciled concurrently.",
+	)
+	fs.DurationVar(
+		&o.ResyncInterval,
+		"resync-interval",
+		controllers.DefaultResyncInterval,
+		"Interval machines are reconciled in without changes, jittered by up to 20%. 0 disables the resync.",
+	)
+	fs.DurationVar(
+		&o.DrainTimeout,
+		"shutdown-drain-timeout",
+		controllers.DefaultDrainTimeout,
+		"Time the reconciliations in flight get to finish on shutdown before they are canceled.",
+	)
+
+	fs.DurationVar(
+		&o.StatsInterval,
+		"stats-interval",
+		stats.DefaultInterval,
+		"Interval the resource usage of the VMs is sampled in. Disabled if 0.",
+	)
+
+	fs.DurationVar(
+		&o.VolumeHealthInterval,
+		"volume-health-interval",
+		health.DefaultInterval,
+		"Interval the attached volumes are probed in. Unavailable vhost-user volumes are mounted and attached "+
+			"again. Disabled if 0.",
+	)
+
+	fs.DurationVar(
+		&o.VolumeGCInterval,
+		"volume-gc-interval",
+		volume.DefaultGarbageCollectionInterval,
+		"Interval the storage daemon exports and volume directories of deleted volumes are removed in, "+
+			"starting on startup. Disabled if 0.",
+	)
+
+	fs.StringSliceVar(
+		&o.EventSinks,
+		"event-sink",
+		nil,
+		fmt.Sprintf("External sinks the machine events are forwarded to (%s, %s).", EventSinkKubernetes,
+			EventSinkWebhook),
+	)
+	fs.StringVar(
+		&o.EventSinkKubeconfig,
+		"event-sink-kubeconfig",
+		"",
+		"Path to the kubeconfig of the cluster the kubernetes event sink creates the events in. "+
+			"The cluster the provider runs in if empty.",
+	)
+	fs.StringVar(
+		&o.EventSinkWebhookURL,
+		"event-sink-webhook-url",
+		"",
+		"URL the webhook event sink posts the events to.",
+	)
+
+	fs.IntVar(
+		&o.ConsoleScrollbackSize,
+		"console-scrollback-size",
+		console.DefaultScrollbackSize,
+		"Bytes of serial console output kept in memory per machine. Disabled if 0.",
+	)
+
+	fs.IntVar(
+		&o.MachineLogVerbosity,
+		"machine-log-verbosity",
+		machinelog.DefaultVerbosity,
+		"Highest verbosity of the log records of a machine written to the log file in its machine directory. "+
+			"Disabled if negative.",
+	)
+	fs.Int64Var(
+		&o.MachineLogMaxSize,
+		"machine-log-max-size",
+		machinelog.DefaultMaxSize,
+		"Bytes a machine log file is rotated at.",
+	)
+	fs.IntVar(
+		&o.MachineLogMaxBackups,
+		"machine-log-max-backups",
+		machinelog.DefaultMaxBackups,
+		"Rotated machine log files kept per machine.",
+	)
+
+	fs.BoolVar(
+		&o.Maintenance,
+		"maintenance",
+		false,
+		"Start with the host in maintenance: no new machines are admitted and existing ones are evacuated. "+
+			"Can be switched at runtime via chp-ctl.",
+	)
+
+	fs.StringVar(
+		&o.MaintenanceEvacuation,
+		"maintenance-evacuation",
+		string(maintenance.EvacuationNone),
+		fmt.Sprintf("How machines are evacuated during maintenance (%s, %s, %s).",
+			maintenance.EvacuationNone, maintenance.EvacuationShutdown, maintenance.EvacuationSnapshot),
+	)
+
+	fs.StringVar(
+		&o.MigrationBindAddress,
+		"migration-bind-address",
+		"",
+		"Address the migration api other providers hand off machines to listens on. Disabled if empty.",
+	)
+	fs.StringVar(
+		&o.MigrationAdvertiseAddress,
+		"migration-advertise-address",
+		"",
+		"Host other providers send migrated VMs to. Defaults to the hostname.",
+	)
+	fs.StringVar(&o.MigrationTLS.CertFile, "migration-tls-cert", "",
+		"Certificate of the migration api and its client. Migrations are unencrypted if empty.")
+	fs.StringVar(&o.MigrationTLS.KeyFile, "migration-tls-key", "", "Key of the migration certificate.")
+	fs.StringVar(&o.MigrationTLS.CAFile, "migration-tls-ca", "", "CA verifying the certificates of other providers.")
+
+	fs.StringSliceVar(
+		&o.CloudHypervisorSocketsPaths,
 		"cloud-hypervisor-sockets-path",
-		"/run/chp/ch/",
-		"Path to the cloud-hypervisor management sockets.",
+		[]string{"/run/chp/ch/"},
+		"Paths to the cloud-hypervisor management sockets. Can be given multiple times, e.g. per NUMA node.",
+	)
+	fs.StringVar(
+		&o.SocketSelection,
+		"socket-selection",
+		string(vmm.SocketSelectionRandom),
+		fmt.Sprintf("Policy the socket of a new machine is chosen from the sockets paths with (%s, %s, %s, %s).",
+			vmm.SocketSelectionRandom, vmm.SocketSelectionSpread, vmm.SocketSelectionFill, vmm.SocketSelectionNUMA),
+	)
+	fs.StringToIntVar(
+		&o.CloudHypervisorNUMANodes,
+		"cloud-hypervisor-numa-nodes",
+		nil,
+		"NUMA nodes of the cloud-hypervisor sockets paths (format: path=node). The instances of a path are pinned "+
+			"to the cpus and memory of its node via their machine cgroup, which requires --cgroup-root.",
 	)
 
 	fs.StringVar(
@@ -96,10 +463,227 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"Path to the cloud-hypervisor firmware.",
 	)
 
+	fs.StringVar(
+		&o.CloudHypervisorMinVersion,
+		"cloud-hypervisor-min-version",
+		vmm.DefaultMinVersion,
+		"Minimum cloud-hypervisor version. Sockets of older instances are not used.",
+	)
+
+	fs.StringSliceVar(
+		&o.CloudHypervisorRequiredFeatures,
+		"cloud-hypervisor-required-features",
+		nil,
+		"Cloud-hypervisor features an instance has to report to be used.",
+	)
+
+	fs.StringVar(
+		&o.CgroupRoot,
+		"cgroup-root",
+		"",
+		"cgroup v2 directory to create the per-machine cgroups of the cloud-hypervisor processes in. "+
+			"Processes are not confined if empty.",
+	)
+
+	fs.BoolVar(
+		&o.ChownMachineDirs,
+		"chown-machine-dirs",
+		false,
+		"Change the owner of machine directories to the owner of the machine's api socket. "+
+			"Required if the cloud-hypervisor instances run as distinct users.",
+	)
+
+	fs.StringVar(
+		&o.SELinuxFileContext,
+		"selinux-file-context",
+		"",
+		"SELinux context to label machine directories with (e.g. system_u:object_r:svirt_image_t:s0).",
+	)
+
+	fs.BoolVar(
+		&o.Landlock,
+		"landlock",
+		false,
+		"Enable landlock for VMs of machine classes that do not configure it.",
+	)
+
+	fs.StringSliceVar(
+		&o.LandlockRules,
+		"landlock-rules",
+		nil,
+		"Additional paths landlocked VMs may access (format: path:access, e.g. /dev/vfio:rw).",
+	)
+
+	fs.StringToStringVar(
+		&o.VMConfigOverrides,
+		"vm-config-overrides",
+		nil,
+		"Fields set in the cloud-hypervisor config of every VM, by dot separated path (e.g. memory.thp=false). "+
+			"Values are parsed as JSON, or taken as string if they are none. Machine classes override them "+
+			"with vm-config.<path>=<value> options.",
+	)
+
+	fs.StringSliceVar(
+		&o.VMConfigOverrideAllowlist,
+		"vm-config-override-allowlist",
+		vmm.DefaultVMConfigOverrideAllowlist,
+		"Fields of the cloud-hypervisor config that may be overridden, an entry allows the fields below it too.",
+	)
+
 	fs.Var(
 		&o.MachineClasses,
 		"machine-class",
-		"Supported machine classes (format: name,cpu,memory)",
+		"Supported machine classes (format: name,cpu,memory[,key=value...]). Options: landlock=<bool>, "+
+			"topology=<sockets>x<cores>x<threads>, amx=<bool>, kvm-hyperv=<bool>, max-phys-bits=<bits>, "+
+			"sgx-epc=<bytes>, clock=<kvm|ptp>, free-page-reporting=<bool>, "+
+			"shared-memory=<bool>, hugepages=<size>, memory-file=<path>, root-disk=<size>, "+
+			"root-disk-medium=ceph, memory-disk=<size>.",
+	)
+
+	fs.StringSliceVar(
+		&o.PrefetchImages,
+		"prefetch-image",
+		nil,
+		"Images to pull into the image cache on startup, before machines are scheduled.",
+	)
+
+	fs.StringVar(
+		&o.ImageCacheBackend,
+		"image-cache-backend",
+		ImageCacheBackendLocal,
+		fmt.Sprintf("Backend of the image cache (%s, %s).", ImageCacheBackendLocal, ImageCacheBackendContainerd),
+	)
+
+	fs.StringVar(
+		&o.ContainerdAddress,
+		"containerd-address",
+		oci.DefaultContainerdAddress,
+		"Socket of containerd, which pulls the images of the containerd image cache backend.",
+	)
+	fs.StringVar(
+		&o.ContainerdNamespace,
+		"containerd-namespace",
+		oci.DefaultContainerdNamespace,
+		"Containerd namespace the containerd image cache backend pulls its images into.",
+	)
+	fs.StringVar(
+		&o.ContainerdContentDir,
+		"containerd-content-dir",
+		oci.DefaultContainerdContentDir,
+		"Root of the content store of containerd, the layers of the pulled images are read from it.",
+	)
+
+	fs.StringVar(
+		&o.RawImplementation,
+		"raw-implementation",
+		raw.Default(),
+		fmt.Sprintf("Implementation creating the raw disks of local disks (%s).", strings.Join(raw.Available(), ", ")),
+	)
+
+	fs.StringVar(
+		&o.MemoryDiskDir,
+		"memory-disk-dir",
+		"/dev/shm/cloud-hypervisor-provider",
+		"Directory on a tmpfs memory disks are stored in. Memory disks are not supported if empty.",
+	)
+
+	fs.StringVar(
+		&o.IgnitionTransport,
+		"ignition-transport",
+		string(api.IgnitionTransportOEMStrings),
+		fmt.Sprintf("Default transport of the ignition payload into the guest (%s, %s). "+
+			"Can be overridden per machine with the %s annotation.",
+			api.IgnitionTransportOEMStrings, api.IgnitionTransportConfigDrive, api.IgnitionTransportAnnotation),
+	)
+
+	fs.Int16Var(
+		&o.NICPCISegments,
+		"nic-pci-segments",
+		0,
+		fmt.Sprintf("Number of PCI segments added to VMs for their network interfaces, one interface per segment, "+
+			"so that interfaces keep their guest PCI address across re-attachments and VM recreations "+
+			"(at most %d). Disabled if 0.", maxNICPCISegments),
+	)
+
+	fs.BoolVar(
+		&o.IgnitionCompression,
+		"ignition-compression",
+		false,
+		"Gzip compress ignition payloads passed via OEM strings. Requires ignition spec 3.1.0 or later, "+
+			"older payloads are passed uncompressed.",
+	)
+
+	fs.StringSliceVar(
+		&o.OEMStringLabels,
+		"oem-string-labels",
+		nil,
+		"Keys of the machine labels passed to the guest as OEM strings ironcore.dev/label/<key>=<value>. "+
+			"A trailing * matches all keys with the prefix.",
+	)
+	fs.StringSliceVar(
+		&o.OEMStringAnnotations,
+		"oem-string-annotations",
+		nil,
+		"Keys of the machine annotations passed to the guest as OEM strings "+
+			"ironcore.dev/annotation/<key>=<value>. A trailing * matches all keys with the prefix.",
+	)
+
+	fs.StringVar(
+		&o.ConfigDriveISOTool,
+		"config-drive-iso-tool",
+		configdrive.DefaultISOTool,
+		"Path to the genisoimage compatible tool used to build config drives.",
+	)
+
+	fs.Uint32Var(
+		&o.MetadataVsockPort,
+		"metadata-vsock-port",
+		0,
+		"Vsock port on which guests can reach the metadata service. The vsock listeners are disabled if 0.",
+	)
+	fs.StringVar(
+		&o.MetadataLinkLocalAddress,
+		"metadata-link-local-address",
+		"",
+		fmt.Sprintf("Address on which guests can reach the metadata service by their network interfaces, e.g. %s. "+
+			"The link-local listener is disabled if empty.", metadata.LinkLocalAddress),
+	)
+
+	fs.StringSliceVar(
+		&o.GuestDNSServers,
+		"guest-dns-servers",
+		nil,
+		fmt.Sprintf("DNS servers of the guests. Can be overridden per machine with the %s annotation.",
+			api.DNSServersAnnotation),
+	)
+
+	fs.StringSliceVar(
+		&o.GuestDNSSearch,
+		"guest-dns-search",
+		nil,
+		fmt.Sprintf("DNS search domains of the guests. Can be overridden per machine with the %s annotation.",
+			api.DNSSearchAnnotation),
+	)
+
+	fs.StringToStringVar(
+		&o.SystemReserved,
+		"system-reserved",
+		nil,
+		"Resources of the host reserved for the system, not allocatable by machines (e.g. cpu=2,memory=4Gi,disk=50Gi).",
+	)
+
+	fs.StringVar(
+		&o.QuotaLabel,
+		"quota-label",
+		"",
+		"IRI label of the machines whose value selects their quota, e.g. the tenant. Quotas are disabled if empty.",
+	)
+
+	fs.Var(
+		&o.Quotas,
+		"quota",
+		fmt.Sprintf("Quota of a value of the quota label (format: value[,key=value...], %q for values without "+
+			"a quota of their own). Options: machines=<count>, cpu=<count>, memory=<size>.", quota.Default),
 	)
 
 	o.NicPlugin = options.NewDefaultOptions()
@@ -114,10 +698,19 @@ func Command() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use: "cloud-hypervisor-provider",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.ConfigFile != "" {
+				cfg, err := loadConfig(cmd.Flags(), opts.ConfigFile)
+				if err != nil {
+					return err
+				}
+				opts.config = cfg
+			}
+
 			logger := zap.New(zap.UseFlagOptions(&zapOpts))
 			ctrl.SetLogger(logger)
 			cmd.SetContext(ctrl.LoggerInto(cmd.Context(), ctrl.Log))
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return Run(cmd.Context(), opts)
@@ -130,6 +723,8 @@ func Command() *cobra.Command {
 
 	opts.AddFlags(cmd.Flags())
 
+	cmd.AddCommand(migrateCommand())
+
 	return cmd
 }
 
@@ -137,135 +732,105 @@ func Run(ctx context.Context, opts Options) error {
 	log := ctrl.LoggerFrom(ctx)
 	setupLog := log.WithName("setup")
 
-	var classes []mcr.MachineClass
-	for _, class := range opts.MachineClasses {
-		classes = append(classes, mcr.MachineClass(class))
-	}
-
-	classRegistry, err := mcr.NewMachineClassRegistry(classes)
+	cephProvider, err := validateOptions(ctx, setupLog, opts)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize provider host")
+		setupLog.Error(err, "invalid options")
 		return err
 	}
 
-	hostPaths, err := host.PathsAt(opts.RootDir)
+	classRegistry, err := mcr.NewMachineClassRegistry(opts.MachineClasses.registryClasses())
 	if err != nil {
 		setupLog.Error(err, "failed to initialize provider host")
 		return err
 	}
 
-	platform, err := ocihostutils.Platform()
+	hostPaths, err := setupHostPaths(setupLog, opts)
 	if err != nil {
-		setupLog.Error(err, "failed to get host platform: %w", err)
+		setupLog.Error(err, "failed to initialize provider host")
 		return err
 	}
-	setupLog.Info("Current platform", "architecture", platform.Architecture)
 
-	reg, err := remote.DockerRegistryWithPlatform(platform)
-	if err != nil {
-		setupLog.Error(err, "failed to initialize registry")
-		return err
+	var machineLogs *machinelog.Files
+	if opts.MachineLogVerbosity >= 0 {
+		machineLogs = machinelog.NewFiles(hostPaths, machinelog.Options{
+			Verbosity:  opts.MachineLogVerbosity,
+			MaxSize:    opts.MachineLogMaxSize,
+			MaxBackups: opts.MachineLogMaxBackups,
+		})
+		log = machinelog.NewLogger(log, machineLogs)
 	}
 
-	ociStore, err := ocistore.New(hostPaths.ImagesDir())
+	imgCache, platformCache, err := setupImageCache(setupLog, log, opts, hostPaths)
 	if err != nil {
-		setupLog.Error(err, "error creating oci store")
+		setupLog.Error(err, "failed to initialize image cache")
 		return err
 	}
 
-	imgCache, err := ociutils.NewLocalCache(log, reg, ociStore, nil)
+	imagePusher, err := oci.NewPusher()
 	if err != nil {
-		setupLog.Error(err, "failed to initialize oci manager")
+		setupLog.Error(err, "failed to initialize image pusher")
 		return err
 	}
 
-	rawInst, err := raw.Instance(raw.Default())
+	imgPrefetcher := oci.NewPrefetcher(log.WithName("image-prefetcher"), platformCache, opts.PrefetchImages)
+
+	pluginManager, rawInst, qmpProvider, err := setupVolumePlugins(ctx, log, opts, hostPaths, cephProvider,
+		platformCache)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize raw instance")
+		setupLog.Error(err, "failed to initialize volume plugins")
 		return err
 	}
 
-	qmpProvider, err := ceph.QMPProvider(
-		ctx,
-		log.WithName("ceph-volume-plugin"),
-		hostPaths,
-		opts.QMPSocketPath,
-	)
+	nicPlugin, nicPluginCleanup, err := setupNetworkInterfacePlugin(opts, hostPaths)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize qmp provider")
+		setupLog.Error(err, "failed to initialize network plugin")
 		return err
 	}
+	defer nicPluginCleanup()
 
-	pluginManager := volume.NewPluginManager()
-	if err := pluginManager.InitPlugins(hostPaths, []volume.Plugin{
-		ceph.NewPlugin(qmpProvider),
-		localdisk.NewPlugin(rawInst, imgCache),
-	}); err != nil {
-		setupLog.Error(err, "failed to initialize plugins")
+	machineStore, machineEvents, err := setupMachineStore(opts)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize machine store")
 		return err
 	}
 
-	nicPlugin, nicPluginCleanup, err := opts.NicPlugin.NetworkInterfacePlugin()
+	virtualMachineManager, err := setupVirtualMachineManager(ctx, setupLog, log, opts, hostPaths, machineStore)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize network plugin")
+		setupLog.Error(err, "failed to initialize virtual-machine-manager")
 		return err
 	}
-	if nicPluginCleanup != nil {
-		defer nicPluginCleanup()
-	}
 
-	if err := nicPlugin.Init(hostPaths); err != nil {
-		setupLog.Error(err, "failed to initialize network plugin")
+	metadataServer, err := setupMetadataServer(log, opts, machineStore, machineEvents, hostPaths)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize metadata server")
 		return err
 	}
 
-	machineStore, err := hostutils.NewStore[*api.Machine](hostutils.Options[*api.Machine]{
-		Dir:            opts.MachineStoreDir,
-		NewFunc:        func() *api.Machine { return &api.Machine{} },
-		CreateStrategy: strategy.MachineStrategy,
-	})
+	evacuation, err := maintenance.ParseEvacuation(opts.MaintenanceEvacuation)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize machine store")
+		setupLog.Error(err, "invalid maintenance evacuation")
 		return err
 	}
+	maintenanceMode := maintenance.NewMode(opts.Maintenance, evacuation)
 
-	machineEvents, err := event.NewListWatchSource[*api.Machine](
-		machineStore.List,
-		machineStore.Watch,
-		event.ListWatchSourceOptions{},
-	)
+	migrationServer, migrationClient, err := setupMigration(log, opts, machineStore, maintenanceMode)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize machine events")
+		setupLog.Error(err, "failed to initialize migration")
 		return err
 	}
 
-	var socketsInUse []string
-	machines, err := machineStore.List(ctx)
+	cgroups, err := setupCgroups(opts)
 	if err != nil {
-		setupLog.Error(err, "failed to get initial machines")
+		setupLog.Error(err, "failed to initialize cgroup manager")
 		return err
 	}
-	for _, machine := range machines {
-		if sock := ptr.Deref(machine.Spec.ApiSocketPath, ""); sock != "" {
-			socketsInUse = append(socketsInUse, sock)
-		}
-	}
 
-	virtualMachineManager, err := vmm.NewManager(
-		log.WithName("virtual-machine-manager"),
-		hostPaths,
-		vmm.ManagerOptions{
-			CHSocketsPath:     opts.CloudHypervisorSocketsPath,
-			FirmwarePath:      opts.CloudHypervisorFirmwarePath,
-			ReservedInstances: socketsInUse,
-		},
-	)
+	eventRecorder, err := setupEventRecorder(log, opts)
 	if err != nil {
-		setupLog.Error(err, "failed to initialize virtual-machine-manager")
+		setupLog.Error(err, "failed to initialize event recorder")
 		return err
 	}
 
-	eventRecorder := recorder.NewEventStore(log, recorder.EventStoreOptions{})
 	machineReconciler, err := controllers.NewMachineReconciler(
 		log.WithName("machine-reconciler"),
 		machineStore,
@@ -275,9 +840,31 @@ func Run(ctx context.Context, opts Options) error {
 		pluginManager,
 		nicPlugin,
 		controllers.MachineReconcilerOptions{
-			ImageCache: imgCache,
-			Raw:        rawInst,
-			Paths:      hostPaths,
+			ImageCache:  platformCache,
+			ImagePusher: imagePusher,
+			Raw:         rawInst,
+			Paths:       hostPaths,
+
+			IgnitionTransport:  api.IgnitionTransport(opts.IgnitionTransport),
+			ConfigDriveBuilder: configdrive.NewBuilder(opts.ConfigDriveISOTool),
+			Cgroups:            cgroups,
+			ChownMachineDirs:   opts.ChownMachineDirs,
+			SELinuxFileContext: opts.SELinuxFileContext,
+			DetachVms:          opts.DetachVms,
+			DeleteForeignVms:   opts.DeleteForeignVms,
+
+			ShutdownGracePeriod: opts.ShutdownGracePeriod,
+			Workers:             opts.Workers,
+			ResyncInterval:      opts.ResyncInterval,
+			DrainTimeout:        opts.DrainTimeout,
+			Maintenance:         maintenanceMode,
+
+			MigrationTLSConfig:        migrationClient.tlsConfig,
+			MigrationAdvertiseAddress: migrationClient.advertiseAddress,
+
+			MachineLogs:    machineLogs,
+			MachineClasses: classRegistry,
+			NICPCISegments: opts.NICPCISegments,
 		},
 	)
 	if err != nil {
@@ -285,60 +872,104 @@ func Run(ctx context.Context, opts Options) error {
 		return err
 	}
 
-	srv, err := server.New(machineStore, server.Options{
-		EventStore:           eventRecorder,
-		MachineClassRegistry: classRegistry,
-	})
+	adminServer, scrollback, err := setupAdminServer(log, opts, machineStore, hostPaths, machineReconciler,
+		maintenanceMode)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize admin server")
+		return err
+	}
+
+	srv, quotas, err := setupServer(opts, machineStore, eventRecorder, classRegistry, maintenanceMode)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize server")
+		return err
+	}
+
+	volumeMonitors, err := setupVolumeMonitors(log, opts, machineStore, eventRecorder, virtualMachineManager,
+		pluginManager)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize volume monitors")
+		return err
+	}
+
+	metricsServer, err := metricsserver.NewServer(metricsserver.Options{BindAddress: opts.MetricsBindAddress}, nil, nil)
 	if err != nil {
-		return fmt.Errorf("error creating server: %w", err)
+		setupLog.Error(err, "failed to initialize metrics server")
+		return err
 	}
 
+	imgCacheRunning := health.NewFlag("image cache")
+	grpcServing := health.NewFlag("grpc server")
+	healthServer, err := setupHealthServer(log, opts, machineStore, imgCacheRunning, grpcServing)
+	if err != nil {
+		setupLog.Error(err, "failed to initialize health server")
+		return err
+	}
+
+	reloader := newConfigReloader(ctx, log, opts, classRegistry, machineStore, machineReconciler, quotas,
+		maintenanceMode)
+
+	components := []component{
+		{name: "oci cache", start: func(ctx context.Context) error {
+			imgCacheRunning.Set(true)
+			defer imgCacheRunning.Set(false)
+			return imgCache.Start(ctx)
+		}},
+		{name: "image prefetcher", start: imgPrefetcher.Start},
+		{name: "virtual machine manager", start: virtualMachineManager.Start},
+		{name: "machine reconciler", start: machineReconciler.Start},
+		{name: "machine events", start: machineEvents.Start},
+	}
+	components = append(components, volumeMonitors...)
+	components = addComponent(components, "console scrollback", scrollback)
+	if metricsServer != nil {
+		components = append(components, component{name: "metrics server", start: metricsServer.Start})
+	}
+	components = addComponent(components, "config reloader", reloader)
+	components = addComponent(components, "health server", healthServer)
+	components = addComponent(components, "admin server", adminServer)
+	components = addComponent(components, "migration server", migrationServer)
+	components = addComponent(components, "metadata server", metadataServer)
+	components = append(components,
+		component{name: "machine events garbage collector", start: func(ctx context.Context) error {
+			eventRecorder.Start(ctx)
+			return nil
+		}},
+		component{name: "grpc server", start: func(ctx context.Context) error {
+			return RunGRPCServer(ctx, setupLog, log, srv, opts.Address, grpcServing)
+		}},
+	)
+
 	g, ctx := errgroup.WithContext(ctx)
-	g.Go(func() error {
-		setupLog.Info("Starting oci cache")
-		if err := imgCache.Start(ctx); err != nil {
-			setupLog.Error(err, "failed to start oci cache")
-			return err
-		}
-		return nil
-	})
-
-	g.Go(func() error {
-		setupLog.Info("Starting machine reconciler")
-		if err := machineReconciler.Start(ctx); err != nil {
-			setupLog.Error(err, "failed to start machine reconciler")
-			return err
-		}
-		return nil
-	})
-
-	g.Go(func() error {
-		setupLog.Info("Starting machine events")
-		if err := machineEvents.Start(ctx); err != nil {
-			setupLog.Error(err, "failed to start machine events")
-			return err
-		}
-		return nil
-	})
-
-	g.Go(func() error {
-		setupLog.Info("Starting machine events garbage collector")
-		eventRecorder.Start(ctx)
-		return nil
-	})
-
-	g.Go(func() error {
-		setupLog.Info("Starting grpc server")
-		if err := RunGRPCServer(ctx, setupLog, log, srv, opts.Address); err != nil {
-			setupLog.Error(err, "failed to start grpc server")
-			return err
-		}
-		return nil
-	})
-	return g.Wait()
+	for _, c := range components {
+		g.Go(func() error {
+			setupLog.Info("Starting " + c.name)
+			if err := c.start(ctx); err != nil {
+				setupLog.Error(err, "failed to start "+c.name)
+				return err
+			}
+			return nil
+		})
+	}
+
+	err = g.Wait()
+
+	// The clients are closed once all components stopped, none is left to use them.
+	virtualMachineManager.Close()
+	if closeErr := qmpProvider.Close(); closeErr != nil {
+		setupLog.Error(closeErr, "failed to disconnect from qmp monitor")
+	}
+	setupLog.Info("Shut down")
+	return err
 }
 
-func RunGRPCServer(ctx context.Context, setupLog, log logr.Logger, srv *server.Server, address string) error {
+func RunGRPCServer(
+	ctx context.Context,
+	setupLog, log logr.Logger,
+	srv *server.Server,
+	address string,
+	serving *health.Flag,
+) error {
 	log.V(1).Info("Cleaning up any previous socket")
 	if err := common.CleanupSocketIfExists(address); err != nil {
 		return fmt.Errorf("error cleaning up socket: %w", err)
@@ -372,9 +1003,11 @@ func RunGRPCServer(ctx context.Context, setupLog, log logr.Logger, srv *server.S
 	go func() {
 		<-ctx.Done()
 		setupLog.Info("Shutting down grpc server")
+		serving.Set(false)
 		grpcSrv.GracefulStop()
 		setupLog.Info("Shut down grpc server")
 	}()
+	serving.Set(true)
 	if err := grpcSrv.Serve(l); err != nil {
 		return fmt.Errorf("error serving grpc: %w", err)
 	}
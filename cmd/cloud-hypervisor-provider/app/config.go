@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	goflag "flag"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	configFlag           = "config"
+	configReloadInterval = 10 * time.Second
+)
+
+// reloadableOptions are applied to the running provider when they change in the config file.
+var reloadableOptions = sets.New("machine-class", "quota", "maintenance", "maintenance-evacuation")
+
+// config is the config file the options were loaded from.
+type config struct {
+	path   string
+	values map[string][]string
+	// commandLine are the options given on the command line, which take precedence over the config file.
+	commandLine sets.Set[string]
+}
+
+// readConfigValues reads the values of the options from the config file. Its keys are the flag names, its values
+// a value or a list of values, each given like on the command line.
+func readConfigValues(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+
+	values := map[string][]string{}
+	for key, rawValue := range raw {
+		if key == configFlag {
+			return nil, fmt.Errorf("config file must not set option %s", configFlag)
+		}
+
+		list, ok := rawValue.([]any)
+		if !ok {
+			list = []any{rawValue}
+		}
+		for _, item := range list {
+			value, err := configValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("option %s: %w", key, err)
+			}
+			values[key] = append(values[key], value)
+		}
+	}
+	return values, nil
+}
+
+func configValue(item any) (string, error) {
+	switch v := item.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("must be a value or a list of values")
+	}
+}
+
+// applyConfigValues sets the options of the config file, except the ones given on the command line.
+func applyConfigValues(fs *pflag.FlagSet, values map[string][]string, commandLine sets.Set[string]) error {
+	for _, key := range sets.List(sets.KeySet(values)) {
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("unknown option %s in config file", key)
+		}
+		if commandLine.Has(key) {
+			continue
+		}
+		for _, value := range values[key] {
+			if err := fs.Set(key, value); err != nil {
+				return fmt.Errorf("invalid value %q of option %s in config file: %w", value, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadConfig sets the options of the config file given by the config flag.
+func loadConfig(fs *pflag.FlagSet, path string) (*config, error) {
+	values, err := readConfigValues(path)
+	if err != nil {
+		return nil, err
+	}
+
+	commandLine := sets.New[string]()
+	fs.Visit(func(f *pflag.Flag) {
+		commandLine.Insert(f.Name)
+	})
+
+	if err := applyConfigValues(fs, values, commandLine); err != nil {
+		return nil, err
+	}
+	return &config{path: path, values: values, commandLine: commandLine}, nil
+}
+
+// configReloader applies changes of the reloadable options in the config file to the running provider.
+type configReloader struct {
+	log    logr.Logger
+	config *config
+	// apply applies the changed reloadable options, taking their values from opts.
+	apply func(opts *Options, changed sets.Set[string]) error
+}
+
+func (r *configReloader) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(configReloadInterval):
+		}
+		r.reload()
+	}
+}
+
+func (r *configReloader) reload() {
+	values, err := readConfigValues(r.config.path)
+	if err != nil {
+		r.log.Error(err, "Failed to read config file, keeping the current options")
+		return
+	}
+	if reflect.DeepEqual(values, r.config.values) {
+		return
+	}
+
+	// The options are parsed into a new flag set, which validates them like on startup.
+	var (
+		opts    Options
+		zapOpts zap.Options
+	)
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	opts.AddFlags(fs)
+	goFlags := goflag.NewFlagSet("", goflag.ContinueOnError)
+	zapOpts.BindFlags(goFlags)
+	fs.AddGoFlagSet(goFlags)
+	if err := applyConfigValues(fs, values, r.config.commandLine); err != nil {
+		r.log.Error(err, "Invalid config file, keeping the current options")
+		return
+	}
+
+	changed := sets.New[string]()
+	for key := range sets.KeySet(values).Union(sets.KeySet(r.config.values)) {
+		if !r.config.commandLine.Has(key) && !slices.Equal(values[key], r.config.values[key]) {
+			changed.Insert(key)
+		}
+	}
+
+	if reloaded := changed.Intersection(reloadableOptions); reloaded.Len() > 0 {
+		if err := r.apply(&opts, reloaded); err != nil {
+			r.log.Error(err, "Failed to apply config file, keeping the current options")
+			return
+		}
+		r.log.Info("Applied changed options", "options", sets.List(reloaded))
+	}
+	if restart := changed.Difference(reloadableOptions); restart.Len() > 0 {
+		r.log.Info("Changed options take effect after a restart", "options", sets.List(restart))
+	}
+	r.config.values = values
+}
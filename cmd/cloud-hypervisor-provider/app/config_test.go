@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/quota"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+var _ = Describe("configReloader", func() {
+	var (
+		configPath      string
+		opts            Options
+		quotas          *quota.Registry
+		maintenanceMode *maintenance.Mode
+		reloader        *configReloader
+	)
+
+	writeConfig := func(content string) {
+		GinkgoHelper()
+		Expect(os.WriteFile(configPath, []byte(content), 0600)).To(Succeed())
+	}
+
+	getQuota := func(value string) quota.Quota {
+		GinkgoHelper()
+		q, found := quotas.Get(value)
+		Expect(found).To(BeTrue())
+		return q
+	}
+
+	BeforeEach(func(ctx SpecContext) {
+		configPath = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		writeConfig("quota-label: example.org/tenant\nquota:\n  - team-a,machines=10\n  - '*,cpu=16'\n")
+
+		opts = Options{}
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		opts.AddFlags(fs)
+		cfg, err := loadConfig(fs, configPath)
+		Expect(err).NotTo(HaveOccurred())
+		opts.config = cfg
+
+		quotas, err = quota.NewRegistry(opts.QuotaLabel, opts.Quotas)
+		Expect(err).NotTo(HaveOccurred())
+		maintenanceMode = maintenance.NewMode(false, maintenance.EvacuationNone)
+		reloader = newConfigReloader(ctx, logr.Discard(), opts, nil, nil, nil, quotas, maintenanceMode)
+	})
+
+	It("should apply changed quotas", func() {
+		Expect(getQuota("team-a")).To(Equal(quota.Quota{Name: "team-a", Machines: 10}))
+
+		By("changing the quotas in the config file")
+		writeConfig("quota-label: example.org/tenant\nquota:\n  - team-a,machines=20\n  - team-b,cpu=8\n")
+		reloader.reload()
+
+		Expect(getQuota("team-a")).To(Equal(quota.Quota{Name: "team-a", Machines: 20}))
+		Expect(getQuota("team-b")).To(Equal(quota.Quota{Name: "team-b", Cpu: 8}))
+		_, found := quotas.Get("team-c")
+		Expect(found).To(BeFalse())
+	})
+
+	It("should keep the quotas if the config file is invalid", func() {
+		writeConfig("quota-label: example.org/tenant\nquota:\n  - team-a,machines=many\n")
+		reloader.reload()
+
+		Expect(getQuota("team-a")).To(Equal(quota.Quota{Name: "team-a", Machines: 10}))
+	})
+
+	It("should apply a changed maintenance mode", func() {
+		writeConfig("quota-label: example.org/tenant\nquota:\n  - team-a,machines=10\n  - '*,cpu=16'\n" +
+			"maintenance: true\n")
+		reloader.reload()
+
+		Expect(maintenanceMode.State().Enabled).To(BeTrue())
+	})
+})
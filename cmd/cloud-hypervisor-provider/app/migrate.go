@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// migrateCommand upgrades the root directory to the current layout without starting the provider, e.g. ahead of
+// an upgrade. The provider runs the same migrations on startup.
+func migrateCommand() *cobra.Command {
+	var rootDir string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the provider root directory to the current layout version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log := ctrl.LoggerFrom(cmd.Context()).WithName("migrate")
+
+			hostPaths, err := host.PathsAt(rootDir)
+			if err != nil {
+				return err
+			}
+			if err := host.MigrateLayout(log, hostPaths, host.LayoutMigrations); err != nil {
+				return err
+			}
+			log.Info("Root directory is up to date", "layoutVersion", host.CurrentLayoutVersion())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(
+		&rootDir,
+		"provider-root-dir",
+		"/var/lib/chp",
+		"Path to the directory where the provider manages its content at.",
+	)
+
+	return cmd
+}
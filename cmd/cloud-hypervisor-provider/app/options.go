@@ -5,29 +5,135 @@ package app
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/events"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/quota"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/server"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 type MachineClass struct {
 	Name        string
 	Cpu         int64
 	MemoryBytes int64
+
+	Landlock    *bool
+	CpuTopology *api.CpuTopology
+	CpuFeatures *api.CpuFeatures
+	Clock       api.GuestClock
+
+	FreePageReporting bool
+	SharedMemory      *bool
+	MemoryBacking     *api.MemoryBacking
+	SerialMode        api.ConsoleMode
+	ConsoleMode       api.ConsoleMode
+	VMMRequirements   *api.VMMRequirements
+
+	RootDiskBytes   int64
+	RootDiskMedium  api.StorageMedium
+	MemoryDiskBytes int64
+
+	VMConfigOverrides map[string]string
+
+	Deprecated bool
 }
 type MachineClassOptions []MachineClass
 
+// vmConfigOptionPrefix prefixes the machine class options overriding a field of the VM config.
+const vmConfigOptionPrefix = "vm-config."
+
 func (ml *MachineClassOptions) String() string {
 	var parts []string
 	for _, m := range *ml {
-		parts = append(parts, fmt.Sprintf("%s,%d,%d", m.Name, m.Cpu, m.MemoryBytes))
+		part := fmt.Sprintf("%s,%d,%d", m.Name, m.Cpu, m.MemoryBytes)
+		if m.Landlock != nil {
+			part += fmt.Sprintf(",landlock=%t", *m.Landlock)
+		}
+		if m.CpuTopology != nil {
+			part += fmt.Sprintf(",topology=%s", m.CpuTopology)
+		}
+		if features := m.CpuFeatures; features != nil {
+			if features.AMX {
+				part += ",amx=true"
+			}
+			if features.KvmHyperV {
+				part += ",kvm-hyperv=true"
+			}
+			if features.MaxPhysBits != 0 {
+				part += fmt.Sprintf(",max-phys-bits=%d", features.MaxPhysBits)
+			}
+			if features.SgxEpcBytes != 0 {
+				part += fmt.Sprintf(",sgx-epc=%d", features.SgxEpcBytes)
+			}
+		}
+		if m.Clock != "" {
+			part += fmt.Sprintf(",clock=%s", m.Clock)
+		}
+		if m.FreePageReporting {
+			part += ",free-page-reporting=true"
+		}
+		if m.SharedMemory != nil {
+			part += fmt.Sprintf(",shared-memory=%t", *m.SharedMemory)
+		}
+		if backing := m.MemoryBacking; backing != nil {
+			if backing.HugepageSizeBytes != 0 {
+				part += fmt.Sprintf(",hugepages=%s", resource.NewQuantity(backing.HugepageSizeBytes, resource.BinarySI))
+			}
+			if backing.File != "" {
+				part += fmt.Sprintf(",memory-file=%s", backing.File)
+			}
+		}
+		if m.SerialMode != "" {
+			part += fmt.Sprintf(",serial=%s", m.SerialMode)
+		}
+		if m.ConsoleMode != "" {
+			part += fmt.Sprintf(",console=%s", m.ConsoleMode)
+		}
+		if requirements := m.VMMRequirements; requirements != nil {
+			if requirements.MinVersion != "" {
+				part += fmt.Sprintf(",vmm-version=%s", requirements.MinVersion)
+			}
+			for _, feature := range requirements.Features {
+				part += fmt.Sprintf(",vmm-feature=%s", feature)
+			}
+		}
+		if m.RootDiskBytes != 0 {
+			part += fmt.Sprintf(",root-disk=%s", resource.NewQuantity(m.RootDiskBytes, resource.BinarySI))
+		}
+		if m.RootDiskMedium == api.StorageMediumCeph {
+			part += ",root-disk-medium=ceph"
+		}
+		if m.MemoryDiskBytes != 0 {
+			part += fmt.Sprintf(",memory-disk=%s", resource.NewQuantity(m.MemoryDiskBytes, resource.BinarySI))
+		}
+		for _, path := range slices.Sorted(maps.Keys(m.VMConfigOverrides)) {
+			part += fmt.Sprintf(",%s%s=%s", vmConfigOptionPrefix, path, m.VMConfigOverrides[path])
+		}
+		if m.Deprecated {
+			part += ",deprecated=true"
+		}
+		parts = append(parts, part)
 	}
 	return strings.Join(parts, "; ")
 }
 
 func (ml *MachineClassOptions) Set(value string) error {
 	parts := strings.Split(value, ",")
-	if len(parts) != 3 {
-		return fmt.Errorf("invalid machine format: expected name,cpu,memory")
+	if len(parts) < 3 {
+		return fmt.Errorf("invalid machine format: expected name,cpu,memory[,key=value...]")
 	}
 
 	cpuMillis, err := strconv.ParseInt(parts[1], 10, 64)
@@ -40,15 +146,477 @@ func (ml *MachineClassOptions) Set(value string) error {
 		return fmt.Errorf("invalid Memory value: %s", parts[2])
 	}
 
-	*ml = append(*ml, MachineClass{
+	class := MachineClass{
 		Name:        parts[0],
 		Cpu:         cpuMillis,
 		MemoryBytes: memoryBytes,
-	})
+	}
+
+	for _, option := range parts[3:] {
+		key, val, ok := strings.Cut(option, "=")
+		if !ok {
+			return fmt.Errorf("invalid machine class option %q: expected key=value", option)
+		}
+
+		if parse, ok := machineClassOptions[key]; ok {
+			if err := parse(&class, val); err != nil {
+				return err
+			}
+			continue
+		}
+
+		path, ok := strings.CutPrefix(key, vmConfigOptionPrefix)
+		if !ok || path == "" {
+			return fmt.Errorf("unknown machine class option %q", key)
+		}
+		if class.VMConfigOverrides == nil {
+			class.VMConfigOverrides = map[string]string{}
+		}
+		class.VMConfigOverrides[path] = val
+	}
+
+	if backing := class.MemoryBacking; backing != nil && backing.HugepageSizeBytes != 0 && backing.File != "" {
+		return fmt.Errorf("machine class %s: hugepages and memory-file are mutually exclusive, "+
+			"use a memory-file on a hugetlbfs mount instead", class.Name)
+	}
+
+	if class.RootDiskMedium == api.StorageMediumCeph && class.SharedMemory != nil && !*class.SharedMemory {
+		return fmt.Errorf("machine class %s: ceph root disks are connected via vhost-user and require shared memory",
+			class.Name)
+	}
+
+	*ml = append(*ml, class)
+
+	return nil
+}
+
+// machineClassOptions parses the value of the machine class options by key into the class. Keys with the
+// vmConfigOptionPrefix are no entries, they override the VM config.
+var machineClassOptions = map[string]func(class *MachineClass, val string) error{
+	"landlock": boolClassOption("landlock", func(class *MachineClass, landlock bool) {
+		class.Landlock = &landlock
+	}),
+	"topology": func(class *MachineClass, val string) error {
+		topology, err := api.ParseCpuTopology(val)
+		if err != nil {
+			return err
+		}
+		if int64(topology.Cpus()) != class.Cpu {
+			return fmt.Errorf("cpu topology %s has %d cpus, class %s has %d", topology, topology.Cpus(),
+				class.Name, class.Cpu)
+		}
+		class.CpuTopology = topology
+		return nil
+	},
+	"amx":           cpuFeatureClassOption("amx"),
+	"kvm-hyperv":    cpuFeatureClassOption("kvm-hyperv"),
+	"max-phys-bits": cpuFeatureClassOption("max-phys-bits"),
+	"sgx-epc":       cpuFeatureClassOption("sgx-epc"),
+	"clock": func(class *MachineClass, val string) error {
+		switch clock := api.GuestClock(val); clock {
+		case api.GuestClockKVM, api.GuestClockPTP:
+			class.Clock = clock
+			return nil
+		default:
+			return fmt.Errorf("invalid clock value: %s", val)
+		}
+	},
+	"free-page-reporting": boolClassOption("free-page-reporting", func(class *MachineClass, enabled bool) {
+		class.FreePageReporting = enabled
+	}),
+	"shared-memory": boolClassOption("shared-memory", func(class *MachineClass, sharedMemory bool) {
+		class.SharedMemory = &sharedMemory
+	}),
+	"hugepages": sizeClassOption("hugepages", func(class *MachineClass, size int64) {
+		if class.MemoryBacking == nil {
+			class.MemoryBacking = &api.MemoryBacking{}
+		}
+		class.MemoryBacking.HugepageSizeBytes = size
+	}),
+	"memory-file": func(class *MachineClass, val string) error {
+		if !filepath.IsAbs(val) {
+			return fmt.Errorf("invalid memory-file value %s: must be an absolute path", val)
+		}
+		if class.MemoryBacking == nil {
+			class.MemoryBacking = &api.MemoryBacking{}
+		}
+		class.MemoryBacking.File = val
+		return nil
+	},
+	"serial": consoleModeClassOption("serial", func(class *MachineClass, mode api.ConsoleMode) {
+		class.SerialMode = mode
+	}),
+	"console": consoleModeClassOption("console", func(class *MachineClass, mode api.ConsoleMode) {
+		class.ConsoleMode = mode
+	}),
+	"vmm-version": func(class *MachineClass, val string) error {
+		if _, err := semver.ParseTolerant(val); err != nil {
+			return fmt.Errorf("invalid vmm-version value %s: %w", val, err)
+		}
+		if class.VMMRequirements == nil {
+			class.VMMRequirements = &api.VMMRequirements{}
+		}
+		class.VMMRequirements.MinVersion = val
+		return nil
+	},
+	"vmm-feature": func(class *MachineClass, val string) error {
+		if val == "" {
+			return fmt.Errorf("vmm-feature must not be empty")
+		}
+		if class.VMMRequirements == nil {
+			class.VMMRequirements = &api.VMMRequirements{}
+		}
+		class.VMMRequirements.Features = append(class.VMMRequirements.Features, val)
+		return nil
+	},
+	"root-disk": sizeClassOption("root-disk", func(class *MachineClass, size int64) {
+		class.RootDiskBytes = size
+	}),
+	"root-disk-medium": func(class *MachineClass, val string) error {
+		if val != "ceph" {
+			return fmt.Errorf("invalid root-disk-medium value: %s", val)
+		}
+		class.RootDiskMedium = api.StorageMediumCeph
+		return nil
+	},
+	"memory-disk": sizeClassOption("memory-disk", func(class *MachineClass, size int64) {
+		class.MemoryDiskBytes = size
+	}),
+	"deprecated": boolClassOption("deprecated", func(class *MachineClass, deprecated bool) {
+		class.Deprecated = deprecated
+	}),
+}
+
+func boolClassOption(key string, set func(class *MachineClass, value bool)) func(*MachineClass, string) error {
+	return func(class *MachineClass, val string) error {
+		value, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %s", key, val)
+		}
+		set(class, value)
+		return nil
+	}
+}
+
+// sizeClassOption parses a positive quantity, e.g. 10Gi.
+func sizeClassOption(key string, set func(class *MachineClass, size int64)) func(*MachineClass, string) error {
+	return func(class *MachineClass, val string) error {
+		size, err := resource.ParseQuantity(val)
+		if err != nil || size.Value() <= 0 {
+			return fmt.Errorf("invalid %s value: %s", key, val)
+		}
+		set(class, size.Value())
+		return nil
+	}
+}
+
+func consoleModeClassOption(
+	key string,
+	set func(class *MachineClass, mode api.ConsoleMode),
+) func(*MachineClass, string) error {
+	return func(class *MachineClass, val string) error {
+		mode, err := api.ParseConsoleMode(val)
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %w", key, err)
+		}
+		set(class, mode)
+		return nil
+	}
+}
+
+func cpuFeatureClassOption(key string) func(*MachineClass, string) error {
+	return func(class *MachineClass, val string) error {
+		if class.CpuFeatures == nil {
+			class.CpuFeatures = &api.CpuFeatures{}
+		}
+		return setCpuFeature(class.CpuFeatures, key, val)
+	}
+}
+
+func setCpuFeature(features *api.CpuFeatures, key, val string) error {
+	var err error
+	switch key {
+	case "amx":
+		features.AMX, err = strconv.ParseBool(val)
+	case "kvm-hyperv":
+		features.KvmHyperV, err = strconv.ParseBool(val)
+	case "max-phys-bits":
+		features.MaxPhysBits, err = strconv.Atoi(val)
+		if err == nil && (features.MaxPhysBits < 32 || features.MaxPhysBits > 64) {
+			err = fmt.Errorf("must be between 32 and 64")
+		}
+	case "sgx-epc":
+		features.SgxEpcBytes, err = strconv.ParseInt(val, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value %s: %w", key, val, err)
+	}
+	return nil
+}
+
+// validateMachineClasses checks that the host supports the classes. The host is not checked with fake VMs.
+func validateMachineClasses(classes []MachineClass, vmmMode, cephClonePool string, overrideAllowlist []string) error {
+	if vmmMode != vmm.ModeFake {
+		hostCPUFlags, err := host.CPUFlags()
+		if err != nil {
+			return fmt.Errorf("failed to get host cpu flags: %w", err)
+		}
+		hostClocksource, err := host.Clocksource()
+		if err != nil {
+			return fmt.Errorf("failed to get host clocksource: %w", err)
+		}
+		if err := validateCpuFeatures(classes, hostCPUFlags, hostClocksource); err != nil {
+			return err
+		}
+		hugepageSizes, err := host.HugepageSizes()
+		if err != nil {
+			return fmt.Errorf("failed to get host hugepage sizes: %w", err)
+		}
+		if err := validateMemoryBacking(classes, hugepageSizes); err != nil {
+			return err
+		}
+	}
+	if err := validateRootDiskMedium(classes, cephClonePool); err != nil {
+		return err
+	}
+	return validateVMConfigOverrides(classes, overrideAllowlist)
+}
+
+// validateCpuFeatures checks that the host supports the cpu features and clocks of the classes.
+func validateCpuFeatures(classes []MachineClass, hostFlags sets.Set[string], hostClocksource string) error {
+	for _, class := range classes {
+		// The KVM PTP hypercall only pairs the guest with the host clock when the host uses the tsc.
+		if class.Clock == api.GuestClockPTP && hostClocksource != "tsc" {
+			return fmt.Errorf("machine class %s requires the ptp clock, which needs the host clocksource tsc, not %s",
+				class.Name, hostClocksource)
+		}
+
+		features := class.CpuFeatures
+		if features == nil {
+			continue
+		}
+		if features.AMX && !hostFlags.Has("amx_tile") {
+			return fmt.Errorf("machine class %s requires amx, which the host does not support", class.Name)
+		}
+		if features.SgxEpcBytes != 0 && !hostFlags.Has("sgx") {
+			return fmt.Errorf("machine class %s requires sgx, which the host does not support", class.Name)
+		}
+	}
+	return nil
+}
+
+// validateMemoryBacking checks that the host provides the memory backing of the classes.
+func validateMemoryBacking(classes []MachineClass, hugepageSizes sets.Set[int64]) error {
+	for _, class := range classes {
+		backing := class.MemoryBacking
+		if backing == nil {
+			continue
+		}
+		if backing.HugepageSizeBytes != 0 && !hugepageSizes.Has(backing.HugepageSizeBytes) {
+			return fmt.Errorf("machine class %s requires hugepages of %d bytes, which the host does not support",
+				class.Name, backing.HugepageSizeBytes)
+		}
+		if backing.File != "" {
+			if _, err := os.Stat(backing.File); err != nil {
+				return fmt.Errorf("machine class %s: memory file: %w", class.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVMConfigOverrides checks that the classes only override allowed fields of the VM config.
+func validateVMConfigOverrides(classes []MachineClass, allowlist []string) error {
+	for _, class := range classes {
+		if err := vmm.ValidateVMConfigOverrides(class.VMConfigOverrides, allowlist); err != nil {
+			return fmt.Errorf("machine class %s: %w", class.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateNUMANodes checks that the NUMA nodes belong to sockets paths and, unless VMs are faked, exist on the host.
+func validateNUMANodes(opts Options) error {
+	paths := sets.New[string]()
+	for _, path := range opts.CloudHypervisorSocketsPaths {
+		paths.Insert(filepath.Clean(path))
+	}
+	for path, node := range opts.CloudHypervisorNUMANodes {
+		if !paths.Has(filepath.Clean(path)) {
+			return fmt.Errorf("numa node %d is set for %s, which is no cloud-hypervisor sockets path", node, path)
+		}
+		if opts.VMMMode == vmm.ModeFake {
+			continue
+		}
+		if _, err := host.NUMANodeCPUs(node); err != nil {
+			return err
+		}
+	}
+	if opts.SocketSelection == string(vmm.SocketSelectionNUMA) && len(opts.CloudHypervisorNUMANodes) == 0 {
+		return fmt.Errorf("socket selection %s requires --cloud-hypervisor-numa-nodes", vmm.SocketSelectionNUMA)
+	}
+	return nil
+}
+
+// validateRootDiskMedium checks that the root disk medium of the classes is configured.
+func validateRootDiskMedium(classes []MachineClass, cephClonePool string) error {
+	for _, class := range classes {
+		if class.RootDiskMedium == api.StorageMediumCeph && cephClonePool == "" {
+			return fmt.Errorf("machine class %s requires ceph root disks, which need a ceph clone pool", class.Name)
+		}
+	}
+	return nil
+}
+
+// QuotaOptions are the quotas of the values of the quota label.
+type QuotaOptions []quota.Quota
+
+func (q *QuotaOptions) String() string {
+	var parts []string
+	for _, entry := range *q {
+		part := entry.Name
+		if entry.Machines != 0 {
+			part += fmt.Sprintf(",machines=%d", entry.Machines)
+		}
+		if entry.Cpu != 0 {
+			part += fmt.Sprintf(",cpu=%d", entry.Cpu)
+		}
+		if entry.MemoryBytes != 0 {
+			part += fmt.Sprintf(",memory=%s", resource.NewQuantity(entry.MemoryBytes, resource.BinarySI))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (q *QuotaOptions) Set(value string) error {
+	parts := strings.Split(value, ",")
+	if parts[0] == "" {
+		return fmt.Errorf("invalid quota format: expected value[,key=value...]")
+	}
+
+	entry := quota.Quota{Name: parts[0]}
+	for _, option := range parts[1:] {
+		key, val, ok := strings.Cut(option, "=")
+		if !ok {
+			return fmt.Errorf("invalid quota option %q: expected key=value", option)
+		}
+
+		switch key {
+		case "machines":
+			machines, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || machines < 0 {
+				return fmt.Errorf("invalid machines value: %s", val)
+			}
+			entry.Machines = machines
+		case "cpu":
+			cpu, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || cpu < 0 {
+				return fmt.Errorf("invalid cpu value: %s", val)
+			}
+			entry.Cpu = cpu
+		case "memory":
+			memory, err := resource.ParseQuantity(val)
+			if err != nil || memory.Sign() < 0 {
+				return fmt.Errorf("invalid memory value: %s", val)
+			}
+			entry.MemoryBytes = memory.Value()
+		default:
+			return fmt.Errorf("unknown quota option %q", key)
+		}
+	}
 
+	*q = append(*q, entry)
 	return nil
 }
 
+func (q *QuotaOptions) Type() string {
+	return "quota"
+}
+
+// hostResources returns the capacity of the host and the resources reserved for the system. The disk capacity is
+// the one of the filesystem of rootDir, which stores the local disks.
+func hostResources(rootDir string, reserved map[string]string) (*server.Resources, server.Resources, error) {
+	var systemReserved server.Resources
+	for name, val := range reserved {
+		quantity, err := resource.ParseQuantity(val)
+		if err != nil || quantity.Sign() < 0 {
+			return nil, server.Resources{}, fmt.Errorf("invalid system reserved %s value: %s", name, val)
+		}
+		switch name {
+		case "cpu":
+			systemReserved.CPUMillis = quantity.MilliValue()
+		case "memory":
+			systemReserved.MemoryBytes = quantity.Value()
+		case "disk":
+			systemReserved.DiskBytes = quantity.Value()
+		default:
+			return nil, server.Resources{}, fmt.Errorf("unknown system reserved resource %q", name)
+		}
+	}
+
+	cpus, err := host.OnlineCPUs()
+	if err != nil {
+		return nil, server.Resources{}, err
+	}
+	memory, err := host.MemTotal()
+	if err != nil {
+		return nil, server.Resources{}, err
+	}
+	disk, err := host.DiskTotal(rootDir)
+	if err != nil {
+		return nil, server.Resources{}, err
+	}
+	return &server.Resources{CPUMillis: cpus * 1000, MemoryBytes: memory, DiskBytes: disk}, systemReserved, nil
+}
+
+// eventSinks returns the external sinks the events are forwarded to.
+func (o *Options) eventSinks() ([]events.Sink, error) {
+	var sinks []events.Sink
+	for _, name := range o.EventSinks {
+		switch name {
+		case EventSinkKubernetes:
+			sink, err := events.NewKubernetesSink(o.EventSinkKubeconfig)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case EventSinkWebhook:
+			if o.EventSinkWebhookURL == "" {
+				return nil, fmt.Errorf("event sink %s requires a webhook url", name)
+			}
+			sink, err := events.NewWebhookSink(o.EventSinkWebhookURL)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", name)
+		}
+	}
+	return sinks, nil
+}
+
+func (ml *MachineClassOptions) registryClasses() []mcr.MachineClass {
+	var classes []mcr.MachineClass
+	for _, class := range *ml {
+		classes = append(classes, mcr.MachineClass(class))
+	}
+	return classes
+}
+
 func (ml *MachineClassOptions) Type() string {
 	return "machine-class"
 }
+
+func parseLandlockRules(values []string) ([]client.LandlockConfig, error) {
+	var rules []client.LandlockConfig
+	for _, value := range values {
+		path, access, ok := strings.Cut(value, ":")
+		if !ok || path == "" || access == "" {
+			return nil, fmt.Errorf("invalid landlock rule %q: expected path:access", value)
+		}
+		rules = append(rules, client.LandlockConfig{Path: path, Access: access})
+	}
+	return rules, nil
+}
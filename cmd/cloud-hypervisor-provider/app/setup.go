@@ -0,0 +1,693 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/admin"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/cgroup"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/console"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/controllers"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/events"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/health"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/metadata"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/migration"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/oci"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/networkinterface"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume/ceph"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume/localdisk"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/quota"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/raw"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/server"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/stats"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/strategy"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"github.com/ironcore-dev/ironcore-image/oci/remote"
+	ocistore "github.com/ironcore-dev/ironcore-image/oci/store"
+	"github.com/ironcore-dev/provider-utils/eventutils/event"
+	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
+	ocihostutils "github.com/ironcore-dev/provider-utils/ociutils/host"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	hostutils "github.com/ironcore-dev/provider-utils/storeutils/host"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+// imageCache is an image cache backend.
+type imageCache interface {
+	ociutils.Cache
+	Start(ctx context.Context) error
+}
+
+// component is run by Run until the provider stops.
+type component struct {
+	name  string
+	start func(ctx context.Context) error
+}
+
+// addComponent adds c to components, unless it is nil because it is disabled.
+func addComponent[T any, PT interface {
+	*T
+	Start(ctx context.Context) error
+}](components []component, name string, c PT) []component {
+	if c == nil {
+		return components
+	}
+	return append(components, component{name: name, start: c.Start})
+}
+
+// validateOptions validates the options that are not validated by the components themselves and returns the
+// ceph provider type.
+func validateOptions(ctx context.Context, setupLog logr.Logger, opts Options) (ceph.ProviderType, error) {
+	if err := validateMachineClasses(opts.MachineClasses, opts.VMMMode, opts.CephClonePool,
+		opts.VMConfigOverrideAllowlist); err != nil {
+		return "", fmt.Errorf("unsupported machine class: %w", err)
+	}
+
+	if err := vmm.ValidateVMConfigOverrides(opts.VMConfigOverrides, opts.VMConfigOverrideAllowlist); err != nil {
+		return "", fmt.Errorf("invalid vm config overrides: %w", err)
+	}
+
+	if opts.NICPCISegments < 0 || opts.NICPCISegments > maxNICPCISegments {
+		return "", fmt.Errorf("--nic-pci-segments must be between 0 and %d", maxNICPCISegments)
+	}
+
+	if _, err := api.ParseIgnitionTransport(opts.IgnitionTransport); err != nil {
+		return "", fmt.Errorf("invalid --ignition-transport: %w", err)
+	}
+
+	if err := validateNUMANodes(opts); err != nil {
+		return "", fmt.Errorf("invalid numa nodes: %w", err)
+	}
+
+	cephProvider, err := ceph.ParseProviderType(opts.CephProvider)
+	if err != nil {
+		return "", fmt.Errorf("invalid ceph provider: %w", err)
+	}
+	if cephProvider == ceph.ProviderTypeQemuStorage {
+		version, err := ceph.CheckStorageDaemon(ctx, opts.StorageDaemonPath)
+		if err != nil {
+			return "", fmt.Errorf("qemu-storage-daemon is not usable: %w", err)
+		}
+		setupLog.Info("Using qemu-storage-daemon", "path", opts.StorageDaemonPath, "version", version)
+	}
+	return cephProvider, nil
+}
+
+func setupHostPaths(setupLog logr.Logger, opts Options) (host.Paths, error) {
+	hostPaths, err := host.PathsAt(opts.RootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize provider host: %w", err)
+	}
+
+	if err := host.MigrateLayout(setupLog, hostPaths, host.LayoutMigrations); err != nil {
+		return nil, fmt.Errorf("failed to migrate root directory layout: %w", err)
+	}
+	return hostPaths, nil
+}
+
+// setupImageCache returns the image cache backend and the cache checking the platform of the images on top of it.
+func setupImageCache(
+	setupLog, log logr.Logger,
+	opts Options,
+	hostPaths host.Paths,
+) (imageCache, *oci.PlatformCache, error) {
+	platform, err := ocihostutils.Platform()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get host platform: %w", err)
+	}
+	setupLog.Info("Current platform", "architecture", platform.Architecture)
+
+	var imgCache imageCache
+	switch opts.ImageCacheBackend {
+	case ImageCacheBackendLocal:
+		reg, err := remote.DockerRegistryWithPlatform(platform)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize registry: %w", err)
+		}
+
+		ociStore, err := ocistore.New(hostPaths.ImagesDir())
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating oci store: %w", err)
+		}
+
+		imgCache, err = ociutils.NewLocalCache(log, reg, ociStore, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize oci manager: %w", err)
+		}
+	case ImageCacheBackendContainerd:
+		imgCache, err = oci.NewContentStoreCache(log, oci.ContainerdOptions{
+			Address:    opts.ContainerdAddress,
+			Namespace:  opts.ContainerdNamespace,
+			ContentDir: opts.ContainerdContentDir,
+		}, platform)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize containerd image cache: %w", err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown image cache backend %q", opts.ImageCacheBackend)
+	}
+
+	platformCache, err := oci.NewPlatformCache(imgCache, platform)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize platform image cache: %w", err)
+	}
+	return imgCache, platformCache, nil
+}
+
+// setupVolumePlugins returns the volume plugins along with the raw implementation and the ceph provider they use.
+// The ceph provider has to be closed by the caller.
+func setupVolumePlugins(
+	ctx context.Context,
+	log logr.Logger,
+	opts Options,
+	hostPaths host.Paths,
+	cephProvider ceph.ProviderType,
+	imageCache ociutils.Cache,
+) (*volume.PluginManager, raw.Raw, ceph.Provider, error) {
+	rawInst, err := raw.Instance(opts.RawImplementation)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize raw instance: %w", err)
+	}
+	if checker, ok := rawInst.(raw.Checker); ok {
+		if err := checker.Check(); err != nil {
+			return nil, nil, nil, fmt.Errorf("raw implementation %q is not usable: %w", opts.RawImplementation, err)
+		}
+	}
+
+	var qmpProvider ceph.Provider
+	switch cephProvider {
+	case ceph.ProviderTypeQemuStorage:
+		qmpProvider, err = ceph.QemuStorageProvider(
+			ctx,
+			log.WithName("ceph-volume-plugin"),
+			hostPaths,
+			ceph.StorageDaemonOptions{
+				BinaryPath:    opts.StorageDaemonPath,
+				QMPSocketPath: opts.QMPSocketPath,
+			},
+		)
+	default:
+		qmpProvider, err = ceph.QMPProvider(
+			ctx,
+			log.WithName("ceph-volume-plugin"),
+			hostPaths,
+			opts.QMPSocketPath,
+		)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize qmp provider: %w", err)
+	}
+
+	pluginManager := volume.NewPluginManager()
+	if err := pluginManager.InitPlugins(hostPaths, []volume.Plugin{
+		ceph.NewPlugin(qmpProvider),
+		ceph.NewClonePlugin(qmpProvider, imageCache, ceph.CloneOptions{
+			Pool:     opts.CephClonePool,
+			Monitors: opts.CephCloneMonitors,
+			UserID:   opts.CephCloneUser,
+			KeyFile:  opts.CephCloneKeyFile,
+		}),
+		localdisk.NewPlugin(rawInst, imageCache, opts.MemoryDiskDir),
+	}); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize plugins: %w", err)
+	}
+	return pluginManager, rawInst, qmpProvider, nil
+}
+
+// setupNetworkInterfacePlugin returns the initialized network plugin and the func cleaning it up.
+func setupNetworkInterfacePlugin(opts Options, hostPaths host.Paths) (networkinterface.Plugin, func(), error) {
+	nicPlugin, cleanup, err := opts.NicPlugin.NetworkInterfacePlugin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize network plugin: %w", err)
+	}
+	if cleanup == nil {
+		cleanup = func() {}
+	}
+
+	if err := nicPlugin.Init(hostPaths); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to initialize network plugin: %w", err)
+	}
+	return nicPlugin, cleanup, nil
+}
+
+func setupMachineStore(
+	opts Options,
+) (*hostutils.Store[*api.Machine], *event.ListWatchSource[*api.Machine], error) {
+	machineStore, err := hostutils.NewStore[*api.Machine](hostutils.Options[*api.Machine]{
+		Dir:            opts.MachineStoreDir,
+		NewFunc:        func() *api.Machine { return &api.Machine{} },
+		CreateStrategy: strategy.MachineStrategy,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize machine store: %w", err)
+	}
+
+	machineEvents, err := event.NewListWatchSource[*api.Machine](
+		machineStore.List,
+		machineStore.Watch,
+		event.ListWatchSourceOptions{},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize machine events: %w", err)
+	}
+	return machineStore, machineEvents, nil
+}
+
+// setupVirtualMachineManager returns the virtual machine manager, reserving the instances of the existing machines.
+func setupVirtualMachineManager(
+	ctx context.Context,
+	setupLog, log logr.Logger,
+	opts Options,
+	hostPaths host.Paths,
+	machineStore store.Store[*api.Machine],
+) (vmm.VirtualMachineManager, error) {
+	var socketsInUse []string
+	machines, err := machineStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial machines: %w", err)
+	}
+	for _, machine := range machines {
+		if sock := ptr.Deref(machine.Spec.ApiSocketPath, ""); sock != "" {
+			socketsInUse = append(socketsInUse, sock)
+		}
+	}
+
+	landlockRules, err := parseLandlockRules(opts.LandlockRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse landlock rules: %w", err)
+	}
+
+	socketSelection, err := vmm.ParseSocketSelection(opts.SocketSelection)
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket selection: %w", err)
+	}
+	if len(opts.CloudHypervisorNUMANodes) > 0 && opts.CgroupRoot == "" {
+		setupLog.Info("No cgroup root, the cloud-hypervisor instances are not pinned to their numa nodes")
+	}
+
+	vmmOpts := vmm.ManagerOptions{
+		CHSocketsPaths:    opts.CloudHypervisorSocketsPaths,
+		SocketSelection:   socketSelection,
+		NUMANodes:         opts.CloudHypervisorNUMANodes,
+		FirmwarePath:      opts.CloudHypervisorFirmwarePath,
+		ReservedInstances: socketsInUse,
+		EnableVsock:       opts.MetadataVsockPort != 0,
+		NICPCISegments:    opts.NICPCISegments,
+
+		IgnitionCompression: opts.IgnitionCompression,
+		MinVersion:          opts.CloudHypervisorMinVersion,
+		RequiredFeatures:    opts.CloudHypervisorRequiredFeatures,
+		LandlockRules:       landlockRules,
+
+		VMConfigOverrides:         opts.VMConfigOverrides,
+		VMConfigOverrideAllowlist: opts.VMConfigOverrideAllowlist,
+
+		OEMStringLabels:      opts.OEMStringLabels,
+		OEMStringAnnotations: opts.OEMStringAnnotations,
+
+		VMInfoCacheTTL: opts.VMInfoCacheTTL,
+		APIRateLimit:   opts.VMMAPIRateLimit,
+		APIBurst:       opts.VMMAPIBurst,
+	}
+
+	var virtualMachineManager vmm.VirtualMachineManager
+	switch opts.VMMMode {
+	case vmm.ModeReal:
+		virtualMachineManager, err = vmm.NewManager(log.WithName("virtual-machine-manager"), hostPaths, vmmOpts)
+	case vmm.ModeFake:
+		if opts.CgroupRoot != "" || opts.ChownMachineDirs {
+			return nil, fmt.Errorf("--cgroup-root and --chown-machine-dirs are not supported with --vmm-mode=%s",
+				vmm.ModeFake)
+		}
+		setupLog.Info("Using fake virtual-machine-manager, VMs are only simulated")
+		virtualMachineManager, err = vmm.NewFakeManager(
+			log.WithName("virtual-machine-manager"),
+			hostPaths,
+			vmmOpts,
+			opts.FakeVMMInstances,
+		)
+	default:
+		return nil, fmt.Errorf("unknown vmm mode %q", opts.VMMMode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize virtual-machine-manager: %w", err)
+	}
+	return virtualMachineManager, nil
+}
+
+// setupMetadataServer returns the metadata server, nil if it is disabled.
+func setupMetadataServer(
+	log logr.Logger,
+	opts Options,
+	machineStore store.Store[*api.Machine],
+	machineEvents event.Source[*api.Machine],
+	hostPaths host.Paths,
+) (*metadata.Server, error) {
+	if opts.MetadataVsockPort == 0 && opts.MetadataLinkLocalAddress == "" {
+		return nil, nil
+	}
+	metadataServer, err := metadata.NewServer(
+		log.WithName("metadata-server"),
+		machineStore,
+		machineEvents,
+		hostPaths,
+		metadata.Options{
+			Port:             opts.MetadataVsockPort,
+			LinkLocalAddress: opts.MetadataLinkLocalAddress,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize metadata server: %w", err)
+	}
+	return metadataServer, nil
+}
+
+// migrationClient configures how the machine reconciler migrates VMs to other providers.
+type migrationClient struct {
+	advertiseAddress string
+	tlsConfig        *tls.Config
+}
+
+// setupMigration returns the migration server, nil if it is disabled, and the config of the migration client.
+func setupMigration(
+	log logr.Logger,
+	opts Options,
+	machineStore store.Store[*api.Machine],
+	maintenanceMode *maintenance.Mode,
+) (*migration.Server, migrationClient, error) {
+	client := migrationClient{advertiseAddress: opts.MigrationAdvertiseAddress}
+	if client.advertiseAddress == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, migrationClient{}, fmt.Errorf("failed to get hostname: %w", err)
+		}
+		client.advertiseAddress = hostname
+	}
+
+	serverTLS, err := migration.ServerTLSConfig(opts.MigrationTLS)
+	if err != nil {
+		return nil, migrationClient{}, fmt.Errorf("failed to load migration tls config: %w", err)
+	}
+	if client.tlsConfig, err = migration.ClientTLSConfig(opts.MigrationTLS); err != nil {
+		return nil, migrationClient{}, fmt.Errorf("failed to load migration tls config: %w", err)
+	}
+
+	if opts.MigrationBindAddress == "" {
+		return nil, client, nil
+	}
+	migrationServer, err := migration.NewServer(
+		log.WithName("migration-server"),
+		machineStore,
+		migration.Options{
+			BindAddress: opts.MigrationBindAddress,
+			TLSConfig:   serverTLS,
+			Maintenance: maintenanceMode,
+		},
+	)
+	if err != nil {
+		return nil, migrationClient{}, fmt.Errorf("failed to initialize migration server: %w", err)
+	}
+	return migrationServer, client, nil
+}
+
+// setupCgroups returns the cgroup manager, nil without cgroup root.
+func setupCgroups(opts Options) (*cgroup.Manager, error) {
+	if opts.CgroupRoot == "" {
+		return nil, nil
+	}
+	cgroups, err := cgroup.NewManager(cgroup.Options{
+		Root:   opts.CgroupRoot,
+		CPUSet: len(opts.CloudHypervisorNUMANodes) > 0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cgroup manager: %w", err)
+	}
+	return cgroups, nil
+}
+
+func setupEventRecorder(log logr.Logger, opts Options) (*events.Recorder, error) {
+	eventSinks, err := opts.eventSinks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize event sinks: %w", err)
+	}
+	return events.NewRecorder(
+		log.WithName("event-recorder"),
+		recorder.NewEventStore(log, recorder.EventStoreOptions{}),
+		eventSinks...,
+	), nil
+}
+
+// setupAdminServer returns the admin server and the console scrollback it serves, each nil if it is disabled.
+func setupAdminServer(
+	log logr.Logger,
+	opts Options,
+	machineStore store.Store[*api.Machine],
+	hostPaths host.Paths,
+	reconciler admin.Reconciler,
+	maintenanceMode *maintenance.Mode,
+) (*admin.Server, *console.Scrollback, error) {
+	var scrollback *console.Scrollback
+	if opts.ConsoleScrollbackSize > 0 {
+		var err error
+		scrollback, err = console.NewScrollback(
+			log.WithName("console-scrollback"),
+			machineStore,
+			hostPaths,
+			opts.ConsoleScrollbackSize,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize console scrollback: %w", err)
+		}
+	}
+
+	if opts.AdminSocket == "" {
+		return nil, scrollback, nil
+	}
+	adminOpts := admin.Options{
+		SocketPath:  opts.AdminSocket,
+		Maintenance: maintenanceMode,
+	}
+	if scrollback != nil {
+		adminOpts.Console = scrollback
+	}
+	adminServer, err := admin.NewServer(
+		log.WithName("admin-server"),
+		reconciler,
+		adminOpts,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize admin server: %w", err)
+	}
+	return adminServer, scrollback, nil
+}
+
+// setupServer returns the IRI server and its quotas, nil without quota label.
+func setupServer(
+	opts Options,
+	machineStore *hostutils.Store[*api.Machine],
+	eventStore recorder.EventStore,
+	classRegistry *mcr.Mcr,
+	maintenanceMode *maintenance.Mode,
+) (*server.Server, *quota.Registry, error) {
+	var quotas *quota.Registry
+	if opts.QuotaLabel != "" {
+		var err error
+		if quotas, err = quota.NewRegistry(opts.QuotaLabel, opts.Quotas); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize quotas: %w", err)
+		}
+	}
+
+	capacity, systemReserved, err := hostResources(opts.RootDir, opts.SystemReserved)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get host resources: %w", err)
+	}
+
+	srv, err := server.New(machineStore, server.Options{
+		EventStore:           eventStore,
+		MachineClassRegistry: classRegistry,
+		IgnitionTransport:    api.IgnitionTransport(opts.IgnitionTransport),
+		IgnitionCompression:  opts.IgnitionCompression,
+		Landlock:             opts.Landlock,
+		DNS: api.DNSSpec{
+			Servers: opts.GuestDNSServers,
+			Search:  opts.GuestDNSSearch,
+		},
+		Maintenance:    maintenanceMode,
+		Capacity:       capacity,
+		SystemReserved: systemReserved,
+		Quotas:         quotas,
+		FreeHugepages:  host.FreeHugepages,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating server: %w", err)
+	}
+	return srv, quotas, nil
+}
+
+// setupVolumeMonitors returns the enabled components watching the volumes and the stats of the machines.
+func setupVolumeMonitors(
+	log logr.Logger,
+	opts Options,
+	machineStore store.Store[*api.Machine],
+	eventRecorder recorder.EventRecorder,
+	virtualMachineManager vmm.VirtualMachineManager,
+	pluginManager *volume.PluginManager,
+) ([]component, error) {
+	var components []component
+	if opts.StatsInterval > 0 {
+		statsCollector, err := stats.NewCollector(
+			log.WithName("stats-collector"),
+			machineStore,
+			virtualMachineManager,
+			stats.Options{
+				Interval:      opts.StatsInterval,
+				VolumePlugins: pluginManager,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize stats collector: %w", err)
+		}
+		components = addComponent(components, "stats collector", statsCollector)
+	}
+
+	if opts.VolumeHealthInterval > 0 {
+		volumeProber, err := health.NewVolumeProber(
+			log.WithName("volume-prober"),
+			machineStore,
+			eventRecorder,
+			health.Options{
+				Interval:      opts.VolumeHealthInterval,
+				VolumePlugins: pluginManager,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize volume prober: %w", err)
+		}
+		components = addComponent(components, "volume prober", volumeProber)
+	}
+
+	if opts.VolumeGCInterval > 0 {
+		volumeGC, err := volume.NewGarbageCollector(
+			log.WithName("volume-gc"),
+			machineStore,
+			pluginManager,
+			opts.VolumeGCInterval,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize volume garbage collector: %w", err)
+		}
+		components = addComponent(components, "volume garbage collector", volumeGC)
+	}
+	return components, nil
+}
+
+// setupHealthServer returns the health server, nil if it is disabled.
+func setupHealthServer(
+	log logr.Logger,
+	opts Options,
+	machineStore store.Store[*api.Machine],
+	imgCacheRunning, grpcServing *health.Flag,
+) (*health.Server, error) {
+	if opts.HealthBindAddress == "0" {
+		return nil, nil
+	}
+	healthServer, err := health.NewServer(log.WithName("health-server"), health.ServerOptions{
+		BindAddress: opts.HealthBindAddress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize health server: %w", err)
+	}
+	healthServer.AddReadinessCheck("machine-store", func(req *http.Request) error {
+		_, err := machineStore.List(req.Context())
+		return err
+	})
+	if opts.VMMMode != vmm.ModeFake {
+		healthServer.AddReadinessCheck("sockets-dir", func(_ *http.Request) error {
+			for _, dir := range opts.CloudHypervisorSocketsPaths {
+				if _, err := os.ReadDir(dir); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	healthServer.AddReadinessCheck("image-cache", imgCacheRunning.Check)
+	healthServer.AddReadinessCheck("grpc", grpcServing.Check)
+	return healthServer, nil
+}
+
+// newConfigReloader returns the reloader of the config file, nil without config file.
+func newConfigReloader(
+	ctx context.Context,
+	log logr.Logger,
+	opts Options,
+	classRegistry *mcr.Mcr,
+	machineStore store.Store[*api.Machine],
+	reconciler *controllers.MachineReconciler,
+	quotas *quota.Registry,
+	maintenanceMode *maintenance.Mode,
+) *configReloader {
+	if opts.config == nil {
+		return nil
+	}
+	return &configReloader{
+		log:    log.WithName("config-reloader"),
+		config: opts.config,
+		apply: func(newOpts *Options, changed sets.Set[string]) error {
+			if changed.Has("machine-class") {
+				if err := validateMachineClasses(newOpts.MachineClasses, opts.VMMMode, opts.CephClonePool,
+					opts.VMConfigOverrideAllowlist); err != nil {
+					return err
+				}
+				if err := classRegistry.Set(newOpts.MachineClasses.registryClasses()); err != nil {
+					return err
+				}
+				// The conditions of machines whose class was deprecated or removed are updated right away.
+				machines, err := machineStore.List(ctx)
+				if err != nil {
+					return err
+				}
+				for _, machine := range machines {
+					if err := reconciler.Requeue(ctx, machine.ID); err != nil {
+						log.Error(err, "Failed to requeue machine", "machineID", machine.ID)
+					}
+				}
+			}
+			if changed.Has("quota") && quotas != nil {
+				if err := quotas.Set(newOpts.Quotas); err != nil {
+					return err
+				}
+			}
+			if changed.HasAny("maintenance", "maintenance-evacuation") {
+				state := maintenanceMode.State()
+				enabled, evacuation := state.Enabled, state.Evacuation
+				if changed.Has("maintenance") {
+					enabled = newOpts.Maintenance
+				}
+				if changed.Has("maintenance-evacuation") {
+					var err error
+					if evacuation, err = maintenance.ParseEvacuation(newOpts.MaintenanceEvacuation); err != nil {
+						return err
+					}
+				}
+				maintenanceMode.Set(enabled, evacuation)
+			}
+			return nil
+		},
+	}
+}
@@ -24,20 +24,52 @@ const (
 	FirmwareName = "firmware"
 	Uid          = 65532 // Desired user ID
 	Gid          = 65532 // Desired group ID
+
+	defaultSubDir = "version"
 )
 
 type Options struct {
 	Download bool
 
+	CheckKVM                    bool
+	RequireNestedVirtualization bool
+	RequiredKernelModules       []string
+	Remediate                   bool
+	KVMUser                     string
+
+	Hugepages          int
+	HugepageSize       string
+	HugetlbfsMountPath string
+	Sysctls            map[string]string
+
 	ProviderBasePath string
 
 	CloudHypervisorBinPath   string
 	CloudHypervisorBinSubDir string
 	CloudHypervisorBinUrl    string
-
-	CloudHypervisorFirmwarePath   string
-	CloudHypervisorFirmwareSubDir string
-	CloudHypervisorFirmwareUrl    string
+	CloudHypervisorVersion   string
+
+	CloudHypervisorFirmwarePath    string
+	CloudHypervisorFirmwareSubDir  string
+	CloudHypervisorFirmwareUrl     string
+	CloudHypervisorFirmwareVersion string
+
+	StorageDaemon        bool
+	StorageDaemonPath    string
+	StorageDaemonSubDir  string
+	StorageDaemonUrl     string
+	StorageDaemonVersion string
+	StorageDaemonSHA256  string
+
+	SocketPoolSize             int
+	CloudHypervisorSocketsPath string
+	SystemdUnitDir             string
+	InstanceUIDBase            int
+	Seccomp                    string
+
+	SELinuxContext     string
+	SELinuxFileContext string
+	AppArmorProfile    string
 }
 
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
@@ -48,6 +80,62 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"Download binaries otherwise it will error if files are not present.",
 	)
 
+	fs.BoolVar(
+		&o.CheckKVM,
+		"check-kvm",
+		true,
+		"Check that the host is able to run virtual machines.",
+	)
+	fs.BoolVar(
+		&o.RequireNestedVirtualization,
+		"require-nested-virtualization",
+		false,
+		"Fail if nested virtualization is not enabled.",
+	)
+	fs.StringSliceVar(
+		&o.RequiredKernelModules,
+		"required-kernel-modules",
+		[]string{"kvm", "vhost_vsock", "vhost_net"},
+		"Kernel modules that have to be loaded.",
+	)
+	fs.BoolVar(
+		&o.Remediate,
+		"remediate",
+		false,
+		"Try to fix failed host checks, e.g. by loading kernel modules or installing udev rules.",
+	)
+	fs.StringVar(
+		&o.KVMUser,
+		"kvm-user",
+		"chp",
+		"User that is added to the kvm group on remediation.",
+	)
+
+	fs.IntVar(
+		&o.Hugepages,
+		"hugepages",
+		0,
+		"Number of hugepages to reserve.",
+	)
+	fs.StringVar(
+		&o.HugepageSize,
+		"hugepage-size",
+		"2Mi",
+		"Size of the hugepages to reserve and mount.",
+	)
+	fs.StringVar(
+		&o.HugetlbfsMountPath,
+		"hugetlbfs-mount-path",
+		"",
+		"Path to mount a hugetlbfs owned by the provider user at. Nothing is mounted if empty.",
+	)
+	fs.StringToStringVar(
+		&o.Sysctls,
+		"sysctl",
+		nil,
+		"Sysctls to set (format: key=value), e.g. vm.max_map_count=262144.",
+	)
+
 	fs.StringVar(
 		&o.ProviderBasePath,
 		"provider-base-path",
@@ -64,7 +152,7 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(
 		&o.CloudHypervisorBinSubDir,
 		"cloud-hypervisor-bin-sub-dir",
-		"version",
+		defaultSubDir,
 		"Sub-directory of the cloud-hypervisor binary.",
 	)
 	fs.StringVar(
@@ -73,6 +161,13 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"",
 		"Cloud-hypervisor binary url.",
 	)
+	fs.StringVar(
+		&o.CloudHypervisorVersion,
+		"cloud-hypervisor-version",
+		"",
+		"Cloud-hypervisor release (e.g. v41.0) to download from GitHub for the host architecture. "+
+			"Used if no binary url is set.",
+	)
 
 	fs.StringVar(
 		&o.CloudHypervisorFirmwarePath,
@@ -83,7 +178,7 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(
 		&o.CloudHypervisorFirmwareSubDir,
 		"cloud-hypervisor-firmware-sub-dir",
-		"version",
+		defaultSubDir,
 		"Sub-directory of the cloud-hypervisor firmware.",
 	)
 	fs.StringVar(
@@ -92,6 +187,99 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 		"",
 		"Cloud-hypervisor firmware url.",
 	)
+	fs.StringVar(
+		&o.CloudHypervisorFirmwareVersion,
+		"cloud-hypervisor-firmware-version",
+		"",
+		"Rust-hypervisor-firmware release (e.g. 0.5.0) to download from GitHub for the host architecture. "+
+			"Used if no firmware url is set.",
+	)
+
+	fs.BoolVar(
+		&o.StorageDaemon,
+		"qemu-storage-daemon",
+		false,
+		"Ensure the qemu-storage-daemon required by the ceph volume plugin is present.",
+	)
+	fs.StringVar(
+		&o.StorageDaemonPath,
+		"qemu-storage-daemon-path",
+		"/usr/local/bin/qemu-storage-daemon",
+		"Path to the qemu-storage-daemon binary.",
+	)
+	fs.StringVar(
+		&o.StorageDaemonSubDir,
+		"qemu-storage-daemon-sub-dir",
+		defaultSubDir,
+		"Sub-directory of the qemu-storage-daemon binary.",
+	)
+	fs.StringVar(
+		&o.StorageDaemonUrl,
+		"qemu-storage-daemon-url",
+		"",
+		"Qemu-storage-daemon binary url.",
+	)
+	fs.StringVar(
+		&o.StorageDaemonVersion,
+		"qemu-storage-daemon-version",
+		"",
+		"Expected qemu-storage-daemon version (e.g. 9.2.0). Used as sub-directory unless one is set.",
+	)
+	fs.StringVar(
+		&o.StorageDaemonSHA256,
+		"qemu-storage-daemon-sha256",
+		"",
+		"Expected sha256 checksum of the qemu-storage-daemon binary.",
+	)
+
+	fs.IntVar(
+		&o.SocketPoolSize,
+		"socket-pool-size",
+		0,
+		"Number of cloud-hypervisor instances to run as systemd units. The pool is not managed if 0.",
+	)
+	fs.StringVar(
+		&o.CloudHypervisorSocketsPath,
+		"cloud-hypervisor-sockets-path",
+		"/run/chp/ch",
+		"Path to the directory of the cloud-hypervisor api sockets.",
+	)
+	fs.StringVar(
+		&o.SystemdUnitDir,
+		"systemd-unit-dir",
+		"/etc/systemd/system",
+		"Directory to write the systemd units of the socket pool to.",
+	)
+	fs.IntVar(
+		&o.InstanceUIDBase,
+		"instance-uid-base",
+		0,
+		"Run cloud-hypervisor instance N as uid <base>+N instead of the provider user. Disabled if 0.",
+	)
+	fs.StringVar(
+		&o.Seccomp,
+		"cloud-hypervisor-seccomp",
+		"true",
+		"Seccomp mode of the cloud-hypervisor instances (true, false, log).",
+	)
+	fs.StringVar(
+		&o.SELinuxContext,
+		"selinux-context",
+		"",
+		"SELinux context to run the cloud-hypervisor instances in (e.g. system_u:system_r:svirt_t:s0).",
+	)
+	fs.StringVar(
+		&o.SELinuxFileContext,
+		"selinux-file-context",
+		"",
+		"SELinux context to label the sockets dir with (e.g. system_u:object_r:svirt_image_t:s0).",
+	)
+	fs.StringVar(
+		&o.AppArmorProfile,
+		"apparmor-profile",
+		"",
+		"AppArmor profile to confine the cloud-hypervisor instances with.",
+	)
 }
 
 func Command() *cobra.Command {
@@ -126,6 +314,16 @@ func Run(ctx context.Context, opts Options) error {
 
 	log.Info("starting host preparation")
 
+	if opts.CheckKVM {
+		if err := checkKVM(ctx, log, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := configureMemory(log, opts); err != nil {
+		return err
+	}
+
 	log.V(1).Info("checking if base path exists", "path", opts.ProviderBasePath)
 	info, err := os.Stat(opts.ProviderBasePath)
 	if os.IsNotExist(err) {
@@ -139,6 +337,10 @@ func Run(ctx context.Context, opts Options) error {
 		return fmt.Errorf("path exists but is not a directory: %s", opts.ProviderBasePath)
 	}
 
+	if err := resolveVersions(ctx, log, &opts); err != nil {
+		return err
+	}
+
 	log.V(1).Info("setting owner", "path", opts.ProviderBasePath, "uid", Uid, "gid", Gid)
 	if err := os.Chown(opts.ProviderBasePath, Uid, Gid); err != nil {
 		return fmt.Errorf("failed to set owner: %w", err)
@@ -196,6 +398,65 @@ func Run(ctx context.Context, opts Options) error {
 		}
 	}
 
+	if opts.StorageDaemon {
+		if err := ensureStorageDaemon(ctx, log, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.SocketPoolSize > 0 {
+		if err := reconcileSocketPool(ctx, log, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveVersions resolves the download urls of versioned artifacts. The version is used as
+// sub-directory unless a different one was configured.
+func resolveVersions(ctx context.Context, log logr.Logger, opts *Options) error {
+	if opts.CloudHypervisorVersion != "" {
+		if opts.CloudHypervisorBinSubDir == defaultSubDir {
+			opts.CloudHypervisorBinSubDir = opts.CloudHypervisorVersion
+		}
+
+		if opts.CloudHypervisorBinUrl == "" {
+			assetName, err := cloudHypervisorAssetName()
+			if err != nil {
+				return err
+			}
+
+			url, err := resolveReleaseAssetURL(ctx, CloudHypervisorRepository, opts.CloudHypervisorVersion, assetName)
+			if err != nil {
+				return fmt.Errorf("failed to resolve cloud-hypervisor binary url: %w", err)
+			}
+			log.V(1).Info("resolved cloud-hypervisor binary url", "version", opts.CloudHypervisorVersion, "url", url)
+			opts.CloudHypervisorBinUrl = url
+		}
+	}
+
+	if opts.CloudHypervisorFirmwareVersion != "" {
+		if opts.CloudHypervisorFirmwareSubDir == defaultSubDir {
+			opts.CloudHypervisorFirmwareSubDir = opts.CloudHypervisorFirmwareVersion
+		}
+
+		if opts.CloudHypervisorFirmwareUrl == "" {
+			assetName, err := firmwareAssetName()
+			if err != nil {
+				return err
+			}
+
+			url, err := resolveReleaseAssetURL(ctx, FirmwareRepository, opts.CloudHypervisorFirmwareVersion, assetName)
+			if err != nil {
+				return fmt.Errorf("failed to resolve cloud-hypervisor firmware url: %w", err)
+			}
+			log.V(1).Info("resolved cloud-hypervisor firmware url",
+				"version", opts.CloudHypervisorFirmwareVersion, "url", url)
+			opts.CloudHypervisorFirmwareUrl = url
+		}
+	}
+
 	return nil
 }
 
@@ -214,6 +475,10 @@ func fetch(log logr.Logger, fileURL, saveDir, fileName string, isExe bool) error
 		_ = resp.Body.Close()
 	}()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download the file: unexpected status %s", resp.Status)
+	}
+
 	outPath := path.Join(saveDir, fileName)
 	outFile, err := os.Create(outPath)
 	if err != nil {
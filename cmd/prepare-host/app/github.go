@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+)
+
+const (
+	CloudHypervisorRepository = "cloud-hypervisor/cloud-hypervisor"
+	FirmwareRepository        = "cloud-hypervisor/rust-hypervisor-firmware"
+
+	gitHubAPIURL = "https://api.github.com"
+)
+
+type gitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// cloudHypervisorAssetName returns the name of the static cloud-hypervisor release asset for the host architecture.
+func cloudHypervisorAssetName() (string, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "cloud-hypervisor-static", nil
+	case "arm64":
+		return "cloud-hypervisor-static-aarch64", nil
+	default:
+		return "", fmt.Errorf("unsupported architecture %s", runtime.GOARCH)
+	}
+}
+
+// firmwareAssetName returns the name of the rust-hypervisor-firmware release asset for the host architecture.
+func firmwareAssetName() (string, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "hypervisor-fw", nil
+	case "arm64":
+		return "hypervisor-fw-aarch64", nil
+	default:
+		return "", fmt.Errorf("unsupported architecture %s", runtime.GOARCH)
+	}
+}
+
+// resolveReleaseAssetURL looks up the download url of an asset of a GitHub release.
+func resolveReleaseAssetURL(ctx context.Context, repository, version, assetName string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", gitHubAPIURL, repository, version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get release %s of %s: %w", version, repository, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get release %s of %s: unexpected status %s", version, repository, resp.Status)
+	}
+
+	release := &gitHubRelease{}
+	if err := json.NewDecoder(resp.Body).Decode(release); err != nil {
+		return "", fmt.Errorf("failed to decode release: %w", err)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name == assetName {
+			return asset.BrowserDownloadURL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s of %s has no asset %s", version, repository, assetName)
+}
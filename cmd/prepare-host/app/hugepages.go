@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	hugepagesSysfsDir = "/sys/kernel/mm/hugepages"
+	procSysDir        = "/proc/sys"
+	procMountsFile    = "/proc/mounts"
+)
+
+// configureMemory reserves hugepages, applies sysctls and mounts hugetlbfs as configured.
+func configureMemory(log logr.Logger, opts Options) error {
+	for key, value := range opts.Sysctls {
+		if err := setSysctl(log, key, value); err != nil {
+			return err
+		}
+	}
+
+	if opts.Hugepages > 0 {
+		if err := reserveHugepages(log, opts.HugepageSize, opts.Hugepages); err != nil {
+			return err
+		}
+	}
+
+	if opts.HugetlbfsMountPath != "" {
+		if err := mountHugetlbfs(log, opts.HugetlbfsMountPath, opts.HugepageSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setSysctl(log logr.Logger, key, value string) error {
+	p := filepath.Join(procSysDir, strings.ReplaceAll(key, ".", "/"))
+	log.V(1).Info("setting sysctl", "key", key, "value", value)
+	if err := os.WriteFile(p, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to set sysctl %s=%s: %w", key, value, err)
+	}
+	return nil
+}
+
+func hugepageSizeKB(size string) (int64, error) {
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hugepage size %q: %w", size, err)
+	}
+	return quantity.Value() / 1024, nil
+}
+
+func reserveHugepages(log logr.Logger, size string, count int) error {
+	sizeKB, err := hugepageSizeKB(size)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(hugepagesSysfsDir, fmt.Sprintf("hugepages-%dkB", sizeKB))
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("hugepage size %s is not supported by the host: %w", size, err)
+	}
+
+	nrHugepages := filepath.Join(dir, "nr_hugepages")
+	log.Info("reserving hugepages", "size", size, "count", count)
+	if err := os.WriteFile(nrHugepages, []byte(strconv.Itoa(count)), 0644); err != nil {
+		return fmt.Errorf("failed to reserve hugepages: %w", err)
+	}
+
+	data, err := os.ReadFile(nrHugepages)
+	if err != nil {
+		return fmt.Errorf("failed to read reserved hugepages: %w", err)
+	}
+	reserved, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("failed to parse reserved hugepages: %w", err)
+	}
+	if reserved < count {
+		return fmt.Errorf("only %d of %d hugepages of size %s could be reserved: "+
+			"reserve them on the kernel command line (hugepagesz=%s hugepages=%d) to avoid fragmentation",
+			reserved, count, size, size, count)
+	}
+	return nil
+}
+
+func isMounted(path string) (bool, error) {
+	f, err := os.Open(procMountsFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[1] == path {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func mountHugetlbfs(log logr.Logger, path, size string) error {
+	mounted, err := isMounted(path)
+	if err != nil {
+		return err
+	}
+	if mounted {
+		log.V(1).Info("hugetlbfs already mounted", "path", path)
+		return nil
+	}
+
+	if err := os.MkdirAll(path, 0770); err != nil {
+		return fmt.Errorf("failed to create hugetlbfs mount point: %w", err)
+	}
+
+	sizeKB, err := hugepageSizeKB(size)
+	if err != nil {
+		return err
+	}
+
+	data := fmt.Sprintf("pagesize=%dK,uid=%d,gid=%d,mode=0770", sizeKB, Uid, Gid)
+	log.Info("mounting hugetlbfs", "path", path, "options", data)
+	if err := syscall.Mount("hugetlbfs", path, "hugetlbfs", 0, data); err != nil {
+		return fmt.Errorf("failed to mount hugetlbfs at %s: %w", path, err)
+	}
+	return nil
+}
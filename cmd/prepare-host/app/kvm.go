@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	KVMDevice       = "/dev/kvm"
+	KVMUdevRulePath = "/etc/udev/rules.d/65-chp-kvm.rules"
+
+	kvmUdevRule = `KERNEL=="kvm", GROUP="kvm", MODE="0660"` + "\n"
+)
+
+var nestedParameterPaths = []string{
+	"/sys/module/kvm_intel/parameters/nested",
+	"/sys/module/kvm_amd/parameters/nested",
+}
+
+// checkKVM verifies that the host is able to run VMs for the provider user and
+// optionally remediates what can be fixed on the host.
+func checkKVM(ctx context.Context, log logr.Logger, opts Options) error {
+	var errs []error
+
+	for _, module := range opts.RequiredKernelModules {
+		if err := checkKernelModule(ctx, log, module, opts.Remediate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := checkKVMDevice(ctx, log, opts); err != nil {
+		errs = append(errs, err)
+	}
+
+	if opts.RequireNestedVirtualization {
+		if err := checkNestedVirtualization(log); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("host is not ready to run virtual machines:\n%w", err)
+	}
+	log.Info("host is ready to run virtual machines")
+	return nil
+}
+
+func checkKernelModule(ctx context.Context, log logr.Logger, module string, remediate bool) error {
+	if _, err := os.Stat(filepath.Join("/sys/module", module)); err == nil {
+		log.V(1).Info("kernel module loaded", "module", module)
+		return nil
+	}
+
+	if !remediate {
+		return fmt.Errorf("kernel module %s is not loaded: run 'modprobe %s' or rerun with --remediate", module, module)
+	}
+
+	log.Info("loading kernel module", "module", module)
+	if out, err := exec.CommandContext(ctx, "modprobe", module).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to load kernel module %s: %w: %s", module, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func checkKVMDevice(ctx context.Context, log logr.Logger, opts Options) error {
+	info, err := os.Stat(KVMDevice)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s does not exist: enable virtualization in the firmware and load kvm_intel or kvm_amd",
+				KVMDevice)
+		}
+		return fmt.Errorf("failed to stat %s: %w", KVMDevice, err)
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("%s is not a character device", KVMDevice)
+	}
+
+	accessible, err := isKVMAccessible(info)
+	if err != nil {
+		return err
+	}
+	if accessible {
+		log.V(1).Info("kvm device accessible", "uid", Uid)
+		return nil
+	}
+
+	if !opts.Remediate {
+		return fmt.Errorf("%s is not accessible by uid %d: add the user to the group of %s "+
+			"(e.g. via a udev rule 'GROUP=\"kvm\", MODE=\"0660\"') or rerun with --remediate", KVMDevice, Uid, KVMDevice)
+	}
+	return remediateKVMAccess(ctx, log, opts.KVMUser)
+}
+
+func isKVMAccessible(info os.FileInfo) (bool, error) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, fmt.Errorf("failed to get owner of %s", KVMDevice)
+	}
+
+	perm := info.Mode().Perm()
+	switch {
+	case perm&0006 == 0006:
+		return true, nil
+	case stat.Uid == Uid && perm&0600 == 0600:
+		return true, nil
+	case perm&0060 != 0060:
+		return false, nil
+	}
+
+	if stat.Gid == Gid {
+		return true, nil
+	}
+
+	u, err := user.LookupId(strconv.Itoa(Uid))
+	if err != nil {
+		var unknownUserErr user.UnknownUserIdError
+		if errors.As(err, &unknownUserErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up uid %d: %w", Uid, err)
+	}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return false, fmt.Errorf("failed to look up groups of uid %d: %w", Uid, err)
+	}
+	return slices.Contains(groups, strconv.Itoa(int(stat.Gid))), nil
+}
+
+func remediateKVMAccess(ctx context.Context, log logr.Logger, kvmUser string) error {
+	log.Info("installing udev rule", "path", KVMUdevRulePath)
+	if err := os.WriteFile(KVMUdevRulePath, []byte(kvmUdevRule), 0644); err != nil {
+		return fmt.Errorf("failed to write udev rule: %w", err)
+	}
+
+	for _, args := range [][]string{
+		{"udevadm", "control", "--reload-rules"},
+		{"udevadm", "trigger", "--name-match=kvm"},
+		{"gpasswd", "-a", kvmUser, "kvm"},
+	} {
+		log.V(1).Info("running remediation", "command", strings.Join(args, " "))
+		if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to run %q: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+func checkNestedVirtualization(log logr.Logger) error {
+	for _, p := range nestedParameterPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(data))
+		if value == "Y" || value == "1" {
+			log.V(1).Info("nested virtualization enabled", "parameter", p)
+			return nil
+		}
+		return fmt.Errorf("nested virtualization is disabled (%s=%s): reload the kvm module with nested=1", p, value)
+	}
+	return fmt.Errorf("nested virtualization is not supported: neither kvm_intel nor kvm_amd is loaded")
+}
@@ -0,0 +1,396 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+)
+
+const (
+	InstanceUnitName = "cloud-hypervisor@.service"
+	PoolTargetName   = "cloud-hypervisor.target"
+)
+
+var instanceUnitTemplate = template.Must(template.New("instance").Parse(`# Generated by prepare-host. Do not edit.
+[Unit]
+Description=Cloud Hypervisor Instance %i
+PartOf={{ .Target }}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+User={{ .Uid }}
+Group={{ .Gid }}
+ExecStartPre=-/bin/rm -f {{ .SocketsPath }}/%i.sock {{ .SocketsPath }}/%i.events
+ExecStart={{ .Binary }} --api-socket {{ .SocketsPath }}/%i.sock --event-monitor path={{ .SocketsPath }}/%i.events \
+  --seccomp {{ .Seccomp }} -v
+UMask=0007
+{{- if .SELinuxContext }}
+SELinuxContext={{ .SELinuxContext }}
+{{- end }}
+{{- if .AppArmorProfile }}
+AppArmorProfile={{ .AppArmorProfile }}
+{{- end }}
+LimitMEMLOCK=infinity
+Restart=on-failure
+RestartSec=1
+StandardOutput=journal
+StandardError=journal
+
+[Install]
+WantedBy={{ .Target }}
+`))
+
+var targetTemplate = template.Must(template.New("target").Parse(`# Generated by prepare-host. Do not edit.
+[Unit]
+Description=Cloud Hypervisor Fleet
+{{- range .Instances }}
+Wants=cloud-hypervisor@{{ . }}.service
+{{- end }}
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// userDropInTemplate runs an instance as a distinct user. The group stays the provider's so that the
+// provider can access the api socket and the files of the instance.
+var userDropInTemplate = template.Must(template.New("user").Parse(`# Generated by prepare-host. Do not edit.
+[Service]
+User={{ .Uid }}
+SupplementaryGroups=kvm
+`))
+
+const userDropInName = "10-user.conf"
+
+type instanceUnit struct {
+	Target      string
+	Uid         int
+	Gid         int
+	SocketsPath string
+	Binary      string
+	Seccomp     string
+
+	SELinuxContext  string
+	AppArmorProfile string
+}
+
+// reconcileSocketPool writes the systemd units of the cloud-hypervisor instances, starts
+// the configured number of instances and removes instances and sockets beyond it. Running
+// instances are only restarted if their units changed and they hold no VM.
+func reconcileSocketPool(ctx context.Context, log logr.Logger, opts Options) error {
+	log = log.WithValues("size", opts.SocketPoolSize, "socketsPath", opts.CloudHypervisorSocketsPath)
+	log.Info("reconciling cloud-hypervisor socket pool")
+
+	switch opts.Seccomp {
+	case "true", "false", "log":
+	default:
+		return fmt.Errorf("invalid seccomp mode %q, expected true, false or log", opts.Seccomp)
+	}
+
+	if err := os.MkdirAll(opts.CloudHypervisorSocketsPath, 0755); err != nil {
+		return fmt.Errorf("failed to create sockets dir: %w", err)
+	}
+	if err := os.Chown(opts.CloudHypervisorSocketsPath, Uid, Gid); err != nil {
+		return fmt.Errorf("failed to set owner of sockets dir: %w", err)
+	}
+	// Instances running as distinct users create their sockets via the group.
+	if err := os.Chmod(opts.CloudHypervisorSocketsPath, 0770); err != nil {
+		return fmt.Errorf("failed to set mode of sockets dir: %w", err)
+	}
+	if opts.SELinuxFileContext != "" {
+		if err := host.SetFileContext(opts.CloudHypervisorSocketsPath, opts.SELinuxFileContext); err != nil {
+			return err
+		}
+	}
+
+	unitPath := filepath.Join(opts.SystemdUnitDir, InstanceUnitName)
+	unitChanged, err := writeUnit(log, unitPath, instanceUnitTemplate, instanceUnit{
+		Target:      PoolTargetName,
+		Uid:         Uid,
+		Gid:         Gid,
+		SocketsPath: filepath.Clean(opts.CloudHypervisorSocketsPath),
+		Binary:      path.Join(opts.CloudHypervisorBinPath, opts.CloudHypervisorBinSubDir, ChName),
+		Seccomp:     opts.Seccomp,
+
+		SELinuxContext:  opts.SELinuxContext,
+		AppArmorProfile: opts.AppArmorProfile,
+	})
+	if err != nil {
+		return err
+	}
+
+	var instances []int
+	for i := 1; i <= opts.SocketPoolSize; i++ {
+		instances = append(instances, i)
+	}
+	if _, err := writeUnit(log, filepath.Join(opts.SystemdUnitDir, PoolTargetName), targetTemplate, struct {
+		Instances []int
+	}{instances}); err != nil {
+		return err
+	}
+
+	var changed []int
+	for _, instance := range instances {
+		dropInChanged, err := reconcileUserDropIn(log, opts, instance)
+		if err != nil {
+			return err
+		}
+		if unitChanged || dropInChanged {
+			changed = append(changed, instance)
+		}
+	}
+
+	if err := systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	}
+
+	stale, err := staleInstances(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, instance := range stale {
+		log.Info("removing stale cloud-hypervisor instance", "instance", instance)
+		if err := systemctl(ctx, "disable", "--now", fmt.Sprintf("cloud-hypervisor@%d.service", instance)); err != nil {
+			return err
+		}
+		if err := removeSocket(opts.CloudHypervisorSocketsPath, instance); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dropInDir(opts, instance)); err != nil {
+			return fmt.Errorf("failed to remove drop-ins of instance %d: %w", instance, err)
+		}
+	}
+
+	// Restarting the target would restart all instances via PartOf and kill their guests, starting it only
+	// starts the instances that are not running yet, e.g. the ones added to the pool.
+	running, err := listInstances(ctx, "--state=active")
+	if err != nil {
+		return err
+	}
+	if err := systemctl(ctx, "enable", PoolTargetName); err != nil {
+		return err
+	}
+	if err := systemctl(ctx, "start", PoolTargetName); err != nil {
+		return err
+	}
+	for _, instance := range changed {
+		if _, ok := running[instance]; !ok {
+			continue
+		}
+		if err := restartInstance(ctx, log, opts, instance); err != nil {
+			return err
+		}
+	}
+
+	return waitForSockets(ctx, log, opts.CloudHypervisorSocketsPath, instances)
+}
+
+func dropInDir(opts Options, instance int) string {
+	return filepath.Join(opts.SystemdUnitDir, fmt.Sprintf("cloud-hypervisor@%d.service.d", instance))
+}
+
+// reconcileUserDropIn lets the instance run as uid InstanceUIDBase+instance, or removes the drop-in if
+// instances share the provider user. It reports whether the drop-in changed.
+func reconcileUserDropIn(log logr.Logger, opts Options, instance int) (bool, error) {
+	dropIn := filepath.Join(dropInDir(opts, instance), userDropInName)
+	if opts.InstanceUIDBase == 0 {
+		if err := os.Remove(dropIn); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to remove %s: %w", dropIn, err)
+		}
+		return true, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dropIn), 0755); err != nil {
+		return false, fmt.Errorf("failed to create drop-in dir: %w", err)
+	}
+	return writeUnit(log, dropIn, userDropInTemplate, struct {
+		Uid int
+	}{opts.InstanceUIDBase + instance})
+}
+
+// writeUnit writes the rendered unit unless the file is up to date and reports whether it changed.
+func writeUnit(log logr.Logger, unitPath string, tmpl *template.Template, data any) (bool, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf("failed to render %s: %w", unitPath, err)
+	}
+
+	current, err := os.ReadFile(unitPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to read %s: %w", unitPath, err)
+	}
+	if err == nil && bytes.Equal(current, buf.Bytes()) {
+		return false, nil
+	}
+
+	log.V(1).Info("writing systemd unit", "path", unitPath)
+	if err := os.WriteFile(unitPath, buf.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", unitPath, err)
+	}
+	return true, nil
+}
+
+// restartInstance restarts the running instance to apply its changed units, unless it holds a VM. Such an
+// instance keeps running with its previous units until the next run finds it free.
+func restartInstance(ctx context.Context, log logr.Logger, opts Options, instance int) error {
+	log = log.WithValues("instance", instance)
+	busy, err := hasVM(ctx, opts.CloudHypervisorSocketsPath, instance)
+	if err != nil {
+		log.Error(err, "not restarting cloud-hypervisor instance, failed to check for a VM")
+		return nil
+	}
+	if busy {
+		log.Info("not restarting cloud-hypervisor instance holding a VM, its units changed")
+		return nil
+	}
+
+	log.Info("restarting cloud-hypervisor instance, its units changed")
+	return systemctl(ctx, "restart", fmt.Sprintf("cloud-hypervisor@%d.service", instance))
+}
+
+// hasVM reports whether the instance holds a VM, an instance without socket holds none.
+func hasVM(ctx context.Context, socketsPath string, instance int) (bool, error) {
+	socket := filepath.Join(socketsPath, fmt.Sprintf("%d.sock", instance))
+	if _, err := os.Stat(socket); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	apiClient, err := vmm.NewUnixSocketClient(socket)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := apiClient.GetVmInfoWithResponse(ctx)
+	switch {
+	case err != nil:
+		return false, fmt.Errorf("failed to get vm info: %w", err)
+	case resp.JSON200 != nil:
+		return true, nil
+	case strings.Contains(string(resp.Body), "VM is not created"):
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %s: %s", resp.Status(), strings.TrimSpace(string(resp.Body)))
+	}
+}
+
+func systemctl(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run systemctl %s: %w: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// listInstances returns the instances whose units systemd lists with the given filter, e.g. --all.
+func listInstances(ctx context.Context, filter string) (map[int]struct{}, error) {
+	found := map[int]struct{}{}
+
+	out, err := exec.CommandContext(ctx, "systemctl", "list-units", filter, "--plain", "--no-legend",
+		"cloud-hypervisor@*.service").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cloud-hypervisor units: %w", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(fields[0], "cloud-hypervisor@"), ".service")
+		if instance, err := strconv.Atoi(name); err == nil {
+			found[instance] = struct{}{}
+		}
+	}
+	return found, nil
+}
+
+// staleInstances returns the instances that are known to systemd or have a socket but are
+// beyond the configured pool size.
+func staleInstances(ctx context.Context, opts Options) ([]int, error) {
+	found, err := listInstances(ctx, "--all")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(opts.CloudHypervisorSocketsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sockets dir: %w", err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".sock")
+		if !ok {
+			continue
+		}
+		if instance, err := strconv.Atoi(name); err == nil {
+			found[instance] = struct{}{}
+		}
+	}
+
+	var stale []int
+	for instance := range found {
+		if instance > opts.SocketPoolSize {
+			stale = append(stale, instance)
+		}
+	}
+	return stale, nil
+}
+
+func removeSocket(socketsPath string, instance int) error {
+	socket := filepath.Join(socketsPath, fmt.Sprintf("%d.sock", instance))
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove socket of instance %d: %w", instance, err)
+	}
+	if err := os.Remove(vmm.EventsFile(socket)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove events file of instance %d: %w", instance, err)
+	}
+	return nil
+}
+
+func waitForSockets(ctx context.Context, log logr.Logger, socketsPath string, instances []int) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	for _, instance := range instances {
+		socket := filepath.Join(socketsPath, fmt.Sprintf("%d.sock", instance))
+		for {
+			conn, err := (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			if err == nil {
+				_ = conn.Close()
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("cloud-hypervisor instance %d did not come up: %w", instance, err)
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}
+
+	log.Info("cloud-hypervisor socket pool is ready", "instances", len(instances))
+	return nil
+}
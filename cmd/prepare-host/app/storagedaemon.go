@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+const StorageDaemonName = "qemu-storage-daemon"
+
+// ensureStorageDaemon makes sure the qemu-storage-daemon used by the ceph volume plugin is present and
+// verifies its checksum and version.
+func ensureStorageDaemon(ctx context.Context, log logr.Logger, opts Options) error {
+	subDir := opts.StorageDaemonSubDir
+	if opts.StorageDaemonVersion != "" && subDir == defaultSubDir {
+		subDir = opts.StorageDaemonVersion
+	}
+	dir := path.Join(opts.StorageDaemonPath, subDir)
+	binPath := path.Join(dir, StorageDaemonName)
+
+	if !isFilePresent(log, binPath) {
+		if !opts.Download {
+			return fmt.Errorf("%s not present at %s", StorageDaemonName, binPath)
+		}
+		if opts.StorageDaemonUrl == "" {
+			return fmt.Errorf("%s not present at %s and no url to download it from", StorageDaemonName, binPath)
+		}
+
+		log.Info("downloading qemu-storage-daemon binary")
+		if err := fetch(log, opts.StorageDaemonUrl, dir, StorageDaemonName, true); err != nil {
+			return err
+		}
+	}
+
+	if opts.StorageDaemonSHA256 != "" {
+		sum, err := sha256File(binPath)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, opts.StorageDaemonSHA256) {
+			if err := os.Remove(binPath); err != nil {
+				log.Error(err, "failed to remove qemu-storage-daemon with invalid checksum", "path", binPath)
+			}
+			return fmt.Errorf("checksum mismatch of %s: expected %s, got %s", binPath, opts.StorageDaemonSHA256, sum)
+		}
+	}
+
+	out, err := exec.CommandContext(ctx, binPath, "--version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run %s --version: %w: %s", binPath, err, strings.TrimSpace(string(out)))
+	}
+	version := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	if !strings.HasPrefix(version, StorageDaemonName) {
+		return fmt.Errorf("%s is not a qemu-storage-daemon: %q", binPath, version)
+	}
+	if want := strings.TrimPrefix(opts.StorageDaemonVersion, "v"); want != "" && !strings.Contains(version, want) {
+		return fmt.Errorf("%s has version %q, expected %s", binPath, version, want)
+	}
+
+	log.V(1).Info("qemu-storage-daemon verified", "path", binPath, "version", version)
+	return nil
+}
+
+func sha256File(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,537 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+)
+
+// Reconciler is the part of the machine reconciler operators can trigger.
+type Reconciler interface {
+	// Requeue clears the backoff of the machine and reconciles it immediately.
+	Requeue(ctx context.Context, machineID string) error
+	// RequestRecreate deletes the VM of the machine on its next reconciliation, so that it is created again.
+	RequestRecreate(ctx context.Context, machineID string) error
+	// RequestReboot reboots the VM of the machine on its next reconciliation.
+	RequestReboot(ctx context.Context, machineID string) error
+	// RequestMigration migrates the VM of the machine to the provider serving the migration api at destination.
+	RequestMigration(ctx context.Context, machineID string, destination string) error
+	// RequestUpgrade moves the VM of the machine to a free cloud-hypervisor instance of a newer version. An empty
+	// method cancels an upgrade that waits for an instance.
+	RequestUpgrade(ctx context.Context, machineID string, method api.UpgradeMethod) error
+	// RequestUpgrades requests the upgrade of every machine with a VM whose instance is older than minVersion and
+	// returns their ids.
+	RequestUpgrades(ctx context.Context, minVersion string, method api.UpgradeMethod) ([]string, error)
+	// Undrained returns the ids of the machines that still have a running VM.
+	Undrained(ctx context.Context) ([]string, error)
+	// Clone creates a machine with copies of the local disks of the machine, which must not run, and returns its
+	// id.
+	Clone(ctx context.Context, machineID string, power api.PowerState) (string, error)
+	// RequestExport exports the root disk of the machine as image to ref.
+	RequestExport(ctx context.Context, machineID string, ref string) error
+	// Import creates a machine for the VM on the api socket of a cloud-hypervisor instance that belongs to no
+	// machine and returns its id. The machine gets the class if not empty. The error wraps vmm.ErrNotFound for
+	// unknown sockets, vmm.ErrVmNotCreated for instances without VM, store.ErrAlreadyExists for VMs belonging
+	// to a machine and store.ErrNotFound for unknown classes.
+	Import(ctx context.Context, socket string, class string) (string, error)
+	// VMConfigs returns the config of the VM of the machine, nil if it has none, and the config computed from
+	// the machine in the store.
+	VMConfigs(ctx context.Context, machineID string) (live *client.VmConfig, desired *client.VmConfig, err error)
+	// VMInfo returns the info cloud-hypervisor reports for the VM of the machine.
+	VMInfo(ctx context.Context, machineID string) (*client.VmInfo, error)
+	// VMMInfo returns the version, pid and features of the cloud-hypervisor instance of the machine.
+	VMMInfo(ctx context.Context, machineID string) (*client.VmmPingResponse, error)
+	// VMCounters returns the counters of the devices of the VM of the machine.
+	VMCounters(ctx context.Context, machineID string) (client.VmCounters, error)
+}
+
+// Console provides the serial console output of the machines.
+type Console interface {
+	// Get returns the scrollback of the serial console of the machine.
+	Get(machineID string) ([]byte, bool)
+}
+
+type Options struct {
+	SocketPath string
+
+	// Maintenance is switched via the admin api. The maintenance endpoints are disabled if nil.
+	Maintenance *maintenance.Mode
+	// Console is read via the admin api. The console endpoint is disabled if nil.
+	Console Console
+}
+
+// ConsoleOutput is the scrollback of the serial console of a machine.
+type ConsoleOutput struct {
+	Output string `json:"output"`
+}
+
+// VMConfig is the config of the VM of a machine as reported by cloud-hypervisor and as computed by the provider.
+type VMConfig struct {
+	// Live is the config of the VM, unset if the machine has no VM.
+	Live *client.VmConfig `json:"live,omitempty"`
+	// Desired is the config the VM is created with from the current machine spec.
+	Desired *client.VmConfig `json:"desired"`
+}
+
+// MaintenanceStatus is the maintenance state of the host and whether it is drained.
+type MaintenanceStatus struct {
+	maintenance.State
+
+	Drained   bool     `json:"drained"`
+	Undrained []string `json:"undrained,omitempty"`
+}
+
+// MaintenanceRequest enables or disables the maintenance of the host.
+type MaintenanceRequest struct {
+	Enabled    bool                   `json:"enabled"`
+	Evacuation maintenance.Evacuation `json:"evacuation,omitempty"`
+}
+
+// MigrationRequest migrates the VM of a machine to another host.
+type MigrationRequest struct {
+	// Destination is the url of the migration api of the provider on the other host.
+	Destination string `json:"destination"`
+}
+
+// UpgradeRequest moves the VM of a machine to a cloud-hypervisor instance of a newer version.
+type UpgradeRequest struct {
+	// Method is how the running VM is moved, defaults to api.UpgradeMethodMigration.
+	Method api.UpgradeMethod `json:"method,omitempty"`
+	// Cancel cancels an upgrade that waits for an instance.
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// UpgradesRequest moves the VMs of all machines on cloud-hypervisor instances older than MinVersion.
+type UpgradesRequest struct {
+	MinVersion string            `json:"minVersion"`
+	Method     api.UpgradeMethod `json:"method,omitempty"`
+}
+
+// UpgradesResponse lists the machines whose upgrade was requested.
+type UpgradesResponse struct {
+	Machines []string `json:"machines"`
+}
+
+// ExportRequest exports the root disk of a machine as image.
+type ExportRequest struct {
+	// Ref is the reference the image is pushed to.
+	Ref string `json:"ref"`
+}
+
+// CloneRequest clones a machine.
+type CloneRequest struct {
+	// PowerOn starts the clone once its disks are copied.
+	PowerOn bool `json:"powerOn,omitempty"`
+}
+
+// CloneResponse identifies the clone of a machine.
+type CloneResponse struct {
+	ID string `json:"id"`
+}
+
+// ImportRequest imports the VM on the api socket of a cloud-hypervisor instance as machine.
+type ImportRequest struct {
+	Socket string `json:"socket"`
+	// Class is the machine class of the machine, defaults to the class of the size of the VM.
+	Class string `json:"class,omitempty"`
+}
+
+// ImportResponse identifies the machine of an imported VM.
+type ImportResponse struct {
+	ID string `json:"id"`
+}
+
+// Server serves an admin api on a unix socket, only accessible on the host.
+type Server struct {
+	log        logr.Logger
+	socketPath string
+	reconciler Reconciler
+
+	maintenance *maintenance.Mode
+	console     Console
+}
+
+func NewServer(log logr.Logger, reconciler Reconciler, opts Options) (*Server, error) {
+	if opts.SocketPath == "" {
+		return nil, fmt.Errorf("must specify socket path")
+	}
+	if reconciler == nil {
+		return nil, fmt.Errorf("must specify reconciler")
+	}
+
+	return &Server{
+		log:         log,
+		socketPath:  opts.SocketPath,
+		reconciler:  reconciler,
+		maintenance: opts.Maintenance,
+		console:     opts.Console,
+	}, nil
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/machines/{id}/requeue", s.machineAction(s.reconciler.Requeue))
+	mux.HandleFunc("POST /v1/machines/{id}/recreate", s.machineAction(s.reconciler.RequestRecreate))
+	mux.HandleFunc("POST /v1/machines/{id}/reboot", s.machineAction(s.reconciler.RequestReboot))
+	mux.HandleFunc("POST /v1/machines/{id}/migrate", s.migrateMachine)
+	mux.HandleFunc("POST /v1/machines/{id}/upgrade", s.upgradeMachine)
+	mux.HandleFunc("POST /v1/machines/{id}/clone", s.cloneMachine)
+	mux.HandleFunc("POST /v1/machines/{id}/export", s.exportMachine)
+	mux.HandleFunc("GET /v1/machines/{id}/vm-config", s.getVMConfig)
+	// Only read-only calls of the cloud-hypervisor api are proxied, named like its endpoints.
+	mux.HandleFunc("GET /v1/machines/{id}/vmm/vm.info", vmmQuery(s, s.reconciler.VMInfo))
+	mux.HandleFunc("GET /v1/machines/{id}/vmm/vmm.ping", vmmQuery(s, s.reconciler.VMMInfo))
+	mux.HandleFunc("GET /v1/machines/{id}/vmm/vm.counters", vmmQuery(s, s.reconciler.VMCounters))
+	mux.HandleFunc("POST /v1/upgrades", s.upgradeMachines)
+	mux.HandleFunc("POST /v1/imports", s.importVM)
+	if s.console != nil {
+		mux.HandleFunc("GET /v1/machines/{id}/console", s.getConsole)
+	}
+	if s.maintenance != nil {
+		mux.HandleFunc("GET /v1/maintenance", s.getMaintenance)
+		mux.HandleFunc("PUT /v1/maintenance", s.setMaintenance)
+	}
+	return mux
+}
+
+func (s *Server) migrateMachine(w http.ResponseWriter, req *http.Request) {
+	var migrationReq MigrationRequest
+	if err := json.NewDecoder(req.Body).Decode(&migrationReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.machineAction(func(ctx context.Context, machineID string) error {
+		return s.reconciler.RequestMigration(ctx, machineID, migrationReq.Destination)
+	})(w, req)
+}
+
+func (s *Server) upgradeMachine(w http.ResponseWriter, req *http.Request) {
+	var upgradeReq UpgradeRequest
+	if err := json.NewDecoder(req.Body).Decode(&upgradeReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	method, err := upgradeMethod(upgradeReq.Method)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if upgradeReq.Cancel {
+		method = ""
+	}
+
+	s.machineAction(func(ctx context.Context, machineID string) error {
+		return s.reconciler.RequestUpgrade(ctx, machineID, method)
+	})(w, req)
+}
+
+func (s *Server) upgradeMachines(w http.ResponseWriter, req *http.Request) {
+	var upgradesReq UpgradesRequest
+	if err := json.NewDecoder(req.Body).Decode(&upgradesReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if upgradesReq.MinVersion == "" {
+		http.Error(w, "must specify minimum version", http.StatusBadRequest)
+		return
+	}
+
+	method, err := upgradeMethod(upgradesReq.Method)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids, err := s.reconciler.RequestUpgrades(req.Context(), upgradesReq.MinVersion, method)
+	if err != nil {
+		s.log.Error(err, "Failed to request upgrades", "minVersion", upgradesReq.MinVersion)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.log.Info("Upgrades requested", "minVersion", upgradesReq.MinVersion, "method", method, "machines", len(ids))
+	writeJSON(w, http.StatusAccepted, UpgradesResponse{Machines: ids})
+}
+
+// upgradeMethod validates the requested upgrade method, which defaults to api.UpgradeMethodMigration.
+func upgradeMethod(method api.UpgradeMethod) (api.UpgradeMethod, error) {
+	switch method {
+	case "":
+		return api.UpgradeMethodMigration, nil
+	case api.UpgradeMethodMigration, api.UpgradeMethodSnapshot:
+		return method, nil
+	default:
+		return "", fmt.Errorf("unsupported upgrade method %q, must be %s or %s", method,
+			api.UpgradeMethodMigration, api.UpgradeMethodSnapshot)
+	}
+}
+
+func (s *Server) exportMachine(w http.ResponseWriter, req *http.Request) {
+	var exportReq ExportRequest
+	if err := json.NewDecoder(req.Body).Decode(&exportReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s.machineAction(func(ctx context.Context, machineID string) error {
+		return s.reconciler.RequestExport(ctx, machineID, exportReq.Ref)
+	})(w, req)
+}
+
+func (s *Server) cloneMachine(w http.ResponseWriter, req *http.Request) {
+	var cloneReq CloneRequest
+	if err := json.NewDecoder(req.Body).Decode(&cloneReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	power := api.PowerStatePowerOff
+	if cloneReq.PowerOn {
+		power = api.PowerStatePowerOn
+	}
+
+	machineID := req.PathValue("id")
+	cloneID, err := s.reconciler.Clone(req.Context(), machineID, power)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("machine %s not found", machineID), http.StatusNotFound)
+			return
+		}
+		s.log.Error(err, "Failed to clone machine", "machineID", machineID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.log.Info("Machine cloned", "machineID", machineID, "cloneID", cloneID)
+	writeJSON(w, http.StatusCreated, CloneResponse{ID: cloneID})
+}
+
+func (s *Server) importVM(w http.ResponseWriter, req *http.Request) {
+	var importReq ImportRequest
+	if err := json.NewDecoder(req.Body).Decode(&importReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if importReq.Socket == "" {
+		http.Error(w, "must specify socket", http.StatusBadRequest)
+		return
+	}
+
+	machineID, err := s.reconciler.Import(req.Context(), importReq.Socket, importReq.Class)
+	if err != nil {
+		switch {
+		case errors.Is(err, vmm.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, vmm.ErrVmNotCreated), errors.Is(err, store.ErrAlreadyExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			s.log.Error(err, "Failed to import vm", "socket", importReq.Socket)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	s.log.Info("VM imported", "socket", importReq.Socket, "machineID", machineID)
+	writeJSON(w, http.StatusCreated, ImportResponse{ID: machineID})
+}
+
+func (s *Server) getVMConfig(w http.ResponseWriter, req *http.Request) {
+	machineID := req.PathValue("id")
+	live, desired, err := s.reconciler.VMConfigs(req.Context(), machineID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("machine %s not found", machineID), http.StatusNotFound)
+			return
+		}
+		s.log.Error(err, "Failed to get vm config", "machineID", machineID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, VMConfig{Live: live, Desired: desired})
+}
+
+// vmmQuery proxies a read-only call of the cloud-hypervisor api of the machine.
+func vmmQuery[T any](s *Server, query func(ctx context.Context, machineID string) (T, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		machineID := req.PathValue("id")
+		result, err := query(req.Context(), machineID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				http.Error(w, fmt.Sprintf("machine %s not found", machineID), http.StatusNotFound)
+			case errors.Is(err, vmm.ErrNotFound):
+				http.Error(w, fmt.Sprintf("machine %s has no cloud-hypervisor instance", machineID),
+					http.StatusConflict)
+			case errors.Is(err, vmm.ErrVmNotCreated):
+				http.Error(w, fmt.Sprintf("machine %s has no vm", machineID), http.StatusConflict)
+			case errors.Is(err, vmm.ErrMigrating):
+				http.Error(w, fmt.Sprintf("machine %s is migrating", machineID), http.StatusConflict)
+			default:
+				s.log.Error(err, "Failed to query cloud-hypervisor", "machineID", machineID, "path", req.URL.Path)
+				http.Error(w, err.Error(), http.StatusBadGateway)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *Server) getConsole(w http.ResponseWriter, req *http.Request) {
+	machineID := req.PathValue("id")
+	output, ok := s.console.Get(machineID)
+	if !ok {
+		http.Error(w, fmt.Sprintf("machine %s not found", machineID), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, ConsoleOutput{Output: string(output)})
+}
+
+func (s *Server) getMaintenance(w http.ResponseWriter, req *http.Request) {
+	status, err := s.maintenanceStatus(req.Context())
+	if err != nil {
+		s.log.Error(err, "Failed to get maintenance status")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) setMaintenance(w http.ResponseWriter, req *http.Request) {
+	var maintenanceReq MaintenanceRequest
+	if err := json.NewDecoder(req.Body).Decode(&maintenanceReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	evacuation := maintenance.EvacuationNone
+	if maintenanceReq.Evacuation != "" {
+		var err error
+		if evacuation, err = maintenance.ParseEvacuation(string(maintenanceReq.Evacuation)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.maintenance.Set(maintenanceReq.Enabled, evacuation)
+	s.log.Info("Maintenance state changed", "enabled", maintenanceReq.Enabled, "evacuation", evacuation)
+
+	status, err := s.maintenanceStatus(req.Context())
+	if err != nil {
+		s.log.Error(err, "Failed to get maintenance status")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) maintenanceStatus(ctx context.Context) (*MaintenanceStatus, error) {
+	status := &MaintenanceStatus{State: s.maintenance.State()}
+	if !status.Enabled {
+		return status, nil
+	}
+
+	undrained, err := s.reconciler.Undrained(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Undrained = undrained
+	status.Drained = len(undrained) == 0
+	return status, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) machineAction(action func(ctx context.Context, machineID string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		machineID := req.PathValue("id")
+		log := s.log.WithValues("machineID", machineID, "path", req.URL.Path)
+
+		if err := action(req.Context(), machineID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, fmt.Sprintf("machine %s not found", machineID), http.StatusNotFound)
+				return
+			}
+			log.Error(err, "Admin action failed")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("Admin action triggered")
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// listen creates the socket in a directory only the provider user can access and moves it into place once only
+// root and the provider user may connect to it, so that nobody else can trigger admin actions in between.
+func (s *Server) listen() (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(s.socketPath), ".admin-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create private socket dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "admin.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	// The socket is removed by Start, it is not at the path it was created at anymore.
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("failed to chmod socket: %w", err)
+	}
+	// Replaces a stale socket.
+	if err := os.Rename(path, s.socketPath); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("failed to move socket into place: %w", err)
+	}
+	return l, nil
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	l, err := s.listen()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(s.socketPath) }()
+
+	srv := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.log.Info("Serving admin api", "socket", s.socketPath)
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving admin api: %w", err)
+	}
+	return nil
+}
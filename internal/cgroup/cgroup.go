@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cgroup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+)
+
+const (
+	// DefaultMemoryOverhead is added to the guest memory to account for the memory used by the VMM itself.
+	DefaultMemoryOverhead = 256 << 20
+
+	// PoolGroup holds the processes of cloud-hypervisor instances not assigned to a machine. cgroup v2 does
+	// not allow processes in a group with enabled controllers and children, so they can't stay in the root.
+	PoolGroup = "pool"
+
+	cpuPeriod      = 100_000
+	cgroup2Magic   = 0x63677270
+	machinePrefix  = "machine-"
+	controllerList = "+cpu +memory"
+	cpusetList     = "+cpuset"
+)
+
+type Options struct {
+	// Root is the cgroup v2 directory the per-machine groups are created in.
+	Root string
+	// MemoryOverhead is added to the guest memory for memory.max. Defaults to DefaultMemoryOverhead.
+	MemoryOverhead int64
+	// CPUSet enables the cpuset controller, which pins machines with a NUMA node to the node.
+	CPUSet bool
+}
+
+// Limits are the resources of a machine, usually derived from its machine class.
+type Limits struct {
+	Cpus        int64
+	MemoryBytes int64
+	// NUMANode confines the machine to the cpus and memory of the NUMA node. Requires Options.CPUSet.
+	NUMANode *int
+}
+
+type Manager struct {
+	root           string
+	memoryOverhead int64
+	cpuset         bool
+}
+
+func NewManager(opts Options) (*Manager, error) {
+	if opts.Root == "" {
+		return nil, fmt.Errorf("must specify cgroup root")
+	}
+	if opts.MemoryOverhead == 0 {
+		opts.MemoryOverhead = DefaultMemoryOverhead
+	}
+
+	if err := os.MkdirAll(opts.Root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup root: %w", err)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(opts.Root, &stat); err != nil {
+		return nil, fmt.Errorf("failed to stat cgroup root: %w", err)
+	}
+	if stat.Type != cgroup2Magic {
+		return nil, fmt.Errorf("%s is not on a cgroup v2 filesystem", opts.Root)
+	}
+
+	if err := os.MkdirAll(filepath.Join(opts.Root, PoolGroup), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create pool cgroup: %w", err)
+	}
+	controllers := controllerList
+	if opts.CPUSet {
+		controllers += " " + cpusetList
+	}
+	if err := write(opts.Root, "cgroup.subtree_control", controllers); err != nil {
+		return nil, fmt.Errorf("failed to enable controllers: %w", err)
+	}
+
+	return &Manager{
+		root:           opts.Root,
+		memoryOverhead: opts.MemoryOverhead,
+		cpuset:         opts.CPUSet,
+	}, nil
+}
+
+func (m *Manager) machineDir(machineID string) string {
+	return filepath.Join(m.root, machinePrefix+machineID)
+}
+
+// Apply creates the cgroup of the machine, sets its limits and moves the process into it.
+// It is safe to call Apply repeatedly.
+func (m *Manager) Apply(machineID string, pid int, limits Limits) error {
+	dir := m.machineDir(machineID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	if limits.Cpus > 0 {
+		if err := write(dir, "cpu.max", fmt.Sprintf("%d %d", limits.Cpus*cpuPeriod, cpuPeriod)); err != nil {
+			return err
+		}
+		// A machine with one cpu gets the default weight of 100.
+		weight := min(limits.Cpus*100, 10000)
+		if err := write(dir, "cpu.weight", strconv.FormatInt(weight, 10)); err != nil {
+			return err
+		}
+	}
+
+	if limits.MemoryBytes > 0 {
+		if err := write(dir, "memory.max", strconv.FormatInt(limits.MemoryBytes+m.memoryOverhead, 10)); err != nil {
+			return err
+		}
+	}
+
+	if limits.NUMANode != nil {
+		if !m.cpuset {
+			return fmt.Errorf("numa node %d requires the cpuset controller", *limits.NUMANode)
+		}
+		cpus, err := host.NUMANodeCPUs(*limits.NUMANode)
+		if err != nil {
+			return err
+		}
+		if err := write(dir, "cpuset.cpus", cpus); err != nil {
+			return err
+		}
+		if err := write(dir, "cpuset.mems", strconv.Itoa(*limits.NUMANode)); err != nil {
+			return err
+		}
+	}
+
+	return m.move(dir, pid)
+}
+
+// Release moves the process back to the pool group and removes the cgroup of the machine.
+func (m *Manager) Release(machineID string, pid int) error {
+	if pid > 0 {
+		if err := m.move(filepath.Join(m.root, PoolGroup), pid); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(m.machineDir(machineID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove cgroup: %w", err)
+	}
+	return nil
+}
+
+// Evict moves a process that no longer belongs to a machine back to the pool group, the cgroup of the machine
+// is kept for its other processes.
+func (m *Manager) Evict(pid int) error {
+	return m.move(filepath.Join(m.root, PoolGroup), pid)
+}
+
+func (m *Manager) move(dir string, pid int) error {
+	if err := write(dir, "cgroup.procs", strconv.Itoa(pid)); err != nil {
+		return fmt.Errorf("failed to move process %d: %w", pid, err)
+	}
+	return nil
+}
+
+func write(dir, file, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package configdrive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	// OpenStackLabel is the volume label of an OpenStack config drive, read by Ignition's openstack platform.
+	OpenStackLabel = "config-2"
+
+	OpenStackUserDataFile = "openstack/latest/user_data"
+
+	// NoCloudLabel is the volume label of a cloud-init NoCloud seed.
+	NoCloudLabel = "cidata"
+
+	NoCloudUserDataFile = "user-data"
+	NoCloudMetaDataFile = "meta-data"
+
+	// OpaqueUserDataFile holds the opaque user data of the machine in config drives of any format.
+	OpaqueUserDataFile = "ironcore/opaque-user-data"
+
+	DefaultISOTool = "genisoimage"
+)
+
+// Builder renders a set of files into an ISO9660 image that is attached to
+// a VM as read-only disk.
+type Builder struct {
+	ISOTool string
+}
+
+func NewBuilder(isoTool string) *Builder {
+	if isoTool == "" {
+		isoTool = DefaultISOTool
+	}
+	return &Builder{ISOTool: isoTool}
+}
+
+// Build writes an ISO with the given volume label and files to path. The
+// file keys are slash separated paths relative to the root of the drive.
+func (b *Builder) Build(ctx context.Context, path, label string, files map[string][]byte) error {
+	stagingDir, err := os.MkdirTemp(filepath.Dir(path), ".configdrive-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(stagingDir) }()
+
+	for name, data := range files {
+		filePath := filepath.Join(stagingDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("failed to create dir for %s: %w", name, err)
+		}
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	out, err := exec.CommandContext(ctx, b.ISOTool,
+		"-output", tmpPath,
+		"-volid", label,
+		"-joliet",
+		"-rock",
+		"-quiet",
+		stagingDir,
+	).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to build config drive: %w: %s", err, string(out))
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to move config drive into place: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+)
+
+const (
+	DefaultScrollbackSize = 64 * 1024
+
+	scrollbackPollInterval = time.Second
+)
+
+type buffer struct {
+	data []byte
+	// offset is the position in the serial log up to which it was read.
+	offset int64
+}
+
+// Scrollback keeps the last output of the serial consoles of the machines in memory, so that it can be read
+// after the serial log was truncated by the creation of a new VM.
+type Scrollback struct {
+	log      logr.Logger
+	machines store.Store[*api.Machine]
+	paths    host.Paths
+	size     int
+
+	mu      sync.Mutex
+	buffers map[string]*buffer
+}
+
+func NewScrollback(log logr.Logger, machines store.Store[*api.Machine], paths host.Paths, size int) (*Scrollback, error) {
+	if machines == nil {
+		return nil, fmt.Errorf("must specify machine store")
+	}
+	if paths == nil {
+		return nil, fmt.Errorf("must specify paths")
+	}
+	if size <= 0 {
+		size = DefaultScrollbackSize
+	}
+
+	return &Scrollback{
+		log:      log,
+		machines: machines,
+		paths:    paths,
+		size:     size,
+		buffers:  map[string]*buffer{},
+	}, nil
+}
+
+func (s *Scrollback) Start(ctx context.Context) error {
+	ticker := time.NewTicker(scrollbackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get returns the scrollback of a machine.
+func (s *Scrollback) Get(machineID string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buf, ok := s.buffers[machineID]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), buf.data...), true
+}
+
+func (s *Scrollback) poll(ctx context.Context) {
+	machines, err := s.machines.List(ctx)
+	if err != nil {
+		s.log.Error(err, "Failed to list machines")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]struct{}{}
+	for _, machine := range machines {
+		seen[machine.ID] = struct{}{}
+
+		buf, ok := s.buffers[machine.ID]
+		if !ok {
+			buf = &buffer{}
+			s.buffers[machine.ID] = buf
+		}
+		if err := s.follow(s.paths.MachineSerialLogFile(machine.ID), buf); err != nil {
+			s.log.V(2).Info("Failed to read serial log", "machineID", machine.ID, "error", err.Error())
+		}
+	}
+
+	for machineID := range s.buffers {
+		if _, ok := seen[machineID]; !ok {
+			delete(s.buffers, machineID)
+		}
+	}
+}
+
+// follow appends what was written to the serial log since it was last read.
+func (s *Scrollback) follow(path string, buf *buffer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The serial log is truncated when a new VM is created, its output continues the scrollback.
+	if info.Size() < buf.offset {
+		buf.offset = 0
+	}
+	// Only the part of the serial log that fits is read, e.g. after a restart of the provider.
+	buf.offset = max(buf.offset, info.Size()-int64(s.size))
+	if info.Size() == buf.offset {
+		return nil
+	}
+
+	data := make([]byte, info.Size()-buf.offset)
+	n, err := f.ReadAt(data, buf.offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	buf.offset += int64(n)
+
+	buf.data = append(buf.data, data[:n]...)
+	if len(buf.data) > s.size {
+		buf.data = append([]byte(nil), buf.data[len(buf.data)-s.size:]...)
+	}
+	return nil
+}
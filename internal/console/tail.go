@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package console reads the serial output of the VMs.
+package console
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ansiEscape matches the terminal control sequences guests write to the serial console.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// Tail returns up to maxLines of the end of the serial log at path, but no more than maxBytes. Terminal control
+// sequences and carriage returns are removed.
+func Tail(path string, maxLines, maxBytes int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Control sequences are dropped, so more is read than returned.
+	offset := max(info.Size()-int64(4*maxBytes), 0)
+	data := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return "", err
+	}
+
+	return clean(string(data), maxLines, maxBytes), nil
+}
+
+func clean(data string, maxLines, maxBytes int) string {
+	data = ansiEscape.ReplaceAllString(data, "")
+	data = strings.ReplaceAll(data, "\r", "")
+	data = strings.ToValidUTF8(data, "")
+
+	lines := strings.Split(strings.TrimRight(data, "\n"), "\n")
+	lines = lines[max(len(lines)-maxLines, 0):]
+
+	tail := strings.Join(lines, "\n")
+	if len(tail) > maxBytes {
+		tail = strings.ToValidUTF8(tail[len(tail)-maxBytes:], "")
+	}
+	return tail
+}
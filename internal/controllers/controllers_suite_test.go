@@ -84,10 +84,10 @@ var _ = BeforeSuite(func(ctx context.Context) {
 
 	volumePlugins := volume.NewPluginManager()
 	Expect(volumePlugins.InitPlugins(hostPaths, []volume.Plugin{
-		localdisk.NewPlugin(rawInst, imgCache),
+		localdisk.NewPlugin(rawInst, imgCache, ""),
 	})).NotTo(HaveOccurred())
 
-	nicPlugin := isolated.NewPlugin()
+	nicPlugin := isolated.NewPlugin(nil)
 	Expect(nicPlugin.Init(hostPaths)).NotTo(HaveOccurred())
 
 	machineStore, err = hostutils.NewStore[*api.Machine](hostutils.Options[*api.Machine]{
@@ -116,15 +116,24 @@ var _ = BeforeSuite(func(ctx context.Context) {
 		chFirmwarePath = "/usr/local/bin/hypervisor-fw"
 	}
 
-	virtualMachineManager, err := vmm.NewManager(
-		log.WithName("virtual-machine-manager"),
-		hostPaths,
-		vmm.ManagerOptions{
-			CHSocketsPath:     chSocketDir,
-			FirmwarePath:      chFirmwarePath,
-			ReservedInstances: nil,
-		},
-	)
+	vmmOpts := vmm.ManagerOptions{
+		CHSocketsPaths:    []string{chSocketDir},
+		FirmwarePath:      chFirmwarePath,
+		ReservedInstances: nil,
+	}
+
+	var virtualMachineManager vmm.VirtualMachineManager
+	if os.Getenv("CH_VMM_MODE") == vmm.ModeFake {
+		log.V(1).Info("use fake virtual machine manager")
+		virtualMachineManager, err = vmm.NewFakeManager(
+			log.WithName("virtual-machine-manager"),
+			hostPaths,
+			vmmOpts,
+			vmm.DefaultFakeInstances,
+		)
+	} else {
+		virtualMachineManager, err = vmm.NewManager(log.WithName("virtual-machine-manager"), hostPaths, vmmOpts)
+	}
 	Expect(err).NotTo(HaveOccurred())
 
 	eventRecorder = recorder.NewEventStore(log, recorder.EventStoreOptions{})
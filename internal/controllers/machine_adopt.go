@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+// adoptVMs assigns the VMs found on the cloud-hypervisor instances to their machines by the platform uuid
+// of the VM. Machines whose recorded api socket is outdated are moved to the socket of their VM, so that
+// running guests are supervised again instead of being recreated.
+func (r *MachineReconciler) adoptVMs(ctx context.Context) error {
+	log := r.log.WithName("adopt")
+
+	vms, err := r.vmm.VMs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list vms: %w", err)
+	}
+
+	machines, err := r.machines.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	byID := make(map[string]*api.Machine, len(machines))
+	for _, machine := range machines {
+		byID[vmUUIDOf(machine)] = machine
+	}
+
+	vmSockets := sets.New[string]()
+	var movedFrom []string
+	for socket, vm := range vms {
+		vmSockets.Insert(socket)
+
+		machineID := ptr.Deref(ptr.Deref(vm.Config.Platform, client.PlatformConfig{}).Uuid, "")
+		machine, ok := byID[machineID]
+		if !ok {
+			log.Info("Found VM of unknown machine, leaving it untouched", "socket", socket, "uuid", machineID)
+			continue
+		}
+
+		previous := ptr.Deref(machine.Spec.ApiSocketPath, "")
+		if previous == socket {
+			log.V(1).Info("Adopted VM", "machine", machine.ID, "socket", socket, "state", vm.State)
+			continue
+		}
+
+		log.Info("Moving machine to the socket of its VM", "machine", machine.ID, "socket", socket, "previous", previous)
+		machine.Spec.ApiSocketPath = ptr.To(socket)
+		updated, err := r.machines.Update(ctx, machine)
+		if err != nil {
+			return fmt.Errorf("failed to update api socket of machine %s: %w", machine.ID, err)
+		}
+		byID[machineID] = updated
+		r.eventRecorder.Eventf(updated.Metadata, corev1.EventTypeNormal, "AdoptedVM", "Adopted VM on %s", socket)
+
+		if previous != "" {
+			movedFrom = append(movedFrom, previous)
+		}
+	}
+
+	inUse := sets.New[string]()
+	for _, machine := range byID {
+		socket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+		if socket == "" {
+			continue
+		}
+
+		// The socket holds the VM of another machine, a new VM has to be created on a free socket.
+		if vm, ok := vms[socket]; ok && ptr.Deref(ptr.Deref(vm.Config.Platform, client.PlatformConfig{}).Uuid, "") != vmUUIDOf(machine) {
+			log.Info("Socket of machine is used by another VM, releasing it", "machine", machine.ID, "socket", socket)
+			machine.Spec.ApiSocketPath = nil
+			if _, err := r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to reset api socket of machine %s: %w", machine.ID, err)
+			}
+			continue
+		}
+		inUse.Insert(socket)
+	}
+
+	for _, socket := range movedFrom {
+		if !inUse.Has(socket) && !vmSockets.Has(socket) {
+			r.vmm.FreeApiSocket(ctx, socket)
+		}
+	}
+
+	log.Info("Adopted VMs", "vms", len(vms), "machines", len(machines))
+	return nil
+}
+
+// recoverForeignVM handles a VM of another machine found on the api socket of the machine, e.g. after the
+// store was restored from a backup. The socket is quarantined with the foreign VM on it, it is not handed out
+// again, and the machine gets a new socket. With deleteForeignVms, a VM that belongs to no stored machine is
+// deleted instead and the machine keeps its socket.
+func (r *MachineReconciler) recoverForeignVM(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vmID string,
+) error {
+	socket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	log = log.WithValues("socket", socket, "vmID", vmID)
+
+	if r.deleteForeignVms {
+		owned := false
+		if vmID != "" {
+			switch _, err := r.machines.Get(ctx, vmID); {
+			case err == nil:
+				owned = true
+			case !errors.Is(err, store.ErrNotFound):
+				return fmt.Errorf("failed to get machine of foreign vm: %w", err)
+			}
+		}
+
+		if !owned {
+			log.Info("Deleting foreign VM of unknown machine on socket of machine")
+			if err := r.vmm.Delete(ctx, socket); err != nil {
+				return fmt.Errorf("failed to delete foreign vm: %w", err)
+			}
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ForeignVMDeleted",
+				"Deleted VM %s of an unknown machine on %s", vmID, socket)
+			r.queue.Add(machine.ID)
+			return nil
+		}
+	}
+
+	log.Info("Socket of machine holds a foreign VM, quarantining socket")
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ForeignVM",
+		"Socket %s holds VM %s of another machine, moving to a new socket", socket, vmID)
+
+	machine.Spec.ApiSocketPath = nil
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to reset api socket: %w", err)
+	}
+
+	r.queue.Add(machine.ID)
+	return nil
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reconcileClass sets the ClassDeprecated condition of a machine whose class was deprecated or removed from the
+// registry, and removes it once the class is available again. The machine itself is not changed.
+func (r *MachineReconciler) reconcileClass(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+) (*api.Machine, error) {
+	if r.machineClasses == nil {
+		return machine, nil
+	}
+	className, ok := api.GetClassLabel(machine)
+	if !ok {
+		return machine, nil
+	}
+
+	var reason, message string
+	switch class, found := r.machineClasses.Get(className); {
+	case !found:
+		reason, message = "Removed", fmt.Sprintf("Machine class %s was removed", className)
+	case class.Deprecated:
+		reason, message = "Deprecated", fmt.Sprintf("Machine class %s is deprecated", className)
+	}
+
+	condition := machine.Status.GetCondition(api.MachineConditionClassDeprecated)
+	switch {
+	case reason == "" && condition == nil:
+		return machine, nil
+	case reason == "":
+		log.V(1).Info("Machine class is available again", "class", className)
+		machine.Status.RemoveCondition(api.MachineConditionClassDeprecated)
+	case condition != nil && condition.Reason == reason:
+		return machine, nil
+	default:
+		log.V(1).Info("Machine class is not available anymore", "class", className, "reason", reason)
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ClassDeprecated",
+			"%s, the machine keeps running", message)
+		machine.Status.SetCondition(api.MachineCondition{
+			Type:               api.MachineConditionClassDeprecated,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: time.Now(),
+		})
+	}
+
+	machine, err := r.machines.Update(ctx, machine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update machine status: %w", err)
+	}
+	return machine, nil
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// Clone creates a machine whose local disks are copies of the ones of the source machine, which must not run.
+// The clone gets no network interfaces and no volumes served from elsewhere, these stay with the source. It
+// returns the id of the clone.
+func (r *MachineReconciler) Clone(ctx context.Context, sourceID string, power api.PowerState) (string, error) {
+	source, err := r.machines.Get(ctx, sourceID)
+	if err != nil {
+		return "", err
+	}
+	if source.DeletedAt != nil {
+		return "", fmt.Errorf("machine %s is being deleted", sourceID)
+	}
+	if err := r.checkCloneSource(ctx, source); err != nil {
+		return "", fmt.Errorf("%w, power it off to clone its disks", err)
+	}
+	if imported(source) {
+		return "", fmt.Errorf("disks of imported machine %s are not managed by the provider", sourceID)
+	}
+
+	clone := &api.Machine{}
+	clone.ID = uuid.NewString()
+	clone.Labels = maps.Clone(source.Labels)
+	clone.Annotations = map[string]string{api.CloneSourceAnnotation: sourceID}
+	// The IRI labels and annotations of the source belong to its owner, the clone is only labeled with its source.
+	if err := api.SetObjectMetadata(clone, &irimeta.ObjectMetadata{
+		Labels:      map[string]string{api.ClonedFromLabel: sourceID},
+		Annotations: map[string]string{},
+	}); err != nil {
+		return "", err
+	}
+
+	clone.Spec = source.Spec
+	clone.Spec.Power = power
+	clone.Spec.ApiSocketPath = nil
+	clone.Spec.NetworkInterfaces = nil
+	clone.Spec.ShutdownAt = time.Time{}
+	clone.Spec.Volumes = nil
+	for _, vol := range source.Spec.Volumes {
+		if vol.LocalDisk == nil || vol.DeletedAt != nil {
+			continue
+		}
+		localDisk := *vol.LocalDisk
+		cloneVol := *vol
+		cloneVol.LocalDisk = &localDisk
+		clone.Spec.Volumes = append(clone.Spec.Volumes, &cloneVol)
+	}
+
+	if _, err := r.machines.Create(ctx, clone); err != nil {
+		return "", fmt.Errorf("failed to create clone: %w", err)
+	}
+	r.eventRecorder.Eventf(source.Metadata, corev1.EventTypeNormal, "Cloning", "Cloning machine as %s", clone.ID)
+	return clone.ID, nil
+}
+
+// checkCloneSource returns an error unless the disks of the source are consistent, which they are only while no
+// VM writes to them: the machine has to be powered off and its VM must neither run nor be paused.
+func (r *MachineReconciler) checkCloneSource(ctx context.Context, source *api.Machine) error {
+	if source.Spec.Power == api.PowerStatePowerOn || source.Status.State == api.MachineStateRunning {
+		return fmt.Errorf("machine %s is powered on", source.ID)
+	}
+
+	apiSocket := ptr.Deref(source.Spec.ApiSocketPath, "")
+	if apiSocket == "" {
+		return nil
+	}
+	vm, err := r.vmm.GetVM(ctx, apiSocket)
+	switch {
+	case errors.Is(err, vmm.ErrVmNotCreated) || errors.Is(err, vmm.ErrNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get vm of machine %s: %w", source.ID, err)
+	case vm.State == client.Running || vm.State == client.Paused:
+		return fmt.Errorf("vm of machine %s is %s", source.ID, vm.State)
+	}
+	return nil
+}
+
+// reconcileClone copies the disks of the source of a cloned machine before its volumes are applied, which
+// provision the disks from their image otherwise.
+func (r *MachineReconciler) reconcileClone(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+) (*api.Machine, error) {
+	sourceID, ok := machine.Annotations[api.CloneSourceAnnotation]
+	if !ok {
+		return machine, nil
+	}
+
+	// The source is not reconciled while its disks are copied, so that its VM is not started meanwhile. Sources
+	// are created before their clones, so the locks are always taken in the same order.
+	r.machineMu.Lock(sourceID)
+	defer r.machineMu.Unlock(sourceID)
+
+	source, err := r.machines.Get(ctx, sourceID)
+	if err != nil {
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "CloneFailed",
+			"Failed to get source machine %s: %v", sourceID, err)
+		return machine, fmt.Errorf("failed to get clone source %s: %w", sourceID, err)
+	}
+	if err := r.checkCloneSource(ctx, source); err != nil {
+		return machine, fmt.Errorf("clone source: %w", err)
+	}
+
+	for _, vol := range machine.Spec.Volumes {
+		if vol.LocalDisk == nil || vol.DeletedAt != nil {
+			continue
+		}
+		plugin, err := r.VolumePluginManager.FindPluginBySpec(vol)
+		if err != nil {
+			return machine, fmt.Errorf("failed to find plugin: %w", err)
+		}
+		cloner, ok := plugin.(volume.ClonePlugin)
+		if !ok {
+			return machine, fmt.Errorf("volume plugin %s does not support cloning", plugin.Name())
+		}
+
+		log.V(1).Info("Copying volume of clone source", "volume", vol.Name, "source", sourceID)
+		if err := cloner.Clone(ctx, vol, sourceID, machine.ID); err != nil {
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "CloneFailed",
+				"Failed to copy volume %s of machine %s: %v", vol.Name, sourceID, err)
+			return machine, fmt.Errorf("failed to clone volume %s: %w", vol.Name, err)
+		}
+	}
+
+	delete(machine.Annotations, api.CloneSourceAnnotation)
+	if machine, err = r.machines.Update(ctx, machine); err != nil {
+		return nil, fmt.Errorf("failed to update machine: %w", err)
+	}
+	log.V(1).Info("Cloned machine", "source", sourceID)
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Cloned", "Copied the disks of machine %s",
+		sourceID)
+	return machine, nil
+}
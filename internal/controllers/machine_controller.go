@@ -5,17 +5,28 @@ package controllers
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"os"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/cgroup"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/configdrive"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/console"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/machinelog"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/migration"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/oci"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/networkinterface"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/raw"
@@ -24,15 +35,38 @@ import (
 	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
 	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
 	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	utilssync "github.com/ironcore-dev/provider-utils/storeutils/sync"
 	"github.com/ironcore-dev/provider-utils/storeutils/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/ptr"
 )
 
 const (
 	MachineFinalizer = "machine"
+
+	// DefaultShutdownGracePeriod is the time a guest gets to shut down after its shutdown deadline
+	// before the VM is powered off.
+	DefaultShutdownGracePeriod = 2 * time.Minute
+
+	shutdownPollInterval = 5 * time.Second
+	// devicePollInterval is the interval the devices being unplugged by the guest or not prepared yet are
+	// checked in.
+	devicePollInterval = 2 * time.Second
+
+	// DefaultWorkers is the number of machines reconciled concurrently.
+	DefaultWorkers = 15
+
+	// DefaultResyncInterval is the interval machines are reconciled in without changes.
+	DefaultResyncInterval = 10 * time.Minute
+	resyncJitterFactor    = 0.2
+
+	// The serial output attached to events is limited to what fits into the note of a Kubernetes event.
+	crashTailLines = 20
+	crashTailBytes = 1024
 )
 
 type MachineReconcilerOptions struct {
@@ -40,6 +74,61 @@ type MachineReconcilerOptions struct {
 	Raw        raw.Raw
 
 	Paths host.Paths
+
+	IgnitionTransport  api.IgnitionTransport
+	ConfigDriveBuilder *configdrive.Builder
+
+	// Cgroups confines the cloud-hypervisor processes per machine. Disabled if nil.
+	Cgroups *cgroup.Manager
+
+	// ChownMachineDirs hands the machine directory to the user owning the api socket of the machine.
+	ChownMachineDirs bool
+
+	// SELinuxFileContext is set on the machine directory and its content if not empty.
+	SELinuxFileContext string
+
+	// DetachVms adopts the VMs running on the cloud-hypervisor instances on startup.
+	DetachVms bool
+
+	// DeleteForeignVms deletes VMs of unknown machines found on the api socket of a machine.
+	DeleteForeignVms bool
+
+	// ShutdownGracePeriod is the time a guest gets to shut down once the shutdown deadline of its machine
+	// passed. Defaults to DefaultShutdownGracePeriod.
+	ShutdownGracePeriod time.Duration
+
+	// Workers is the number of machines reconciled concurrently. Defaults to DefaultWorkers.
+	Workers int
+
+	// ResyncInterval is the interval machines are reconciled in without changes, plus a jitter of up to 20%.
+	// Disabled if not positive.
+	ResyncInterval time.Duration
+
+	// Maintenance stops creating VMs and evacuates the existing ones while the host is in maintenance.
+	Maintenance *maintenance.Mode
+
+	// MigrationTLSConfig secures the calls to the migration api of other providers.
+	MigrationTLSConfig *tls.Config
+	// MigrationAdvertiseAddress is the host other providers send migrated VMs to.
+	MigrationAdvertiseAddress string
+
+	// DrainTimeout is the time the reconciliations in flight get to finish once the reconciler is stopped.
+	// Defaults to DefaultDrainTimeout.
+	DrainTimeout time.Duration
+
+	// MachineLogs are the log files of the machines, closed once a machine is deleted. Optional.
+	MachineLogs *machinelog.Files
+
+	// MachineClasses are the classes of the provider, machines of deprecated or removed classes get a condition.
+	// Optional.
+	MachineClasses mcr.MachineClassRegistry
+
+	// NICPCISegments are the PCI segments the VMs have for their network interfaces, see
+	// vmm.ManagerOptions.NICPCISegments. Disabled if 0.
+	NICPCISegments int16
+
+	// ImagePusher pushes the root disks of machines exported as image. Exports are disabled if nil.
+	ImagePusher *oci.Pusher
 }
 
 func NewMachineReconciler(
@@ -47,7 +136,7 @@ func NewMachineReconciler(
 	machines store.Store[*api.Machine],
 	machineEvents event.Source[*api.Machine],
 	eventRecorder recorder.EventRecorder,
-	vmm *vmm.Manager,
+	vmm vmm.VirtualMachineManager,
 	volumePluginManager *volume.PluginManager,
 	nicPlugin networkinterface.Plugin,
 	opts MachineReconcilerOptions,
@@ -60,33 +149,99 @@ func NewMachineReconciler(
 		return nil, fmt.Errorf("must specify machine events")
 	}
 
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+
+	if opts.DrainTimeout == 0 {
+		opts.DrainTimeout = DefaultDrainTimeout
+	}
+
+	if opts.ShutdownGracePeriod == 0 {
+		opts.ShutdownGracePeriod = DefaultShutdownGracePeriod
+	}
+
 	return &MachineReconciler{
 		log: log,
 		queue: workqueue.NewTypedRateLimitingQueue[string](
 			workqueue.DefaultTypedControllerRateLimiter[string](),
 		),
-		machines:               machines,
-		machineEvents:          machineEvents,
-		eventRecorder:          eventRecorder,
-		imageCache:             opts.ImageCache,
-		raw:                    opts.Raw,
-		paths:                  opts.Paths,
-		vmm:                    vmm,
-		VolumePluginManager:    volumePluginManager,
-		networkInterfacePlugin: nicPlugin,
+		machineMu:                 utilssync.NewMutexMap[string](),
+		machines:                  machines,
+		machineEvents:             machineEvents,
+		eventRecorder:             eventRecorder,
+		imageCache:                opts.ImageCache,
+		raw:                       opts.Raw,
+		paths:                     opts.Paths,
+		vmm:                       vmm,
+		VolumePluginManager:       volumePluginManager,
+		networkInterfacePlugin:    nicPlugin,
+		ignitionTransport:         opts.IgnitionTransport,
+		configDriveBuilder:        opts.ConfigDriveBuilder,
+		cgroups:                   opts.Cgroups,
+		chownMachineDirs:          opts.ChownMachineDirs,
+		selinuxFileContext:        opts.SELinuxFileContext,
+		detachVms:                 opts.DetachVms,
+		deleteForeignVms:          opts.DeleteForeignVms,
+		shutdownGracePeriod:       opts.ShutdownGracePeriod,
+		workers:                   opts.Workers,
+		resyncInterval:            opts.ResyncInterval,
+		maintenance:               opts.Maintenance,
+		machineLogs:               opts.MachineLogs,
+		machineClasses:            opts.MachineClasses,
+		nicPCISegments:            opts.NICPCISegments,
+		drainTimeout:              opts.DrainTimeout,
+		pending:                   sets.New[string](),
+		migrationTLSConfig:        opts.MigrationTLSConfig,
+		migrationAdvertiseAddress: opts.MigrationAdvertiseAddress,
+		imagePusher:               opts.ImagePusher,
 	}, nil
 }
 
 type MachineReconciler struct {
 	log   logr.Logger
 	queue workqueue.TypedRateLimitingInterface[string]
+	// machineMu serializes the reconciliations of a machine, so that their store updates do not interleave.
+	machineMu *utilssync.MutexMap[string]
 
 	imageCache ociutils.Cache
 	raw        raw.Raw
 
 	paths host.Paths
 
-	vmm *vmm.Manager
+	ignitionTransport  api.IgnitionTransport
+	configDriveBuilder *configdrive.Builder
+
+	cgroups            *cgroup.Manager
+	chownMachineDirs   bool
+	selinuxFileContext string
+
+	detachVms           bool
+	deleteForeignVms    bool
+	shutdownGracePeriod time.Duration
+	workers             int
+	resyncInterval      time.Duration
+	maintenance         *maintenance.Mode
+	machineLogs         *machinelog.Files
+	machineClasses      mcr.MachineClassRegistry
+	nicPCISegments      int16
+
+	// draining is set once the reconciler is stopped, the machines still queued are pending until the next start.
+	drainTimeout time.Duration
+	draining     atomic.Bool
+	pendingMu    sync.Mutex
+	pending      sets.Set[string]
+
+	migrationTLSConfig        *tls.Config
+	migrationAdvertiseAddress string
+	// receivers are the cancel funcs of the machines receiving a migrated VM.
+	receivers sync.Map
+
+	imagePusher *oci.Pusher
+	// exports are the cancel funcs of the machines whose root disk is exported.
+	exports sync.Map
+
+	vmm vmm.VirtualMachineManager
 
 	VolumePluginManager    *volume.PluginManager
 	networkInterfacePlugin networkinterface.Plugin
@@ -100,8 +255,11 @@ type MachineReconciler struct {
 func (r *MachineReconciler) Start(ctx context.Context) error {
 	log := r.log
 
-	// TODO make configurable
-	workerSize := 15
+	if r.detachVms {
+		if err := r.adoptVMs(ctx); err != nil {
+			return fmt.Errorf("failed to adopt vms: %w", err)
+		}
+	}
 
 	r.imageCache.AddListener(ociutils.ListenerFuncs{
 		HandlePullDoneFunc: func(evt ociutils.PullDoneEvent) {
@@ -121,6 +279,43 @@ func (r *MachineReconciler) Start(ctx context.Context) error {
 		},
 	})
 
+	if notifier, ok := r.imageCache.(oci.PullFailureNotifier); ok {
+		notifier.AddPullFailedListener(func(evt oci.PullFailedEvent) {
+			machines, err := r.machines.List(ctx)
+			if err != nil {
+				log.Error(err, "failed to list machine")
+				return
+			}
+
+			for _, machine := range machines {
+				if api.IsImageReferenced(machine, evt.Ref) {
+					log.V(1).Info("Image pull failed: Requeue machines", "Image", evt.Ref, "Machine", machine.ID)
+					r.queue.Add(machine.ID)
+				}
+			}
+		})
+	}
+
+	if watcher, ok := r.networkInterfacePlugin.(networkinterface.Watcher); ok {
+		go func() {
+			if err := watcher.Watch(ctx, func(machineID string) {
+				log.V(2).Info("Network interface changed: Requeue machine", "machineID", machineID)
+				r.queue.Add(machineID)
+			}); err != nil {
+				log.Error(err, "failed to watch network interfaces")
+			}
+		}()
+	}
+
+	if r.maintenance != nil {
+		r.maintenance.AddListener(func(state maintenance.State) {
+			log.Info("Maintenance state changed, requeue machines", "enabled", state.Enabled, "evacuation", state.Evacuation)
+			r.requeueAll(ctx)
+		})
+	}
+
+	r.restoreQueue(log)
+
 	machineEventHandlerRegistration, err := r.machineEvents.AddHandler(
 		event.HandlerFunc[*api.Machine](func(evt event.Event[*api.Machine]) {
 			log.V(2).Info("Machine event received", "type", evt.Type, "id", evt.Object.ID)
@@ -135,25 +330,102 @@ func (r *MachineReconciler) Start(ctx context.Context) error {
 		}
 	}()
 
+	reconcileCtx, drained := r.drain(ctx, log)
 	var wg sync.WaitGroup
-	go func() {
-		<-ctx.Done()
-		r.queue.ShutDown()
-	}()
-
-	for i := 0; i < workerSize; i++ {
+	for i := 0; i < r.workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for r.processNextWorkItem(ctx, log) {
+			for r.processNextWorkItem(reconcileCtx, log) {
 			}
 		}()
 	}
 
 	wg.Wait()
+	drained()
+	r.persistQueue(log)
+	return nil
+}
+
+// Requeue clears the backoff of the machine and reconciles it immediately.
+func (r *MachineReconciler) Requeue(ctx context.Context, machineID string) error {
+	if _, err := r.machines.Get(ctx, machineID); err != nil {
+		return err
+	}
+
+	r.queue.Forget(machineID)
+	r.queue.Add(machineID)
 	return nil
 }
 
+// RequestRecreate marks the machine so that its VM is deleted and created again on the next reconciliation.
+func (r *MachineReconciler) RequestRecreate(ctx context.Context, machineID string) error {
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return err
+	}
+	if imported(machine) {
+		return fmt.Errorf("vm of imported machine %s cannot be created again", machineID)
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[api.RecreateRequestedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	return r.Requeue(ctx, machineID)
+}
+
+// RequestReboot marks the machine so that its VM is rebooted on the next reconciliation.
+func (r *MachineReconciler) RequestReboot(ctx context.Context, machineID string) error {
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return err
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[api.RebootRequestedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	return r.Requeue(ctx, machineID)
+}
+
+// VMConfigs returns the config of the running VM of the machine, nil if it has none, and the config the VM would
+// be created with from the current spec. Devices are hot-plugged after the creation and are only part of the
+// live config.
+func (r *MachineReconciler) VMConfigs(
+	ctx context.Context, machineID string,
+) (live *client.VmConfig, desired *client.VmConfig, err error) {
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if desired, err = r.vmm.Config(machine); err != nil {
+		return nil, nil, fmt.Errorf("failed to build vm config: %w", err)
+	}
+
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	if apiSocket == "" {
+		return nil, desired, nil
+	}
+	vm, err := r.vmm.GetVM(ctx, apiSocket)
+	if err != nil {
+		if errors.Is(err, vmm.ErrVmNotCreated) || errors.Is(err, vmm.ErrNotFound) {
+			return nil, desired, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get vm: %w", err)
+	}
+	return &vm.Config, desired, nil
+}
+
 func (r *MachineReconciler) processNextWorkItem(ctx context.Context, log logr.Logger) bool {
 	id, shutdown := r.queue.Get()
 	if shutdown {
@@ -161,12 +433,18 @@ func (r *MachineReconciler) processNextWorkItem(ctx context.Context, log logr.Lo
 	}
 	defer r.queue.Done(id)
 
+	if r.deferWhileDraining(id) {
+		return true
+	}
+
 	log = log.WithValues("machineID", id)
 	ctx = logr.NewContext(ctx, log)
 
 	if err := r.reconcileMachine(ctx, id); err != nil {
 		log.Error(err, "failed to reconcile machine")
-		r.queue.AddRateLimited(id)
+		if !r.deferWhileDraining(id) {
+			r.queue.AddRateLimited(id)
+		}
 		return true
 	}
 
@@ -174,6 +452,25 @@ func (r *MachineReconciler) processNextWorkItem(ctx context.Context, log logr.Lo
 	return true
 }
 
+// resync requeues the machine after the resync interval. The interval is jittered, so that machines reconciled
+// together, e.g. on startup, are spread out.
+func (r *MachineReconciler) resync(id string) {
+	if r.resyncInterval <= 0 {
+		return
+	}
+	r.queue.AddAfter(id, wait.Jitter(r.resyncInterval, resyncJitterFactor))
+}
+
+func pendingNICs(machine *api.Machine) []string {
+	var pending []string
+	for _, status := range machine.Status.NetworkInterfaceStatus {
+		if status.State == api.NetworkInterfaceStatePending {
+			pending = append(pending, status.Name)
+		}
+	}
+	return pending
+}
+
 func getNicName(id string) *string {
 	parts := strings.Split(id, "//")
 	if len(parts) != 2 {
@@ -187,6 +484,7 @@ func getNicName(id string) *string {
 	return &parts[1]
 }
 
+// getMachineState returns the state cloud-hypervisor reports for the VM of the machine, Shutdown if it has none.
 func (r *MachineReconciler) getMachineState(
 	ctx context.Context, machine *api.Machine,
 ) (client.VmInfoState, error) {
@@ -198,10 +496,36 @@ func (r *MachineReconciler) getMachineState(
 		}
 		return client.Shutdown, err
 	}
-	if vm.State == client.Running {
-		return client.Running, nil
+	return vm.State, nil
+}
+
+// machineStateOf returns the state of a powered on machine whose VM is in the state.
+func machineStateOf(state client.VmInfoState) api.MachineState {
+	switch state {
+	case client.Running:
+		return api.MachineStateRunning
+	case client.Paused, vmm.BreakPoint:
+		return api.MachineStateSuspended
+	case client.Created:
+		return api.MachineStatePending
+	default:
+		return api.MachineStateTerminated
+	}
+}
+
+// recordSuspension records a warning event once the VM of a powered on machine was found paused or stopped at a
+// breakpoint. The VM is not started again, it continues once it is resumed via its api socket or debugger.
+func (r *MachineReconciler) recordSuspension(machine *api.Machine, state client.VmInfoState) {
+	if machine.Status.State == api.MachineStateSuspended {
+		return
 	}
-	return client.Shutdown, nil
+	if state == vmm.BreakPoint {
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "VMBreakPoint",
+			"VM stopped at a breakpoint of the debugger")
+		return
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "VMPaused",
+		"VM was paused outside of the provider, it is not started again until it is resumed")
 }
 
 func getVolumeStatus(volumes []api.VolumeStatus, name string) api.VolumeStatus {
@@ -229,6 +553,9 @@ func getNICStatus(nics []api.NetworkInterfaceStatus, name string) api.NetworkInt
 }
 
 func (r *MachineReconciler) deleteMachine(ctx context.Context, log logr.Logger, machine *api.Machine) error {
+	r.stopReceiving(machine.ID)
+	r.stopExport(machine.ID)
+
 	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
 
 	state, err := r.getMachineState(ctx, machine)
@@ -247,6 +574,7 @@ func (r *MachineReconciler) deleteMachine(ctx context.Context, log logr.Logger,
 	}
 
 	log.V(1).Info("Delete machine")
+	machineBootTime.DeleteLabelValues(machine.ID)
 	if err := r.vmm.Delete(ctx, apiSocket); err != nil {
 		if !errors.Is(err, vmm.ErrNotFound) {
 			return fmt.Errorf("failed to delete machine: %w", err)
@@ -255,6 +583,9 @@ func (r *MachineReconciler) deleteMachine(ctx context.Context, log logr.Logger,
 
 	log.V(1).Info("Delete volumes")
 	for _, vol := range machine.Spec.Volumes {
+		if vol.Imported {
+			continue
+		}
 		plugin, err := r.VolumePluginManager.FindPluginBySpec(vol)
 		if err != nil {
 			return fmt.Errorf("failed to find plugin: %w", err)
@@ -268,19 +599,29 @@ func (r *MachineReconciler) deleteMachine(ctx context.Context, log logr.Logger,
 
 	log.V(1).Info("Delete NICs")
 	for _, nic := range machine.Spec.NetworkInterfaces {
+		if nic.Imported {
+			continue
+		}
 		log.V(2).Info("Delete NIC", "name", nic.Name)
 		if err := r.networkInterfacePlugin.Delete(ctx, nic.Name, machine.ID); err != nil {
 			return fmt.Errorf("failed to delete nic %s: %w", nic.Name, err)
 		}
 	}
 
+	if err := r.releaseCgroup(ctx, log, machine); err != nil {
+		return err
+	}
+
 	if apiSocket != "" {
-		r.vmm.FreeApiSocket(ctx, apiSocket)
+		r.freeApiSocket(ctx, log, apiSocket)
 	}
 
 	if err := os.RemoveAll(r.paths.MachineDir(machine.ID)); err != nil {
 		return fmt.Errorf("failed to remove machine directory: %w", err)
 	}
+	if r.machineLogs != nil {
+		r.machineLogs.Close(machine.ID)
+	}
 	log.V(1).Info("Removed machine directory")
 
 	machine.Finalizers = utils.DeleteSliceElement(machine.Finalizers, MachineFinalizer)
@@ -298,6 +639,17 @@ func (r *MachineReconciler) reconcileVolumes(ctx context.Context, log logr.Logge
 	var updatedVolumeSpec []*api.VolumeSpec
 
 	for _, vol := range machine.Spec.Volumes {
+		if vol.Imported {
+			// Disks of imported VMs are kept as they are until they are removed from the VM.
+			status := getVolumeStatus(machine.Status.VolumeStatus, vol.Name)
+			if vol.DeletedAt != nil && status.State != api.VolumeStateAttached {
+				removeDiskOrder(machine, vol.Name)
+				continue
+			}
+			updatedVolumeSpec = append(updatedVolumeSpec, vol)
+			updatedVolumeStatus = append(updatedVolumeStatus, status)
+			continue
+		}
 
 		plugin, err := r.VolumePluginManager.FindPluginBySpec(vol)
 		if err != nil {
@@ -313,17 +665,25 @@ func (r *MachineReconciler) reconcileVolumes(ctx context.Context, log logr.Logge
 				if err := plugin.Delete(ctx, vol.Name, machine.ID); err != nil {
 					return fmt.Errorf("failed to delete volume %s: %w", vol.Name, err)
 				}
+				removeDiskOrder(machine, vol.Name)
 				continue
 			}
 			log.V(2).Info("Volume attached but deletion timestamp set", "name", vol.Name)
 		}
 
 		appliedVolume, err := plugin.Apply(ctx, vol, machine.ID)
-		if err != nil {
+		switch {
+		case errors.Is(err, volume.ErrConnectionNotUpdated) && appliedVolume != nil:
+			log.Error(err, "Volume keeps its previous connection", "name", vol.Name)
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "VolumeConnectionNotUpdated",
+				"Volume %s keeps its previous connection: %v", vol.Name, err)
+		case err != nil:
 			return fmt.Errorf("failed to apply volume: %w", err)
 		}
 		if status.State == api.VolumeStateAttached {
 			appliedVolume.State = status.State
+			appliedVolume.Stats = status.Stats
+			appliedVolume.Health = status.Health
 		}
 		updatedVolumeSpec = append(updatedVolumeSpec, vol)
 		updatedVolumeStatus = append(updatedVolumeStatus, *appliedVolume)
@@ -346,11 +706,18 @@ func (r *MachineReconciler) reconcileNics(ctx context.Context, log logr.Logger,
 
 	plugin := r.networkInterfacePlugin
 	for _, nic := range machine.Spec.NetworkInterfaces {
+		status := getNICStatus(machine.Status.NetworkInterfaceStatus, nic.Name)
+		if nic.Imported {
+			// Network interfaces of imported VMs are kept as they are until they are removed from the VM.
+			if nic.DeletedAt == nil || status.State == api.NetworkInterfaceStateAttached {
+				updatedNICSpec = append(updatedNICSpec, nic)
+				updatedNICStatus = append(updatedNICStatus, status)
+			}
+			continue
+		}
 
 		log.V(2).Info("Reconcile NIC", "name", nic.Name, "plugin", plugin.Name())
 
-		status := getNICStatus(machine.Status.NetworkInterfaceStatus, nic.Name)
-
 		if nic.DeletedAt != nil {
 			if status.State != api.NetworkInterfaceStateAttached {
 				log.V(2).Info("Delete detached  NIC", "name", nic.Name)
@@ -366,8 +733,17 @@ func (r *MachineReconciler) reconcileNics(ctx context.Context, log logr.Logger,
 		if err != nil {
 			return fmt.Errorf("failed to apply NIC: %w", err)
 		}
+		if len(appliedNIC.IPs) == 0 {
+			// Plugins not assigning addresses themselves configure the ones of the spec.
+			appliedNIC.IPs = nic.Ips
+		}
 		if status.State == api.NetworkInterfaceStateAttached {
 			appliedNIC.State = status.State
+			appliedNIC.Stats = status.Stats
+			// The interface stays on the segment it was plugged into until it is detached.
+			appliedNIC.PCISegment = status.PCISegment
+		} else {
+			appliedNIC.PCISegment = r.reserveNICPCISegment(log, machine, nic.Name)
 		}
 		updatedNICSpec = append(updatedNICSpec, nic)
 		updatedNICStatus = append(updatedNICStatus, *appliedNIC)
@@ -401,31 +777,80 @@ func (r *MachineReconciler) attachDetachDisks(
 		}
 		currentDevices.Insert(ptr.Deref(id, ""))
 	}
+	sharedMemory := vmm.MemoryShared(vm.Memory)
 
+	// All disks are attached and detached in one pass, a failing one does not hold back the others.
+	var errs []error
+	removing := false
 	var updatedVolumeStatus []api.VolumeStatus
 	for _, vol := range machine.Spec.Volumes {
 		status := getVolumeStatus(machine.Status.VolumeStatus, vol.Name)
 
+		if vol.Imported {
+			// Disks of imported VMs are only removed, they cannot be added again once they are gone.
+			switch {
+			case !currentDevices.Has(status.Handle):
+				status.State = api.VolumeStatePrepared
+			case vol.DeletedAt != nil:
+				if err := r.vmm.RemoveDevice(ctx, apiSocket, status.Handle); err != nil {
+					errs = append(errs, fmt.Errorf("failed to remove disk %s: %w", vol.Name, err))
+				} else {
+					log.V(1).Info("Removed disk", "disk", vol.Name)
+					removing = true
+				}
+			}
+			updatedVolumeStatus = append(updatedVolumeStatus, status)
+			continue
+		}
+
 		if vol.DeletedAt == nil {
 			if !currentDevices.Has(status.Handle) {
 				if status.State != api.VolumeStatePrepared {
 					log.V(1).Info("Skip disk attachment: not prepared", "disk", vol.Name)
+					updatedVolumeStatus = append(updatedVolumeStatus, status)
+					continue
+				}
+				if status.Type == api.VolumeSocketType && !sharedMemory {
+					log.V(1).Info("Skip disk attachment: vhost-user requires shared memory", "disk", vol.Name)
+					r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "SharedMemoryRequired",
+						"Volume %s requires shared memory, recreate the VM to attach it", vol.Name)
+					updatedVolumeStatus = append(updatedVolumeStatus, status)
 					continue
 				}
 				if err := r.vmm.AddDisk(ctx, apiSocket, ptr.To(status)); err != nil {
-					return fmt.Errorf("failed to add disk %s: %w", vol.Name, err)
+					errs = append(errs, fmt.Errorf("failed to add disk %s: %w", vol.Name, err))
+					updatedVolumeStatus = append(updatedVolumeStatus, status)
+					continue
 				}
+				appendDiskOrder(machine, vol.Name)
 
 				log.V(1).Info("Added disk", "disk", vol.Name)
+			} else if volumeUnavailable(status) {
+				// The volume was mounted again before, the guest only reconnects to it when it is plugged again.
+				if err := r.vmm.RemoveDevice(ctx, apiSocket, status.Handle); err != nil {
+					errs = append(errs, fmt.Errorf("failed to remove unavailable disk %s: %w", vol.Name, err))
+					updatedVolumeStatus = append(updatedVolumeStatus, status)
+					continue
+				}
+				log.V(1).Info("Removed unavailable disk", "disk", vol.Name)
+				r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "VolumeReattaching",
+					"Volume %s was unavailable, attaching it again", vol.Name)
+
+				status.State = api.VolumeStatePrepared
+				status.Health = nil
+				updatedVolumeStatus = append(updatedVolumeStatus, status)
+				continue
 			}
 			status.State = api.VolumeStateAttached
 			updatedVolumeStatus = append(updatedVolumeStatus, status)
 		} else {
 			if currentDevices.Has(status.Handle) {
 				if err := r.vmm.RemoveDevice(ctx, apiSocket, status.Handle); err != nil {
-					return fmt.Errorf("failed to remove disk %s: %w", vol.Name, err)
+					errs = append(errs, fmt.Errorf("failed to remove disk %s: %w", vol.Name, err))
+				} else {
+					log.V(1).Info("Removed disk", "disk", vol.Name)
+					removing = true
 				}
-				log.V(1).Info("Removed disk", "disk", vol.Name)
 
 				updatedVolumeStatus = append(updatedVolumeStatus, status)
 				continue
@@ -441,8 +866,19 @@ func (r *MachineReconciler) attachDetachDisks(
 	if _, err := r.machines.Update(ctx, machine); err != nil {
 		return fmt.Errorf("failed to update machine status: %w", err)
 	}
+	if removing {
+		// The disks are gone once the guest released them, they are deleted in a later reconciliation.
+		r.queue.AddAfter(machine.ID, devicePollInterval)
+	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// volumeUnavailable reports whether the vhost-user daemon serving the attached volume was found to not serve it
+// anymore.
+func volumeUnavailable(status api.VolumeStatus) bool {
+	return status.Type == api.VolumeSocketType && status.Health != nil &&
+		status.Health.State == api.VolumeHealthStateUnavailable
 }
 
 // nolint: dupl
@@ -462,21 +898,62 @@ func (r *MachineReconciler) attachDetachNICs(
 		}
 		currentDevices.Insert(ptr.Deref(name, ""))
 	}
+	netIDs := sets.New[string]()
+	for _, net := range ptr.Deref(vm.Net, []client.NetConfig{}) {
+		netIDs.Insert(ptr.Deref(net.Id, ""))
+		name := getNicName(ptr.Deref(net.Id, ""))
+		if name == nil {
+			continue
+		}
+		currentDevices.Insert(ptr.Deref(name, ""))
+	}
 
+	// All NICs are attached and detached in one pass, a failing or not prepared one does not hold back the others.
+	var errs []error
+	removing, pending := false, false
 	var updatedNICStatus []api.NetworkInterfaceStatus
 	for _, nic := range machine.Spec.NetworkInterfaces {
 		status := getNICStatus(machine.Status.NetworkInterfaceStatus, nic.Name)
 
+		if nic.Imported {
+			// Network interfaces of imported VMs are only removed, they cannot be added again once they are gone.
+			switch {
+			case !netIDs.Has(status.Handle):
+				status.State = api.NetworkInterfaceStatePrepared
+			case nic.DeletedAt != nil:
+				if err := r.vmm.RemoveDevice(ctx, apiSocket, status.Handle); err != nil {
+					errs = append(errs, fmt.Errorf("failed to remove NIC %s: %w", nic.Name, err))
+				} else {
+					log.V(1).Info("Removed NIC", "nic", nic.Name)
+					removing = true
+				}
+			}
+			updatedNICStatus = append(updatedNICStatus, status)
+			continue
+		}
+
 		if nic.DeletedAt == nil {
 			if !currentDevices.Has(status.Name) {
 				if status.State != api.NetworkInterfaceStatePrepared {
 					log.V(1).Info("Skip NIC attachment: not prepared", "nic", nic.Name)
+					updatedNICStatus = append(updatedNICStatus, status)
+					pending = true
 					continue
 				}
 
-				if err := r.vmm.AddNIC(ctx, apiSocket, ptr.To(status)); err != nil {
-					return fmt.Errorf("failed to add disk %s: %w", nic.Name, err)
+				nicStatus := status
+				if nicStatus.PCISegment >= vmm.NumPCISegments(vm.Platform) {
+					// The VM was created before the segments were enabled, it gets them once it is recreated.
+					log.V(1).Info("PCI segment of NIC not present in VM, using the default segment",
+						"nic", nic.Name, "pciSegment", nicStatus.PCISegment)
+					nicStatus.PCISegment = 0
 				}
+				if err := r.vmm.AddNIC(ctx, apiSocket, &nicStatus); err != nil {
+					errs = append(errs, fmt.Errorf("failed to add NIC %s: %w", nic.Name, err))
+					updatedNICStatus = append(updatedNICStatus, status)
+					continue
+				}
+				status.PCISegment = nicStatus.PCISegment
 
 				log.V(1).Info("Added NIC", "nic", nic.Name)
 			}
@@ -485,12 +962,13 @@ func (r *MachineReconciler) attachDetachNICs(
 		} else {
 			if currentDevices.Has(status.Name) {
 				if err := r.vmm.RemoveNIC(ctx, apiSocket, nic.Name); err != nil {
-					return fmt.Errorf("failed to remove NIC %s: %w", status.Name, err)
+					errs = append(errs, fmt.Errorf("failed to remove NIC %s: %w", status.Name, err))
+				} else {
+					log.V(1).Info("Removed NIC", "nic", status.Name)
+					removing = true
 				}
-				log.V(1).Info("Removed NIC", "nic", status.Name)
 
 				updatedNICStatus = append(updatedNICStatus, status)
-				r.queue.Add(machine.ID)
 				continue
 			}
 
@@ -504,7 +982,348 @@ func (r *MachineReconciler) attachDetachNICs(
 	if _, err := r.machines.Update(ctx, machine); err != nil {
 		return fmt.Errorf("failed to update machine status: %w", err)
 	}
+	// The machine is checked again once for all NICs the guest is unplugging and, unless the plugin requeues the
+	// machine itself, for the ones not prepared yet.
+	_, watched := r.networkInterfacePlugin.(networkinterface.Watcher)
+	if removing || pending && !watched {
+		r.queue.AddAfter(machine.ID, devicePollInterval)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *MachineReconciler) reconcileConfigDrive(ctx context.Context, log logr.Logger, machine *api.Machine) error {
+	transport := machine.Spec.IgnitionTransport
+	if transport == "" {
+		transport = r.ignitionTransport
+	}
+
+	var (
+		configDrive *api.ConfigDriveStatus
+		label       string
+		files       map[string][]byte
+	)
+	switch {
+	case machine.Spec.CloudInit != nil:
+		configDrive = &api.ConfigDriveStatus{Format: api.ConfigDriveFormatNoCloud}
+		label = configdrive.NoCloudLabel
+		metaData := machine.Spec.CloudInit.MetaData
+		if len(metaData) == 0 {
+			metaData = []byte(fmt.Sprintf("instance-id: %s\n", machine.ID))
+		}
+		files = map[string][]byte{
+			configdrive.NoCloudUserDataFile: machine.Spec.CloudInit.UserData,
+			configdrive.NoCloudMetaDataFile: metaData,
+		}
+	case transport == api.IgnitionTransportConfigDrive &&
+		(machine.Spec.Ignition != nil || machine.Spec.OpaqueUserData != nil):
+		configDrive = &api.ConfigDriveStatus{Format: api.ConfigDriveFormatOpenStack}
+		label = configdrive.OpenStackLabel
+		files = map[string][]byte{}
+		if machine.Spec.Ignition != nil {
+			files[configdrive.OpenStackUserDataFile] = machine.Spec.Ignition
+		}
+	}
+	if configDrive != nil && machine.Spec.OpaqueUserData != nil {
+		files[configdrive.OpaqueUserDataFile] = machine.Spec.OpaqueUserData
+	}
 
+	if configDrive != nil {
+		if r.configDriveBuilder == nil {
+			return fmt.Errorf("config drive is not configured")
+		}
+
+		configDrive.Path = r.paths.MachineConfigDriveFile(machine.ID)
+		log.V(1).Info("Building config drive", "path", configDrive.Path, "format", configDrive.Format)
+		if err := r.configDriveBuilder.Build(ctx, configDrive.Path, label, files); err != nil {
+			return err
+		}
+	}
+
+	if equality.Semantic.DeepEqual(machine.Status.ConfigDrive, configDrive) {
+		return nil
+	}
+
+	machine.Status.ConfigDrive = configDrive
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine status: %w", err)
+	}
+	return nil
+}
+
+func (r *MachineReconciler) recreateVM(ctx context.Context, log logr.Logger, machine *api.Machine, vm *client.VmInfo) error {
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	log.V(1).Info("Recreation requested, deleting VM")
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "RecreatingVM", "Recreating VM as requested")
+
+	if vm.State == client.Running {
+		if err := r.vmm.PowerOff(ctx, apiSocket); err != nil {
+			return fmt.Errorf("failed to power off VM: %w", err)
+		}
+	}
+	if err := r.vmm.Delete(ctx, apiSocket); err != nil {
+		return fmt.Errorf("failed to delete VM: %w", err)
+	}
+
+	// The devices went away with the VM and are passed to the new one on creation.
+	for i := range machine.Status.VolumeStatus {
+		if machine.Status.VolumeStatus[i].State == api.VolumeStateAttached {
+			machine.Status.VolumeStatus[i].State = api.VolumeStatePrepared
+		}
+	}
+	for i := range machine.Status.NetworkInterfaceStatus {
+		if machine.Status.NetworkInterfaceStatus[i].State == api.NetworkInterfaceStateAttached {
+			machine.Status.NetworkInterfaceStatus[i].State = api.NetworkInterfaceStatePrepared
+		}
+	}
+	machine.Status.State = api.MachineStatePending
+
+	delete(machine.Annotations, api.RecreateRequestedAnnotation)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	r.queue.Add(machine.ID)
+	return nil
+}
+
+// reconcileReboot reboots the VM if requested. The request is dropped if the VM is not running, since it
+// boots with a fresh guest state anyway.
+func (r *MachineReconciler) reconcileReboot(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+) (*api.Machine, error) {
+	requestedAt, ok := machine.Annotations[api.RebootRequestedAnnotation]
+	if !ok {
+		return machine, nil
+	}
+
+	// The VM state is from before a power on or off of this reconciliation.
+	if vm.State == client.Running && machine.Spec.Power == api.PowerStatePowerOn && !shutdownDeadlinePassed(machine) {
+		log.V(1).Info("Rebooting VM", "requestedAt", requestedAt)
+		if err := r.vmm.Reboot(ctx, ptr.Deref(machine.Spec.ApiSocketPath, "")); err != nil {
+			return nil, err
+		}
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Rebooted", "Rebooted VM")
+	} else {
+		log.V(1).Info("VM is not running, dropping reboot request")
+	}
+
+	delete(machine.Annotations, api.RebootRequestedAnnotation)
+	return r.machines.Update(ctx, machine)
+}
+
+// recordUnexpectedStop records a warning event with the last serial output of a VM that stopped although the
+// machine is powered on, e.g. after a guest panic.
+func (r *MachineReconciler) recordUnexpectedStop(log logr.Logger, machine *api.Machine) {
+	tail, err := console.Tail(r.paths.MachineSerialLogFile(machine.ID), crashTailLines, crashTailBytes)
+	if err != nil {
+		log.V(1).Info("Failed to read serial log", "error", err.Error())
+	}
+	if tail == "" {
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "VMStopped",
+			"VM stopped unexpectedly, starting it again")
+		return
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "VMStopped",
+		"VM stopped unexpectedly, starting it again. Serial console:\n%s", tail)
+}
+
+// reportSocketUnavailable sets the SocketUnavailable condition of a machine no cloud-hypervisor instance is free
+// for. The returned error requeues the machine with backoff until an instance is freed or added.
+func (r *MachineReconciler) reportSocketUnavailable(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	err error,
+) error {
+	reason, message := "NoFreeSocket", "All cloud-hypervisor instances are in use"
+	event := "No free cloud-hypervisor instance available, waiting for one to be freed"
+	if errors.Is(err, vmm.ErrNoCompatibleSocket) {
+		reason, message = "NoCompatibleSocket", "No free cloud-hypervisor instance satisfies the requirements "+
+			"of the machine"
+		event = message + ", waiting for one to be freed or added"
+	}
+	if condition := machine.Status.GetCondition(api.MachineConditionSocketUnavailable); condition == nil ||
+		condition.Reason != reason {
+		log.V(1).Info("No free api socket available", "reason", reason)
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "SocketUnavailable", "%s", event)
+		machine.Status.SetCondition(api.MachineCondition{
+			Type:               api.MachineConditionSocketUnavailable,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: time.Now(),
+		})
+		if _, err := r.machines.Update(ctx, machine); err != nil {
+			return fmt.Errorf("failed to update machine status: %w", err)
+		}
+	}
+	return err
+}
+
+// reportImagePullFailure sets the ImagePullBackOff condition of a machine whose boot image failed to pull. The
+// returned error requeues the machine with backoff.
+func (r *MachineReconciler) reportImagePullFailure(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	pullErr error,
+) error {
+	message := pullErr.Error()
+	condition := machine.Status.GetCondition(api.MachineConditionImagePullBackOff)
+	if condition == nil || condition.Message != message {
+		log.V(1).Info("Image pull failed", "error", message)
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ImagePullFailed", "%s", message)
+		machine.Status.SetCondition(api.MachineCondition{
+			Type:               api.MachineConditionImagePullBackOff,
+			Reason:             "ImagePullFailed",
+			Message:            message,
+			LastTransitionTime: time.Now(),
+		})
+		if _, err := r.machines.Update(ctx, machine); err != nil {
+			return fmt.Errorf("failed to update machine status: %w", err)
+		}
+	}
+	return pullErr
+}
+
+// freeApiSocket returns the socket to the free sockets and requeues the machines waiting for one.
+func (r *MachineReconciler) freeApiSocket(ctx context.Context, log logr.Logger, socket string) {
+	r.vmm.FreeApiSocket(ctx, socket)
+
+	machines, err := r.machines.List(ctx)
+	if err != nil {
+		log.Error(err, "Failed to list machines waiting for a socket")
+		return
+	}
+	for _, machine := range machines {
+		if machine.Status.HasCondition(api.MachineConditionSocketUnavailable) {
+			r.queue.Forget(machine.ID)
+			r.queue.Add(machine.ID)
+		}
+	}
+}
+
+func shutdownDeadlinePassed(machine *api.Machine) bool {
+	return !machine.Spec.ShutdownAt.IsZero() && !time.Now().Before(machine.Spec.ShutdownAt)
+}
+
+// shutdownAtDeadline asks the guest to shut down once the shutdown deadline of the machine passed and
+// powers the VM off if it is still running after the grace period.
+func (r *MachineReconciler) shutdownAtDeadline(ctx context.Context, log logr.Logger, machine *api.Machine) error {
+	return r.shutdownGracefully(ctx, log, machine, machine.Spec.ShutdownAt, "ShutdownDeadlineReached",
+		fmt.Sprintf("Shutdown deadline %s passed, stopping VM", machine.Spec.ShutdownAt.Format(time.RFC3339)))
+}
+
+// shutdownGracefully presses the power button of the VM and powers it off if it is still running the grace
+// period after since. The machine is Terminating until the VM stopped.
+func (r *MachineReconciler) shutdownGracefully(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	since time.Time,
+	reason, message string,
+) error {
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	if time.Since(since) >= r.shutdownGracePeriod {
+		log.V(1).Info("Guest did not shut down within the grace period, powering off VM")
+		if err := r.powerOff(ctx, machine); err != nil {
+			return err
+		}
+		r.queue.Add(machine.ID)
+		return nil
+	}
+
+	if machine.Status.State != api.MachineStateTerminating {
+		log.V(1).Info("Pressing power button", "reason", reason)
+		if err := r.vmm.PowerButton(ctx, apiSocket); err != nil {
+			return fmt.Errorf("failed to press power button: %w", err)
+		}
+
+		machine.Status.State = api.MachineStateTerminating
+		if _, err := r.machines.Update(ctx, machine); err != nil {
+			return fmt.Errorf("failed to update machine status: %w", err)
+		}
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, reason, "%s", message)
+	}
+
+	r.queue.AddAfter(machine.ID, shutdownPollInterval)
+	return nil
+}
+
+// reconcileMachineDirAccess grants the cloud-hypervisor instance on the api socket access to the machine
+// directory.
+func (r *MachineReconciler) reconcileMachineDirAccess(log logr.Logger, machineID, apiSocket string) error {
+	if r.chownMachineDirs {
+		uid, gid, err := vmm.SocketOwner(apiSocket)
+		if err != nil {
+			return fmt.Errorf("failed to get api socket owner: %w", err)
+		}
+
+		log.V(2).Info("Changing owner of machine directory", "uid", uid, "gid", gid)
+		if err := host.ChownMachineDir(r.paths, machineID, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	if r.selinuxFileContext != "" {
+		log.V(2).Info("Labeling machine directory", "context", r.selinuxFileContext)
+		if err := host.LabelMachineDir(r.paths, machineID, r.selinuxFileContext); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *MachineReconciler) applyCgroup(ctx context.Context, log logr.Logger, machine *api.Machine) error {
+	if r.cgroups == nil {
+		return nil
+	}
+
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	pid, err := r.vmm.Pid(ctx, apiSocket)
+	if err != nil {
+		return err
+	}
+
+	limits := r.cgroupLimits(machine, apiSocket)
+	log.V(2).Info("Applying cgroup", "pid", pid, "numaNode", limits.NUMANode)
+	return r.cgroups.Apply(machine.ID, pid, limits)
+}
+
+// cgroupLimits returns the limits of the machine on the instance of the api socket.
+func (r *MachineReconciler) cgroupLimits(machine *api.Machine, apiSocket string) cgroup.Limits {
+	limits := cgroup.Limits{
+		Cpus:        machine.Spec.Cpu,
+		MemoryBytes: machine.Spec.MemoryBytes,
+	}
+	if node, ok := r.vmm.NUMANode(apiSocket); ok {
+		limits.NUMANode = &node
+	}
+	return limits
+}
+
+func (r *MachineReconciler) releaseCgroup(ctx context.Context, log logr.Logger, machine *api.Machine) error {
+	if r.cgroups == nil {
+		return nil
+	}
+
+	// The instance may already be gone, the cgroup is removed nonetheless.
+	pid, err := r.vmm.Pid(ctx, ptr.Deref(machine.Spec.ApiSocketPath, ""))
+	if err != nil {
+		log.V(1).Info("Failed to get vmm pid, not moving it to the pool", "error", err.Error())
+		pid = 0
+	}
+
+	log.V(2).Info("Releasing cgroup", "pid", pid)
+	if err := r.cgroups.Release(machine.ID, pid); err != nil {
+		return fmt.Errorf("failed to release cgroup: %w", err)
+	}
 	return nil
 }
 
@@ -512,6 +1331,9 @@ func (r *MachineReconciler) attachDetachNICs(
 func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) error {
 	log := logr.FromContextOrDiscard(ctx)
 
+	r.machineMu.Lock(id)
+	defer r.machineMu.Unlock(id)
+
 	log.V(1).Info("Reconciling machine", "id", id)
 	log.V(2).Info("Getting machine from store", "id", id)
 	machine, err := r.machines.Get(ctx, id)
@@ -531,6 +1353,10 @@ func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) err
 		return nil
 	}
 
+	// Changes outside the provider, e.g. a crashed storage daemon, emit no events, so that machines are
+	// reconciled periodically in addition.
+	defer r.resync(id)
+
 	if !slices.Contains(machine.Finalizers, MachineFinalizer) {
 		machine.Finalizers = append(machine.Finalizers, MachineFinalizer)
 		if _, err := r.machines.Update(ctx, machine); err != nil {
@@ -539,33 +1365,70 @@ func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) err
 		return nil
 	}
 
+	machine, err = r.reconcileClass(ctx, log, machine)
+	if err != nil {
+		return err
+	}
+
+	if migratedAway(machine) {
+		log.V(1).Info("VM was migrated to another host")
+		if machine.Status.State != api.MachineStateTerminated {
+			machine.Status.State = api.MachineStateTerminated
+			if _, err := r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update machine status: %w", err)
+			}
+		}
+		return nil
+	}
+
 	log.V(2).Info("Making machine directories")
 	if err := host.MakeMachineDirs(r.paths, machine.ID); err != nil {
 		return fmt.Errorf("error making machine directories: %w", err)
 	}
 	log.V(2).Info("Successfully made machine directories")
 
+	machine, err = r.reconcileClone(ctx, log, machine)
+	if err != nil {
+		return err
+	}
+
 	if bootImage := api.HasBootImage(machine); bootImage != nil {
 		log.V(1).Info("Boot image referenced", "image", bootImage)
 
-		_, err := r.imageCache.Get(ctx, *bootImage)
+		err := timeStage(stageImage, func() error {
+			_, err := r.imageCache.Get(ctx, *bootImage)
+			return err
+		})
 		if err != nil {
 			if errors.Is(err, ociutils.ErrImagePulling) {
 				log.V(1).Info("Image is pulling, reconcile later")
 				r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "PullingImage", "Pulling image in progress")
 				return nil
 			}
+			if errors.Is(err, oci.ErrNoMatchingPlatform) {
+				log.V(1).Info("Image has no compatible platform", "image", bootImage)
+				r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "NoCompatiblePlatform", "%s", err.Error())
+				return nil
+			}
+			if errors.Is(err, oci.ErrImagePullFailed) {
+				return r.reportImagePullFailure(ctx, log, machine, err)
+			}
 			return err
 		}
 		log.V(2).Info("Image is present")
+		machine.Status.RemoveCondition(api.MachineConditionImagePullBackOff)
 	}
 
 	if machine.Spec.ApiSocketPath == nil {
-		sock, err := r.vmm.GetFreeApiSocket()
+		sock, err := r.vmm.GetFreeApiSocket(machine.Spec.VMMRequirements)
 		if err != nil {
+			if errors.Is(err, vmm.ErrNoFreeSocket) {
+				return r.reportSocketUnavailable(ctx, log, machine, err)
+			}
 			return fmt.Errorf("failed to get free api socket: %w", err)
 		}
 		machine.Spec.ApiSocketPath = sock
+		machine.Status.RemoveCondition(api.MachineConditionSocketUnavailable)
 		machine, err = r.machines.Update(ctx, machine)
 		if err != nil {
 			return fmt.Errorf("failed to update machine status: %w", err)
@@ -574,15 +1437,29 @@ func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) err
 
 	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
 
+	// The instance does not answer while it receives the VM, the receiver enqueues the machine once it is done.
+	if _, receiving := r.receivers.Load(machine.ID); receiving {
+		log.V(1).Info("Receiving migrated VM")
+		return nil
+	}
+
 	if err := r.vmm.Ping(ctx, apiSocket); err != nil {
+		if errors.Is(err, vmm.ErrMigrating) {
+			log.V(1).Info("Instance is migrating the VM")
+			return nil
+		}
 		return fmt.Errorf("failed to ping vmm: %w", err)
 	}
 
-	if err := r.reconcileVolumes(ctx, log, machine); err != nil {
+	if err := timeStage(stageVolumes, func() error {
+		return r.reconcileVolumes(ctx, log, machine)
+	}); err != nil {
 		return fmt.Errorf("failed to reconcile volumes: %w", err)
 	}
 
-	if err := r.reconcileNics(ctx, log, machine); err != nil {
+	if err := timeStage(stageNICs, func() error {
+		return r.reconcileNics(ctx, log, machine)
+	}); err != nil {
 		return fmt.Errorf("failed to reconcile nics: %w", err)
 	}
 
@@ -594,7 +1471,91 @@ func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) err
 
 		log.V(1).Info("VM not created", "machine", machine.ID)
 
-		if err := r.vmm.CreateVM(ctx, machine); err != nil {
+		if completed, err := r.completeInterruptedUpgrade(ctx, log, machine); err != nil || completed {
+			return err
+		}
+
+		if machine.Status.BootedAt != nil {
+			recordStop(machine)
+			if machine, err = r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update machine status: %w", err)
+			}
+		}
+
+		if err := r.reconcileConfigDrive(ctx, log, machine); err != nil {
+			return fmt.Errorf("failed to reconcile config drive: %w", err)
+		}
+
+		if err := r.reconcileMachineDirAccess(log, machine.ID, ptr.Deref(machine.Spec.ApiSocketPath, "")); err != nil {
+			return fmt.Errorf("failed to reconcile machine directory access: %w", err)
+		}
+
+		if _, ok := machine.Annotations[api.RecreateRequestedAnnotation]; ok {
+			log.V(1).Info("VM does not exist, dropping recreation request and snapshot")
+			if err := os.RemoveAll(r.paths.MachineSnapshotDir(machine.ID)); err != nil {
+				return fmt.Errorf("failed to remove snapshot: %w", err)
+			}
+			delete(machine.Annotations, api.RecreateRequestedAnnotation)
+			if machine, err = r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update machine: %w", err)
+			}
+		}
+
+		if imported(machine) {
+			log.V(1).Info("VM of imported machine is gone, not creating VM")
+			return r.reconcileImportedVMGone(ctx, machine)
+		}
+
+		if exporting, err := r.reconcileExport(ctx, log, machine, nil); err != nil || exporting {
+			return err
+		}
+
+		if shutdownDeadlinePassed(machine) {
+			log.V(1).Info("Shutdown deadline passed, not creating VM", "shutdownAt", machine.Spec.ShutdownAt)
+			machine.Status.State = api.MachineStateTerminated
+			if _, err := r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update machine status: %w", err)
+			}
+			return nil
+		}
+
+		if r.maintenance.Enabled() {
+			log.V(1).Info("Host is in maintenance, not creating VM")
+			return nil
+		}
+
+		if receiving, err := r.receiveMigration(ctx, log, machine); err != nil || receiving {
+			return err
+		}
+
+		if restored, err := r.restoreSnapshot(ctx, log, machine); err != nil || restored {
+			return err
+		}
+
+		if reprovisioned, err := r.reconcileImageUpdate(ctx, log, machine, nil); err != nil || reprovisioned {
+			return err
+		}
+
+		// The VM is created with the prepared network interfaces, the others are plugged once they are prepared. It
+		// is only held back while none is prepared, so that the guest does not boot without network.
+		if pending := pendingNICs(machine); len(pending) > 0 && len(pending) == len(machine.Status.NetworkInterfaceStatus) {
+			// Plugins that watch their network interfaces requeue the machine once they are prepared.
+			if _, ok := r.networkInterfacePlugin.(networkinterface.Watcher); ok {
+				log.V(1).Info("Waiting for network interfaces to be prepared", "nics", pending)
+				return nil
+			}
+			return fmt.Errorf("network interfaces %v are not prepared", pending)
+		}
+
+		if recordDiskOrder(machine) {
+			if machine, err = r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update disk order: %w", err)
+			}
+		}
+
+		if err := timeStage(stageVMCreate, func() error {
+			return r.vmm.CreateVM(ctx, machine)
+		}); err != nil {
 			log.V(1).Info("Failed to create VM", "machine", machine.ID)
 			return fmt.Errorf("failed to create VM: %w", err)
 		}
@@ -604,38 +1565,127 @@ func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) err
 		return nil
 	}
 
-	if platform := ptr.Deref(vm.Config.Platform, client.PlatformConfig{}); ptr.Deref(platform.Uuid, "") != machine.ID {
-		return fmt.Errorf("machine and vm IDs do not match")
+	if _, ok := machine.Annotations[api.RecreateRequestedAnnotation]; ok {
+		return r.recreateVM(ctx, log, machine, vm)
+	}
+
+	if platform := ptr.Deref(vm.Config.Platform, client.PlatformConfig{}); ptr.Deref(platform.Uuid, "") != vmUUIDOf(machine) {
+		return r.recoverForeignVM(ctx, log, machine, ptr.Deref(platform.Uuid, ""))
+	}
+
+	if err := r.applyCgroup(ctx, log, machine); err != nil {
+		return fmt.Errorf("failed to apply cgroup: %w", err)
+	}
+
+	if exporting, err := r.reconcileExport(ctx, log, machine, vm); err != nil || exporting {
+		return err
+	}
+
+	if _, ok := machine.Annotations[api.MigrationDestinationAnnotation]; ok &&
+		migration.StatusOf(machine).State != api.MigrationStateFailed {
+		return r.sendMigration(ctx, log, machine, vm)
+	}
+
+	if _, ok := machine.Annotations[api.VMMUpgradeAnnotation]; ok {
+		return r.upgradeVMM(ctx, log, machine, vm)
+	}
+
+	if state := r.maintenance.State(); state.Enabled && state.Evacuation != maintenance.EvacuationNone {
+		return r.evacuate(ctx, log, machine, vm, state)
 	}
 
-	switch machine.Spec.Power {
-	case api.PowerStatePowerOn:
-		if vm.State != client.Running {
-			if err := r.vmm.PowerOn(ctx, apiSocket); err != nil {
-				return fmt.Errorf("failed to power on VM: %w", err)
+	if reprovisioned, err := r.reconcileImageUpdate(ctx, log, machine, vm); err != nil || reprovisioned {
+		return err
+	}
+
+	vmState := vm.State
+	deadlinePassed := shutdownDeadlinePassed(machine)
+	switch {
+	case deadlinePassed:
+		switch vm.State {
+		case client.Running:
+			return r.shutdownAtDeadline(ctx, log, machine)
+		case client.Paused:
+			// A paused guest cannot handle the power button, the VM is powered off right away.
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "ShutdownDeadlineReached",
+				"Shutdown deadline %s passed, powering off paused VM", machine.Spec.ShutdownAt.Format(time.RFC3339))
+			if err := timeStage(stagePower, func() error {
+				return r.powerOff(ctx, machine)
+			}); err != nil {
+				return err
+			}
+		default:
+			if machine.Status.State == api.MachineStateTerminating {
+				r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Stopped", "Guest shut down")
+			}
+		}
+	case machine.Spec.Power == api.PowerStatePowerOn:
+		switch vm.State {
+		case client.Running:
+		case client.Paused, vmm.BreakPoint:
+			r.recordSuspension(machine, vm.State)
+		default:
+			if machine.Status.State == api.MachineStateRunning {
+				r.recordUnexpectedStop(log, machine)
+			}
+			if err := timeStage(stagePower, func() (err error) {
+				vmState, err = r.powerOn(ctx, log, machine)
+				return err
+			}); err != nil {
+				return err
 			}
 		}
-	case api.PowerStatePowerOff:
-		if vm.State == client.Running {
-			if err := r.vmm.PowerOff(ctx, apiSocket); err != nil {
-				return fmt.Errorf("failed to power off VM: %w", err)
+	case machine.Spec.Power == api.PowerStatePowerOff:
+		// A VM stopped at a breakpoint cannot be shut down until the debugger continues it.
+		if vm.State == client.Running || vm.State == client.Paused {
+			if err := timeStage(stagePower, func() error {
+				return r.powerOff(ctx, machine)
+			}); err != nil {
+				return err
 			}
 		}
 	}
 
-	if err := r.attachDetachDisks(ctx, log, machine, vm.Config); err != nil {
-		return fmt.Errorf("failed to attach detach disks: %w", err)
+	if err := r.reconcileMachineDirAccess(log, machine.ID, ptr.Deref(machine.Spec.ApiSocketPath, "")); err != nil {
+		return fmt.Errorf("failed to reconcile machine directory access: %w", err)
+	}
+
+	if machine, err = r.reconcileReboot(ctx, log, machine, vm); err != nil {
+		return fmt.Errorf("failed to reboot VM: %w", err)
 	}
 
-	if err := r.attachDetachNICs(ctx, log, machine, vm.Config); err != nil {
-		return fmt.Errorf("failed to attach detach disks: %w", err)
+	if err := timeStage(stageAttach, func() error {
+		if err := r.attachDetachDisks(ctx, log, machine, vm.Config); err != nil {
+			return fmt.Errorf("failed to attach detach disks: %w", err)
+		}
+		if err := r.attachDetachNICs(ctx, log, machine, vm.Config); err != nil {
+			return fmt.Errorf("failed to attach detach NICs: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	switch machine.Spec.Power {
-	case api.PowerStatePowerOn:
-		machine.Status.State = api.MachineStateRunning
-	case api.PowerStatePowerOff:
+	if err := r.reconcileDrift(ctx, log, machine, vm); err != nil {
+		return fmt.Errorf("failed to reconcile drift: %w", err)
+	}
+
+	switch {
+	case deadlinePassed:
+		machine.Status.State = api.MachineStateTerminated
+		recordStop(machine)
+	case machine.Spec.Power == api.PowerStatePowerOn:
+		machine.Status.State = machineStateOf(vmState)
+		// VMs that were running already, e.g. after a provider restart, keep their boot time.
+		switch {
+		case machine.Status.BootedAt != nil:
+			machineBootTime.WithLabelValues(machine.ID).Set(float64(machine.Status.BootedAt.Unix()))
+		case vm.State == client.Running:
+			recordBoot(machine, time.Now())
+		}
+	case machine.Spec.Power == api.PowerStatePowerOff:
 		machine.Status.State = api.MachineStateTerminated
+		recordStop(machine)
 	}
 
 	machine, err = r.machines.Update(ctx, machine)
@@ -643,6 +1693,10 @@ func (r *MachineReconciler) reconcileMachine(ctx context.Context, id string) err
 		return fmt.Errorf("failed to update machine status: %w", err)
 	}
 
+	if !deadlinePassed && !machine.Spec.ShutdownAt.IsZero() {
+		r.queue.AddAfter(machine.ID, time.Until(machine.Spec.ShutdownAt))
+	}
+
 	log.V(1).Info("Reconciled machine successfully ", "machine", machine.ID)
 	return nil
 }
@@ -92,6 +92,26 @@ var _ = Describe("MachineController", func() {
 				return resp.JSON200.State
 			}).Should(Equal(client.Running))
 
+			By("verifying the power events were recorded")
+			Eventually(func() []string {
+				var reasons []string
+				for _, evt := range eventRecorder.ListEvents() {
+					if evt.InvolvedObjectMeta.ID == machineID {
+						reasons = append(reasons, evt.Reason)
+					}
+				}
+				return reasons
+			}).Should(ContainElements("Starting", "Started", "Booted"))
+
+			By("verifying the boot time was recorded")
+			Eventually(func(g Gomega) *time.Time {
+				machine, err := machineStore.Get(ctx, machineID)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(machine.Status.BootDuration).To(BeNumerically(">", 0))
+
+				return machine.Status.BootedAt
+			}).ShouldNot(BeNil())
+
 			Expect(machineStore.Delete(ctx, machineID)).Should(Succeed())
 
 			By("waiting for the api socket path to be set")
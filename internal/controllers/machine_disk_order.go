@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"slices"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+)
+
+// recordDiskOrder completes the disk order of the machine before its VM is created: volumes no longer in the spec
+// are dropped, and the volumes plugged into the VM for the first time are appended. It reports whether the order
+// changed.
+func recordDiskOrder(machine *api.Machine) bool {
+	order := slices.DeleteFunc(slices.Clone(machine.Status.DiskOrder), func(name string) bool {
+		return !slices.ContainsFunc(machine.Spec.Volumes, func(vol *api.VolumeSpec) bool {
+			return vol.Name == name && vol.DeletedAt == nil
+		})
+	})
+
+	var appended []string
+	for _, vol := range machine.Status.VolumeStatus {
+		if vol.State == api.VolumeStatePrepared && !slices.Contains(order, vol.Name) {
+			appended = append(appended, vol.Name)
+		}
+	}
+	order = append(order, appended...)
+
+	if slices.Equal(order, machine.Status.DiskOrder) {
+		return false
+	}
+	machine.Status.DiskOrder = order
+	return true
+}
+
+// appendDiskOrder records a volume hot-plugged into the VM at the end of the disk order.
+func appendDiskOrder(machine *api.Machine, name string) {
+	if !slices.Contains(machine.Status.DiskOrder, name) {
+		machine.Status.DiskOrder = append(machine.Status.DiskOrder, name)
+	}
+}
+
+// removeDiskOrder drops a deleted volume from the disk order.
+func removeDiskOrder(machine *api.Machine, name string) {
+	machine.Status.DiskOrder = slices.DeleteFunc(machine.Status.DiskOrder, func(n string) bool {
+		return n == name
+	})
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// DefaultDrainTimeout is the time the reconciliations in flight get to finish on shutdown.
+const DefaultDrainTimeout = 30 * time.Second
+
+// drain stops the workers from starting reconciliations once ctx is done. The reconciliations in flight run on
+// the returned context, which is canceled once they finished or the drain timeout passed.
+func (r *MachineReconciler) drain(ctx context.Context, log logr.Logger) (context.Context, func()) {
+	reconcileCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	drained := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-drained:
+			return
+		}
+
+		log.Info("Draining reconciliations", "timeout", r.drainTimeout)
+		r.draining.Store(true)
+		r.queue.ShutDown()
+
+		select {
+		case <-drained:
+		case <-time.After(r.drainTimeout):
+			log.Info("Drain timeout passed, canceling reconciliations")
+			cancel()
+		}
+	}()
+
+	return reconcileCtx, func() {
+		close(drained)
+		cancel()
+	}
+}
+
+// deferWhileDraining records the machine to be reconciled on the next start if the reconciler is draining.
+func (r *MachineReconciler) deferWhileDraining(id string) bool {
+	if !r.draining.Load() {
+		return false
+	}
+
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	r.pending.Insert(id)
+	return true
+}
+
+// persistQueue stores the machines that were queued on shutdown. They are reconciled first on the next start.
+func (r *MachineReconciler) persistQueue(log logr.Logger) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	if r.pending.Len() == 0 {
+		return
+	}
+
+	data, err := json.Marshal(sets.List(r.pending))
+	if err != nil {
+		log.Error(err, "Failed to marshal reconcile queue")
+		return
+	}
+	if err := os.WriteFile(r.paths.ReconcileQueueFile(), data, 0600); err != nil {
+		log.Error(err, "Failed to persist reconcile queue")
+		return
+	}
+	log.Info("Persisted reconcile queue", "machines", r.pending.Len())
+}
+
+// restoreQueue queues the machines persisted on the last shutdown.
+func (r *MachineReconciler) restoreQueue(log logr.Logger) {
+	data, err := os.ReadFile(r.paths.ReconcileQueueFile())
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Error(err, "Failed to read persisted reconcile queue")
+		}
+		return
+	}
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		log.Error(err, "Failed to unmarshal persisted reconcile queue")
+	} else {
+		for _, id := range ids {
+			r.queue.Add(id)
+		}
+		log.V(1).Info("Restored reconcile queue", "machines", len(ids))
+	}
+
+	if err := os.Remove(r.paths.ReconcileQueueFile()); err != nil {
+		log.Error(err, "Failed to remove persisted reconcile queue")
+	}
+}
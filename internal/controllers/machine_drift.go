@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// reconcileDrift compares the VM with the config computed from the machine spec. Vcpus and memory are resized
+// in place if the VM allows it, other differences are reported by the RequiresRestart condition until the VM
+// is created again.
+func (r *MachineReconciler) reconcileDrift(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+) error {
+	desired, err := r.vmm.Config(machine)
+	if err != nil {
+		return fmt.Errorf("failed to build vm config: %w", err)
+	}
+
+	drift := vmm.ConfigDrift(&vm.Config, desired)
+	if (drift.Vcpus != nil || drift.MemoryBytes != nil) && vm.State == client.Running {
+		log.V(1).Info("Resizing VM", "vcpus", drift.Vcpus, "memoryBytes", drift.MemoryBytes)
+		if err := r.vmm.Resize(ctx, ptr.Deref(machine.Spec.ApiSocketPath, ""), drift.Vcpus, drift.MemoryBytes); err != nil {
+			return fmt.Errorf("failed to resize VM: %w", err)
+		}
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Resized", "Resized VM to match the spec")
+	}
+
+	if len(drift.Fields) == 0 {
+		machine.Status.RemoveCondition(api.MachineConditionRequiresRestart)
+		return nil
+	}
+
+	message := fmt.Sprintf("VM differs from the spec in %s", strings.Join(drift.Fields, ", "))
+	if !machine.Status.HasCondition(api.MachineConditionRequiresRestart) {
+		log.V(1).Info("VM requires restart", "fields", drift.Fields)
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "RequiresRestart", "%s", message)
+	}
+	machine.Status.SetCondition(api.MachineCondition{
+		Type:               api.MachineConditionRequiresRestart,
+		Reason:             "ConfigDrift",
+		Message:            message,
+		LastTransitionTime: time.Now(),
+	})
+	return nil
+}
@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	"github.com/opencontainers/go-digest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+// RequestExport exports the root disk of the machine as image to ref. A running VM is paused while its disk is
+// staged, not while it is pushed.
+func (r *MachineReconciler) RequestExport(ctx context.Context, machineID string, ref string) error {
+	if r.imagePusher == nil {
+		return fmt.Errorf("image export is disabled")
+	}
+	named, err := reference.ParseDockerRef(ref)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return err
+	}
+	if machine.DeletedAt != nil {
+		return fmt.Errorf("machine %s is being deleted", machineID)
+	}
+	if rootDisk(machine) == nil {
+		return fmt.Errorf("machine %s has no disk provisioned from an image", machineID)
+	}
+	if api.ExportState(machine.Annotations[api.ExportStateAnnotation]) == api.ExportStatePending {
+		return fmt.Errorf("root disk is already exported to %s", machine.Annotations[api.ExportRefAnnotation])
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[api.ExportRefAnnotation] = named.String()
+	machine.Annotations[api.ExportStateAnnotation] = string(api.ExportStatePending)
+	delete(machine.Annotations, api.ExportMessageAnnotation)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	return r.Requeue(ctx, machineID)
+}
+
+// rootDisk returns the first disk of the machine provisioned from an image, nil if it has none.
+func rootDisk(machine *api.Machine) *api.VolumeSpec {
+	for _, vol := range machine.Spec.Volumes {
+		if vol.LocalDisk != nil && vol.LocalDisk.Image != nil && vol.DeletedAt == nil {
+			return vol
+		}
+	}
+	return nil
+}
+
+// reconcileExport starts the requested export of the root disk of the machine, pausing its VM if it runs. It
+// returns true while the export is in progress, the machine is left as is meanwhile.
+func (r *MachineReconciler) reconcileExport(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+) (bool, error) {
+	if api.ExportState(machine.Annotations[api.ExportStateAnnotation]) != api.ExportStatePending {
+		return false, nil
+	}
+	if _, exporting := r.exports.Load(machine.ID); exporting {
+		return true, nil
+	}
+
+	ref := machine.Annotations[api.ExportRefAnnotation]
+	log = log.WithValues("ref", ref)
+
+	disk := rootDisk(machine)
+	if disk == nil {
+		return false, r.failExport(ctx, machine, "machine has no root disk")
+	}
+
+	// A VM paused already was paused for an export interrupted by a restart of the provider.
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	resume := vm != nil && (vm.State == client.Running || vm.State == client.Paused)
+	if vm != nil && vm.State == client.Running {
+		log.V(1).Info("Pausing VM to export its root disk")
+		if err := r.vmm.Pause(ctx, apiSocket); err != nil {
+			return true, fmt.Errorf("failed to pause vm: %w", err)
+		}
+	}
+
+	exportCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	r.exports.Store(machine.ID, cancel)
+	go r.export(exportCtx, log, machine.ID, disk, ref, apiSocket, resume)
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "ExportStarted", "Exporting root disk to %s", ref)
+	return true, nil
+}
+
+func (r *MachineReconciler) export(
+	ctx context.Context,
+	log logr.Logger,
+	machineID string,
+	disk *api.VolumeSpec,
+	ref string,
+	apiSocket string,
+	resume bool,
+) {
+	defer func() {
+		r.exports.Delete(machineID)
+		r.queue.Add(machineID)
+	}()
+
+	log.V(1).Info("Exporting root disk", "volume", disk.Name)
+	// The VM is resumed as soon as the disk is staged, the push does not read the disk anymore.
+	rootFS, base, err := r.stageDisk(ctx, machineID, disk)
+
+	if resume {
+		log.V(1).Info("Resuming VM after staging its root disk")
+		if err := r.vmm.Resume(context.WithoutCancel(ctx), apiSocket); err != nil {
+			log.Error(err, "Failed to resume VM after staging its root disk")
+		}
+	}
+
+	var manifestDigest digest.Digest
+	if err == nil {
+		manifestDigest, err = r.imagePusher.PushDisk(ctx, ref, rootFS, base)
+	}
+
+	state, message := api.ExportStateCompleted, manifestDigest.String()
+	if err != nil {
+		log.Error(err, "Failed to export root disk")
+		state, message = api.ExportStateFailed, err.Error()
+	} else {
+		log.V(1).Info("Exported root disk", "digest", manifestDigest)
+	}
+
+	if err := r.updateExportState(context.WithoutCancel(ctx), machineID, state, message); err != nil &&
+		!errors.Is(err, store.ErrNotFound) {
+		log.Error(err, "Failed to update export state")
+	}
+}
+
+// stageDisk compresses the disk into the export file of the machine. It returns the staged layer and the image
+// the disk was provisioned from.
+func (r *MachineReconciler) stageDisk(
+	ctx context.Context,
+	machineID string,
+	disk *api.VolumeSpec,
+) (*ociutils.FileLayer, *ociutils.Image, error) {
+	plugin, err := r.VolumePluginManager.FindPluginBySpec(disk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find plugin: %w", err)
+	}
+	exporter, ok := plugin.(volume.ExportPlugin)
+	if !ok {
+		return nil, nil, fmt.Errorf("volume plugin %s does not support exports", plugin.Name())
+	}
+
+	// The kernel and initramfs of images booted directly are part of the image, not of the disk.
+	base, err := r.imageCache.Get(ctx, *disk.LocalDisk.Image)
+	if err != nil {
+		return nil, nil, fmt.Errorf("image %s of the disk is not available: %w", *disk.LocalDisk.Image, err)
+	}
+
+	data, err := exporter.Open(ctx, disk, machineID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open disk: %w", err)
+	}
+	defer func() {
+		_ = data.Close()
+	}()
+
+	rootFS, err := r.imagePusher.StageDisk(data, r.paths.MachineExportFile(machineID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return rootFS, base, nil
+}
+
+func (r *MachineReconciler) failExport(ctx context.Context, machine *api.Machine, message string) error {
+	machine.Annotations[api.ExportStateAnnotation] = string(api.ExportStateFailed)
+	machine.Annotations[api.ExportMessageAnnotation] = message
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ExportFailed",
+		"Failed to export root disk to %s: %s", machine.Annotations[api.ExportRefAnnotation], message)
+	return nil
+}
+
+func (r *MachineReconciler) updateExportState(
+	ctx context.Context,
+	machineID string,
+	state api.ExportState,
+	message string,
+) error {
+	r.machineMu.Lock(machineID)
+	defer r.machineMu.Unlock(machineID)
+
+	var machine *api.Machine
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.Is(err, store.ErrResourceVersionNotLatest)
+	}, func() error {
+		var err error
+		if machine, err = r.machines.Get(ctx, machineID); err != nil {
+			return err
+		}
+		machine.Annotations[api.ExportStateAnnotation] = string(state)
+		machine.Annotations[api.ExportMessageAnnotation] = message
+		machine, err = r.machines.Update(ctx, machine)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	ref := machine.Annotations[api.ExportRefAnnotation]
+	switch state {
+	case api.ExportStateCompleted:
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Exported", "Exported root disk to %s@%s",
+			ref, message)
+	case api.ExportStateFailed:
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ExportFailed",
+			"Failed to export root disk to %s: %s", ref, message)
+	}
+	return nil
+}
+
+// stopExport cancels the export of the root disk of a machine that is deleted.
+func (r *MachineReconciler) stopExport(machineID string) {
+	if cancel, ok := r.exports.LoadAndDelete(machineID); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// bootVolume returns the spec and status of the volume provisioned from the boot image of the machine.
+func bootVolume(machine *api.Machine) (*api.VolumeSpec, *api.VolumeStatus) {
+	for _, volume := range machine.Spec.Volumes {
+		if volume.DeletedAt != nil || volume.LocalDisk == nil || volume.LocalDisk.Image == nil {
+			continue
+		}
+		for i := range machine.Status.VolumeStatus {
+			if status := &machine.Status.VolumeStatus[i]; status.Name == volume.Name {
+				return volume, status
+			}
+		}
+	}
+	return nil, nil
+}
+
+// reconcileImageUpdate provisions the boot volume again if the image of the machine changed. A running VM
+// keeps its disk until it stops, the pending update is reported by the ImageUpdatePending condition. Once the
+// VM is not running, it is deleted together with the disk, so that both are created again. It reports whether
+// the machine was reprovisioned.
+func (r *MachineReconciler) reconcileImageUpdate(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+) (bool, error) {
+	spec, status := bootVolume(machine)
+	if spec == nil {
+		machine.Status.RemoveCondition(api.MachineConditionImageUpdatePending)
+		return false, nil
+	}
+
+	image := ptr.Deref(spec.LocalDisk.Image, "")
+	if status.Image == "" || status.Image == image {
+		machine.Status.ImageRef = image
+		machine.Status.RemoveCondition(api.MachineConditionImageUpdatePending)
+		return false, nil
+	}
+	machine.Status.ImageRef = status.Image
+
+	if vm != nil && vm.State == client.Running {
+		if !machine.Status.HasCondition(api.MachineConditionImageUpdatePending) {
+			log.V(1).Info("Image changed, provisioning on next power cycle", "image", image, "current", status.Image)
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "ImageUpdatePending",
+				"Image changed to %s, the root disk is provisioned from it when the VM is powered off", image)
+		}
+		machine.Status.SetCondition(api.MachineCondition{
+			Type:               api.MachineConditionImageUpdatePending,
+			Reason:             "ImageChanged",
+			Message:            fmt.Sprintf("Running image %s, pending image %s", status.Image, image),
+			LastTransitionTime: time.Now(),
+		})
+		return false, nil
+	}
+
+	log.V(1).Info("Provisioning root disk from new image", "image", image, "previous", status.Image)
+	if vm != nil {
+		if err := r.vmm.Delete(ctx, ptr.Deref(machine.Spec.ApiSocketPath, "")); err != nil {
+			return false, fmt.Errorf("failed to delete VM: %w", err)
+		}
+	}
+
+	plugin, err := r.VolumePluginManager.FindPluginBySpec(spec)
+	if err != nil {
+		return false, fmt.Errorf("failed to find plugin: %w", err)
+	}
+	if err := plugin.Delete(ctx, spec.Name, machine.ID); err != nil {
+		return false, fmt.Errorf("failed to delete volume %s: %w", spec.Name, err)
+	}
+
+	machine.Status.RemoveCondition(api.MachineConditionImageUpdatePending)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return false, fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "ImageUpdated",
+		"Provisioning root disk from image %s, replacing %s", image, status.Image)
+	r.queue.Add(machine.ID)
+	return true, nil
+}
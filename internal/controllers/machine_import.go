@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// Import creates a machine for the VM on the api socket of a cloud-hypervisor instance of the provider that
+// belongs to no machine, e.g. a VM started by hand. The machine gets the vcpus, the memory and the power state of
+// the VM, and the class of the same size unless class is given. The disks and network interfaces of the VM become
+// imported volumes and network interfaces, which the provider only removes. It returns the id of the machine.
+//
+// The error wraps vmm.ErrNotFound for unknown sockets, vmm.ErrVmNotCreated for instances without VM,
+// store.ErrAlreadyExists for VMs belonging to a machine and store.ErrNotFound for unknown classes.
+func (r *MachineReconciler) Import(ctx context.Context, socket string, class string) (string, error) {
+	vm, err := r.vmm.GetVM(ctx, socket)
+	if err != nil {
+		switch {
+		case errors.Is(err, vmm.ErrNotFound):
+			return "", fmt.Errorf("%s is not the api socket of a cloud-hypervisor instance of the provider: %w",
+				socket, err)
+		case errors.Is(err, vmm.ErrVmNotCreated):
+			return "", fmt.Errorf("cloud-hypervisor instance %s has no vm: %w", socket, err)
+		}
+		return "", fmt.Errorf("failed to get vm: %w", err)
+	}
+
+	machines, err := r.machines.List(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list machines: %w", err)
+	}
+	vmUUID := ptr.Deref(ptr.Deref(vm.Config.Platform, client.PlatformConfig{}).Uuid, "")
+	for _, machine := range machines {
+		if ptr.Deref(machine.Spec.ApiSocketPath, "") == socket || (vmUUID != "" && vmUUIDOf(machine) == vmUUID) {
+			return "", fmt.Errorf("%w: vm on %s belongs to machine %s", store.ErrAlreadyExists, socket, machine.ID)
+		}
+	}
+
+	cpus := int64(vm.Config.Cpus.BootVcpus)
+	memory := ptr.Deref(vm.Config.Memory, client.MemoryConfig{})
+	memoryBytes := memory.Size + ptr.Deref(memory.HotpluggedSize, 0)
+	if class, err = r.importClass(class, cpus, memoryBytes); err != nil {
+		return "", err
+	}
+
+	machine := &api.Machine{}
+	// The machine keeps the id of a VM started with one, the id of a VM cannot be changed.
+	machine.ID = vmUUID
+	if _, err := uuid.Parse(vmUUID); err != nil {
+		machine.ID = uuid.NewString()
+	}
+	machine.Annotations = map[string]string{api.ImportedVMUUIDAnnotation: vmUUID}
+	if err := api.SetObjectMetadata(machine, &irimeta.ObjectMetadata{
+		Labels:      map[string]string{api.ImportedLabel: "true"},
+		Annotations: map[string]string{},
+	}); err != nil {
+		return "", err
+	}
+	if class != "" {
+		api.SetClassLabel(machine, class)
+	}
+	api.SetManagerLabel(machine, api.MachineManager)
+
+	machine.Spec.ApiSocketPath = ptr.To(socket)
+	machine.Spec.Cpu = cpus
+	machine.Spec.MemoryBytes = memoryBytes
+	machine.Spec.Power = api.PowerStatePowerOff
+	if vm.State == client.Running || vm.State == client.Paused {
+		machine.Spec.Power = api.PowerStatePowerOn
+	}
+
+	var volumeStatus []api.VolumeStatus
+	machine.Spec.Volumes, volumeStatus = importedDisks(ptr.Deref(vm.Config.Disks, nil))
+	var nicStatus []api.NetworkInterfaceStatus
+	machine.Spec.NetworkInterfaces, nicStatus = importedNICs(ptr.Deref(vm.Config.Net, nil))
+
+	// The status is reset on creation, the machine is not reconciled until it is set.
+	r.machineMu.Lock(machine.ID)
+	defer r.machineMu.Unlock(machine.ID)
+	machine, err = r.machines.Create(ctx, machine)
+	if err != nil {
+		return "", fmt.Errorf("failed to create machine: %w", err)
+	}
+	machine.Status.VolumeStatus = volumeStatus
+	machine.Status.NetworkInterfaceStatus = nicStatus
+	for _, status := range volumeStatus {
+		appendDiskOrder(machine, status.Name)
+	}
+	if machine, err = r.machines.Update(ctx, machine); err != nil {
+		return "", fmt.Errorf("failed to set status of imported machine: %w", err)
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Imported",
+		"Imported VM on %s with %d disks and %d network interfaces", socket,
+		len(machine.Spec.Volumes), len(machine.Spec.NetworkInterfaces))
+	return machine.ID, nil
+}
+
+// importedDisks returns the volumes of the disks of an imported VM, attached as they are.
+func importedDisks(disks []client.DiskConfig) ([]*api.VolumeSpec, []api.VolumeStatus) {
+	var (
+		specs    []*api.VolumeSpec
+		statuses []api.VolumeStatus
+	)
+	for i, disk := range disks {
+		id := ptr.Deref(disk.Id, "")
+		name := id
+		if name == "" {
+			name = fmt.Sprintf("disk%d", i)
+		}
+
+		status := api.VolumeStatus{
+			Name:   name,
+			Handle: id,
+			State:  api.VolumeStateAttached,
+			Type:   api.VolumeFileType,
+			Path:   ptr.Deref(disk.Path, ""),
+		}
+		if ptr.Deref(disk.VhostUser, false) {
+			status.Type = api.VolumeSocketType
+			status.Path = ptr.Deref(disk.VhostSocket, "")
+		}
+		specs = append(specs, &api.VolumeSpec{Name: name, Imported: true})
+		statuses = append(statuses, status)
+	}
+	return specs, statuses
+}
+
+// importedNICs returns the network interfaces of the nets of an imported VM, attached as they are.
+func importedNICs(nets []client.NetConfig) ([]*api.NetworkInterfaceSpec, []api.NetworkInterfaceStatus) {
+	var (
+		specs    []*api.NetworkInterfaceSpec
+		statuses []api.NetworkInterfaceStatus
+	)
+	for i, net := range nets {
+		id := ptr.Deref(net.Id, "")
+		name := id
+		if name == "" {
+			name = fmt.Sprintf("net%d", i)
+		}
+
+		status := api.NetworkInterfaceStatus{
+			Name:       name,
+			Handle:     id,
+			State:      api.NetworkInterfaceStateAttached,
+			MAC:        ptr.Deref(net.Mac, ""),
+			PCISegment: ptr.Deref(net.PciSegment, 0),
+		}
+		if tap := ptr.Deref(net.Tap, ""); tap != "" {
+			status.Type = api.NetworkInterfaceTAPType
+			status.Path = tap
+		}
+		specs = append(specs, &api.NetworkInterfaceSpec{Name: name, Imported: true})
+		statuses = append(statuses, status)
+	}
+	return specs, statuses
+}
+
+// importClass returns the class of an imported VM. A given class has to exist, otherwise the class of the size of
+// the VM is chosen, if any.
+func (r *MachineReconciler) importClass(class string, cpus, memoryBytes int64) (string, error) {
+	if r.machineClasses == nil {
+		if class != "" {
+			return "", fmt.Errorf("machine class %s: %w", class, store.ErrNotFound)
+		}
+		return "", nil
+	}
+	if class != "" {
+		if _, ok := r.machineClasses.Get(class); !ok {
+			return "", fmt.Errorf("machine class %s: %w", class, store.ErrNotFound)
+		}
+		return class, nil
+	}
+	for _, c := range r.machineClasses.List() {
+		if c.Cpu == cpus && c.MemoryBytes == memoryBytes && !c.Deprecated {
+			return c.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// imported reports whether the VM of the machine was imported, it cannot be created again from the machine spec.
+func imported(machine *api.Machine) bool {
+	_, ok := machine.Annotations[api.ImportedVMUUIDAnnotation]
+	return ok
+}
+
+// vmUUIDOf returns the platform uuid of the VM of the machine, the id of the machine unless the VM was imported.
+func vmUUIDOf(machine *api.Machine) string {
+	if vmUUID, ok := machine.Annotations[api.ImportedVMUUIDAnnotation]; ok {
+		return vmUUID
+	}
+	return machine.ID
+}
+
+// reconcileImportedVMGone terminates an imported machine whose VM is gone, e.g. after a reboot of the host. Its
+// VM is not created again, no plugin provides its disks and network interfaces.
+func (r *MachineReconciler) reconcileImportedVMGone(ctx context.Context, machine *api.Machine) error {
+	if machine.Status.State == api.MachineStateTerminated {
+		return nil
+	}
+
+	machine.Status.State = api.MachineStateTerminated
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine status: %w", err)
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "ImportedVMGone",
+		"VM of the imported machine is gone, it is not created again")
+	return nil
+}
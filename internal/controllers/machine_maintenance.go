@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// Undrained returns the ids of the machines that still have a running VM.
+func (r *MachineReconciler) Undrained(ctx context.Context) ([]string, error) {
+	machines, err := r.machines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	var ids []string
+	for _, machine := range machines {
+		switch machine.Status.State {
+		case api.MachineStateRunning, api.MachineStateTerminating:
+			ids = append(ids, machine.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *MachineReconciler) requeueAll(ctx context.Context) {
+	machines, err := r.machines.List(ctx)
+	if err != nil {
+		r.log.Error(err, "failed to list machines")
+		return
+	}
+
+	for _, machine := range machines {
+		r.queue.Add(machine.ID)
+	}
+}
+
+// evacuate stops the VM of the machine for the host maintenance. With snapshot evacuation, the VM is
+// snapshotted and deleted, and falls back to a shutdown if the snapshot fails.
+func (r *MachineReconciler) evacuate(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+	state maintenance.State,
+) error {
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	if vm.State != client.Running {
+		if machine.Spec.Power == api.PowerStatePowerOn && machine.Status.State != api.MachineStateSuspended {
+			machine.Status.State = api.MachineStateSuspended
+			if _, err := r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update machine status: %w", err)
+			}
+		}
+		return nil
+	}
+
+	// A machine is Terminating if its snapshot failed before and it is shut down instead.
+	if state.Evacuation == maintenance.EvacuationSnapshot && machine.Status.State != api.MachineStateTerminating {
+		snapshotDir := r.paths.MachineSnapshotDir(machine.ID)
+		if err := os.RemoveAll(snapshotDir); err != nil {
+			return fmt.Errorf("failed to remove old snapshot: %w", err)
+		}
+
+		log.V(1).Info("Snapshotting VM for maintenance", "dir", snapshotDir)
+		if err := r.vmm.Snapshot(ctx, apiSocket, snapshotDir); err != nil {
+			log.Error(err, "Failed to snapshot VM, shutting it down instead")
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "SnapshotFailed",
+				"Failed to snapshot VM, shutting it down instead: %s", err.Error())
+			_ = os.RemoveAll(snapshotDir)
+		} else {
+			if err := r.vmm.Delete(ctx, apiSocket); err != nil {
+				return fmt.Errorf("failed to delete snapshotted VM: %w", err)
+			}
+
+			machine.Status.State = api.MachineStateSuspended
+			if _, err := r.machines.Update(ctx, machine); err != nil {
+				return fmt.Errorf("failed to update machine status: %w", err)
+			}
+			r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Evacuated", "Snapshotted VM for host maintenance")
+			return nil
+		}
+	}
+
+	return r.shutdownGracefully(ctx, log, machine, state.Since, "Evacuating", "Shutting down VM for host maintenance")
+}
+
+// restoreSnapshot restores the VM of the machine from its snapshot if there is one.
+func (r *MachineReconciler) restoreSnapshot(ctx context.Context, log logr.Logger, machine *api.Machine) (bool, error) {
+	snapshotDir := r.paths.MachineSnapshotDir(machine.ID)
+	if _, err := os.Stat(snapshotDir); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+
+	log.V(1).Info("Restoring VM from snapshot", "dir", snapshotDir)
+	if err := r.vmm.Restore(ctx, ptr.Deref(machine.Spec.ApiSocketPath, ""), snapshotDir); err != nil {
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "RestoreFailed",
+			"Failed to restore VM from snapshot, recreate the machine to boot it without: %s", err.Error())
+		return false, fmt.Errorf("failed to restore VM: %w", err)
+	}
+
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return true, fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Restored", "Restored VM from snapshot")
+	r.queue.Add(machine.ID)
+	return true, nil
+}
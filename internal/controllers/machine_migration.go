@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/migration"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	migrationPollInterval = 2 * time.Second
+
+	// migrationReceiverStartup is the time given to cloud-hypervisor to listen for the migration before the
+	// receiver is advertised to the source.
+	migrationReceiverStartup = time.Second
+)
+
+// RequestMigration migrates the VM of the machine to the provider serving the migration api at destination.
+// An empty destination cancels a failed migration.
+func (r *MachineReconciler) RequestMigration(ctx context.Context, machineID string, destination string) error {
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := machine.Annotations[api.MigrationDestinationAnnotation]; ok {
+		switch migration.StatusOf(machine).State {
+		case api.MigrationStatePending, api.MigrationStateCompleted:
+			return fmt.Errorf("machine is already migrated to %s", machine.Annotations[api.MigrationDestinationAnnotation])
+		}
+	}
+
+	if destination != "" && imported(machine) {
+		return fmt.Errorf("vm of imported machine %s cannot be migrated", machineID)
+	}
+	if destination != "" {
+		if _, err := migration.NewClient(destination, nil); err != nil {
+			return err
+		}
+	}
+
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	migration.SetStatus(machine, migration.Status{})
+	if destination == "" {
+		delete(machine.Annotations, api.MigrationDestinationAnnotation)
+	} else {
+		machine.Annotations[api.MigrationDestinationAnnotation] = destination
+	}
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	return r.Requeue(ctx, machineID)
+}
+
+// migratedAway reports whether the VM of the machine was migrated to another host. The machine stays
+// Terminated until it is deleted.
+func migratedAway(machine *api.Machine) bool {
+	_, ok := machine.Annotations[api.MigrationDestinationAnnotation]
+	return ok && migration.StatusOf(machine).State == api.MigrationStateCompleted
+}
+
+// sendMigration registers the machine on the destination and sends its VM once the destination listens
+// for it.
+func (r *MachineReconciler) sendMigration(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+) error {
+	destination := machine.Annotations[api.MigrationDestinationAnnotation]
+	log = log.WithValues("destination", destination)
+
+	migrationClient, err := migration.NewClient(destination, r.migrationTLSConfig)
+	if err != nil {
+		return r.failMigration(ctx, machine, err.Error())
+	}
+
+	switch migration.StatusOf(machine).State {
+	case "":
+		if vm.State != client.Running {
+			return r.failMigration(ctx, machine, "vm is not running")
+		}
+		for _, volume := range machine.Spec.Volumes {
+			if volume.LocalDisk != nil {
+				return r.failMigration(ctx, machine, fmt.Sprintf("local disk %s cannot be migrated", volume.Name))
+			}
+		}
+
+		log.V(1).Info("Registering machine on the destination")
+		if err := migrationClient.Start(ctx, migration.Request{
+			Machine:    machine,
+			SourceHost: r.migrationAdvertiseAddress,
+		}); err != nil {
+			return r.failMigration(ctx, machine, fmt.Sprintf("failed to register machine on destination: %v", err))
+		}
+
+		migration.SetStatus(machine, migration.Status{State: api.MigrationStatePending})
+		if _, err := r.machines.Update(ctx, machine); err != nil {
+			return fmt.Errorf("failed to update machine: %w", err)
+		}
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "MigrationStarted", "Migrating VM to %s", destination)
+		r.queue.AddAfter(machine.ID, migrationPollInterval)
+		return nil
+
+	case api.MigrationStatePending:
+		status, err := migrationClient.Status(ctx, machine.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get migration status: %w", err)
+		}
+
+		switch status.State {
+		case api.MigrationStateFailed:
+			return r.failMigration(ctx, machine, fmt.Sprintf("destination failed to receive vm: %s", status.Message))
+		case api.MigrationStateReady:
+			return r.handOff(ctx, log, machine, status.ReceiverURL)
+		default:
+			log.V(2).Info("Destination is not ready yet", "state", status.State)
+			r.queue.AddAfter(machine.ID, migrationPollInterval)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// handOff sends the VM to the receiver and releases the cloud-hypervisor instance of the machine.
+func (r *MachineReconciler) handOff(ctx context.Context, log logr.Logger, machine *api.Machine, receiverURL string) error {
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	log.V(1).Info("Sending VM", "receiver", receiverURL)
+	if err := r.vmm.SendMigration(ctx, apiSocket, receiverURL); err != nil {
+		return r.failMigration(ctx, machine, err.Error())
+	}
+	log.V(1).Info("Sent VM")
+
+	if err := r.vmm.Delete(ctx, apiSocket); err != nil && !errors.Is(err, vmm.ErrNotFound) {
+		log.V(1).Info("Failed to delete migrated VM", "error", err.Error())
+	}
+	if err := r.releaseCgroup(ctx, log, machine); err != nil {
+		log.Error(err, "Failed to release cgroup of migrated VM")
+	}
+	r.freeApiSocket(ctx, log, apiSocket)
+
+	machine.Spec.ApiSocketPath = nil
+	machine.Status.State = api.MachineStateTerminated
+	migration.SetStatus(machine, migration.Status{State: api.MigrationStateCompleted})
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Migrated", "Migrated VM to %s",
+		machine.Annotations[api.MigrationDestinationAnnotation])
+	return nil
+}
+
+func (r *MachineReconciler) failMigration(ctx context.Context, machine *api.Machine, message string) error {
+	migration.SetStatus(machine, migration.Status{State: api.MigrationStateFailed, Message: message})
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "MigrationFailed", "Migration failed: %s", message)
+	return nil
+}
+
+// receiveMigration lets the cloud-hypervisor instance of a machine migrated from another host listen for its
+// VM. It returns false once the VM was received or the machine is no migration target.
+func (r *MachineReconciler) receiveMigration(ctx context.Context, log logr.Logger, machine *api.Machine) (bool, error) {
+	if _, ok := machine.Annotations[api.MigrationSourceAnnotation]; !ok {
+		return false, nil
+	}
+
+	_, receiving := r.receivers.Load(machine.ID)
+
+	switch migration.StatusOf(machine).State {
+	case api.MigrationStatePending:
+		if receiving {
+			return true, nil
+		}
+	case api.MigrationStateReady:
+		if receiving {
+			return true, nil
+		}
+		// The provider restarted while receiving, the source has to start over.
+		log.V(1).Info("Receiver of migration is gone, failing migration")
+		return true, r.failMigration(ctx, machine, "receiver stopped before the vm was received")
+	case api.MigrationStateFailed:
+		log.V(1).Info("Migration failed, not creating VM")
+		return true, nil
+	default:
+		return false, nil
+	}
+
+	if r.migrationAdvertiseAddress == "" {
+		return true, r.failMigration(ctx, machine, "destination has no migration advertise address")
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return true, fmt.Errorf("failed to find free port: %w", err)
+	}
+
+	receiveCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	r.receivers.Store(machine.ID, cancel)
+	go r.receive(receiveCtx, log, machine.ID, ptr.Deref(machine.Spec.ApiSocketPath, ""), port)
+	return true, nil
+}
+
+func (r *MachineReconciler) receive(ctx context.Context, log logr.Logger, machineID, apiSocket string, port int) {
+	defer func() {
+		r.receivers.Delete(machineID)
+		r.queue.Add(machineID)
+	}()
+
+	listenURL := fmt.Sprintf("tcp:0.0.0.0:%d", port)
+	receiverURL := "tcp:" + net.JoinHostPort(r.migrationAdvertiseAddress, strconv.Itoa(port))
+	log = log.WithValues("receiver", receiverURL)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.vmm.ReceiveMigration(ctx, apiSocket, listenURL)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(migrationReceiverStartup):
+		log.V(1).Info("Listening for VM")
+		if err = r.updateMigrationStatus(ctx, machineID, migration.Status{
+			State:       api.MigrationStateReady,
+			ReceiverURL: receiverURL,
+		}); err != nil {
+			log.Error(err, "Failed to advertise receiver")
+		}
+		err = errors.Join(err, <-done)
+	}
+
+	status := migration.Status{State: api.MigrationStateCompleted}
+	if err != nil {
+		log.Error(err, "Failed to receive VM")
+		status = migration.Status{State: api.MigrationStateFailed, Message: err.Error()}
+	} else {
+		log.V(1).Info("Received VM")
+	}
+
+	if err := r.updateMigrationStatus(context.WithoutCancel(ctx), machineID, status); err != nil &&
+		!errors.Is(err, store.ErrNotFound) {
+		log.Error(err, "Failed to update migration status")
+	}
+}
+
+func (r *MachineReconciler) updateMigrationStatus(ctx context.Context, machineID string, status migration.Status) error {
+	r.machineMu.Lock(machineID)
+	defer r.machineMu.Unlock(machineID)
+
+	var machine *api.Machine
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.Is(err, store.ErrResourceVersionNotLatest)
+	}, func() error {
+		var err error
+		if machine, err = r.machines.Get(ctx, machineID); err != nil {
+			return err
+		}
+		migration.SetStatus(machine, status)
+		machine, err = r.machines.Update(ctx, machine)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	switch status.State {
+	case api.MigrationStateCompleted:
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "MigrationReceived", "Received VM from %s",
+			machine.Annotations[api.MigrationSourceAnnotation])
+	case api.MigrationStateFailed:
+		r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "MigrationFailed", "Migration failed: %s", status.Message)
+	}
+	return nil
+}
+
+// stopReceiving stops listening for the VM of a machine that is deleted while it is migrated.
+func (r *MachineReconciler) stopReceiving(machineID string) {
+	if cancel, ok := r.receivers.LoadAndDelete(machineID); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
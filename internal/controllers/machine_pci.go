@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// reserveNICPCISegment returns the PCI segment of the network interface, reserving a free one if it has none yet.
+// cloud-hypervisor plugs a device into the lowest free slot of its segment, so an interface alone on its segment
+// always gets the same guest PCI address, no matter the order interfaces are attached in. Without a free segment,
+// the reservation of an interface no longer in the spec is taken over, and as a last resort the interface shares
+// the default segment.
+func (r *MachineReconciler) reserveNICPCISegment(log logr.Logger, machine *api.Machine, nicName string) int16 {
+	if r.nicPCISegments == 0 {
+		return 0
+	}
+	if machine.Status.PCISegments == nil {
+		machine.Status.PCISegments = map[string]int16{}
+	}
+	reservations := machine.Status.PCISegments
+
+	if segment, ok := reservations[nicName]; ok {
+		if segment > 0 && segment <= r.nicPCISegments {
+			return segment
+		}
+		// The number of segments was lowered since the reservation was made.
+		delete(reservations, nicName)
+	}
+
+	owners := map[int16]string{}
+	for name, segment := range reservations {
+		owners[segment] = name
+	}
+	for segment := int16(1); segment <= r.nicPCISegments; segment++ {
+		if _, reserved := owners[segment]; !reserved {
+			reservations[nicName] = segment
+			log.V(1).Info("Reserved PCI segment for NIC", "nic", nicName, "pciSegment", segment)
+			return segment
+		}
+	}
+
+	specNICs := sets.New[string]()
+	for _, nic := range machine.Spec.NetworkInterfaces {
+		specNICs.Insert(nic.Name)
+	}
+	for segment := int16(1); segment <= r.nicPCISegments; segment++ {
+		if owner := owners[segment]; !specNICs.Has(owner) {
+			delete(reservations, owner)
+			reservations[nicName] = segment
+			log.V(1).Info("Took over PCI segment of removed NIC", "nic", nicName, "pciSegment", segment,
+				"previousNIC", owner)
+			return segment
+		}
+	}
+
+	log.Info("No free PCI segment for NIC, using the default segment", "nic", nicName)
+	return 0
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// powerOn boots the VM of the machine and records the Starting, Started and, once cloud-hypervisor reports the
+// VM as running, Booted events. It returns the state of the VM after the boot.
+func (r *MachineReconciler) powerOn(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+) (client.VmInfoState, error) {
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Starting", "Starting VM")
+	if err := r.vmm.PowerOn(ctx, ptr.Deref(machine.Spec.ApiSocketPath, "")); err != nil {
+		return "", fmt.Errorf("failed to power on VM: %w", err)
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Started", "Started VM")
+
+	state, err := r.getMachineState(ctx, machine)
+	if err != nil {
+		return "", fmt.Errorf("failed to get VM state: %w", err)
+	}
+	if state != client.Running {
+		log.V(1).Info("VM is not running after power on", "state", state)
+		return state, nil
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Booted", "VM is running")
+	recordBoot(machine, time.Now())
+	return state, nil
+}
+
+// powerOff powers the VM of the machine off and records the Stopping and Stopped events.
+func (r *MachineReconciler) powerOff(ctx context.Context, machine *api.Machine) error {
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Stopping", "Stopping VM")
+	if err := r.vmm.PowerOff(ctx, ptr.Deref(machine.Spec.ApiSocketPath, "")); err != nil {
+		return fmt.Errorf("failed to power off VM: %w", err)
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Stopped", "Stopped VM")
+	recordStop(machine)
+	return nil
+}
+
+// recordBoot records that the VM of the machine entered Running at bootedAt. The first boot of the machine
+// also records its boot duration.
+func recordBoot(machine *api.Machine, bootedAt time.Time) {
+	machine.Status.BootedAt = &bootedAt
+	machineBootTime.WithLabelValues(machine.ID).Set(float64(bootedAt.Unix()))
+
+	if machine.Status.BootDuration == 0 {
+		machine.Status.BootDuration = bootedAt.Sub(machine.CreatedAt)
+		machineBootDuration.Observe(machine.Status.BootDuration.Seconds())
+	}
+}
+
+// recordStop records that the VM of the machine does not run.
+func recordStop(machine *api.Machine) {
+	machine.Status.BootedAt = nil
+	machineBootTime.DeleteLabelValues(machine.ID)
+}
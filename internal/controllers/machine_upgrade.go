@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+// upgradeRetryInterval is how often an upgrade waiting for a free instance of a newer version is retried.
+const upgradeRetryInterval = 30 * time.Second
+
+// RequestUpgrade moves the VM of the machine to a free cloud-hypervisor instance of a newer version with the
+// method. An empty method cancels an upgrade that waits for an instance.
+func (r *MachineReconciler) RequestUpgrade(ctx context.Context, machineID string, method api.UpgradeMethod) error {
+	switch method {
+	case "", api.UpgradeMethodMigration, api.UpgradeMethodSnapshot:
+	default:
+		return fmt.Errorf("unsupported upgrade method %q", method)
+	}
+
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return err
+	}
+
+	if method != "" && ptr.Deref(machine.Spec.ApiSocketPath, "") == "" {
+		return fmt.Errorf("machine %s has no cloud-hypervisor instance", machineID)
+	}
+
+	if method == "" {
+		delete(machine.Annotations, api.VMMUpgradeAnnotation)
+	} else {
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[api.VMMUpgradeAnnotation] = string(method)
+	}
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	return r.Requeue(ctx, machineID)
+}
+
+// RequestUpgrades requests the upgrade of every machine with a VM whose instance is older than minVersion and
+// returns their ids.
+func (r *MachineReconciler) RequestUpgrades(
+	ctx context.Context,
+	minVersion string,
+	method api.UpgradeMethod,
+) ([]string, error) {
+	v, err := semver.ParseTolerant(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minimum version %q: %w", minVersion, err)
+	}
+
+	machines, err := r.machines.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	var ids []string
+	for _, machine := range machines {
+		apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+		if apiSocket == "" {
+			continue
+		}
+		if _, err := r.vmm.GetVM(ctx, apiSocket); err != nil {
+			continue
+		}
+
+		info, err := r.vmm.VMMInfo(ctx, apiSocket)
+		if err != nil {
+			r.log.V(1).Info("Failed to get version of instance, not upgrading it", "machine", machine.ID,
+				"error", err.Error())
+			continue
+		}
+		if current, err := semver.ParseTolerant(info.Version); err != nil || !current.LT(v) {
+			continue
+		}
+
+		if err := r.RequestUpgrade(ctx, machine.ID, method); err != nil {
+			return ids, fmt.Errorf("failed to request upgrade of machine %s: %w", machine.ID, err)
+		}
+		ids = append(ids, machine.ID)
+	}
+	return ids, nil
+}
+
+// upgradeVMM moves the VM of the machine to a free instance of a newer version. A running VM is moved with the
+// requested method, a VM that is not running is deleted and created again on the new instance.
+func (r *MachineReconciler) upgradeVMM(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	vm *client.VmInfo,
+) error {
+	if completed, err := r.completeInterruptedUpgrade(ctx, log, machine); err != nil || completed {
+		return err
+	}
+
+	method := api.UpgradeMethod(machine.Annotations[api.VMMUpgradeAnnotation])
+	from := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	if vm.State != client.Running && imported(machine) {
+		return r.failUpgrade(ctx, machine, "vm of imported machine is not running and cannot be created again")
+	}
+
+	socket, err := r.vmm.GetUpgradeApiSocket(ctx, from, machine.Spec.VMMRequirements)
+	if errors.Is(err, vmm.ErrNoFreeSocket) {
+		log.V(1).Info("No free instance to upgrade to, retrying", "reason", err.Error())
+		r.queue.AddAfter(machine.ID, upgradeRetryInterval)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get socket to upgrade to: %w", err)
+	}
+	to := *socket
+	log = log.WithValues("to", to, "method", method)
+
+	machine.Annotations[api.VMMUpgradeTargetAnnotation] = to
+	if machine, err = r.machines.Update(ctx, machine); err != nil {
+		r.freeApiSocket(ctx, log, to)
+		return fmt.Errorf("failed to record upgrade target: %w", err)
+	}
+
+	oldPid := r.instancePid(ctx, from)
+	if err := r.reconcileMachineDirAccess(log, machine.ID, to); err != nil {
+		r.abandonUpgrade(ctx, log, machine.ID, from, to)
+		return fmt.Errorf("failed to grant the new instance access to the machine directory: %w", err)
+	}
+
+	if vm.State == client.Running {
+		log.V(1).Info("Moving VM to instance of newer version")
+		if err := r.moveVM(ctx, log, machine, to, method); err != nil {
+			r.abandonUpgrade(ctx, log, machine.ID, from, to)
+			return r.failUpgrade(ctx, machine, err.Error())
+		}
+	} else {
+		log.V(1).Info("Deleting VM that is not running, it is created on the new instance")
+		if err := r.vmm.Delete(ctx, from); err != nil {
+			r.abandonUpgrade(ctx, log, machine.ID, from, to)
+			return fmt.Errorf("failed to delete vm: %w", err)
+		}
+	}
+
+	if err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.Is(err, store.ErrResourceVersionNotLatest)
+	}, func() error {
+		latest, err := r.machines.Get(ctx, machine.ID)
+		if err != nil {
+			return err
+		}
+		latest.Spec.ApiSocketPath = ptr.To(to)
+		delete(latest.Annotations, api.VMMUpgradeAnnotation)
+		delete(latest.Annotations, api.VMMUpgradeTargetAnnotation)
+		machine, err = r.machines.Update(ctx, latest)
+		return err
+	}); err != nil {
+		// The upgrade is completed on the next reconciliation by its recorded target.
+		return fmt.Errorf("failed to update api socket of upgraded machine: %w", err)
+	}
+
+	if oldPid > 0 {
+		if err := r.cgroups.Evict(oldPid); err != nil {
+			log.V(1).Info("Failed to move old instance out of the machine cgroup", "error", err.Error())
+		}
+	}
+	r.freeApiSocket(ctx, log, from)
+
+	version := "unknown"
+	if info, err := r.vmm.VMMInfo(ctx, to); err == nil {
+		version = info.Version
+	}
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Upgraded",
+		"Moved VM to cloud-hypervisor %s on %s", version, to)
+	r.queue.Add(machine.ID)
+	return nil
+}
+
+// completeInterruptedUpgrade moves the machine to the recorded target of an upgrade that was interrupted after
+// its VM reached the target, e.g. by a restart of the provider. It returns false if the target has no VM of the
+// machine, the upgrade is then started over if it is still requested.
+func (r *MachineReconciler) completeInterruptedUpgrade(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+) (bool, error) {
+	to, ok := machine.Annotations[api.VMMUpgradeTargetAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	vm, err := r.vmm.GetVM(ctx, to)
+	if err != nil || ptr.Deref(ptr.Deref(vm.Config.Platform, client.PlatformConfig{}).Uuid, "") != vmUUIDOf(machine) {
+		delete(machine.Annotations, api.VMMUpgradeTargetAnnotation)
+		if _, err := r.machines.Update(ctx, machine); err != nil {
+			return false, fmt.Errorf("failed to drop upgrade target: %w", err)
+		}
+		r.queue.Add(machine.ID)
+		return true, nil
+	}
+
+	from := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	log.Info("Completing interrupted upgrade", "from", from, "to", to)
+	if err := r.vmm.Delete(ctx, from); err != nil && !errors.Is(err, vmm.ErrNotFound) {
+		return false, fmt.Errorf("failed to delete vm left on the old instance: %w", err)
+	}
+
+	machine.Spec.ApiSocketPath = ptr.To(to)
+	delete(machine.Annotations, api.VMMUpgradeAnnotation)
+	delete(machine.Annotations, api.VMMUpgradeTargetAnnotation)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return false, fmt.Errorf("failed to update api socket of upgraded machine: %w", err)
+	}
+	r.freeApiSocket(ctx, log, from)
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Upgraded", "Completed upgrade to %s", to)
+	r.queue.Add(machine.ID)
+	return true, nil
+}
+
+// moveVM moves the running VM to the instance to. While it is moved, the machine cgroup holds both instances,
+// with room for the memory of both VMs. The limits are reset on the next reconciliation.
+func (r *MachineReconciler) moveVM(
+	ctx context.Context,
+	log logr.Logger,
+	machine *api.Machine,
+	to string,
+	method api.UpgradeMethod,
+) error {
+	newPid := 0
+	if r.cgroups != nil {
+		pid, err := r.vmm.Pid(ctx, to)
+		if err != nil {
+			return fmt.Errorf("failed to get pid of new instance: %w", err)
+		}
+		limits := r.cgroupLimits(machine, to)
+		limits.MemoryBytes *= 2
+		if err := r.cgroups.Apply(machine.ID, pid, limits); err != nil {
+			return fmt.Errorf("failed to move new instance into the machine cgroup: %w", err)
+		}
+		newPid = pid
+	}
+
+	if err := r.vmm.Upgrade(ctx, machine, to, method); err != nil {
+		if newPid > 0 {
+			if evictErr := r.cgroups.Evict(newPid); evictErr != nil {
+				log.V(1).Info("Failed to move new instance out of the machine cgroup", "error", evictErr.Error())
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// abandonUpgrade frees the instance the VM was not moved to and hands the machine directory back to the
+// instance of the machine.
+func (r *MachineReconciler) abandonUpgrade(ctx context.Context, log logr.Logger, machineID, from, to string) {
+	r.freeApiSocket(ctx, log, to)
+	if err := r.reconcileMachineDirAccess(log, machineID, from); err != nil {
+		log.Error(err, "Failed to restore access of the instance to the machine directory")
+	}
+}
+
+// instancePid returns the pid of the instance if machine cgroups are enabled, 0 otherwise or if it is unknown.
+func (r *MachineReconciler) instancePid(ctx context.Context, apiSocket string) int {
+	if r.cgroups == nil {
+		return 0
+	}
+	pid, err := r.vmm.Pid(ctx, apiSocket)
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+func (r *MachineReconciler) failUpgrade(ctx context.Context, machine *api.Machine, message string) error {
+	delete(machine.Annotations, api.VMMUpgradeAnnotation)
+	delete(machine.Annotations, api.VMMUpgradeTargetAnnotation)
+	if _, err := r.machines.Update(ctx, machine); err != nil {
+		return fmt.Errorf("failed to update machine: %w", err)
+	}
+
+	r.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "UpgradeFailed",
+		"Upgrade of cloud-hypervisor failed: %s", message)
+	return nil
+}
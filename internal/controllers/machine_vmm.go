@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"k8s.io/utils/ptr"
+)
+
+// VMInfo returns the info cloud-hypervisor reports for the VM of the machine.
+func (r *MachineReconciler) VMInfo(ctx context.Context, machineID string) (*client.VmInfo, error) {
+	apiSocket, err := r.apiSocketOf(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	return r.vmm.GetVM(ctx, apiSocket)
+}
+
+// VMMInfo returns the version, pid and features of the cloud-hypervisor instance of the machine.
+func (r *MachineReconciler) VMMInfo(ctx context.Context, machineID string) (*client.VmmPingResponse, error) {
+	apiSocket, err := r.apiSocketOf(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	return r.vmm.VMMInfo(ctx, apiSocket)
+}
+
+// VMCounters returns the counters of the devices of the VM of the machine.
+func (r *MachineReconciler) VMCounters(ctx context.Context, machineID string) (client.VmCounters, error) {
+	apiSocket, err := r.apiSocketOf(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	return r.vmm.Counters(ctx, apiSocket)
+}
+
+// apiSocketOf returns the api socket of the cloud-hypervisor instance of the machine, vmm.ErrNotFound if it has
+// none.
+func (r *MachineReconciler) apiSocketOf(ctx context.Context, machineID string) (string, error) {
+	machine, err := r.machines.Get(ctx, machineID)
+	if err != nil {
+		return "", err
+	}
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	if apiSocket == "" {
+		return "", vmm.ErrNotFound
+	}
+	return apiSocket, nil
+}
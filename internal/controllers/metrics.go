@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The stages of the reconciliation of a machine whose durations are recorded.
+const (
+	stageImage    = "image"
+	stageVolumes  = "volumes"
+	stageNICs     = "nics"
+	stageVMCreate = "vm_create"
+	stagePower    = "power"
+	stageAttach   = "attach"
+)
+
+var (
+	machineBootTime = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "machine_boot_time_seconds",
+			Help:      "Unix time the VM of a running machine entered Running.",
+		},
+		[]string{"machine"},
+	)
+
+	machineBootDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "machine_boot_duration_seconds",
+			Help:      "Time from the creation of a machine until its VM first entered Running.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		},
+	)
+
+	reconcileStageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "machine_reconcile_stage_duration_seconds",
+			Help:      "Duration of the stages of the reconciliation of a machine by stage and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"stage", "result"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(machineBootTime, machineBootDuration, reconcileStageDuration)
+}
+
+// timeStage runs the stage of a reconciliation and records its duration. Stages waiting for an image that is
+// still pulling are recorded as pending rather than failed.
+func timeStage(stage string, f func() error) error {
+	start := time.Now()
+	err := f()
+
+	result := "success"
+	switch {
+	case errors.Is(err, ociutils.ErrImagePulling):
+		result = "pending"
+	case err != nil:
+		result = "error"
+	}
+	reconcileStageDuration.WithLabelValues(stage, result).Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	computev1alpha1 "github.com/ironcore-dev/ironcore/api/compute/v1alpha1"
+	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
+	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const eventSourceComponent = "cloud-hypervisor-provider"
+
+// KubernetesSink creates every event of a machine as Kubernetes event of its ironcore machine. Events of
+// machines without the labels of the machinepoollet are skipped.
+type KubernetesSink struct {
+	client client.Client
+	host   string
+}
+
+// NewKubernetesSink connects to the cluster of the kubeconfig, or to the cluster the provider runs in if empty.
+func NewKubernetesSink(kubeconfig string) (*KubernetesSink, error) {
+	var cfg *rest.Config
+	var err error
+	if kubeconfig != "" {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		cfg, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event sink config: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event sink client: %w", err)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return &KubernetesSink{client: c, host: host}, nil
+}
+
+func (s *KubernetesSink) Name() string {
+	return "kubernetes"
+}
+
+func (s *KubernetesSink) Send(ctx context.Context, event *recorder.Event) error {
+	labels, err := api.GetLabelsAnnotation(event.InvolvedObjectMeta)
+	if err != nil {
+		return nil
+	}
+	namespace := labels[machinepoolletv1alpha1.MachineNamespaceLabel]
+	name := labels[machinepoolletv1alpha1.MachineNameLabel]
+	if namespace == "" || name == "" {
+		return nil
+	}
+
+	eventTime := metav1.NewTime(time.Unix(event.EventTime, 0))
+	return s.client.Create(ctx, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    namespace,
+			GenerateName: name + ".",
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: computev1alpha1.SchemeGroupVersion.String(),
+			Kind:       "Machine",
+			Namespace:  namespace,
+			Name:       name,
+			UID:        types.UID(labels[machinepoolletv1alpha1.MachineUIDLabel]),
+		},
+		Type:    event.Type,
+		Reason:  event.Reason,
+		Message: event.Message,
+		Source: corev1.EventSource{
+			Component: eventSourceComponent,
+			Host:      s.host,
+		},
+		FirstTimestamp:      eventTime,
+		LastTimestamp:       eventTime,
+		Count:               1,
+		ReportingController: eventSourceComponent,
+		ReportingInstance:   s.host,
+	})
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	sinkFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "event_sink_failures_total",
+			Help:      "Events a sink failed to receive.",
+		},
+		[]string{"sink"},
+	)
+
+	droppedEvents = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "event_sink_dropped_total",
+			Help:      "Events dropped as the sinks did not keep up.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(sinkFailures, droppedEvents)
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package events forwards the recorded machine events to external sinks.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiutils "github.com/ironcore-dev/provider-utils/apiutils/api"
+	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// queueSize is the number of events waiting to be forwarded, further events are dropped.
+	queueSize = 1000
+
+	sendAttempts = 3
+	sendBackoff  = time.Second
+)
+
+// Sink receives the recorded events.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event *recorder.Event) error
+}
+
+// Recorder records events in the event store the IRI lists them from and forwards them to the sinks. Its resource
+// version counts the recorded events, clients wait for it to change instead of listing the events repeatedly.
+type Recorder struct {
+	*recorder.Store
+
+	log   logr.Logger
+	sinks []Sink
+	queue chan *recorder.Event
+
+	versionMu sync.Mutex
+	version   uint64
+	// changed is closed and replaced whenever an event is recorded.
+	changed chan struct{}
+}
+
+func NewRecorder(log logr.Logger, store *recorder.Store, sinks ...Sink) *Recorder {
+	return &Recorder{
+		Store:   store,
+		log:     log,
+		sinks:   sinks,
+		queue:   make(chan *recorder.Event, queueSize),
+		changed: make(chan struct{}),
+	}
+}
+
+// ResourceVersion returns the number of events recorded since the recorder was created.
+func (r *Recorder) ResourceVersion() uint64 {
+	r.versionMu.Lock()
+	defer r.versionMu.Unlock()
+	return r.version
+}
+
+// WaitForEvents waits until an event is recorded after the resource version or the context is done and returns
+// the resource version then. It returns immediately for a resource version the recorder did not reach, e.g. of
+// the recorder of a previous run of the provider.
+func (r *Recorder) WaitForEvents(ctx context.Context, resourceVersion uint64) uint64 {
+	for {
+		r.versionMu.Lock()
+		version, changed := r.version, r.changed
+		r.versionMu.Unlock()
+		if version != resourceVersion {
+			return version
+		}
+
+		select {
+		case <-ctx.Done():
+			return version
+		case <-changed:
+		}
+	}
+}
+
+func (r *Recorder) Eventf(metadata apiutils.Metadata, eventType, reason, messageFormat string, args ...any) {
+	r.Store.Eventf(metadata, eventType, reason, messageFormat, args...)
+
+	r.versionMu.Lock()
+	r.version++
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.versionMu.Unlock()
+
+	if len(r.sinks) == 0 {
+		return
+	}
+
+	event := &recorder.Event{
+		InvolvedObjectMeta: metadata,
+		Type:               eventType,
+		Reason:             reason,
+		Message:            fmt.Sprintf(messageFormat, args...),
+		EventTime:          time.Now().Unix(),
+	}
+	select {
+	case r.queue <- event:
+	default:
+		r.log.V(1).Info("Dropping event, sink queue is full", "machineID", metadata.ID, "reason", reason)
+		droppedEvents.Inc()
+	}
+}
+
+// Start forwards the events to the sinks and expires the events of the store until the context is done.
+func (r *Recorder) Start(ctx context.Context) {
+	if len(r.sinks) > 0 {
+		go r.forward(ctx)
+	}
+	r.Store.Start(ctx)
+}
+
+func (r *Recorder) forward(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-r.queue:
+			for _, sink := range r.sinks {
+				if err := r.send(ctx, sink, event); err != nil {
+					r.log.Error(err, "Failed to forward event", "sink", sink.Name(), "machineID",
+						event.InvolvedObjectMeta.ID, "reason", event.Reason)
+					sinkFailures.WithLabelValues(sink.Name()).Inc()
+				}
+			}
+		}
+	}
+}
+
+func (r *Recorder) send(ctx context.Context, sink Sink, event *recorder.Event) error {
+	var err error
+	for attempt := range sendAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait.Jitter(sendBackoff, 1)):
+			}
+		}
+		if err = sink.Send(ctx, event); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
+	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// WebhookEvent is the body of the requests of the webhook sink.
+type WebhookEvent struct {
+	MachineID        string    `json:"machineID"`
+	MachineNamespace string    `json:"machineNamespace,omitempty"`
+	MachineName      string    `json:"machineName,omitempty"`
+	MachineUID       string    `json:"machineUID,omitempty"`
+	Type             string    `json:"type"`
+	Reason           string    `json:"reason"`
+	Message          string    `json:"message"`
+	Time             time.Time `json:"time"`
+}
+
+// WebhookSink posts every event as JSON to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(rawURL string) (*WebhookSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook url %s must be http or https", rawURL)
+	}
+
+	return &WebhookSink{
+		url:    rawURL,
+		client: &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Send(ctx context.Context, event *recorder.Event) error {
+	// Events of machines with invalid labels are still forwarded, without the ironcore machine.
+	labels, _ := api.GetLabelsAnnotation(event.InvolvedObjectMeta)
+	body, err := json.Marshal(WebhookEvent{
+		MachineID:        event.InvolvedObjectMeta.ID,
+		MachineNamespace: labels[machinepoolletv1alpha1.MachineNamespaceLabel],
+		MachineName:      labels[machinepoolletv1alpha1.MachineNameLabel],
+		MachineUID:       labels[machinepoolletv1alpha1.MachineUIDLabel],
+		Type:             event.Type,
+		Reason:           event.Reason,
+		Message:          event.Message,
+		Time:             time.Unix(event.EventTime, 0).UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+type ServerOptions struct {
+	BindAddress string
+}
+
+// Server serves the liveness of the provider on /healthz and its readiness on /readyz. Single checks are
+// served below them, e.g. /readyz/grpc, ?verbose lists the results of all checks.
+type Server struct {
+	log         logr.Logger
+	bindAddress string
+
+	mu              sync.Mutex
+	livenessChecks  map[string]healthz.Checker
+	readinessChecks map[string]healthz.Checker
+}
+
+func NewServer(log logr.Logger, opts ServerOptions) (*Server, error) {
+	if opts.BindAddress == "" {
+		return nil, fmt.Errorf("must specify bind address")
+	}
+
+	return &Server{
+		log:             log,
+		bindAddress:     opts.BindAddress,
+		livenessChecks:  map[string]healthz.Checker{"ping": healthz.Ping},
+		readinessChecks: map[string]healthz.Checker{},
+	}, nil
+}
+
+func (s *Server) AddLivenessCheck(name string, check healthz.Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.livenessChecks[name] = check
+}
+
+func (s *Server) AddReadinessCheck(name string, check healthz.Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readinessChecks[name] = check
+}
+
+func (s *Server) handler() http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mux := http.NewServeMux()
+	for path, checks := range map[string]map[string]healthz.Checker{
+		"/healthz": s.livenessChecks,
+		"/readyz":  s.readinessChecks,
+	} {
+		handler := http.StripPrefix(path, &healthz.Handler{Checks: checks})
+		mux.Handle(path, handler)
+		mux.Handle(path+"/", handler)
+	}
+	return mux
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.bindAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.bindAddress, err)
+	}
+
+	srv := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.log.Info("Serving health probes", "address", s.bindAddress)
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving health probes: %w", err)
+	}
+	return nil
+}
+
+// Flag is a check failing until the flag is set, e.g. once a component runs. Setting a nil flag is a no-op.
+type Flag struct {
+	name string
+	set  atomic.Bool
+}
+
+func NewFlag(name string) *Flag {
+	return &Flag{name: name}
+}
+
+func (f *Flag) Set(set bool) {
+	if f == nil {
+		return
+	}
+	f.set.Store(set)
+}
+
+func (f *Flag) Check(_ *http.Request) error {
+	if !f.set.Load() {
+		return fmt.Errorf("%s is not running", f.name)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package health probes the volumes attached to the VMs and publishes their health in the machine status, and
+// serves the liveness and readiness of the provider.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const DefaultInterval = 10 * time.Second
+
+type Options struct {
+	// Interval between two probes. Defaults to DefaultInterval.
+	Interval time.Duration
+	// VolumePlugins probe the volumes served by a daemon.
+	VolumePlugins *volume.PluginManager
+}
+
+// VolumeProber periodically probes the attached volumes of the running machines. Changes of their health are
+// recorded as events, the machine controller mounts and attaches unavailable volumes again.
+type VolumeProber struct {
+	log           logr.Logger
+	machines      store.Store[*api.Machine]
+	eventRecorder recorder.EventRecorder
+
+	interval      time.Duration
+	volumePlugins *volume.PluginManager
+}
+
+func NewVolumeProber(
+	log logr.Logger,
+	machines store.Store[*api.Machine],
+	eventRecorder recorder.EventRecorder,
+	opts Options,
+) (*VolumeProber, error) {
+	if machines == nil {
+		return nil, fmt.Errorf("must specify machine store")
+	}
+	if eventRecorder == nil {
+		return nil, fmt.Errorf("must specify event recorder")
+	}
+	if opts.VolumePlugins == nil {
+		return nil, fmt.Errorf("must specify volume plugins")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+
+	return &VolumeProber{
+		log:           log,
+		machines:      machines,
+		eventRecorder: eventRecorder,
+		interval:      opts.Interval,
+		volumePlugins: opts.VolumePlugins,
+	}, nil
+}
+
+func (p *VolumeProber) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *VolumeProber) probe(ctx context.Context) {
+	machines, err := p.machines.List(ctx)
+	if err != nil {
+		p.log.Error(err, "Failed to list machines")
+		return
+	}
+
+	for _, machine := range machines {
+		if machine.DeletedAt != nil {
+			continue
+		}
+		if err := p.probeMachine(ctx, machine); err != nil {
+			p.log.V(1).Info("Failed to update volume health", "machineID", machine.ID, "error", err.Error())
+		}
+	}
+}
+
+func (p *VolumeProber) probeMachine(ctx context.Context, machine *api.Machine) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	running := machine.Status.State == api.MachineStateRunning
+
+	changed := false
+	health := map[string]*api.VolumeHealth{}
+	for _, status := range machine.Status.VolumeStatus {
+		if !running || status.State != api.VolumeStateAttached {
+			changed = changed || status.Health != nil
+			continue
+		}
+
+		state, message := p.probeVolume(ctx, machine, &status)
+		current := status.Health
+		if current != nil && current.State == state && current.Message == message {
+			health[status.Name] = current
+			continue
+		}
+		changed = true
+		health[status.Name] = &api.VolumeHealth{State: state, Message: message, Since: now}
+		if current != nil && current.State == state {
+			health[status.Name].Since = current.Since
+			continue
+		}
+
+		p.log.V(1).Info("Volume health changed", "machineID", machine.ID, "volume", status.Name, "state", state,
+			"message", message)
+		switch {
+		case state != api.VolumeHealthStateHealthy:
+			p.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeWarning, "Volume"+string(state),
+				"Volume %s is %s: %s", status.Name, state, message)
+		case current != nil:
+			p.eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "VolumeHealthy",
+				"Volume %s is healthy again", status.Name)
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.Is(err, store.ErrResourceVersionNotLatest)
+	}, func() error {
+		machine, err := p.machines.Get(ctx, machine.ID)
+		if err != nil {
+			return err
+		}
+		for i := range machine.Status.VolumeStatus {
+			status := &machine.Status.VolumeStatus[i]
+			// Volumes detached meanwhile are probed again once they are attached.
+			if status.State != api.VolumeStateAttached {
+				status.Health = nil
+				continue
+			}
+			status.Health = health[status.Name]
+		}
+		_, err = p.machines.Update(ctx, machine)
+		return err
+	})
+	return store.IgnoreErrNotFound(err)
+}
+
+// probeVolume returns the health of an attached volume. Plugins serving volumes with a daemon probe them, the
+// files and sockets of the other volumes have to exist.
+func (p *VolumeProber) probeVolume(
+	ctx context.Context,
+	machine *api.Machine,
+	status *api.VolumeStatus,
+) (api.VolumeHealthState, string) {
+	for _, spec := range machine.Spec.Volumes {
+		if spec.Name != status.Name {
+			continue
+		}
+		if spec.Imported {
+			break
+		}
+
+		plugin, err := p.volumePlugins.FindPluginBySpec(spec)
+		if err != nil {
+			return api.VolumeHealthStateUnavailable, fmt.Sprintf("failed to find plugin: %v", err)
+		}
+		if healthPlugin, ok := plugin.(volume.HealthPlugin); ok {
+			return healthPlugin.Probe(ctx, status, machine.ID)
+		}
+		break
+	}
+
+	stat, err := os.Stat(status.Path)
+	switch {
+	case err != nil:
+		return api.VolumeHealthStateUnavailable, fmt.Sprintf("%s is missing", status.Path)
+	case status.Type == api.VolumeSocketType && stat.Mode()&os.ModeSocket == 0:
+		return api.VolumeHealthStateUnavailable, fmt.Sprintf("%s is no socket", status.Path)
+	}
+	return api.VolumeHealthStateHealthy, ""
+}
@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package host
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+const SELinuxXattr = "security.selinux"
+
+// LabelMachineDir sets the SELinux file context of the machine directory and everything in it, so that
+// a confined cloud-hypervisor process can access it.
+func LabelMachineDir(paths Paths, machineUID string, context string) error {
+	return filepath.WalkDir(paths.MachineDir(machineUID), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return SetFileContext(path, context)
+	})
+}
+
+// SetFileContext sets the SELinux context of a file without following symlinks.
+func SetFileContext(path, context string) error {
+	current := make([]byte, 256)
+	if n, err := unix.Lgetxattr(path, SELinuxXattr, current); err == nil && string(trimNull(current[:n])) == context {
+		return nil
+	}
+
+	if err := unix.Lsetxattr(path, SELinuxXattr, []byte(context), 0); err != nil {
+		return fmt.Errorf("error setting selinux context of %s: %w", path, err)
+	}
+	return nil
+}
+
+func trimNull(b []byte) []byte {
+	if len(b) > 0 && b[len(b)-1] == 0 {
+		return b[:len(b)-1]
+	}
+	return b
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package host
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+)
+
+// LayoutMigration upgrades the root directory from the previous layout version to Version.
+type LayoutMigration struct {
+	Version     int
+	Description string
+	Migrate     func(log logr.Logger, paths Paths) error
+}
+
+// LayoutMigrations are the migrations of the root directory layout, ordered by version. A release changing the
+// on-disk format appends a migration, which makes its version the current one.
+var LayoutMigrations = []LayoutMigration{
+	{
+		Version:     1,
+		Description: "create the directories of the current layout in the machine directories",
+		Migrate:     migrateMachineDirs,
+	},
+}
+
+// CurrentLayoutVersion is the layout version of the root directory written by this provider.
+func CurrentLayoutVersion() int {
+	return LayoutMigrations[len(LayoutMigrations)-1].Version
+}
+
+// LayoutVersion returns the layout version the root directory is stamped with, 0 if it is not stamped yet.
+func LayoutVersion(paths Paths) (int, error) {
+	data, err := os.ReadFile(paths.LayoutVersionFile())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error reading layout version: %w", err)
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing layout version: %w", err)
+	}
+	return version, nil
+}
+
+func writeLayoutVersion(paths Paths, version int) error {
+	tmpFile := paths.LayoutVersionFile() + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(strconv.Itoa(version)+"\n"), 0644); err != nil {
+		return fmt.Errorf("error writing layout version: %w", err)
+	}
+	if err := os.Rename(tmpFile, paths.LayoutVersionFile()); err != nil {
+		return fmt.Errorf("error writing layout version: %w", err)
+	}
+	return nil
+}
+
+// MigrateLayout runs the migrations the root directory is missing and stamps it with their versions, one by one,
+// so that an interrupted migration continues where it stopped. A root directory of a newer layout is rejected.
+func MigrateLayout(log logr.Logger, paths Paths, migrations []LayoutMigration) error {
+	version, err := LayoutVersion(paths)
+	if err != nil {
+		return err
+	}
+	current := migrations[len(migrations)-1].Version
+	if version > current {
+		return fmt.Errorf("root directory %s has layout version %d, newer than the supported version %d",
+			paths.RootDir(), version, current)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		log.Info("Migrating root directory layout", "from", version, "to", migration.Version,
+			"migration", migration.Description)
+		if err := migration.Migrate(log, paths); err != nil {
+			return fmt.Errorf("error migrating layout to version %d: %w", migration.Version, err)
+		}
+		if err := writeLayoutVersion(paths, migration.Version); err != nil {
+			return err
+		}
+		version = migration.Version
+	}
+	return nil
+}
+
+// migrateMachineDirs creates the sub-directories missing in machine directories of older providers. They get the
+// owner of their machine directory.
+func migrateMachineDirs(log logr.Logger, paths Paths) error {
+	entries, err := os.ReadDir(paths.MachinesDir())
+	if err != nil {
+		return fmt.Errorf("error reading machines directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		machineUID := entry.Name()
+
+		info, err := os.Stat(paths.MachineDir(machineUID))
+		if err != nil {
+			return fmt.Errorf("error stating machine directory: %w", err)
+		}
+
+		var created []string
+		for _, dir := range []string{
+			paths.MachineRootFSDir(machineUID),
+			paths.MachineVolumesDir(machineUID),
+			paths.MachineIgnitionsDir(machineUID),
+			paths.MachineNetworkInterfacesDir(machineUID),
+		} {
+			if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+				created = append(created, dir)
+			}
+		}
+		if len(created) == 0 {
+			continue
+		}
+
+		if err := MakeMachineDirs(paths, machineUID); err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			for _, dir := range created {
+				if err := os.Lchown(dir, int(stat.Uid), int(stat.Gid)); err != nil {
+					return fmt.Errorf("error changing owner of %s: %w", dir, err)
+				}
+			}
+		}
+		log.V(1).Info("Created machine directories", "machineID", machineUID, "dirs", created)
+	}
+	return nil
+}
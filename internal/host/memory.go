@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package host
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// HugepageSizes returns the hugepage sizes in bytes supported by the host.
+func HugepageSizes() (sets.Set[int64], error) {
+	entries, err := os.ReadDir("/sys/kernel/mm/hugepages")
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := sets.New[int64]()
+	for _, entry := range entries {
+		var sizeKB int64
+		if _, err := fmt.Sscanf(strings.TrimPrefix(entry.Name(), "hugepages-"), "%dkB", &sizeKB); err != nil {
+			continue
+		}
+		sizes.Insert(sizeKB * 1024)
+	}
+	return sizes, nil
+}
+
+// FreeHugepages returns the bytes of the hugepages of the size that are neither used nor reserved by a mapping.
+func FreeHugepages(sizeBytes int64) (int64, error) {
+	dir := fmt.Sprintf("/sys/kernel/mm/hugepages/hugepages-%dkB", sizeBytes/1024)
+	free, err := readCount(filepath.Join(dir, "free_hugepages"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read free hugepages of %d bytes: %w", sizeBytes, err)
+	}
+	reserved, err := readCount(filepath.Join(dir, "resv_hugepages"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read reserved hugepages of %d bytes: %w", sizeBytes, err)
+	}
+	return max(free-reserved, 0) * sizeBytes, nil
+}
+
+func readCount(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
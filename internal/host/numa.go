@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package host
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func numaNodeDir(node int) string {
+	return fmt.Sprintf("/sys/devices/system/node/node%d", node)
+}
+
+// NUMANodeCPUs returns the cpus of the NUMA node as cpu list, e.g. 0-15,32-47.
+func NUMANodeCPUs(node int) (string, error) {
+	data, err := os.ReadFile(numaNodeDir(node) + "/cpulist")
+	if err != nil {
+		return "", fmt.Errorf("failed to read cpus of numa node %d: %w", node, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// NUMANodeMemFree returns the free memory of the NUMA node in bytes.
+func NUMANodeMemFree(node int) (int64, error) {
+	f, err := os.Open(numaNodeDir(node) + "/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read memory of numa node %d: %w", node, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	// The lines look like "Node 0 MemFree:        1234 kB".
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != "MemFree:" {
+			continue
+		}
+		freeKB, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse free memory of numa node %d: %w", node, err)
+		}
+		return freeKB * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no free memory found for numa node %d", node)
+}
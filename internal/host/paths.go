@@ -5,6 +5,7 @@ package host
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
@@ -13,10 +14,20 @@ const (
 	DefaultImagesDir  = "images"
 	DefaultPluginsDir = "plugins"
 
+	DefaultReconcileQueueFile = "reconcile-queue.json"
+	DefaultLayoutVersionFile  = "layout-version"
+
 	DefaultMachinesDir                 = "machines"
 	DefaultMachineVolumesDir           = "volumes"
 	DefaultMachineIgnitionsDir         = "ignitions"
 	DefaultMachineIgnitionFile         = "data.ign"
+	DefaultMachineConfigDriveFile      = "config-drive.iso"
+	DefaultMachineVsockFile            = "vsock.sock"
+	DefaultMachineSerialLogFile        = "serial.log"
+	DefaultMachineConsoleLogFile       = "console.log"
+	DefaultMachineLogFile              = "provider.log"
+	DefaultMachineSnapshotDir          = "snapshot"
+	DefaultMachineExportFile           = "export.layer"
 	DefaultMachineRootFSDir            = "rootfs"
 	DefaultMachineRootFSFile           = "rootfs"
 	DefaultMachinePluginsDir           = "plugins"
@@ -29,6 +40,8 @@ type Paths interface {
 	MachinesDir() string
 	ImagesDir() string
 	PluginsDir() string
+	ReconcileQueueFile() string
+	LayoutVersionFile() string
 
 	PluginDir(pluginName string) string
 	MachinePluginsDir(machineUID string) string
@@ -47,6 +60,15 @@ type Paths interface {
 
 	MachineIgnitionsDir(machineUID string) string
 	MachineIgnitionFile(machineUID string) string
+	MachineConfigDriveFile(machineUID string) string
+
+	MachineVsockFile(machineUID string) string
+	MachineSerialLogFile(machineUID string) string
+	MachineConsoleLogFile(machineUID string) string
+	MachineLogFile(machineUID string) string
+
+	MachineSnapshotDir(machineUID string) string
+	MachineExportFile(machineUID string) string
 }
 
 type paths struct {
@@ -69,6 +91,14 @@ func (p *paths) PluginsDir() string {
 	return filepath.Join(p.rootDir, DefaultPluginsDir)
 }
 
+func (p *paths) ReconcileQueueFile() string {
+	return filepath.Join(p.rootDir, DefaultReconcileQueueFile)
+}
+
+func (p *paths) LayoutVersionFile() string {
+	return filepath.Join(p.rootDir, DefaultLayoutVersionFile)
+}
+
 func (p *paths) PluginDir(pluginName string) string {
 	return filepath.Join(p.PluginsDir(), pluginName)
 }
@@ -121,6 +151,34 @@ func (p *paths) MachineIgnitionFile(machineUID string) string {
 	return filepath.Join(p.MachineIgnitionsDir(machineUID), DefaultMachineIgnitionFile)
 }
 
+func (p *paths) MachineConfigDriveFile(machineUID string) string {
+	return filepath.Join(p.MachineIgnitionsDir(machineUID), DefaultMachineConfigDriveFile)
+}
+
+func (p *paths) MachineVsockFile(machineUID string) string {
+	return filepath.Join(p.MachineDir(machineUID), DefaultMachineVsockFile)
+}
+
+func (p *paths) MachineSerialLogFile(machineUID string) string {
+	return filepath.Join(p.MachineDir(machineUID), DefaultMachineSerialLogFile)
+}
+
+func (p *paths) MachineConsoleLogFile(machineUID string) string {
+	return filepath.Join(p.MachineDir(machineUID), DefaultMachineConsoleLogFile)
+}
+
+func (p *paths) MachineLogFile(machineUID string) string {
+	return filepath.Join(p.MachineDir(machineUID), DefaultMachineLogFile)
+}
+
+func (p *paths) MachineSnapshotDir(machineUID string) string {
+	return filepath.Join(p.MachineDir(machineUID), DefaultMachineSnapshotDir)
+}
+
+func (p *paths) MachineExportFile(machineUID string) string {
+	return filepath.Join(p.MachineDir(machineUID), DefaultMachineExportFile)
+}
+
 func PathsAt(rootDir string) (Paths, error) {
 	p := &paths{rootDir}
 	if err := os.MkdirAll(p.RootDir(), os.ModePerm); err != nil {
@@ -135,6 +193,20 @@ func PathsAt(rootDir string) (Paths, error) {
 	return p, nil
 }
 
+// ChownMachineDir changes the owner of the machine directory and everything in it, so that a
+// cloud-hypervisor instance running as a dedicated user can access it.
+func ChownMachineDir(paths Paths, machineUID string, uid, gid int) error {
+	return filepath.WalkDir(paths.MachineDir(machineUID), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, uid, gid); err != nil {
+			return fmt.Errorf("error changing owner of %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
 func MakeMachineDirs(paths Paths, machineUID string) error {
 	if err := os.MkdirAll(paths.MachineDir(machineUID), os.ModePerm); err != nil {
 		return fmt.Errorf("error creating machine directory: %w", err)
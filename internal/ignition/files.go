@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// File is a file written by ignition.
+type File struct {
+	Path     string
+	Contents string
+}
+
+// AddFiles adds files to the ignition config data, unless the config writes a file of the same path itself.
+// Configs with a spec version before 3.0.0 are returned unchanged, their file entries differ.
+func AddFiles(data []byte, files []File) ([]byte, error) {
+	if len(data) == 0 || len(files) == 0 || !atLeastVersion(data, 3, 0) {
+		return data, nil
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	storage, ok := cfg["storage"].(map[string]any)
+	if !ok {
+		storage = map[string]any{}
+		cfg["storage"] = storage
+	}
+	entries, _ := storage["files"].([]any)
+
+	var paths []string
+	for _, entry := range entries {
+		if entry, ok := entry.(map[string]any); ok {
+			if path, ok := entry["path"].(string); ok {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	for _, file := range files {
+		if slices.Contains(paths, file.Path) {
+			continue
+		}
+		entries = append(entries, map[string]any{
+			"path":      file.Path,
+			"mode":      0644,
+			"overwrite": true,
+			"contents": map[string]any{
+				"source": "data:," + url.PathEscape(file.Contents),
+			},
+		})
+	}
+	storage["files"] = entries
+
+	return json.Marshal(cfg)
+}
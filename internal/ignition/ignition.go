@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"bytes"
+	"compress/gzip"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+)
+
+const (
+	// MaxOEMStringsSize is the maximum size of the base64 encoded payload passed via SMBIOS OEM strings.
+	MaxOEMStringsSize = 48 * 1024
+	// MaxConfigDriveSize is the maximum size of the payload written to a config drive.
+	MaxConfigDriveSize = 16 * 1024 * 1024
+)
+
+var ErrInvalid = errors.New("invalid ignition")
+
+type config struct {
+	Ignition struct {
+		Version string `json:"version"`
+	} `json:"ignition"`
+}
+
+// Validate checks that data is a JSON ignition config with a version.
+func Validate(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if cfg.Ignition.Version == "" {
+		return fmt.Errorf("%w: ignition.version is not set", ErrInvalid)
+	}
+	return nil
+}
+
+// supportsCompression reports whether the ignition spec version supports
+// compressed data URLs in ignition.config.replace (spec 3.1.0 and later).
+func supportsCompression(data []byte) bool {
+	return atLeastVersion(data, 3, 1)
+}
+
+// atLeastVersion reports whether the ignition spec version is major.minor or later.
+func atLeastVersion(data []byte, major, minor int) bool {
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return false
+	}
+	majorPart, rest, _ := strings.Cut(cfg.Ignition.Version, ".")
+	minorPart, _, _ := strings.Cut(rest, ".")
+
+	majorVersion, err := strconv.Atoi(majorPart)
+	if err != nil {
+		return false
+	}
+	minorVersion, err := strconv.Atoi(minorPart)
+	if err != nil {
+		return false
+	}
+	return majorVersion > major || (majorVersion == major && minorVersion >= minor)
+}
+
+// Compress wraps data into an ignition config that replaces itself with the
+// gzip compressed original. Configs whose spec version does not support
+// compression are returned unchanged.
+func Compress(data []byte) ([]byte, error) {
+	if len(data) == 0 || !supportsCompression(data) {
+		return data, nil
+	}
+
+	var version config
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress ignition: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress ignition: %w", err)
+	}
+
+	wrapper := map[string]any{
+		"ignition": map[string]any{
+			"version": version.Ignition.Version,
+			"config": map[string]any{
+				"replace": map[string]any{
+					"source":      "data:;base64," + b64.StdEncoding.EncodeToString(buf.Bytes()),
+					"compression": "gzip",
+				},
+			},
+		},
+	}
+	compressed, err := json.Marshal(wrapper)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal compressed ignition: %w", err)
+	}
+
+	if len(compressed) >= len(data) {
+		return data, nil
+	}
+	return compressed, nil
+}
+
+// Encode renders data for the given transport.
+func Encode(data []byte, transport api.IgnitionTransport, compress bool) ([]byte, error) {
+	if compress {
+		var err error
+		if data, err = Compress(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if transport == api.IgnitionTransportConfigDrive {
+		return data, nil
+	}
+	return []byte(b64.StdEncoding.EncodeToString(data)), nil
+}
+
+// CheckSize verifies that the encoded payload fits into the given transport.
+func CheckSize(encoded []byte, transport api.IgnitionTransport) error {
+	limit := MaxOEMStringsSize
+	if transport == api.IgnitionTransportConfigDrive {
+		limit = MaxConfigDriveSize
+	}
+
+	if len(encoded) > limit {
+		return fmt.Errorf("%w: encoded size %d exceeds the limit of %d bytes for transport %s",
+			ErrInvalid, len(encoded), limit, transport)
+	}
+	return nil
+}
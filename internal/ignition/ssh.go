@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// DefaultSSHUser is the user SSH keys are authorized for, the default user of Flatcar and Fedora CoreOS.
+const DefaultSSHUser = "core"
+
+// minimalConfig is used if SSH keys are authorized for a machine without ignition. Spec 3.0.0 is understood
+// by every ignition v2 release.
+const minimalConfig = `{"ignition":{"version":"3.0.0"}}`
+
+// AddSSHAuthorizedKeys authorizes keys for user in the ignition config data. The user is added if the config
+// has none of that name. Without data, a minimal config is created.
+func AddSSHAuthorizedKeys(data []byte, user string, keys []string) ([]byte, error) {
+	if len(keys) == 0 {
+		return data, nil
+	}
+	if len(data) == 0 {
+		data = []byte(minimalConfig)
+	}
+
+	var cfg map[string]any
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	passwd, ok := cfg["passwd"].(map[string]any)
+	if !ok {
+		passwd = map[string]any{}
+		cfg["passwd"] = passwd
+	}
+	users, _ := passwd["users"].([]any)
+
+	var entry map[string]any
+	for _, u := range users {
+		if u, ok := u.(map[string]any); ok && u["name"] == user {
+			entry = u
+			break
+		}
+	}
+	if entry == nil {
+		entry = map[string]any{"name": user}
+		users = append(users, entry)
+	}
+
+	authorized, _ := entry["sshAuthorizedKeys"].([]any)
+	for _, key := range keys {
+		if !slices.Contains(authorized, any(key)) {
+			authorized = append(authorized, key)
+		}
+	}
+	entry["sshAuthorizedKeys"] = authorized
+	passwd["users"] = users
+
+	return json.Marshal(cfg)
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package machinelog writes the log records of a machine to a log file in its machine directory.
+package machinelog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+)
+
+const (
+	DefaultVerbosity  = 2
+	DefaultMaxSize    = 10 * 1024 * 1024
+	DefaultMaxBackups = 3
+
+	filePerm = 0640
+)
+
+type Options struct {
+	// Verbosity is the highest verbosity written to the log files, independent of the verbosity of the provider
+	// log.
+	Verbosity int
+	// MaxSize is the size a log file is rotated at.
+	MaxSize int64
+	// MaxBackups is the number of rotated log files that are kept.
+	MaxBackups int
+}
+
+// Files are the log files of the machines.
+type Files struct {
+	paths host.Paths
+	opts  Options
+
+	mu    sync.Mutex
+	files map[string]*file
+}
+
+func NewFiles(paths host.Paths, opts Options) *Files {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxBackups < 0 {
+		opts.MaxBackups = 0
+	}
+
+	return &Files{
+		paths: paths,
+		opts:  opts,
+		files: map[string]*file{},
+	}
+}
+
+// Write appends the line to the log file of the machine. Lines of machines without machine directory are
+// dropped, the directory is not created.
+func (f *Files) Write(machineID string, line []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lf, ok := f.files[machineID]
+	if !ok {
+		var err error
+		lf, err = openFile(f.paths.MachineLogFile(machineID))
+		if err != nil {
+			return
+		}
+		f.files[machineID] = lf
+	}
+
+	if lf.size+int64(len(line)) > f.opts.MaxSize && lf.size > 0 {
+		if err := lf.rotate(f.opts.MaxBackups); err != nil {
+			delete(f.files, machineID)
+			return
+		}
+	}
+	n, _ := lf.f.Write(line)
+	lf.size += int64(n)
+}
+
+// Close closes the log file of the machine, it is reopened on the next write.
+func (f *Files) Close(machineID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if lf, ok := f.files[machineID]; ok {
+		_ = lf.f.Close()
+		delete(f.files, machineID)
+	}
+}
+
+type file struct {
+	path string
+	f    *os.File
+	size int64
+}
+
+func openFile(path string) (*file, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, filePerm)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &file{path: path, f: f, size: info.Size()}, nil
+}
+
+func (lf *file) rotate(maxBackups int) error {
+	if err := lf.f.Close(); err != nil {
+		return err
+	}
+
+	if maxBackups == 0 {
+		if err := os.Remove(lf.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	for i := maxBackups; i > 0; i-- {
+		src := lf.path
+		if i > 1 {
+			src = fmt.Sprintf("%s.%d", lf.path, i-1)
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", lf.path, i)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	f, err := openFile(lf.path)
+	if err != nil {
+		return err
+	}
+	*lf = *f
+	return nil
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package machinelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// MachineIDKey is the key of the log values the machine of a record is taken from.
+const MachineIDKey = "machineID"
+
+// sink passes the records to the provider log and writes the ones of a machine to its log file.
+type sink struct {
+	base  logr.LogSink
+	files *Files
+
+	name      string
+	values    []any
+	machineID string
+}
+
+// NewLogger returns a logger writing to the log and to the log files of the machines.
+func NewLogger(log logr.Logger, files *Files) logr.Logger {
+	return logr.New(&sink{base: log.GetSink(), files: files})
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.base.Init(logr.RuntimeInfo{CallDepth: info.CallDepth + 1})
+}
+
+func (s *sink) Enabled(level int) bool {
+	return s.base.Enabled(level) || level <= s.files.opts.Verbosity
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...any) {
+	if s.base.Enabled(level) {
+		s.base.Info(level, msg, keysAndValues...)
+	}
+	if level <= s.files.opts.Verbosity {
+		s.write("info", level, msg, nil, keysAndValues)
+	}
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...any) {
+	s.base.Error(err, msg, keysAndValues...)
+	s.write("error", 0, msg, err, keysAndValues)
+}
+
+func (s *sink) WithValues(keysAndValues ...any) logr.LogSink {
+	c := *s
+	c.base = s.base.WithValues(keysAndValues...)
+	c.values = append(s.values[:len(s.values):len(s.values)], keysAndValues...)
+	if id := machineID(keysAndValues); id != "" {
+		c.machineID = id
+	}
+	return &c
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	c := *s
+	c.base = s.base.WithName(name)
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+	c.name = name
+	return &c
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	callDepthSink, ok := s.base.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	c := *s
+	c.base = callDepthSink.WithCallDepth(depth)
+	return &c
+}
+
+func (s *sink) write(level string, v int, msg string, err error, keysAndValues []any) {
+	id := s.machineID
+	if callID := machineID(keysAndValues); callID != "" {
+		id = callID
+	}
+	if id == "" {
+		return
+	}
+
+	record := map[string]any{
+		"ts":    time.Now().UTC().Format(time.RFC3339Nano),
+		"level": level,
+		"msg":   msg,
+	}
+	if v > 0 {
+		record["v"] = v
+	}
+	if s.name != "" {
+		record["logger"] = s.name
+	}
+	if err != nil {
+		record["error"] = err.Error()
+	}
+	addValues(record, s.values)
+	addValues(record, keysAndValues)
+
+	line, mErr := json.Marshal(record)
+	if mErr != nil {
+		for k, v := range record {
+			record[k] = fmt.Sprintf("%+v", v)
+		}
+		if line, mErr = json.Marshal(record); mErr != nil {
+			return
+		}
+	}
+	s.files.Write(id, append(line, '\n'))
+}
+
+func machineID(keysAndValues []any) string {
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i].(string); ok && key == MachineIDKey {
+			id, _ := keysAndValues[i+1].(string)
+			return id
+		}
+	}
+	return ""
+}
+
+func addValues(record map[string]any, keysAndValues []any) {
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		switch v := keysAndValues[i+1].(type) {
+		case error:
+			record[key] = v.Error()
+		case fmt.Stringer:
+			record[key] = v.String()
+		default:
+			record[key] = v
+		}
+	}
+}
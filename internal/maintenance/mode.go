@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package maintenance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Evacuation is how the machines of a host in maintenance are handled.
+type Evacuation string
+
+const (
+	// EvacuationNone keeps the VMs running, only new machines are not admitted.
+	EvacuationNone Evacuation = "none"
+	// EvacuationShutdown shuts the VMs down, they are started again once the maintenance ends.
+	EvacuationShutdown Evacuation = "shutdown"
+	// EvacuationSnapshot snapshots the VMs to the machine dir and deletes them, they are restored once the
+	// maintenance ends.
+	EvacuationSnapshot Evacuation = "snapshot"
+)
+
+func ParseEvacuation(s string) (Evacuation, error) {
+	switch e := Evacuation(s); e {
+	case EvacuationNone, EvacuationShutdown, EvacuationSnapshot:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown evacuation %q, must be one of %s, %s, %s",
+			s, EvacuationNone, EvacuationShutdown, EvacuationSnapshot)
+	}
+}
+
+// State is the maintenance state of the host.
+type State struct {
+	Enabled    bool       `json:"enabled"`
+	Evacuation Evacuation `json:"evacuation"`
+	Since      time.Time  `json:"since,omitzero"`
+}
+
+// Mode holds the maintenance state, shared by the components that act on it.
+type Mode struct {
+	mu        sync.RWMutex
+	state     State
+	listeners []func(State)
+}
+
+func NewMode(enabled bool, evacuation Evacuation) *Mode {
+	m := &Mode{}
+	m.state = newState(enabled, evacuation)
+	return m
+}
+
+func newState(enabled bool, evacuation Evacuation) State {
+	state := State{Enabled: enabled, Evacuation: evacuation}
+	if enabled {
+		state.Since = time.Now()
+	}
+	return state
+}
+
+// State returns the current maintenance state. A nil Mode is never in maintenance.
+func (m *Mode) State() State {
+	if m == nil {
+		return State{Evacuation: EvacuationNone}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *Mode) Enabled() bool {
+	return m.State().Enabled
+}
+
+// Set changes the maintenance state and notifies the listeners if it changed.
+func (m *Mode) Set(enabled bool, evacuation Evacuation) {
+	m.mu.Lock()
+	if m.state.Enabled == enabled && m.state.Evacuation == evacuation {
+		m.mu.Unlock()
+		return
+	}
+	since := m.state.Since
+	m.state = newState(enabled, evacuation)
+	if enabled && !since.IsZero() {
+		m.state.Since = since
+	}
+	state := m.state
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
+// AddListener registers a function called whenever the maintenance state changes.
+func (m *Mode) AddListener(listener func(State)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
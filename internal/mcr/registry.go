@@ -5,6 +5,9 @@ package mcr
 
 import (
 	"fmt"
+	"sync"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 )
 
 type MachineClassRegistry interface {
@@ -16,33 +19,83 @@ type MachineClass struct {
 	Name        string
 	Cpu         int64
 	MemoryBytes int64
+
+	// Landlock overrides whether landlock is enabled for machines of the class.
+	Landlock *bool
+	// CpuTopology is presented to the guests of the class. Every vcpu is a socket if nil.
+	CpuTopology *api.CpuTopology
+	// CpuFeatures are enabled for the guests of the class.
+	CpuFeatures *api.CpuFeatures
+	// Clock is the clock the guests of the class synchronize with.
+	Clock api.GuestClock
+	// FreePageReporting enables free page reporting for the guests of the class.
+	FreePageReporting bool
+	// SharedMemory overrides whether the guest memory of the class is shared.
+	SharedMemory *bool
+	// MemoryBacking places the guest memory of the class. Anonymous memory is used if nil.
+	MemoryBacking *api.MemoryBacking
+	// SerialMode and ConsoleMode are where the serial port and the virtio console of the guests of the class are
+	// written to, the defaults of the machine spec if empty.
+	SerialMode  api.ConsoleMode
+	ConsoleMode api.ConsoleMode
+	// VMMRequirements restrict the cloud-hypervisor instances the machines of the class are placed on.
+	VMMRequirements *api.VMMRequirements
+
+	// RootDiskBytes is the size of the disks provisioned from an image for machines of the class, if the
+	// volume specifies none.
+	RootDiskBytes int64
+	// RootDiskMedium is the medium of the disks provisioned from an image for machines of the class, the
+	// default medium of the host if empty.
+	RootDiskMedium api.StorageMedium
+	// MemoryDiskBytes is the host memory the memory disks of a machine of the class may use in total.
+	MemoryDiskBytes int64
+
+	// VMConfigOverrides are set in the VM config of the machines of the class, by dot separated path of the field.
+	VMConfigOverrides map[string]string
+
+	// Deprecated classes are not advertised and admit no new machines, the existing machines keep running.
+	Deprecated bool
 }
 
 func NewMachineClassRegistry(classes []MachineClass) (*Mcr, error) {
-	registry := Mcr{
-		classes: map[string]MachineClass{},
+	registry := &Mcr{}
+	if err := registry.Set(classes); err != nil {
+		return nil, err
 	}
+	return registry, nil
+}
+
+type Mcr struct {
+	mu      sync.RWMutex
+	classes map[string]MachineClass
+}
 
+// Set replaces the classes of the registry.
+func (m *Mcr) Set(classes []MachineClass) error {
+	byName := map[string]MachineClass{}
 	for _, class := range classes {
-		if _, ok := registry.classes[class.Name]; ok {
-			return nil, fmt.Errorf("multiple classes with same name (%s) found", class.Name)
+		if _, ok := byName[class.Name]; ok {
+			return fmt.Errorf("multiple classes with same name (%s) found", class.Name)
 		}
-		registry.classes[class.Name] = class
+		byName[class.Name] = class
 	}
 
-	return &registry, nil
-}
-
-type Mcr struct {
-	classes map[string]MachineClass
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.classes = byName
+	return nil
 }
 
 func (m *Mcr) Get(machineClassName string) (MachineClass, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	class, found := m.classes[machineClassName]
 	return class, found
 }
 
 func (m *Mcr) List() []MachineClass {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var classes []MachineClass
 	for name := range m.classes {
 		class := m.classes[name]
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+)
+
+// arpTablePath is the IPv4 neighbor table of the host.
+const arpTablePath = "/proc/net/arp"
+
+// arpFlagComplete marks resolved entries of the neighbor table.
+const arpFlagComplete = 0x2
+
+// neighborLookup returns the MAC address the host resolved an address of a directly connected guest to.
+type neighborLookup func(addr netip.Addr) (net.HardwareAddr, error)
+
+// arpTableNeighbor looks up the address in the IPv4 neighbor table of the host. The host resolved the address of
+// a guest by the time its request arrives, as it answered the connection of the guest.
+func arpTableNeighbor(addr netip.Addr) (net.HardwareAddr, error) {
+	f, err := os.Open(arpTablePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open neighbor table: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	// The first line is the header.
+	scanner.Scan()
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] != addr.String() {
+			continue
+		}
+		var flags int
+		if _, err := fmt.Sscanf(fields[2], "0x%x", &flags); err != nil || flags&arpFlagComplete == 0 {
+			continue
+		}
+		mac, err := net.ParseMAC(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC address %q in neighbor table: %w", fields[3], err)
+		}
+		return mac, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read neighbor table: %w", err)
+	}
+	return nil, store.ErrNotFound
+}
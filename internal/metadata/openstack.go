@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"net/http"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+)
+
+// openStackVersion is the only version of the OpenStack metadata layout that is served.
+const openStackVersion = "latest"
+
+// OpenStackMetaData is the subset of the meta_data.json of the OpenStack metadata service that is served,
+// as read by cloud-init and Ignition.
+type OpenStackMetaData struct {
+	UUID     string            `json:"uuid"`
+	Name     string            `json:"name"`
+	Hostname string            `json:"hostname"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
+// addOpenStackHandlers serves the layout of the OpenStack metadata service below /openstack.
+func (s *Server) addOpenStackHandlers(mux *http.ServeMux, lookup machineLookup) {
+	versions := func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(openStackVersion + "\n"))
+	}
+	mux.HandleFunc("GET /openstack", versions)
+	mux.HandleFunc("GET /openstack/{$}", versions)
+	mux.HandleFunc("GET /openstack/"+openStackVersion+"/meta_data.json", func(w http.ResponseWriter, r *http.Request) {
+		machine, ok := s.getMachine(w, r, lookup)
+		if !ok {
+			return
+		}
+
+		labels, _ := api.GetLabelsAnnotation(machine.Metadata)
+		writeJSON(w, OpenStackMetaData{
+			UUID:     machine.ID,
+			Name:     machine.ID,
+			Hostname: hostname(machine),
+			Meta:     labels,
+		})
+	})
+	mux.HandleFunc("GET /openstack/"+openStackVersion+"/user_data", func(w http.ResponseWriter, r *http.Request) {
+		machine, ok := s.getMachine(w, r, lookup)
+		if !ok {
+			return
+		}
+		writeUserData(w, machine)
+	})
+}
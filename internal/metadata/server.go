@@ -0,0 +1,376 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/provider-utils/eventutils/event"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+)
+
+// LinkLocalAddress is the link-local address of the metadata services of cloud platforms, e.g. of OpenStack.
+const LinkLocalAddress = "169.254.169.254:80"
+
+// errAmbiguousSource is returned for requests on the link-local address that match the network interfaces of
+// several machines.
+var errAmbiguousSource = errors.New("source of request matches several network interfaces")
+
+type Options struct {
+	// Port is the vsock port the guests connect to on the host (CID 2), 0 disables the vsock listeners.
+	Port uint32
+	// LinkLocalAddress is the address the link-local listener binds, empty disables it.
+	LinkLocalAddress string
+}
+
+// Server serves machine metadata to guests over cloud-hypervisor's hybrid
+// vsock. A guest connecting to port P of the host makes cloud-hypervisor dial
+// the unix socket "<vsock socket>_P", on which the server listens per machine.
+//
+// The server also listens on a link-local address, such as the 169.254.169.254
+// of cloud platforms, and identifies the machine by the tap network interface
+// the request came in on.
+type Server struct {
+	log logr.Logger
+
+	machines         store.Store[*api.Machine]
+	machineEvents    event.Source[*api.Machine]
+	paths            host.Paths
+	port             uint32
+	linkLocalAddress string
+	neighbors        neighborLookup
+
+	mu        sync.Mutex
+	listeners map[string]*http.Server
+}
+
+func NewServer(
+	log logr.Logger,
+	machines store.Store[*api.Machine],
+	machineEvents event.Source[*api.Machine],
+	paths host.Paths,
+	opts Options,
+) (*Server, error) {
+	if machines == nil {
+		return nil, fmt.Errorf("must specify machine store")
+	}
+	if machineEvents == nil {
+		return nil, fmt.Errorf("must specify machine events")
+	}
+	if opts.Port == 0 && opts.LinkLocalAddress == "" {
+		return nil, fmt.Errorf("must specify vsock port or link-local address")
+	}
+
+	return &Server{
+		log:              log,
+		machines:         machines,
+		machineEvents:    machineEvents,
+		paths:            paths,
+		port:             opts.Port,
+		linkLocalAddress: opts.LinkLocalAddress,
+		neighbors:        arpTableNeighbor,
+		listeners:        map[string]*http.Server{},
+	}, nil
+}
+
+func (s *Server) SocketPath(machineID string) string {
+	return fmt.Sprintf("%s_%d", s.paths.MachineVsockFile(machineID), s.port)
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	if s.linkLocalAddress != "" {
+		l, err := net.Listen("tcp", s.linkLocalAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", s.linkLocalAddress, err)
+		}
+		srv := &http.Server{
+			Handler:           s.handler(s.machineByRemoteAddr),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		s.log.V(1).Info("Serving metadata", "address", s.linkLocalAddress)
+		go func() {
+			if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.log.Error(err, "failed to serve metadata", "address", s.linkLocalAddress)
+			}
+		}()
+		defer func() { _ = srv.Close() }()
+	}
+	if s.port == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	registration, err := s.machineEvents.AddHandler(event.HandlerFunc[*api.Machine](func(evt event.Event[*api.Machine]) {
+		machine := evt.Object
+		if evt.Type == event.TypeDeleted || machine.DeletedAt != nil {
+			s.stopListener(machine.ID)
+			return
+		}
+		if err := s.ensureListener(machine.ID); err != nil {
+			s.log.Error(err, "failed to start metadata listener", "machineID", machine.ID)
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.machineEvents.RemoveHandler(registration); err != nil {
+			s.log.Error(err, "failed to remove machine event handler")
+		}
+	}()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, srv := range s.listeners {
+		_ = srv.Close()
+		delete(s.listeners, id)
+	}
+	return nil
+}
+
+func (s *Server) ensureListener(machineID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.listeners[machineID]; ok {
+		return nil
+	}
+
+	socketPath := s.SocketPath(machineID)
+	if err := os.MkdirAll(filepath.Dir(socketPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create socket dir: %w", err)
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+
+	srv := &http.Server{
+		Handler: s.handler(func(r *http.Request) (*api.Machine, error) {
+			return s.machines.Get(r.Context(), machineID)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.listeners[machineID] = srv
+
+	log := s.log.WithValues("machineID", machineID)
+	log.V(1).Info("Serving metadata", "socket", socketPath)
+	go func() {
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(err, "failed to serve metadata")
+		}
+	}()
+	return nil
+}
+
+func (s *Server) stopListener(machineID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	srv, ok := s.listeners[machineID]
+	if !ok {
+		return
+	}
+	_ = srv.Close()
+	delete(s.listeners, machineID)
+	s.log.V(1).Info("Stopped serving metadata", "machineID", machineID)
+}
+
+type NetworkInterface struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips,omitempty"`
+}
+
+type MetaData struct {
+	ID                string             `json:"id"`
+	Hostname          string             `json:"hostname"`
+	Labels            map[string]string  `json:"labels,omitempty"`
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+	DNS               *api.DNSSpec       `json:"dns,omitempty"`
+}
+
+// machineLookup returns the machine a request was made by.
+type machineLookup func(r *http.Request) (*api.Machine, error)
+
+func (s *Server) handler(lookup machineLookup) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/meta-data", func(w http.ResponseWriter, r *http.Request) {
+		machine, ok := s.getMachine(w, r, lookup)
+		if !ok {
+			return
+		}
+
+		labels, _ := api.GetLabelsAnnotation(machine.Metadata)
+		md := MetaData{
+			ID:                machine.ID,
+			Hostname:          hostname(machine),
+			Labels:            labels,
+			NetworkInterfaces: networkInterfaces(machine),
+			DNS:               machine.Spec.DNS,
+		}
+		writeJSON(w, md)
+	})
+	mux.HandleFunc("GET /v1/network", func(w http.ResponseWriter, r *http.Request) {
+		machine, ok := s.getMachine(w, r, lookup)
+		if !ok {
+			return
+		}
+		writeJSON(w, networkInterfaces(machine))
+	})
+	mux.HandleFunc("GET /v1/user-data", func(w http.ResponseWriter, r *http.Request) {
+		machine, ok := s.getMachine(w, r, lookup)
+		if !ok {
+			return
+		}
+		writeUserData(w, machine)
+	})
+	mux.HandleFunc("GET /v1/opaque-user-data", func(w http.ResponseWriter, r *http.Request) {
+		machine, ok := s.getMachine(w, r, lookup)
+		if !ok {
+			return
+		}
+
+		if machine.Spec.OpaqueUserData == nil {
+			http.Error(w, "no opaque user data", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(machine.Spec.OpaqueUserData)
+	})
+	s.addOpenStackHandlers(mux, lookup)
+	return mux
+}
+
+func (s *Server) getMachine(w http.ResponseWriter, r *http.Request, lookup machineLookup) (*api.Machine, bool) {
+	machine, err := lookup(r)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "machine not found", http.StatusNotFound)
+			return nil, false
+		}
+		if errors.Is(err, errAmbiguousSource) {
+			s.log.Info("Rejected metadata request matching several machines", "remoteAddr", r.RemoteAddr)
+			http.Error(w, "ambiguous source address", http.StatusForbidden)
+			return nil, false
+		}
+		s.log.Error(err, "failed to get machine", "remoteAddr", r.RemoteAddr)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return machine, true
+}
+
+// machineByRemoteAddr returns the machine with the tap network interface the request came in on. The network
+// interface is identified by the MAC address the host resolved the source address to and the address leased to
+// it, its firewall drops the frames of the guest with other source addresses. Spec IPs are not considered, they
+// may overlap across networks.
+func (s *Server) machineByRemoteAddr(r *http.Request) (*api.Machine, error) {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote address %q: %w", r.RemoteAddr, err)
+	}
+	addr := addrPort.Addr().Unmap()
+
+	mac, err := s.neighbors(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	machines, err := s.machines.List(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+	var found *api.Machine
+	for _, machine := range machines {
+		if machine.DeletedAt != nil {
+			continue
+		}
+		for _, nic := range machine.Status.NetworkInterfaceStatus {
+			if !isSourceNetworkInterface(nic, addr, mac) {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("%w: %s (%s)", errAmbiguousSource, addr, mac)
+			}
+			found = machine
+		}
+	}
+	if found == nil {
+		return nil, store.ErrNotFound
+	}
+	return found, nil
+}
+
+// isSourceNetworkInterface reports whether a request from addr with the MAC address mac came in on the network
+// interface.
+func isSourceNetworkInterface(nic api.NetworkInterfaceStatus, addr netip.Addr, mac net.HardwareAddr) bool {
+	if nic.Type != api.NetworkInterfaceTAPType || !strings.EqualFold(nic.MAC, mac.String()) {
+		return false
+	}
+	return slices.ContainsFunc(nic.IPs, func(ip string) bool {
+		nicAddr, err := netip.ParseAddr(ip)
+		return err == nil && nicAddr.Unmap() == addr
+	})
+}
+
+func hostname(machine *api.Machine) string {
+	if machine.Spec.Hostname != "" {
+		return machine.Spec.Hostname
+	}
+	return machine.ID
+}
+
+// writeUserData writes the cloud-init user-data of the machine if set, otherwise its ignition payload.
+func writeUserData(w http.ResponseWriter, machine *api.Machine) {
+	var userData []byte
+	switch {
+	case machine.Spec.CloudInit != nil:
+		userData = machine.Spec.CloudInit.UserData
+	default:
+		userData = machine.Spec.Ignition
+	}
+	if userData == nil {
+		http.Error(w, "no user data", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(userData)
+}
+
+func networkInterfaces(machine *api.Machine) []NetworkInterface {
+	var nics []NetworkInterface
+	for _, nic := range machine.Spec.NetworkInterfaces {
+		if nic.DeletedAt != nil {
+			continue
+		}
+		nics = append(nics, NetworkInterface{Name: nic.Name, IPs: nic.Ips})
+	}
+	return nics
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
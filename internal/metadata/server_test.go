@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/strategy"
+	apiutils "github.com/ironcore-dev/provider-utils/apiutils/api"
+	hostutils "github.com/ironcore-dev/provider-utils/storeutils/host"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Link-local listener", func() {
+	var (
+		machineStore *hostutils.Store[*api.Machine]
+		neighbors    map[netip.Addr]string
+		srv          *Server
+	)
+
+	BeforeEach(func() {
+		var err error
+		machineStore, err = hostutils.NewStore[*api.Machine](hostutils.Options[*api.Machine]{
+			Dir:            GinkgoT().TempDir(),
+			NewFunc:        func() *api.Machine { return &api.Machine{} },
+			CreateStrategy: strategy.MachineStrategy,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		neighbors = map[netip.Addr]string{}
+		srv = &Server{
+			log:      logr.Discard(),
+			machines: machineStore,
+			neighbors: func(addr netip.Addr) (net.HardwareAddr, error) {
+				mac, ok := neighbors[addr]
+				if !ok {
+					return nil, store.ErrNotFound
+				}
+				return net.ParseMAC(mac)
+			},
+		}
+	})
+
+	createMachine := func(ctx SpecContext, id, mac, ip string) {
+		GinkgoHelper()
+		machine, err := machineStore.Create(ctx, &api.Machine{
+			Metadata: apiutils.Metadata{ID: id},
+			Spec: api.MachineSpec{
+				NetworkInterfaces: []*api.NetworkInterfaceSpec{{Name: "nic", Ips: []string{ip}}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		machine.Status.NetworkInterfaceStatus = []api.NetworkInterfaceStatus{{
+			Name: "nic",
+			Type: api.NetworkInterfaceTAPType,
+			MAC:  mac,
+			IPs:  []string{ip},
+		}}
+		_, err = machineStore.Update(ctx, machine)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		GinkgoHelper()
+		req := httptest.NewRequest(http.MethodGet, "/openstack/latest/meta_data.json", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		srv.handler(srv.machineByRemoteAddr).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("should identify the machine by the network interface of the source address", func(ctx SpecContext) {
+		By("creating machines with overlapping IPs on different network interfaces")
+		createMachine(ctx, "machine-a", "02:00:00:00:00:0a", "10.0.0.2")
+		createMachine(ctx, "machine-b", "02:00:00:00:00:0b", "10.0.0.2")
+		neighbors[netip.MustParseAddr("10.0.0.2")] = "02:00:00:00:00:0b"
+
+		By("requesting the meta data")
+		rec := get("10.0.0.2:40000")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var md OpenStackMetaData
+		Expect(json.Unmarshal(rec.Body.Bytes(), &md)).To(Succeed())
+		Expect(md.UUID).To(Equal("machine-b"))
+	})
+
+	It("should reject requests matching several machines", func(ctx SpecContext) {
+		By("creating machines with the same MAC and IP")
+		createMachine(ctx, "machine-a", "02:00:00:00:00:0a", "10.0.0.2")
+		createMachine(ctx, "machine-b", "02:00:00:00:00:0a", "10.0.0.2")
+		neighbors[netip.MustParseAddr("10.0.0.2")] = "02:00:00:00:00:0a"
+
+		Expect(get("10.0.0.2:40000").Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("should not serve requests from unknown network interfaces", func(ctx SpecContext) {
+		createMachine(ctx, "machine-a", "02:00:00:00:00:0a", "10.0.0.2")
+
+		By("requesting from an address not resolved by the host")
+		Expect(get("10.0.0.2:40000").Code).To(Equal(http.StatusNotFound))
+
+		By("requesting from the address of the machine with another MAC")
+		neighbors[netip.MustParseAddr("10.0.0.2")] = "02:00:00:00:00:0c"
+		Expect(get("10.0.0.2:40000").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package migration
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned if the destination already has a machine with the same id.
+var ErrConflict = errors.New("machine already exists on the destination")
+
+// Client calls the migration api of a destination provider.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+func NewClient(baseURL string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination %q: %w", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid destination %q: scheme must be http or https", baseURL)
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			Timeout:   30 * time.Second,
+		},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// Start registers the machine on the destination.
+func (c *Client) Start(ctx context.Context, req Request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/v1/migrations", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return ErrConflict
+	default:
+		return responseError(resp)
+	}
+}
+
+// Status returns the state of the migration of the machine on the destination.
+func (c *Client) Status(ctx context.Context, machineID string) (*Status, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/migrations/"+url.PathEscape(machineID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	status := &Status{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, fmt.Errorf("failed to decode migration status: %w", err)
+	}
+	return status, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call migration api: %w", err)
+	}
+	return resp, nil
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("migration api returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package migration coordinates the hand-off of machines between the providers of two hosts. The
+// destination serves an api the source registers the machine with. Once the destination prepared the
+// machine and listens for the VM, the source sends it via cloud-hypervisor's live migration.
+package migration
+
+import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+)
+
+// Request registers a machine migrated to the destination.
+type Request struct {
+	Machine    *api.Machine `json:"machine"`
+	SourceHost string       `json:"sourceHost"`
+}
+
+// Status is the state of a migration on the destination.
+type Status struct {
+	State       api.MigrationState `json:"state"`
+	ReceiverURL string             `json:"receiverUrl,omitempty"`
+	Message     string             `json:"message,omitempty"`
+}
+
+// StatusOf returns the migration status recorded in the annotations of a machine.
+func StatusOf(machine *api.Machine) Status {
+	return Status{
+		State:       api.MigrationState(machine.Annotations[api.MigrationStateAnnotation]),
+		ReceiverURL: machine.Annotations[api.MigrationReceiverAnnotation],
+		Message:     machine.Annotations[api.MigrationMessageAnnotation],
+	}
+}
+
+// SetStatus records the migration status in the annotations of a machine.
+func SetStatus(machine *api.Machine, status Status) {
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	setOrDelete(machine.Annotations, api.MigrationStateAnnotation, string(status.State))
+	setOrDelete(machine.Annotations, api.MigrationReceiverAnnotation, status.ReceiverURL)
+	setOrDelete(machine.Annotations, api.MigrationMessageAnnotation, status.Message)
+}
+
+func setOrDelete(m map[string]string, key, value string) {
+	if value == "" {
+		delete(m, key)
+		return
+	}
+	m[key] = value
+}
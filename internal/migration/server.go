@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package migration
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+)
+
+type Options struct {
+	// BindAddress is the tcp address the migration api listens on.
+	BindAddress string
+	// TLSConfig secures the migration api. It is served without TLS if nil.
+	TLSConfig *tls.Config
+	// Maintenance rejects migrations while the host is in maintenance.
+	Maintenance *maintenance.Mode
+}
+
+// Server is the migration api of the destination. It registers migrated machines in the machine store,
+// the machine reconciler prepares them and receives their VM.
+type Server struct {
+	log         logr.Logger
+	machines    store.Store[*api.Machine]
+	bindAddress string
+	tlsConfig   *tls.Config
+	maintenance *maintenance.Mode
+}
+
+func NewServer(log logr.Logger, machines store.Store[*api.Machine], opts Options) (*Server, error) {
+	if machines == nil {
+		return nil, fmt.Errorf("must specify machine store")
+	}
+	if opts.BindAddress == "" {
+		return nil, fmt.Errorf("must specify bind address")
+	}
+
+	return &Server{
+		log:         log,
+		machines:    machines,
+		bindAddress: opts.BindAddress,
+		tlsConfig:   opts.TLSConfig,
+		maintenance: opts.Maintenance,
+	}, nil
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/migrations", s.createMigration)
+	mux.HandleFunc("GET /v1/migrations/{id}", s.getMigration)
+	return mux
+}
+
+func (s *Server) createMigration(w http.ResponseWriter, req *http.Request) {
+	if s.maintenance.Enabled() {
+		http.Error(w, "host is in maintenance", http.StatusServiceUnavailable)
+		return
+	}
+
+	var migrationReq Request
+	if err := json.NewDecoder(req.Body).Decode(&migrationReq); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if migrationReq.Machine == nil || migrationReq.Machine.ID == "" {
+		http.Error(w, "invalid request: machine is missing", http.StatusBadRequest)
+		return
+	}
+
+	machine := incomingMachine(migrationReq.Machine, migrationReq.SourceHost)
+	log := s.log.WithValues("machineID", machine.ID, "source", migrationReq.SourceHost)
+
+	if _, err := s.machines.Create(req.Context(), machine); err != nil {
+		if errors.Is(err, store.ErrAlreadyExists) {
+			http.Error(w, fmt.Sprintf("machine %s already exists", machine.ID), http.StatusConflict)
+			return
+		}
+		log.Error(err, "Failed to create migrated machine")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Registered migrated machine")
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) getMigration(w http.ResponseWriter, req *http.Request) {
+	machineID := req.PathValue("id")
+
+	machine, err := s.machines.Get(req.Context(), machineID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("migration of machine %s not found", machineID), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, ok := machine.Annotations[api.MigrationSourceAnnotation]; !ok {
+		http.Error(w, fmt.Sprintf("migration of machine %s not found", machineID), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(StatusOf(machine))
+}
+
+// incomingMachine returns the machine to create on the destination. Host specific state of the source is
+// dropped, the machine is prepared again on this host.
+func incomingMachine(source *api.Machine, sourceHost string) *api.Machine {
+	machine := &api.Machine{}
+	machine.ID = source.ID
+	machine.Labels = source.Labels
+	machine.Annotations = map[string]string{}
+	for key, value := range source.Annotations {
+		switch key {
+		case api.MigrationDestinationAnnotation, api.MigrationStateAnnotation,
+			api.MigrationReceiverAnnotation, api.MigrationMessageAnnotation:
+			continue
+		}
+		machine.Annotations[key] = value
+	}
+	machine.Annotations[api.MigrationSourceAnnotation] = sourceHost
+	SetStatus(machine, Status{State: api.MigrationStatePending})
+
+	machine.Spec = source.Spec
+	machine.Spec.ApiSocketPath = nil
+	return machine
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.bindAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.bindAddress, err)
+	}
+	if s.tlsConfig != nil {
+		l = tls.NewListener(l, s.tlsConfig)
+	} else {
+		s.log.Info("Serving migration api without TLS, machine specs including volume secrets are sent in clear text")
+	}
+
+	srv := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.log.Info("Serving migration api", "address", s.bindAddress)
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving migration api: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package migration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configure mutual TLS between the migration api and its clients. TLS is disabled if CertFile is
+// empty.
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the peer's certificate.
+	CAFile string
+}
+
+func (o TLSOptions) enabled() bool {
+	return o.CertFile != ""
+}
+
+func (o TLSOptions) load() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	data, err := os.ReadFile(o.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to read ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return tls.Certificate{}, nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+	}
+	return cert, pool, nil
+}
+
+// ServerTLSConfig returns the TLS config of the migration api, requiring client certificates signed by the
+// CA. It returns nil if TLS is disabled.
+func ServerTLSConfig(o TLSOptions) (*tls.Config, error) {
+	if !o.enabled() {
+		return nil, nil
+	}
+
+	cert, pool, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLSConfig returns the TLS config of migration api clients. It returns nil if TLS is disabled.
+func ClientTLSConfig(o TLSOptions) (*tls.Config, error) {
+	if !o.enabled() {
+		return nil, nil
+	}
+
+	cert, pool, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,319 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/platforms"
+	"github.com/go-logr/logr"
+	ironcoreimage "github.com/ironcore-dev/ironcore-image"
+	"github.com/ironcore-dev/ironcore-image/oci/remote"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	"github.com/opencontainers/go-digest"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Suffixes of the media types of compressed rootfs layers, the disks provisioned from them are decompressed
+// while copying.
+const (
+	gzipSuffix = "+gzip"
+	zstdSuffix = "+zstd"
+)
+
+const (
+	// DefaultContainerdAddress is the socket of containerd on most hosts.
+	DefaultContainerdAddress = "/run/containerd/containerd.sock"
+	// DefaultContainerdNamespace keeps the images of the provider apart from the ones of other containerd clients.
+	DefaultContainerdNamespace = "cloud-hypervisor-provider"
+	// DefaultContainerdContentDir is the root of the content store of containerd with its default config.
+	DefaultContainerdContentDir = "/var/lib/containerd/io.containerd.content.v1.content"
+)
+
+// errContentMissing is returned for images whose record is present, but some of whose content is missing or
+// unreadable. The image is pulled again.
+var errContentMissing = errors.New("content of image is missing")
+
+// ContainerdOptions locate containerd and its content store.
+type ContainerdOptions struct {
+	// Address is the socket of containerd.
+	Address string
+	// Namespace is the containerd namespace the images are pulled into.
+	Namespace string
+	// ContentDir is the root of the content store of containerd. Its blobs are only read, to hand the paths of the
+	// layers to the disk provisioning.
+	ContentDir string
+}
+
+// ContentStoreCache is an image cache backed by containerd. Images are pulled by containerd into its content
+// store, each ref is an image record in the namespace of the provider. The records and the leases of running
+// pulls protect the content from the garbage collection of containerd, so that the store is shared with the
+// other clients of containerd on the host.
+type ContentStoreCache struct {
+	log logr.Logger
+
+	client   *containerd.Client
+	root     string
+	platform ocispecv1.Platform
+	resolver remotes.Resolver
+
+	mu              sync.Mutex
+	running         bool
+	pulling         sets.Set[string]
+	failures        map[string]*pullFailure
+	listeners       []ociutils.Listener
+	failedListeners []func(evt PullFailedEvent)
+}
+
+func NewContentStoreCache(
+	log logr.Logger,
+	opts ContainerdOptions,
+	platform *ocispecv1.Platform,
+) (*ContentStoreCache, error) {
+	if platform == nil {
+		return nil, fmt.Errorf("must specify platform")
+	}
+
+	client, err := containerd.New(opts.Address, containerd.WithDefaultNamespace(opts.Namespace))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to containerd at %s: %w", opts.Address, err)
+	}
+
+	credFunc, err := remote.DockerCredentialFunc("")
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("error creating credential function: %w", err)
+	}
+
+	return &ContentStoreCache{
+		log:      log,
+		client:   client,
+		root:     opts.ContentDir,
+		platform: platforms.Normalize(*platform),
+		resolver: docker.NewResolver(docker.ResolverOptions{Credentials: credFunc}),
+		pulling:  sets.New[string](),
+		failures: map[string]*pullFailure{},
+	}, nil
+}
+
+func (c *ContentStoreCache) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return fmt.Errorf("already running")
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	<-ctx.Done()
+
+	c.mu.Lock()
+	c.running = false
+	c.mu.Unlock()
+	return c.client.Close()
+}
+
+func (c *ContentStoreCache) AddListener(listener ociutils.Listener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *ContentStoreCache) AddPullFailedListener(listener func(evt PullFailedEvent)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failedListeners = append(c.failedListeners, listener)
+}
+
+func (c *ContentStoreCache) Get(ctx context.Context, ref string) (*ociutils.Image, error) {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("need to start cache first")
+	}
+	if c.pulling.Has(ref) {
+		c.mu.Unlock()
+		return nil, ociutils.ErrImagePulling
+	}
+	if failure, ok := c.failures[ref]; ok && time.Now().Before(failure.retryAt) {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w: %w", ErrImagePullFailed, failure.err)
+	}
+	c.mu.Unlock()
+
+	img, err := c.resolveImage(ctx, ref)
+	if err == nil {
+		return img, nil
+	}
+	if !errdefs.IsNotFound(err) && !errors.Is(err, errContentMissing) {
+		return nil, err
+	}
+	if errors.Is(err, errContentMissing) {
+		if err := c.dropUnreadableContent(ctx, ref, err); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	c.pulling.Insert(ref)
+	c.mu.Unlock()
+
+	go c.pull(context.WithoutCancel(ctx), ref)
+	return nil, ociutils.ErrImagePulling
+}
+
+func (c *ContentStoreCache) pull(ctx context.Context, ref string) {
+	log := c.log.WithValues("Ref", ref)
+
+	log.V(1).Info("Start pulling")
+	err := c.pullRef(ctx, ref)
+
+	c.mu.Lock()
+	c.pulling.Delete(ref)
+	if err != nil {
+		c.failures[ref] = nextPullFailure(c.failures[ref], err)
+	} else {
+		delete(c.failures, ref)
+	}
+	listeners, failedListeners := c.listeners, c.failedListeners
+	c.mu.Unlock()
+
+	if err != nil {
+		log.Error(err, "Error pulling image")
+		for _, listener := range failedListeners {
+			listener(PullFailedEvent{Ref: ref, Err: err})
+		}
+		return
+	}
+
+	log.V(1).Info("Successfully pulled")
+	for _, listener := range listeners {
+		listener.HandlePullDone(ociutils.PullDoneEvent{Ref: ref})
+	}
+}
+
+// pullRef lets containerd pull the manifest of the host platform of the image with its config and layers. The
+// image record is created once all content is present, the lease of the pull protects it until then.
+func (c *ContentStoreCache) pullRef(ctx context.Context, ref string) error {
+	img, err := c.client.Fetch(setupMediaTypeKeyPrefixes(ctx), ref,
+		containerd.WithResolver(c.resolver),
+		containerd.WithPlatformMatcher(platforms.Only(c.platform)),
+	)
+	if err != nil {
+		return fmt.Errorf("error pulling %s: %w", ref, err)
+	}
+
+	if _, err := images.Manifest(ctx, c.client.ContentStore(), img.Target, platforms.Only(c.platform)); err != nil {
+		if !errdefs.IsNotFound(err) {
+			return fmt.Errorf("error reading manifest of %s: %w", ref, err)
+		}
+		// The index was pulled without any manifest, the record must not pretend the image is present.
+		if err := c.client.ImageService().Delete(ctx, ref); err != nil && !errdefs.IsNotFound(err) {
+			c.log.V(1).Info("Failed to delete image without matching platform", "Ref", ref, "Error", err.Error())
+		}
+		return fmt.Errorf("%w: image %s has no manifest for %s",
+			ErrNoMatchingPlatform, ref, platforms.Format(c.platform))
+	}
+	return nil
+}
+
+func (c *ContentStoreCache) readJSON(ctx context.Context, desc ocispecv1.Descriptor, v any) error {
+	data, err := content.ReadBlob(ctx, c.client.ContentStore(), desc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *ContentStoreCache) blobPath(dgst digest.Digest) string {
+	return filepath.Join(c.root, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+}
+
+// dropUnreadableContent deletes the record of an image whose content is unreadable, collecting the content that
+// is not referenced otherwise, so that the next pull fetches it again. Content that is only missing is fetched by
+// the pull anyway.
+func (c *ContentStoreCache) dropUnreadableContent(ctx context.Context, ref string, cause error) error {
+	if errdefs.IsNotFound(cause) {
+		return nil
+	}
+	c.log.V(1).Info("Content of image is unreadable, pulling it again", "Ref", ref, "Error", cause.Error())
+	if err := c.client.ImageService().Delete(ctx, ref, images.SynchronousDelete()); err != nil &&
+		!errdefs.IsNotFound(err) {
+		return fmt.Errorf("error deleting image %s with unreadable content: %w", ref, err)
+	}
+	return nil
+}
+
+// resolveImage returns the image of the ref if its record and all of its content are present. The error satisfies
+// errdefs.IsNotFound without record and wraps errContentMissing if content is missing or unreadable.
+func (c *ContentStoreCache) resolveImage(ctx context.Context, ref string) (*ociutils.Image, error) {
+	record, err := c.client.ImageService().Get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	store := c.client.ContentStore()
+	manifest, err := images.Manifest(ctx, store, record.Target, platforms.Only(c.platform))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading manifest: %w", errContentMissing, err)
+	}
+
+	var config ironcoreimage.Config
+	if err := c.readJSON(ctx, manifest.Config, &config); err != nil {
+		return nil, fmt.Errorf("%w: error reading config: %w", errContentMissing, err)
+	}
+
+	img := &ociutils.Image{Config: config}
+	for _, layer := range manifest.Layers {
+		if _, err := store.Info(ctx, layer.Digest); err != nil {
+			return nil, fmt.Errorf("%w: error reading layer %s: %w", errContentMissing, layer.Digest, err)
+		}
+
+		fileLayer := &ociutils.FileLayer{Descriptor: layer, Path: c.blobPath(layer.Digest)}
+		switch layer.MediaType {
+		case ironcoreimage.RootFSLayerMediaType, ironcoreimage.SquashFSLayerMediaType,
+			ironcoreimage.RootFSLayerMediaType + gzipSuffix, ironcoreimage.RootFSLayerMediaType + zstdSuffix:
+			img.RootFS = fileLayer
+		case ironcoreimage.KernelLayerMediaType:
+			img.Kernel = fileLayer
+		case ironcoreimage.InitRAMFSLayerMediaType:
+			img.InitRAMFs = fileLayer
+		}
+	}
+
+	if img.RootFS == nil {
+		return nil, fmt.Errorf("incomplete oci: components are missing: [%s]", ociutils.RootFSLayer)
+	}
+	return img, nil
+}
+
+func setupMediaTypeKeyPrefixes(ctx context.Context) context.Context {
+	mediaTypeToPrefix := map[string]string{
+		ironcoreimage.ConfigMediaType:         "config",
+		ironcoreimage.InitRAMFSLayerMediaType: "layer",
+		ironcoreimage.KernelLayerMediaType:    "layer",
+		ironcoreimage.RootFSLayerMediaType:    "layer",
+		ironcoreimage.SquashFSLayerMediaType:  "layer",
+
+		ironcoreimage.RootFSLayerMediaType + gzipSuffix: "layer",
+		ironcoreimage.RootFSLayerMediaType + zstdSuffix: "layer",
+	}
+	for mediaType, prefix := range mediaTypeToPrefix {
+		ctx = remotes.WithMediaTypeKeyPrefix(ctx, mediaType, prefix)
+	}
+	return ctx
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/platforms"
+	"github.com/ironcore-dev/ironcore-image/oci/remote"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var ErrNoMatchingPlatform = errors.New("no matching platform")
+
+const (
+	// platformCheckTTL is how long an image found to provide the host platform is not checked again.
+	platformCheckTTL = time.Hour
+	// noMatchingPlatformTTL is how long an image without the host platform is rejected, its tag may gain the
+	// platform later.
+	noMatchingPlatformTTL = 5 * time.Minute
+)
+
+// platformCheck is the result of a check of the platforms of an image.
+type platformCheck struct {
+	err       error
+	expiresAt time.Time
+	// backOff is the time a registry error is kept, doubled by each subsequent error.
+	backOff time.Duration
+}
+
+// PlatformCache checks that an image provides a manifest for the host platform
+// before handing it over to the underlying cache. Images without an index are
+// assumed to be compatible.
+type PlatformCache struct {
+	ociutils.Cache
+
+	platform ocispecv1.Platform
+	resolver remotes.Resolver
+
+	mu      sync.Mutex
+	checked map[string]platformCheck
+}
+
+func NewPlatformCache(cache ociutils.Cache, platform *ocispecv1.Platform) (*PlatformCache, error) {
+	if platform == nil {
+		return nil, fmt.Errorf("must specify platform")
+	}
+
+	credFunc, err := remote.DockerCredentialFunc("")
+	if err != nil {
+		return nil, fmt.Errorf("error creating credential function: %w", err)
+	}
+
+	return &PlatformCache{
+		Cache:    cache,
+		platform: platforms.Normalize(*platform),
+		resolver: docker.NewResolver(docker.ResolverOptions{Credentials: credFunc}),
+		checked:  map[string]platformCheck{},
+	}, nil
+}
+
+func (c *PlatformCache) Get(ctx context.Context, ref string) (*ociutils.Image, error) {
+	resolveErr := c.checkPlatform(ctx, ref)
+	if errors.Is(resolveErr, ErrNoMatchingPlatform) {
+		return nil, resolveErr
+	}
+
+	img, err := c.Cache.Get(ctx, ref)
+	if resolveErr != nil && errors.Is(err, ociutils.ErrImagePulling) {
+		// The image is not cached and the registry failed to resolve it, its pull fails for the same reason.
+		return nil, fmt.Errorf("%w: %w", ErrImagePullFailed, resolveErr)
+	}
+	return img, err
+}
+
+// AddPullFailedListener registers the listener with the underlying cache if it reports failed pulls.
+func (c *PlatformCache) AddPullFailedListener(listener func(evt PullFailedEvent)) {
+	if notifier, ok := c.Cache.(PullFailureNotifier); ok {
+		notifier.AddPullFailedListener(listener)
+	}
+}
+
+func (c *PlatformCache) checkPlatform(ctx context.Context, ref string) error {
+	now := time.Now()
+	c.mu.Lock()
+	check, ok := c.checked[ref]
+	c.mu.Unlock()
+	if ok && now.Before(check.expiresAt) {
+		return check.err
+	}
+
+	err := c.resolvePlatform(ctx, ref)
+	var backOff time.Duration
+	ttl := platformCheckTTL
+	switch {
+	case errors.Is(err, ErrNoMatchingPlatform):
+		ttl = noMatchingPlatformTTL
+	case err != nil:
+		// Registry errors are transient, but the registry is not asked again for every machine using the image
+		// while it is unreachable.
+		backOff = initialPullBackOff
+		if ok && check.backOff > 0 {
+			backOff = min(2*check.backOff, maxPullBackOff)
+		}
+		ttl = backOff
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for checkedRef, check := range c.checked {
+		if !now.Before(check.expiresAt) {
+			delete(c.checked, checkedRef)
+		}
+	}
+	c.checked[ref] = platformCheck{err: err, expiresAt: now.Add(ttl), backOff: backOff}
+	return err
+}
+
+func (c *PlatformCache) resolvePlatform(ctx context.Context, ref string) error {
+	_, desc, err := c.resolver.Resolve(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", ref, err)
+	}
+
+	if desc.MediaType != ocispecv1.MediaTypeImageIndex {
+		return nil
+	}
+
+	fetcher, err := c.resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("error getting fetcher for %s: %w", ref, err)
+	}
+
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("error fetching index of %s: %w", ref, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	var index ocispecv1.Index
+	if err := json.NewDecoder(rc).Decode(&index); err != nil {
+		return fmt.Errorf("error decoding index of %s: %w", ref, err)
+	}
+
+	matcher := platforms.Only(c.platform)
+	var available []string
+	for _, manifest := range index.Manifests {
+		if manifest.Platform == nil {
+			continue
+		}
+		if matcher.Match(*manifest.Platform) {
+			return nil
+		}
+		available = append(available, platforms.Format(*manifest.Platform))
+	}
+
+	return fmt.Errorf("%w: image %s has no manifest for %s (available: %v)",
+		ErrNoMatchingPlatform, ref, platforms.Format(c.platform), available)
+}
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// prefetchAttempts is how many pulls of an image may fail before it is given up.
+	prefetchAttempts = 5
+	// prefetchRetryInterval is the delay before the second attempt, it doubles with every further attempt.
+	prefetchRetryInterval = 15 * time.Second
+)
+
+// Prefetcher pre-pulls a set of images into the image cache so that the first
+// machines scheduled on a fresh host do not have to wait for their image.
+type Prefetcher struct {
+	log   logr.Logger
+	cache ociutils.Cache
+	refs  []string
+
+	mu sync.Mutex
+	// pending are the images that are neither present nor given up.
+	pending sets.Set[string]
+	// ready are the pending images whose pull finished or whose retry is due.
+	ready sets.Set[string]
+	// failures are the failed pulls by image, an image is in it once its pull was started.
+	failures map[string]int
+	// readyCh is signalled whenever an image is added to ready.
+	readyCh chan struct{}
+}
+
+func NewPrefetcher(log logr.Logger, cache ociutils.Cache, refs []string) *Prefetcher {
+	return &Prefetcher{
+		log:      log,
+		cache:    cache,
+		refs:     refs,
+		pending:  sets.New[string](),
+		ready:    sets.New[string](),
+		failures: map[string]int{},
+		readyCh:  make(chan struct{}, 1),
+	}
+}
+
+// done marks the pull of the image as finished, whether it succeeded or not.
+func (p *Prefetcher) done(ref string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.pending.Has(ref) {
+		return
+	}
+	p.ready.Insert(ref)
+	select {
+	case p.readyCh <- struct{}{}:
+	default:
+	}
+}
+
+// Start triggers the pull of all configured images and blocks until they are
+// present in the cache, were given up after prefetchAttempts failed pulls, or
+// the context is cancelled.
+func (p *Prefetcher) Start(ctx context.Context) error {
+	if len(p.refs) == 0 {
+		return nil
+	}
+
+	p.cache.AddListener(ociutils.ListenerFuncs{
+		HandlePullDoneFunc: func(evt ociutils.PullDoneEvent) {
+			p.done(evt.Ref)
+		},
+	})
+	if notifier, ok := p.cache.(PullFailureNotifier); ok {
+		notifier.AddPullFailedListener(func(evt PullFailedEvent) {
+			p.done(evt.Ref)
+		})
+	}
+
+	p.mu.Lock()
+	for _, ref := range p.refs {
+		p.pending.Insert(ref)
+		p.ready.Insert(ref)
+	}
+	p.mu.Unlock()
+
+	for {
+		p.mu.Lock()
+		remaining := p.pending.Len()
+		ready := p.ready.UnsortedList()
+		p.ready.Clear()
+		p.mu.Unlock()
+		if remaining == 0 {
+			p.log.Info("Finished prefetching images", "Count", len(p.refs))
+			return nil
+		}
+
+		for _, ref := range ready {
+			p.check(ctx, ref)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.readyCh:
+		}
+	}
+}
+
+// check gets the image from the cache, which starts its pull if it is missing. A failed pull is retried with
+// backoff until prefetchAttempts pulls failed.
+func (p *Prefetcher) check(ctx context.Context, ref string) {
+	_, err := p.cache.Get(ctx, ref)
+	if err == nil {
+		p.log.Info("Prefetched image", "Image", ref)
+		p.finish(ref)
+		return
+	}
+
+	p.mu.Lock()
+	failures, seen := p.failures[ref]
+	pulling := errors.Is(err, ociutils.ErrImagePulling)
+	if seen || !pulling {
+		failures++
+	}
+	p.failures[ref] = failures
+	p.mu.Unlock()
+
+	switch {
+	case pulling && !seen:
+		p.log.V(1).Info("Prefetching image", "Image", ref)
+		return
+	case pulling:
+		// Caches without failure events report a failed pull as done and pull again on the next Get.
+		err = errors.New("pull finished without the image")
+	}
+
+	if failures >= prefetchAttempts {
+		p.log.Error(err, "Failed to prefetch image, giving up", "Image", ref, "Attempts", failures)
+		p.finish(ref)
+		return
+	}
+	if pulling {
+		p.log.Info("Failed to prefetch image, retrying", "Image", ref, "Error", err.Error())
+		return
+	}
+
+	delay := prefetchRetryInterval << (failures - 1)
+	p.log.Info("Failed to prefetch image, retrying", "Image", ref, "Error", err.Error(), "After", delay)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+			p.done(ref)
+		}
+	}()
+}
+
+func (p *Prefetcher) finish(ref string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending.Delete(ref)
+	p.ready.Delete(ref)
+}
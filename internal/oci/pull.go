@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrImagePullFailed is returned for images whose last pull failed, wrapping the error of the pull.
+var ErrImagePullFailed = errors.New("image pull failed")
+
+const (
+	initialPullBackOff = 10 * time.Second
+	maxPullBackOff     = 5 * time.Minute
+)
+
+// PullFailedEvent is emitted when an image could not be pulled.
+type PullFailedEvent struct {
+	Ref string
+	Err error
+}
+
+// PullFailureNotifier is implemented by caches that report failed pulls. Caches only emit a PullDoneEvent for
+// successful pulls then.
+type PullFailureNotifier interface {
+	AddPullFailedListener(listener func(evt PullFailedEvent))
+}
+
+// pullFailure is the last failed pull of an image. The image is not pulled again before retryAt.
+type pullFailure struct {
+	err     error
+	retryAt time.Time
+	backOff time.Duration
+}
+
+// nextPullFailure returns the failure of a pull following the previous one, doubling the back-off.
+func nextPullFailure(previous *pullFailure, err error) *pullFailure {
+	backOff := initialPullBackOff
+	if previous != nil {
+		backOff = min(2*previous.backOff, maxPullBackOff)
+	}
+	return &pullFailure{
+		err:     err,
+		retryAt: time.Now().Add(backOff),
+		backOff: backOff,
+	}
+}
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	ironcoreimage "github.com/ironcore-dev/ironcore-image"
+	"github.com/ironcore-dev/ironcore-image/oci/remote"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Pusher pushes disks as ironcore images to registries, with the credentials images are pulled with.
+type Pusher struct {
+	resolver remotes.Resolver
+}
+
+func NewPusher() (*Pusher, error) {
+	credFunc, err := remote.DockerCredentialFunc("")
+	if err != nil {
+		return nil, fmt.Errorf("error creating credential function: %w", err)
+	}
+
+	return &Pusher{
+		resolver: docker.NewResolver(docker.ResolverOptions{Credentials: credFunc}),
+	}, nil
+}
+
+// StageDisk compresses the raw disk into layerFile as zstd compressed rootfs layer, so that the disk is not read
+// anymore while the layer is pushed. The file is removed if staging fails.
+func (p *Pusher) StageDisk(disk io.Reader, layerFile string) (*ociutils.FileLayer, error) {
+	rootFS, err := writeZstdLayer(disk, layerFile)
+	if err != nil {
+		_ = os.Remove(layerFile)
+		return nil, fmt.Errorf("error writing rootfs layer: %w", err)
+	}
+	return &ociutils.FileLayer{Descriptor: rootFS, Path: layerFile}, nil
+}
+
+// PushDisk pushes the rootfs layer staged by StageDisk as image to ref and removes its file afterwards. The kernel,
+// the initramfs and the config are taken from base if not nil, so that an image booted directly is captured with
+// its boot files. It returns the digest of the pushed manifest.
+func (p *Pusher) PushDisk(
+	ctx context.Context,
+	ref string,
+	rootFS *ociutils.FileLayer,
+	base *ociutils.Image,
+) (digest.Digest, error) {
+	ctx = setupMediaTypeKeyPrefixes(ctx)
+
+	defer func() {
+		_ = os.Remove(rootFS.Path)
+	}()
+
+	var config ironcoreimage.Config
+	layers := []*ociutils.FileLayer{rootFS}
+	if base != nil {
+		config = base.Config
+		for _, layer := range []*ociutils.FileLayer{base.Kernel, base.InitRAMFs} {
+			if layer != nil {
+				layers = append(layers, layer)
+			}
+		}
+	}
+
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("error encoding config: %w", err)
+	}
+	manifest := ocispecv1.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispecv1.MediaTypeImageManifest,
+		Config:    blobDescriptor(ironcoreimage.ConfigMediaType, configData),
+	}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, layer.Descriptor)
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("error encoding manifest: %w", err)
+	}
+	manifestDesc := blobDescriptor(ocispecv1.MediaTypeImageManifest, manifestData)
+
+	pusher, err := p.resolver.Pusher(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("error getting pusher for %s: %w", ref, err)
+	}
+	for _, layer := range layers {
+		if err := pushFile(ctx, pusher, layer); err != nil {
+			return "", err
+		}
+	}
+	if err := push(ctx, pusher, manifest.Config, bytes.NewReader(configData)); err != nil {
+		return "", err
+	}
+	// The manifest is pushed last, registries refuse manifests whose blobs are missing.
+	if err := push(ctx, pusher, manifestDesc, bytes.NewReader(manifestData)); err != nil {
+		return "", err
+	}
+	return manifestDesc.Digest, nil
+}
+
+// writeZstdLayer compresses the disk into the file and returns the descriptor of the layer.
+func writeZstdLayer(disk io.Reader, filename string) (ocispecv1.Descriptor, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return ocispecv1.Descriptor{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	digester := digest.Canonical.Digester()
+	counter := &countingWriter{w: io.MultiWriter(f, digester.Hash())}
+	enc, err := zstd.NewWriter(counter)
+	if err != nil {
+		return ocispecv1.Descriptor{}, err
+	}
+	if _, err := io.Copy(enc, disk); err != nil {
+		_ = enc.Close()
+		return ocispecv1.Descriptor{}, err
+	}
+	if err := enc.Close(); err != nil {
+		return ocispecv1.Descriptor{}, err
+	}
+	if err := f.Close(); err != nil {
+		return ocispecv1.Descriptor{}, err
+	}
+
+	return ocispecv1.Descriptor{
+		MediaType: ironcoreimage.RootFSLayerMediaType + zstdSuffix,
+		Digest:    digester.Digest(),
+		Size:      counter.n,
+	}, nil
+}
+
+func blobDescriptor(mediaType string, data []byte) ocispecv1.Descriptor {
+	return ocispecv1.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+}
+
+func pushFile(ctx context.Context, pusher remotes.Pusher, layer *ociutils.FileLayer) error {
+	f, err := os.Open(layer.Path)
+	if err != nil {
+		return fmt.Errorf("error opening blob %s: %w", layer.Descriptor.Digest, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return push(ctx, pusher, layer.Descriptor, f)
+}
+
+func push(ctx context.Context, pusher remotes.Pusher, desc ocispecv1.Descriptor, r io.Reader) error {
+	w, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("error pushing blob %s: %w", desc.Digest, err)
+	}
+	defer func() {
+		_ = w.Close()
+	}()
+
+	if err := content.Copy(ctx, w, r, desc.Size, desc.Digest); err != nil && !errdefs.IsAlreadyExists(err) {
+		return fmt.Errorf("error pushing blob %s: %w", desc.Digest, err)
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -24,6 +24,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -35,15 +36,24 @@ const (
 
 	filePerm     = 0666
 	pluginAPInet = "apinet"
+
+	// machineIDLabel maps the apinet network interfaces to their machine, since their names are hashes.
+	machineIDLabel = "cloud-hypervisor-provider.ironcore.dev/machine-id"
+	// networkInterfaceLabel selects the apinet network interface in the network policy of its firewall.
+	networkInterfaceLabel = "cloud-hypervisor-provider.ironcore.dev/network-interface"
+
+	watchRetryInterval = 5 * time.Second
 )
 
 type Plugin struct {
 	nodeName     string
 	host         host.Paths
-	apinetClient client.Client
+	apinetClient client.WithWatch
 }
 
-func NewPlugin(nodeName string, client client.Client) networkinterface.Plugin {
+var _ networkinterface.Watcher = (*Plugin)(nil)
+
+func NewPlugin(nodeName string, client client.WithWatch) networkinterface.Plugin {
 	return &Plugin{
 		nodeName:     nodeName,
 		apinetClient: client,
@@ -137,6 +147,10 @@ func (p *Plugin) Apply(
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: apinetNamespace,
 			Name:      p.APInetNicName(machineID, spec.Name),
+			Labels: map[string]string{
+				machineIDLabel:        machineID,
+				networkInterfaceLabel: p.APInetNicName(machineID, spec.Name),
+			},
 		},
 		Spec: apinetv1alpha1.NetworkInterfaceSpec{
 			NetworkRef: corev1.LocalObjectReference{
@@ -154,6 +168,11 @@ func (p *Plugin) Apply(
 		return nil, fmt.Errorf("error applying apinet network interface: %w", err)
 	}
 
+	log.V(2).Info("Applying apinet network policy")
+	if err := p.applyNetworkPolicy(ctx, apinetNamespace, apinetNic.Name, apinetNetworkName, spec.Firewall); err != nil {
+		return nil, err
+	}
+
 	if apinetNic.Status.State == apinetv1alpha1.NetworkInterfaceStateReady {
 		path, deviceType, err := getDeviceInfo(&apinetNic.Status)
 		if err != nil {
@@ -168,9 +187,10 @@ func (p *Plugin) Apply(
 				apinetNic.Spec.NodeRef.Name,
 				apinetNic.UID,
 			),
-			State: api.NetworkInterfaceStatePrepared,
-			Type:  deviceType,
-			Path:  path,
+			State:     api.NetworkInterfaceStatePrepared,
+			Type:      deviceType,
+			Path:      path,
+			PublicIPs: publicIPs(&apinetNic.Status),
 		}, nil
 	}
 
@@ -210,9 +230,10 @@ func (p *Plugin) Apply(
 				apinetNic.Spec.NodeRef.Name,
 				apinetNic.UID,
 			),
-			State: api.NetworkInterfaceStatePrepared,
-			Type:  deviceType,
-			Path:  path,
+			State:     api.NetworkInterfaceStatePrepared,
+			Type:      deviceType,
+			Path:      path,
+			PublicIPs: publicIPs(&apinetNic.Status),
 		}, nil
 	}
 
@@ -222,6 +243,52 @@ func (p *Plugin) Apply(
 	}, nil
 }
 
+// Watch calls handler for the machines whose apinet network interfaces on this node change, so that they are
+// reconciled once their interfaces become ready. The watch is restarted when it ends.
+func (p *Plugin) Watch(ctx context.Context, handler func(machineID string)) error {
+	log := ctrl.LoggerFrom(ctx)
+	for {
+		if err := p.watch(ctx, handler); err != nil {
+			log.Error(err, "Watching apinet network interfaces failed, retrying")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (p *Plugin) watch(ctx context.Context, handler func(machineID string)) error {
+	watcher, err := p.apinetClient.Watch(ctx, &apinetv1alpha1.NetworkInterfaceList{}, client.HasLabels{machineIDLabel})
+	if err != nil {
+		return fmt.Errorf("error watching apinet network interfaces: %w", err)
+	}
+	defer watcher.Stop()
+
+	for evt := range watcher.ResultChan() {
+		if evt.Type == watch.Error {
+			return apierrors.FromObject(evt.Object)
+		}
+
+		apinetNic, ok := evt.Object.(*apinetv1alpha1.NetworkInterface)
+		if !ok || apinetNic.Spec.NodeRef.Name != p.nodeName {
+			continue
+		}
+		handler(apinetNic.Labels[machineIDLabel])
+	}
+	return nil
+}
+
+func publicIPs(status *apinetv1alpha1.NetworkInterfaceStatus) []string {
+	var ips []string
+	for _, ip := range status.PublicIPs {
+		ips = append(ips, ip.String())
+	}
+	return ips
+}
+
 func getDeviceInfo(status *apinetv1alpha1.NetworkInterfaceStatus) (string, api.NetworkInterfaceType, error) {
 	if status.PCIAddress != nil {
 		pciDevice := status.PCIAddress
@@ -262,6 +329,10 @@ func (p *Plugin) Delete(ctx context.Context, computeNicName string, machineID st
 	}
 	log = log.WithValues("APInetNetworkInterfaceKey", apinetNicKey)
 
+	if err := p.deleteNetworkPolicy(ctx, apinetNicKey.Namespace, apinetNicKey.Name); err != nil {
+		return err
+	}
+
 	if err := p.apinetClient.Delete(ctx, &apinetv1alpha1.NetworkInterface{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: apinetNicKey.Namespace,
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package apinet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	apinetv1alpha1 "github.com/ironcore-dev/ironcore-net/api/core/v1alpha1"
+	apinet "github.com/ironcore-dev/ironcore-net/apimachinery/api/net"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyNetworkPolicy enforces the firewall of the network interface with a network policy of the same name,
+// selecting only the apinet network interface. Without firewall, the network policy is deleted.
+func (p *Plugin) applyNetworkPolicy(
+	ctx context.Context,
+	namespace, name, networkName string,
+	firewall *api.FirewallSpec,
+) error {
+	if firewall == nil {
+		return p.deleteNetworkPolicy(ctx, namespace, name)
+	}
+
+	spec, err := networkPolicySpec(firewall)
+	if err != nil {
+		return fmt.Errorf("error converting firewall: %w", err)
+	}
+	spec.NetworkRef = corev1.LocalObjectReference{Name: networkName}
+	spec.NetworkInterfaceSelector = metav1.LabelSelector{
+		MatchLabels: map[string]string{networkInterfaceLabel: name},
+	}
+
+	policy := &apinetv1alpha1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apinetv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: *spec,
+	}
+	if err := p.apinetClient.Patch(ctx, policy, client.Apply, fieldOwner, client.ForceOwnership); err != nil {
+		return fmt.Errorf("error applying apinet network policy: %w", err)
+	}
+	return nil
+}
+
+func (p *Plugin) deleteNetworkPolicy(ctx context.Context, namespace, name string) error {
+	if err := p.apinetClient.Delete(ctx, &apinetv1alpha1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting apinet network policy: %w", err)
+	}
+	return nil
+}
+
+func networkPolicySpec(firewall *api.FirewallSpec) (*apinetv1alpha1.NetworkPolicySpec, error) {
+	spec := &apinetv1alpha1.NetworkPolicySpec{}
+	for _, policyType := range firewall.EffectivePolicyTypes() {
+		spec.PolicyTypes = append(spec.PolicyTypes, apinetv1alpha1.PolicyType(policyType))
+	}
+
+	for _, rule := range firewall.Ingress {
+		peers, ports, err := networkPolicyRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		spec.Ingress = append(spec.Ingress, apinetv1alpha1.NetworkPolicyIngressRule{From: peers, Ports: ports})
+	}
+	for _, rule := range firewall.Egress {
+		peers, ports, err := networkPolicyRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		spec.Egress = append(spec.Egress, apinetv1alpha1.NetworkPolicyEgressRule{To: peers, Ports: ports})
+	}
+	return spec, nil
+}
+
+func networkPolicyRule(
+	rule api.FirewallRule,
+) ([]apinetv1alpha1.NetworkPolicyPeer, []apinetv1alpha1.NetworkPolicyPort, error) {
+	var peers []apinetv1alpha1.NetworkPolicyPeer
+	for _, cidr := range rule.CIDRs {
+		prefix, err := apinet.ParseIPPrefix(cidr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CIDR %s: %w", cidr, err)
+		}
+		peers = append(peers, apinetv1alpha1.NetworkPolicyPeer{
+			IPBlock: &apinetv1alpha1.IPBlock{CIDR: prefix},
+		})
+	}
+
+	switch rule.Protocol {
+	case "":
+		return peers, nil, nil
+	case api.FirewallProtocolICMP:
+		return nil, nil, fmt.Errorf("protocol %s is not supported by apinet network policies", rule.Protocol)
+	}
+
+	port := apinetv1alpha1.NetworkPolicyPort{
+		Protocol: ptr.To(corev1.Protocol(rule.Protocol)),
+		Port:     rule.Port,
+	}
+	if rule.EndPort != 0 {
+		port.EndPort = ptr.To(rule.EndPort)
+	}
+	return peers, []apinetv1alpha1.NetworkPolicyPort{port}, nil
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	dhcpServerPort = 67
+	dhcpClientPort = 68
+
+	dhcpLeaseTime = 24 * time.Hour
+
+	// dhcpHeaderLen is the length of the fixed BOOTP fields, followed by the magic cookie and the options.
+	dhcpHeaderLen = 236
+	dhcpMaxLen    = 1500
+
+	bootRequest = 1
+	bootReply   = 2
+)
+
+var dhcpMagicCookie = []byte{99, 130, 83, 99}
+
+const (
+	dhcpDiscover = 1
+	dhcpOffer    = 2
+	dhcpRequest  = 3
+	dhcpAck      = 5
+	dhcpNak      = 6
+)
+
+const (
+	optPad         = 0
+	optSubnetMask  = 1
+	optRouter      = 3
+	optDNS         = 6
+	optRequestedIP = 50
+	optLeaseTime   = 51
+	optMessageType = 53
+	optServerID    = 54
+	optEnd         = 255
+)
+
+// dhcpServer answers the DHCP requests of the guests on the bridge. It only hands out the leases of the
+// network interfaces, requests of unknown MAC addresses are ignored.
+type dhcpServer struct {
+	log     logr.Logger
+	network *Network
+	leases  *leases
+}
+
+func (s *dhcpServer) serve(ctx context.Context) error {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = errors.Join(
+					unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1),
+					unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_BROADCAST, 1),
+					unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, s.network.Bridge),
+				)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	conn, err := lc.ListenPacket(ctx, "udp4", fmt.Sprintf(":%d", dhcpServerPort))
+	if err != nil {
+		return fmt.Errorf("failed listening for DHCP requests on %s: %w", s.network.Bridge, err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, dhcpMaxLen)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed reading DHCP request: %w", err)
+		}
+
+		reply, dst, err := s.handle(buf[:n])
+		if err != nil {
+			s.log.V(2).Info("Ignoring DHCP request", "reason", err.Error())
+			continue
+		}
+		if reply == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(reply, dst); err != nil {
+			s.log.Error(err, "failed sending DHCP reply")
+		}
+	}
+}
+
+// handle returns the reply to a DHCP message and where to send it.
+func (s *dhcpServer) handle(msg []byte) ([]byte, net.Addr, error) {
+	if len(msg) < dhcpHeaderLen+len(dhcpMagicCookie) || msg[0] != bootRequest ||
+		string(msg[dhcpHeaderLen:dhcpHeaderLen+4]) != string(dhcpMagicCookie) {
+		return nil, nil, fmt.Errorf("no DHCP request")
+	}
+	hlen := int(msg[2])
+	if hlen != 6 {
+		return nil, nil, fmt.Errorf("unsupported hardware address length %d", hlen)
+	}
+	mac := net.HardwareAddr(msg[28 : 28+hlen])
+	options := parseOptions(msg[dhcpHeaderLen+4:])
+
+	addr, ok := s.leases.lookup(mac.String())
+	if !ok {
+		return nil, nil, fmt.Errorf("no lease for %s", mac)
+	}
+	ciaddr, _ := netip.AddrFromSlice(msg[12:16])
+
+	var msgType byte
+	switch t := options[optMessageType]; {
+	case len(t) != 1:
+		return nil, nil, fmt.Errorf("missing message type")
+	case t[0] == dhcpDiscover:
+		msgType = dhcpOffer
+	case t[0] == dhcpRequest:
+		requested := ciaddr
+		if opt := options[optRequestedIP]; len(opt) == 4 {
+			requested = netip.AddrFrom4([4]byte(opt))
+		}
+		msgType = dhcpAck
+		if requested != addr {
+			msgType = dhcpNak
+		}
+	default:
+		return nil, nil, nil
+	}
+	s.log.V(2).Info("Answering DHCP request", "mac", mac, "address", addr, "type", msgType)
+
+	dst := &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpClientPort}
+	if ciaddr.IsValid() && !ciaddr.IsUnspecified() && msgType == dhcpAck {
+		dst.IP = ciaddr.AsSlice()
+	}
+	return s.reply(msg, msgType, addr), dst, nil
+}
+
+func (s *dhcpServer) reply(req []byte, msgType byte, addr netip.Addr) []byte {
+	reply := make([]byte, dhcpHeaderLen, dhcpMaxLen)
+	reply[0] = bootReply
+	copy(reply[1:3], req[1:3])     // htype, hlen
+	copy(reply[4:8], req[4:8])     // xid
+	copy(reply[10:12], req[10:12]) // flags
+	copy(reply[12:16], req[12:16]) // ciaddr
+	copy(reply[24:28], req[24:28]) // giaddr
+	copy(reply[28:44], req[28:44]) // chaddr
+	reply = append(reply, dhcpMagicCookie...)
+
+	gateway := s.network.gateway().AsSlice()
+	reply = appendOption(reply, optMessageType, msgType)
+	reply = appendOption(reply, optServerID, gateway...)
+	if msgType != dhcpNak {
+		copy(reply[16:20], addr.AsSlice()) // yiaddr
+		copy(reply[20:24], gateway)        // siaddr
+
+		mask := net.CIDRMask(s.network.Subnet.Bits(), 32)
+		reply = appendOption(reply, optSubnetMask, mask...)
+		reply = appendOption(reply, optRouter, gateway...)
+		reply = appendOption(reply, optLeaseTime, binary.BigEndian.AppendUint32(nil, uint32(dhcpLeaseTime.Seconds()))...)
+		if len(s.network.DNSServers) > 0 {
+			var dns []byte
+			for _, server := range s.network.DNSServers {
+				dns = append(dns, server.AsSlice()...)
+			}
+			reply = appendOption(reply, optDNS, dns...)
+		}
+	}
+	return append(reply, optEnd)
+}
+
+func parseOptions(data []byte) map[byte][]byte {
+	options := make(map[byte][]byte)
+	for i := 0; i < len(data); {
+		code := data[i]
+		switch code {
+		case optPad:
+			i++
+			continue
+		case optEnd:
+			return options
+		}
+		if i+1 >= len(data) || i+2+int(data[i+1]) > len(data) {
+			return options
+		}
+		length := int(data[i+1])
+		options[code] = data[i+2 : i+2+length]
+		i += 2 + length
+	}
+	return options
+}
+
+func appendOption(data []byte, code byte, value ...byte) []byte {
+	return append(append(data, code, byte(len(value))), value...)
+}
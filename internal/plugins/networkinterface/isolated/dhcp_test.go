@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DHCP", func() {
+	var (
+		s   *dhcpServer
+		mac = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}
+		xid = []byte{0xde, 0xad, 0xbe, 0xef}
+	)
+
+	BeforeEach(func() {
+		network := &Network{
+			Bridge:     "chp0",
+			Subnet:     netip.MustParsePrefix("10.0.0.0/24"),
+			DNSServers: []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("8.8.8.8")},
+		}
+		s = &dhcpServer{log: logr.Discard(), network: network, leases: newLeases(network.Subnet)}
+		_, err := s.leases.get(GinkgoT().TempDir(), mac.String(), []string{"10.0.0.7"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// request builds a DHCP request of the guest with the options.
+	request := func(hwaddr net.HardwareAddr, ciaddr netip.Addr, options ...[]byte) []byte {
+		msg := make([]byte, dhcpHeaderLen)
+		msg[0] = bootRequest
+		msg[1] = 1 // ethernet
+		msg[2] = byte(len(hwaddr))
+		copy(msg[4:8], xid)
+		if ciaddr.IsValid() {
+			copy(msg[12:16], ciaddr.AsSlice())
+		}
+		copy(msg[28:44], hwaddr)
+		msg = append(msg, dhcpMagicCookie...)
+		for _, option := range options {
+			msg = append(msg, option...)
+		}
+		return append(msg, optEnd)
+	}
+	messageType := func(t byte) []byte {
+		return appendOption(nil, optMessageType, t)
+	}
+	requestedIP := func(addr string) []byte {
+		return appendOption(nil, optRequestedIP, netip.MustParseAddr(addr).AsSlice()...)
+	}
+
+	// expectReply checks the fixed fields of a reply to the guest and returns its options.
+	expectReply := func(reply []byte, yiaddr string) map[byte][]byte {
+		Expect(len(reply)).To(BeNumerically(">", dhcpHeaderLen+len(dhcpMagicCookie)))
+		Expect(reply[0]).To(Equal(byte(bootReply)))
+		Expect(reply[4:8]).To(Equal(xid))
+		Expect(net.HardwareAddr(reply[28:34])).To(Equal(mac))
+		Expect(net.IP(reply[16:20]).String()).To(Equal(yiaddr))
+		Expect(reply[dhcpHeaderLen : dhcpHeaderLen+4]).To(Equal(dhcpMagicCookie))
+		Expect(reply[len(reply)-1]).To(Equal(byte(optEnd)))
+		return parseOptions(reply[dhcpHeaderLen+4:])
+	}
+	expectLease := func(options map[byte][]byte) {
+		Expect(options).To(HaveKeyWithValue(byte(optServerID), []byte{10, 0, 0, 1}))
+		Expect(options).To(HaveKeyWithValue(byte(optSubnetMask), []byte{255, 255, 255, 0}))
+		Expect(options).To(HaveKeyWithValue(byte(optRouter), []byte{10, 0, 0, 1}))
+		Expect(options).To(HaveKeyWithValue(byte(optDNS), []byte{1, 1, 1, 1, 8, 8, 8, 8}))
+		Expect(options).To(HaveKey(byte(optLeaseTime)))
+		Expect(binary.BigEndian.Uint32(options[optLeaseTime])).To(Equal(uint32(dhcpLeaseTime.Seconds())))
+	}
+	broadcastAddr := &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpClientPort}
+
+	It("should offer the lease on DISCOVER", func() {
+		reply, dst, err := s.handle(request(mac, netip.Addr{}, messageType(dhcpDiscover)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dst).To(Equal(broadcastAddr))
+
+		options := expectReply(reply, "10.0.0.7")
+		Expect(options).To(HaveKeyWithValue(byte(optMessageType), []byte{dhcpOffer}))
+		expectLease(options)
+	})
+
+	It("should acknowledge a REQUEST of the leased address", func() {
+		reply, dst, err := s.handle(request(mac, netip.Addr{}, messageType(dhcpRequest), requestedIP("10.0.0.7")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dst).To(Equal(broadcastAddr))
+
+		options := expectReply(reply, "10.0.0.7")
+		Expect(options).To(HaveKeyWithValue(byte(optMessageType), []byte{dhcpAck}))
+		expectLease(options)
+	})
+
+	It("should acknowledge a renewal to the client address", func() {
+		reply, dst, err := s.handle(request(mac, netip.MustParseAddr("10.0.0.7"), messageType(dhcpRequest)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dst).To(Equal(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 7).To4(), Port: dhcpClientPort}))
+
+		options := expectReply(reply, "10.0.0.7")
+		Expect(options).To(HaveKeyWithValue(byte(optMessageType), []byte{dhcpAck}))
+	})
+
+	It("should NAK a REQUEST of another address", func() {
+		reply, dst, err := s.handle(request(mac, netip.Addr{}, messageType(dhcpRequest), requestedIP("10.0.0.8")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dst).To(Equal(broadcastAddr))
+
+		options := expectReply(reply, "0.0.0.0")
+		Expect(options).To(HaveKeyWithValue(byte(optMessageType), []byte{dhcpNak}))
+		Expect(options).To(HaveKeyWithValue(byte(optServerID), []byte{10, 0, 0, 1}))
+		Expect(options).NotTo(HaveKey(byte(optRouter)))
+		Expect(options).NotTo(HaveKey(byte(optLeaseTime)))
+	})
+
+	It("should NAK a renewal of another address", func() {
+		reply, dst, err := s.handle(request(mac, netip.MustParseAddr("10.0.0.8"), messageType(dhcpRequest)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dst).To(Equal(broadcastAddr))
+		Expect(expectReply(reply, "0.0.0.0")).To(HaveKeyWithValue(byte(optMessageType), []byte{dhcpNak}))
+	})
+
+	It("should ignore other message types", func() {
+		release := byte(7)
+		reply, _, err := s.handle(request(mac, netip.Addr{}, messageType(release)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply).To(BeNil())
+	})
+
+	DescribeTable("rejecting invalid requests",
+		func(msg func() []byte, reason string) {
+			reply, _, err := s.handle(msg())
+			Expect(err).To(MatchError(ContainSubstring(reason)))
+			Expect(reply).To(BeNil())
+		},
+		Entry("too short", func() []byte { return make([]byte, dhcpHeaderLen) }, "no DHCP request"),
+		Entry("reply", func() []byte {
+			msg := request(mac, netip.Addr{}, messageType(dhcpDiscover))
+			msg[0] = bootReply
+			return msg
+		}, "no DHCP request"),
+		Entry("without magic cookie", func() []byte {
+			msg := request(mac, netip.Addr{}, messageType(dhcpDiscover))
+			msg[dhcpHeaderLen] = 0
+			return msg
+		}, "no DHCP request"),
+		Entry("other hardware address length", func() []byte {
+			return request(net.HardwareAddr{1, 2, 3, 4, 5, 6, 7, 8}, netip.Addr{}, messageType(dhcpDiscover))
+		}, "unsupported hardware address length"),
+		Entry("unknown MAC address", func() []byte {
+			return request(net.HardwareAddr{0x02, 0, 0, 0, 0, 0x02}, netip.Addr{}, messageType(dhcpDiscover))
+		}, "no lease"),
+		Entry("without message type", func() []byte {
+			return request(mac, netip.Addr{})
+		}, "missing message type"),
+	)
+
+	DescribeTable("parsing options",
+		func(data []byte, expected map[byte][]byte) {
+			Expect(parseOptions(data)).To(Equal(expected))
+		},
+		Entry("empty", []byte{}, map[byte][]byte{}),
+		Entry("options up to the end",
+			[]byte{optMessageType, 1, dhcpDiscover, optPad, optPad, optRequestedIP, 4, 10, 0, 0, 7, optEnd, optRouter, 0},
+			map[byte][]byte{optMessageType: {dhcpDiscover}, optRequestedIP: {10, 0, 0, 7}}),
+		Entry("without end", []byte{optMessageType, 1, dhcpRequest},
+			map[byte][]byte{optMessageType: {dhcpRequest}}),
+		Entry("truncated length", []byte{optMessageType, 1, dhcpRequest, optRequestedIP},
+			map[byte][]byte{optMessageType: {dhcpRequest}}),
+		Entry("truncated value", []byte{optMessageType, 1, dhcpRequest, optRequestedIP, 4, 10, 0},
+			map[byte][]byte{optMessageType: {dhcpRequest}}),
+	)
+})
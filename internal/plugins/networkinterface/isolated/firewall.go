@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+)
+
+const firewallTablePrefix = "chp_firewall_"
+
+// firewallTable returns the nft table of the firewall of a tap device. The table is in the bridge family, so
+// that it sees the traffic to other guests on the bridge as well as the traffic to the host and beyond.
+func firewallTable(tap string) string {
+	return firewallTablePrefix + tap
+}
+
+// applyFirewall replaces the firewall of the tap device. The guest may only send from the address of its
+// lease, also without firewall.
+func applyFirewall(log logr.Logger, tap string, guest *lease, firewall *api.FirewallSpec) error {
+	if err := run(log, strings.NewReader(renderFirewall(tap, guest, firewall)), "nft", "-f", "-"); err != nil {
+		return fmt.Errorf("failed applying firewall: %w", err)
+	}
+	return nil
+}
+
+func deleteFirewall(log logr.Logger, tap string) error {
+	// Declaring the table first makes deleting it succeed if it does not exist.
+	script := fmt.Sprintf("table bridge %[1]s {}\ndelete table bridge %[1]s\n", firewallTable(tap))
+	if err := run(log, strings.NewReader(script), "nft", "-f", "-"); err != nil {
+		return fmt.Errorf("failed deleting firewall: %w", err)
+	}
+	return nil
+}
+
+// renderFirewall returns the nft script of the firewall. Traffic from the tap device is the egress of the
+// guest, traffic to it the ingress. Egress with another source address than the MAC and IP of the lease is
+// dropped, so that the guest cannot pose as another one. Replies, ARP, neighbor discovery and DHCP are always
+// allowed. Without firewall, only the source addresses are checked.
+func renderFirewall(tap string, guest *lease, firewall *api.FirewallSpec) string {
+	var b strings.Builder
+	table := firewallTable(tap)
+	fmt.Fprintf(&b, "table bridge %[1]s {}\ndelete table bridge %[1]s\ntable bridge %[1]s {\n", table)
+	fmt.Fprintf(&b, `	chain forward {
+		type filter hook forward priority filter; policy accept;
+		iifname "%[1]s" jump egress
+		oifname "%[1]s" jump ingress
+	}
+	chain input {
+		type filter hook input priority filter; policy accept;
+		iifname "%[1]s" jump egress
+	}
+	chain output {
+		type filter hook output priority filter; policy accept;
+		oifname "%[1]s" jump ingress
+	}
+`, tap)
+
+	b.WriteString("\tchain ingress {\n")
+	if firewall != nil && firewall.Restricts(api.FirewallPolicyTypeIngress) {
+		writeFirewallChain(&b, "saddr", "udp sport 67 udp dport 68 accept", firewall.Ingress)
+	}
+	b.WriteString("\t}\n\tchain egress {\n")
+	writeAntiSpoofing(&b, guest)
+	if firewall != nil && firewall.Restricts(api.FirewallPolicyTypeEgress) {
+		writeFirewallChain(&b, "daddr", "udp sport 68 udp dport 67 accept", firewall.Egress)
+	}
+	b.WriteString("\t}\n}\n")
+	return b.String()
+}
+
+// writeAntiSpoofing drops the egress of the guest with other source addresses than the ones of its lease. ARP
+// probes and DHCP requests are sent before the guest has its address.
+func writeAntiSpoofing(b *strings.Builder, guest *lease) {
+	for _, line := range []string{
+		fmt.Sprintf("ether saddr != %s drop", guest.MAC),
+		fmt.Sprintf("arp saddr ether != %s drop", guest.MAC),
+		fmt.Sprintf("arp saddr ip != { 0.0.0.0, %s } drop", guest.IP),
+		"ip saddr 0.0.0.0 udp sport 68 udp dport 67 accept",
+		fmt.Sprintf("ip saddr != %s drop", guest.IP),
+	} {
+		fmt.Fprintf(b, "\t\t%s\n", line)
+	}
+}
+
+func writeFirewallChain(b *strings.Builder, peer, dhcp string, rules []api.FirewallRule) {
+	for _, line := range []string{
+		"ct state established,related accept",
+		"ether type arp accept",
+		"icmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept",
+		dhcp,
+	} {
+		fmt.Fprintf(b, "\t\t%s\n", line)
+	}
+	for _, rule := range rules {
+		for _, match := range firewallRuleMatches(peer, rule) {
+			fmt.Fprintf(b, "\t\t%s accept\n", strings.TrimSpace(match))
+		}
+	}
+	b.WriteString("\t\tdrop\n")
+}
+
+// firewallRuleMatches returns the nft matches of the rule, one per address family of its CIDRs.
+func firewallRuleMatches(peer string, rule api.FirewallRule) []string {
+	var ipv4, ipv6 []string
+	for _, cidr := range rule.CIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			ipv4 = append(ipv4, prefix.Masked().String())
+		} else {
+			ipv6 = append(ipv6, prefix.Masked().String())
+		}
+	}
+
+	if len(rule.CIDRs) == 0 {
+		return []string{firewallProtocolMatch(rule, "")}
+	}
+	var matches []string
+	if len(ipv4) > 0 {
+		matches = append(matches, fmt.Sprintf("ip %s { %s } %s", peer, strings.Join(ipv4, ", "),
+			firewallProtocolMatch(rule, "ip")))
+	}
+	if len(ipv6) > 0 {
+		matches = append(matches, fmt.Sprintf("ip6 %s { %s } %s", peer, strings.Join(ipv6, ", "),
+			firewallProtocolMatch(rule, "ip6")))
+	}
+	return matches
+}
+
+func firewallProtocolMatch(rule api.FirewallRule, family string) string {
+	switch rule.Protocol {
+	case "":
+		return ""
+	case api.FirewallProtocolICMP:
+		switch family {
+		case "ip":
+			return "meta l4proto icmp"
+		case "ip6":
+			return "meta l4proto ipv6-icmp"
+		default:
+			return "meta l4proto { icmp, ipv6-icmp }"
+		}
+	}
+
+	protocol := strings.ToLower(string(rule.Protocol))
+	switch {
+	case rule.Port == 0:
+		return "meta l4proto " + protocol
+	case rule.EndPort == 0:
+		return fmt.Sprintf("%s dport %d", protocol, rule.Port)
+	default:
+		return fmt.Sprintf("%s dport %d-%d", protocol, rule.Port, rule.EndPort)
+	}
+}
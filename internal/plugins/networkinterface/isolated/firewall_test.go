@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Firewall", func() {
+	guest := &lease{MAC: "02:00:00:00:00:01", IP: netip.MustParseAddr("10.0.0.2")}
+
+	antiSpoofing := []string{
+		"ether saddr != 02:00:00:00:00:01 drop",
+		"arp saddr ether != 02:00:00:00:00:01 drop",
+		"arp saddr ip != { 0.0.0.0, 10.0.0.2 } drop",
+		"ip saddr 0.0.0.0 udp sport 68 udp dport 67 accept",
+		"ip saddr != 10.0.0.2 drop",
+	}
+	restricted := func(dhcp string, rules ...string) []string {
+		return append(append([]string{
+			"ct state established,related accept",
+			"ether type arp accept",
+			"icmpv6 type { nd-neighbor-solicit, nd-neighbor-advert, nd-router-solicit, nd-router-advert } accept",
+			dhcp,
+		}, rules...), "drop")
+	}
+	ingress := func(rules ...string) []string {
+		return restricted("udp sport 67 udp dport 68 accept", rules...)
+	}
+	egress := func(rules ...string) []string {
+		return append(slices.Clone(antiSpoofing), restricted("udp sport 68 udp dport 67 accept", rules...)...)
+	}
+
+	DescribeTable("rendering the nft script",
+		func(firewall *api.FirewallSpec, expectedIngress, expectedEgress []string) {
+			script := renderFirewall("tap0", guest, firewall)
+
+			Expect(script).To(HavePrefix("table bridge chp_firewall_tap0 {}\ndelete table bridge chp_firewall_tap0\n"))
+			Expect(script).To(ContainSubstring("\t\tiifname \"tap0\" jump egress\n"))
+			Expect(script).To(ContainSubstring("\t\toifname \"tap0\" jump ingress\n"))
+			Expect(chainRules(script, "ingress")).To(Equal(expectedIngress))
+			Expect(chainRules(script, "egress")).To(Equal(expectedEgress))
+		},
+		Entry("without firewall only the source addresses are checked",
+			nil, []string(nil), antiSpoofing),
+		Entry("an empty firewall restricts ingress",
+			&api.FirewallSpec{}, ingress(), antiSpoofing),
+		Entry("egress rules restrict both directions",
+			&api.FirewallSpec{Egress: []api.FirewallRule{{Protocol: api.FirewallProtocolUDP, Port: 53}}},
+			ingress(), egress("udp dport 53 accept")),
+		Entry("policy types restrict only their directions",
+			&api.FirewallSpec{PolicyTypes: []api.FirewallPolicyType{api.FirewallPolicyTypeEgress}},
+			[]string(nil), egress()),
+		Entry("rules match the cidrs per address family",
+			&api.FirewallSpec{Ingress: []api.FirewallRule{{
+				Protocol: api.FirewallProtocolTCP,
+				Port:     22,
+				CIDRs:    []string{"10.1.2.3/8", "192.168.0.0/16", "fd00::1/8"},
+			}}},
+			ingress(
+				"ip saddr { 10.0.0.0/8, 192.168.0.0/16 } tcp dport 22 accept",
+				"ip6 saddr { fd00::/8 } tcp dport 22 accept",
+			),
+			antiSpoofing),
+		Entry("egress rules match the remote address",
+			&api.FirewallSpec{Egress: []api.FirewallRule{{CIDRs: []string{"0.0.0.0/0"}}}},
+			ingress(), egress("ip daddr { 0.0.0.0/0 } accept")),
+		Entry("port ranges",
+			&api.FirewallSpec{Ingress: []api.FirewallRule{{Protocol: api.FirewallProtocolSCTP, Port: 8000, EndPort: 8999}}},
+			ingress("sctp dport 8000-8999 accept"), antiSpoofing),
+		Entry("protocols without port",
+			&api.FirewallSpec{Ingress: []api.FirewallRule{{Protocol: api.FirewallProtocolTCP}}},
+			ingress("meta l4proto tcp accept"), antiSpoofing),
+		Entry("icmp of both families",
+			&api.FirewallSpec{Ingress: []api.FirewallRule{
+				{Protocol: api.FirewallProtocolICMP},
+				{Protocol: api.FirewallProtocolICMP, CIDRs: []string{"10.0.0.0/8", "fd00::/8"}},
+			}},
+			ingress(
+				"meta l4proto { icmp, ipv6-icmp } accept",
+				"ip saddr { 10.0.0.0/8 } meta l4proto icmp accept",
+				"ip6 saddr { fd00::/8 } meta l4proto ipv6-icmp accept",
+			),
+			antiSpoofing),
+		Entry("any traffic",
+			&api.FirewallSpec{Ingress: []api.FirewallRule{{}}},
+			ingress("accept"), antiSpoofing),
+	)
+})
+
+// chainRules returns the rules of the chain in the nft script.
+func chainRules(script, chain string) []string {
+	_, body, found := strings.Cut(script, "\tchain "+chain+" {\n")
+	Expect(found).To(BeTrue(), "chain %s not found", chain)
+	body, _, _ = strings.Cut(body, "\t}\n")
+
+	var rules []string
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if line != "" {
+			rules = append(rules, strings.TrimSpace(line))
+		}
+	}
+	return rules
+}
@@ -5,6 +5,7 @@ package isolated
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
@@ -17,39 +18,120 @@ const (
 	pluginIsolated = "isolated"
 )
 
-type plugin struct {
-	host host.Paths
+var _ networkinterface.Plugin = (*Plugin)(nil)
+
+type Plugin struct {
+	host    host.Paths
+	network *Network
+	leases  *leases
+	stop    context.CancelFunc
 }
 
-func NewPlugin() networkinterface.Plugin {
-	return &plugin{}
+// NewPlugin returns the isolated plugin. Without network, network interfaces are never prepared and machines
+// have none. With network, they are tap devices on its bridge.
+func NewPlugin(network *Network) *Plugin {
+	return &Plugin{
+		network: network,
+	}
 }
 
-func (p *plugin) Init(host host.Paths) error {
+func (p *Plugin) Init(host host.Paths) error {
 	p.host = host
+	if p.network == nil {
+		return nil
+	}
+
+	log := ctrl.Log.WithName("isolated").WithValues("bridge", p.network.Bridge)
+	if err := p.network.validate(); err != nil {
+		return fmt.Errorf("invalid isolated network: %w", err)
+	}
+	if err := setupBridge(log, p.network); err != nil {
+		return fmt.Errorf("failed setting up bridge: %w", err)
+	}
+
+	p.leases = newLeases(p.network.Subnet)
+	if err := p.leases.load(host); err != nil {
+		return fmt.Errorf("failed loading leases: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stop = cancel
+	server := &dhcpServer{log: log.WithName("dhcp"), network: p.network, leases: p.leases}
+	go func() {
+		if err := server.serve(ctx); err != nil {
+			log.Error(err, "DHCP server stopped")
+		}
+	}()
 	return nil
 }
 
-func (p *plugin) Apply(ctx context.Context,
+// Stop stops the DHCP server. The bridge, the tap devices and the NAT rules are kept, so that running VMs stay
+// connected.
+func (p *Plugin) Stop() {
+	if p.stop != nil {
+		p.stop()
+	}
+}
+
+func (p *Plugin) Apply(ctx context.Context,
 	spec *api.NetworkInterfaceSpec,
 	machineID string,
 ) (*api.NetworkInterfaceStatus, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	log.V(1).Info("Writing network interface dir")
-	if err := os.MkdirAll(p.host.MachineNetworkInterfaceDir(machineID, spec.Name), os.ModePerm); err != nil {
+	dir := p.host.MachineNetworkInterfaceDir(machineID, spec.Name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	if p.network == nil {
+		return &api.NetworkInterfaceStatus{
+			State: api.NetworkInterfaceStatePending,
+		}, nil
+	}
+
+	mac := guestMAC(machineID, spec.Name).String()
+	lease, err := p.leases.get(dir, mac, spec.Ips)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting lease: %w", err)
+	}
+
+	tap := tapName(machineID, spec.Name)
+	if err := setupTap(log, tap, p.network); err != nil {
+		return nil, fmt.Errorf("failed setting up tap: %w", err)
+	}
+	if err := applyFirewall(log, tap, lease, spec.Firewall); err != nil {
 		return nil, err
 	}
+	log.V(1).Info("Network interface prepared", "tap", tap, "mac", mac, "ip", lease.IP)
 
 	return &api.NetworkInterfaceStatus{
-		State: api.NetworkInterfaceStatePending,
+		Name:   spec.Name,
+		Handle: fmt.Sprintf("%s://%s", pluginIsolated, tap),
+		State:  api.NetworkInterfaceStatePrepared,
+		Type:   api.NetworkInterfaceTAPType,
+		Path:   tap,
+		MAC:    mac,
+		IPs:    []string{lease.IP.String()},
 	}, nil
 }
 
-func (p *plugin) Delete(ctx context.Context, computeNicName string, machineID string) error {
+func (p *Plugin) Delete(ctx context.Context, computeNicName string, machineID string) error {
+	if p.network != nil {
+		log := ctrl.LoggerFrom(ctx)
+		tap := tapName(machineID, computeNicName)
+		if err := deleteFirewall(log, tap); err != nil {
+			return err
+		}
+		if err := deleteTap(log, tap); err != nil {
+			return fmt.Errorf("failed deleting tap: %w", err)
+		}
+		p.leases.release(guestMAC(machineID, computeNicName).String())
+	}
 	return os.RemoveAll(p.host.MachineNetworkInterfaceDir(machineID, computeNicName))
 }
 
-func (p *plugin) Name() string {
+func (p *Plugin) Name() string {
 	return pluginIsolated
 }
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+)
+
+const leaseFile = "lease.json"
+
+// lease is the address of a network interface. It is stored in the network interface dir, so that it is kept
+// when the provider restarts and released when the network interface is deleted.
+type lease struct {
+	MAC string     `json:"mac"`
+	IP  netip.Addr `json:"ip"`
+}
+
+// leases assigns the addresses of the subnet to the network interfaces.
+type leases struct {
+	mu     sync.RWMutex
+	subnet netip.Prefix
+	byMAC  map[string]netip.Addr
+	used   map[netip.Addr]string
+}
+
+func newLeases(subnet netip.Prefix) *leases {
+	return &leases{
+		subnet: subnet.Masked(),
+		byMAC:  make(map[string]netip.Addr),
+		used:   make(map[netip.Addr]string),
+	}
+}
+
+// load restores the leases of all network interfaces on the host.
+func (l *leases) load(paths host.Paths) error {
+	files, err := filepath.Glob(filepath.Join(paths.MachinesDir(), "*", host.DefaultMachineNetworkInterfacesDir, "*",
+		leaseFile))
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, file := range files {
+		lease, err := readLease(file)
+		if err != nil {
+			return err
+		}
+		if !l.subnet.Contains(lease.IP) {
+			// The subnet was changed, the network interface gets a new address when it is applied.
+			continue
+		}
+		l.byMAC[lease.MAC] = lease.IP
+		l.used[lease.IP] = lease.MAC
+	}
+	return nil
+}
+
+// get returns the lease of the network interface, assigning it an address if it has none yet. The first
+// requested address that is free is preferred.
+func (l *leases) get(dir, mac string, requested []string) (*lease, error) {
+	file := filepath.Join(dir, leaseFile)
+	existing, err := readLease(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if existing != nil && existing.MAC == mac && l.subnet.Contains(existing.IP) {
+		// Another network interface may hold the address, e.g. if it was assigned to both while the lease of
+		// the other one was not loaded. The network interface gets a new address then.
+		if holder, used := l.used[existing.IP]; !used || holder == mac {
+			l.byMAC[mac] = existing.IP
+			l.used[existing.IP] = mac
+			return existing, nil
+		}
+	}
+
+	addr, err := l.allocate(mac, requested)
+	if err != nil {
+		return nil, err
+	}
+	lease := &lease{MAC: mac, IP: addr}
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed writing lease: %w", err)
+	}
+	l.byMAC[mac] = addr
+	l.used[addr] = mac
+	return lease, nil
+}
+
+func (l *leases) allocate(mac string, requested []string) (netip.Addr, error) {
+	if addr, ok := l.byMAC[mac]; ok && l.used[addr] == mac {
+		return addr, nil
+	}
+
+	gateway := l.subnet.Addr().Next()
+	for _, req := range requested {
+		addr, err := netip.ParseAddr(req)
+		if err != nil || !l.available(addr, gateway) {
+			continue
+		}
+		return addr, nil
+	}
+	for addr := gateway.Next(); l.subnet.Contains(addr); addr = addr.Next() {
+		if l.available(addr, gateway) {
+			return addr, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("no free address in subnet %s", l.subnet)
+}
+
+func (l *leases) available(addr, gateway netip.Addr) bool {
+	if !l.subnet.Contains(addr) || addr == l.subnet.Addr() || addr == gateway || addr == broadcast(l.subnet) {
+		return false
+	}
+	_, used := l.used[addr]
+	return !used
+}
+
+func (l *leases) lookup(mac string) (netip.Addr, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	addr, ok := l.byMAC[mac]
+	return addr, ok
+}
+
+func (l *leases) release(mac string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if addr, ok := l.byMAC[mac]; ok {
+		if l.used[addr] == mac {
+			delete(l.used, addr)
+		}
+		delete(l.byMAC, mac)
+	}
+}
+
+func readLease(file string) (*lease, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	lease := &lease{}
+	if err := json.Unmarshal(data, lease); err != nil {
+		return nil, fmt.Errorf("failed reading lease %s: %w", file, err)
+	}
+	return lease, nil
+}
+
+func broadcast(subnet netip.Prefix) netip.Addr {
+	addr := subnet.Masked().Addr().As4()
+	for i := subnet.Bits(); i < 32; i++ {
+		addr[i/8] |= 1 << (7 - i%8)
+	}
+	return netip.AddrFrom4(addr)
+}
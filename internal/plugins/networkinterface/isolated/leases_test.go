@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Leases", func() {
+	var (
+		l        *leases
+		dirA     string
+		dirB     string
+		macA     = "02:00:00:00:00:0a"
+		macB     = "02:00:00:00:00:0b"
+		readFile = func(dir string) *lease {
+			lease, err := readLease(filepath.Join(dir, leaseFile))
+			Expect(err).NotTo(HaveOccurred())
+			return lease
+		}
+	)
+
+	BeforeEach(func() {
+		l = newLeases(netip.MustParsePrefix("10.0.0.0/29"))
+		dirA = GinkgoT().TempDir()
+		dirB = GinkgoT().TempDir()
+	})
+
+	It("should assign the first free address after the gateway", func() {
+		assigned, err := l.get(dirA, macA, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(assigned).To(Equal(&lease{MAC: macA, IP: netip.MustParseAddr("10.0.0.2")}))
+		Expect(readFile(dirA)).To(Equal(assigned))
+
+		addr, ok := l.lookup(macA)
+		Expect(ok).To(BeTrue())
+		Expect(addr).To(Equal(netip.MustParseAddr("10.0.0.2")))
+	})
+
+	It("should prefer the first free requested address", func() {
+		_, err := l.get(dirA, macA, []string{"10.0.0.5"})
+		Expect(err).NotTo(HaveOccurred())
+
+		lease, err := l.get(dirB, macB, []string{"invalid", "10.0.0.1", "10.0.0.5", "10.0.0.6"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lease.IP).To(Equal(netip.MustParseAddr("10.0.0.6")))
+	})
+
+	It("should keep the address of the lease file", func() {
+		writeLease(dirA, &lease{MAC: macA, IP: netip.MustParseAddr("10.0.0.4")})
+
+		lease, err := l.get(dirA, macA, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lease.IP).To(Equal(netip.MustParseAddr("10.0.0.4")))
+	})
+
+	It("should reallocate the address of a lease file that is held by another network interface", func() {
+		_, err := l.get(dirB, macB, nil)
+		Expect(err).NotTo(HaveOccurred())
+		writeLease(dirA, &lease{MAC: macA, IP: netip.MustParseAddr("10.0.0.2")})
+
+		lease, err := l.get(dirA, macA, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lease.IP).To(Equal(netip.MustParseAddr("10.0.0.3")))
+		Expect(readFile(dirA)).To(Equal(lease))
+
+		addr, _ := l.lookup(macB)
+		Expect(addr).To(Equal(netip.MustParseAddr("10.0.0.2")))
+	})
+
+	It("should free the address on release", func() {
+		_, err := l.get(dirA, macA, nil)
+		Expect(err).NotTo(HaveOccurred())
+		l.release(macA)
+
+		_, ok := l.lookup(macA)
+		Expect(ok).To(BeFalse())
+		lease, err := l.get(dirB, macB, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lease.IP).To(Equal(netip.MustParseAddr("10.0.0.2")))
+	})
+
+	It("should fail if the subnet is exhausted", func() {
+		for i := range 5 {
+			_, err := l.get(GinkgoT().TempDir(), fmt.Sprintf("02:00:00:00:01:%02x", i), nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := l.get(dirA, macA, nil)
+		Expect(err).To(MatchError(ContainSubstring("no free address")))
+	})
+})
+
+func writeLease(dir string, lease *lease) {
+	data, err := json.Marshal(lease)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(os.WriteFile(filepath.Join(dir, leaseFile), data, 0644)).To(Succeed())
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package isolated
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	natTable       = "cloud_hypervisor_provider_isolated"
+	ipForwardFile  = "/proc/sys/net/ipv4/ip_forward"
+	tapNamePrefix  = "chp"
+	tapNameHashLen = 12
+)
+
+// Network connects the network interfaces to a host bridge. Guests get their address from the subnet by DHCP,
+// the first address of the subnet is the bridge's and their gateway.
+type Network struct {
+	Bridge     string
+	Subnet     netip.Prefix
+	NAT        bool
+	DNSServers []netip.Addr
+	// TapUser owns the tap devices, so that cloud-hypervisor can open them without CAP_NET_ADMIN.
+	TapUser string
+}
+
+func (n *Network) gateway() netip.Addr {
+	return n.Subnet.Masked().Addr().Next()
+}
+
+func (n *Network) validate() error {
+	if n.Bridge == "" {
+		return fmt.Errorf("must specify bridge")
+	}
+	if !n.Subnet.Addr().Is4() {
+		return fmt.Errorf("subnet %s is no IPv4 subnet", n.Subnet)
+	}
+	if n.Subnet.Bits() > 30 {
+		return fmt.Errorf("subnet %s is too small", n.Subnet)
+	}
+	for _, addr := range n.DNSServers {
+		if !addr.Is4() {
+			return fmt.Errorf("DNS server %s is no IPv4 address", addr)
+		}
+	}
+	return nil
+}
+
+// setupBridge creates the bridge if it does not exist and assigns the gateway address to it. If NAT is enabled,
+// traffic of the subnet leaving the host through other interfaces is masqueraded.
+func setupBridge(log logr.Logger, n *Network) error {
+	if _, err := net.InterfaceByName(n.Bridge); err != nil {
+		log.V(1).Info("Creating bridge", "bridge", n.Bridge)
+		if err := ip(log, "link", "add", "name", n.Bridge, "type", "bridge"); err != nil {
+			return err
+		}
+	}
+
+	gateway := netip.PrefixFrom(n.gateway(), n.Subnet.Bits())
+	if err := ip(log, "addr", "replace", gateway.String(), "dev", n.Bridge); err != nil {
+		return err
+	}
+	if err := ip(log, "link", "set", n.Bridge, "up"); err != nil {
+		return err
+	}
+
+	if !n.NAT {
+		return nil
+	}
+	if err := os.WriteFile(ipForwardFile, []byte("1"), 0644); err != nil {
+		return fmt.Errorf("failed enabling ip forwarding: %w", err)
+	}
+	// Declaring and deleting the table first makes the script replace the rules of earlier runs.
+	script := fmt.Sprintf(`table ip %[1]s {}
+delete table ip %[1]s
+table ip %[1]s {
+	chain postrouting {
+		type nat hook postrouting priority srcnat; policy accept;
+		ip saddr %[2]s oifname != "%[3]s" masquerade
+	}
+}
+`, natTable, n.Subnet.Masked(), n.Bridge)
+	if err := run(log, strings.NewReader(script), "nft", "-f", "-"); err != nil {
+		return fmt.Errorf("failed setting up NAT: %w", err)
+	}
+	return nil
+}
+
+// tapName returns the name of the tap device of a network interface. Interface names are limited to 15
+// characters, so it is derived from a hash.
+func tapName(machineID, nicName string) string {
+	sum := sha256.Sum256([]byte(machineID + "/" + nicName))
+	return tapNamePrefix + hex.EncodeToString(sum[:])[:tapNameHashLen]
+}
+
+// guestMAC returns a locally administered MAC address for the guest side of a network interface, so that the
+// DHCP lease survives recreating the VM.
+func guestMAC(machineID, nicName string) net.HardwareAddr {
+	sum := sha256.Sum256([]byte("mac/" + machineID + "/" + nicName))
+	mac := net.HardwareAddr(sum[:6])
+	mac[0] = mac[0]&0xfe | 0x02
+	return mac
+}
+
+func setupTap(log logr.Logger, tap string, n *Network) error {
+	if _, err := net.InterfaceByName(tap); err != nil {
+		log.V(1).Info("Creating tap", "tap", tap)
+		args := []string{"tuntap", "add", "dev", tap, "mode", "tap"}
+		if n.TapUser != "" {
+			args = append(args, "user", n.TapUser)
+		}
+		if err := ip(log, args...); err != nil {
+			return err
+		}
+	}
+	return ip(log, "link", "set", tap, "master", n.Bridge, "up")
+}
+
+func deleteTap(log logr.Logger, tap string) error {
+	if _, err := net.InterfaceByName(tap); err != nil {
+		return nil
+	}
+	log.V(1).Info("Deleting tap", "tap", tap)
+	return ip(log, "link", "del", tap)
+}
+
+func ip(log logr.Logger, args ...string) error {
+	return run(log, nil, "ip", args...)
+}
+
+func run(log logr.Logger, stdin io.Reader, name string, args ...string) error {
+	log.V(2).Info("Running command", "name", name, "args", args)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
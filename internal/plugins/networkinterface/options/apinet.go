@@ -62,7 +62,7 @@ func (o *apinetOptions) NetworkInterfacePlugin() (networkinterface.Plugin, func(
 		}
 	}
 
-	apinetClient, err := client.New(apinetCfg, client.Options{Scheme: scheme})
+	apinetClient, err := client.NewWithWatch(apinetCfg, client.Options{Scheme: scheme})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to initialize api-net client: %w", err)
 	}
@@ -4,22 +4,65 @@
 package options
 
 import (
+	"fmt"
+	"net/netip"
+
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/networkinterface"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/networkinterface/isolated"
 	"github.com/spf13/pflag"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 )
 
-type isolatedOptions struct{}
+type isolatedOptions struct {
+	Bridge     string
+	Subnet     string
+	NAT        bool
+	DNSServers []string
+	TapUser    string
+}
 
-func (o *isolatedOptions) AddFlags(fs *pflag.FlagSet) {}
+func (o *isolatedOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Bridge, "isolated-bridge", "",
+		"Bridge to connect isolated network interfaces to. If empty, machines get no network interfaces.")
+	fs.StringVar(&o.Subnet, "isolated-subnet", "192.168.100.0/24",
+		"IPv4 subnet the isolated network interfaces get their addresses from by DHCP.")
+	fs.BoolVar(&o.NAT, "isolated-nat", true, "Masquerade traffic of the isolated subnet leaving the host.")
+	fs.StringSliceVar(&o.DNSServers, "isolated-dns-servers", nil,
+		"DNS servers announced to guests of the isolated network.")
+	fs.StringVar(&o.TapUser, "isolated-tap-user", "",
+		"User owning the tap devices of isolated network interfaces, e.g. the user running cloud-hypervisor.")
+}
 
 func (o *isolatedOptions) PluginName() string {
 	return "isolated"
 }
 
 func (o *isolatedOptions) NetworkInterfacePlugin() (networkinterface.Plugin, func(), error) {
-	return isolated.NewPlugin(), nil, nil
+	if o.Bridge == "" {
+		return isolated.NewPlugin(nil), nil, nil
+	}
+
+	subnet, err := netip.ParsePrefix(o.Subnet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid isolated-subnet: %w", err)
+	}
+	var dnsServers []netip.Addr
+	for _, server := range o.DNSServers {
+		addr, err := netip.ParseAddr(server)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid isolated-dns-servers: %w", err)
+		}
+		dnsServers = append(dnsServers, addr)
+	}
+
+	plugin := isolated.NewPlugin(&isolated.Network{
+		Bridge:     o.Bridge,
+		Subnet:     subnet,
+		NAT:        o.NAT,
+		DNSServers: dnsServers,
+		TapUser:    o.TapUser,
+	})
+	return plugin, plugin.Stop, nil
 }
 
 func init() {
@@ -17,3 +17,11 @@ type Plugin interface {
 	Apply(ctx context.Context, spec *api.NetworkInterfaceSpec, machineID string) (*api.NetworkInterfaceStatus, error)
 	Delete(ctx context.Context, computeNicName string, machineID string) error
 }
+
+// Watcher is implemented by plugins whose network interfaces become prepared asynchronously, after Apply
+// returned them pending.
+type Watcher interface {
+	// Watch calls handler with the id of the machine whenever one of its network interfaces changes, until
+	// ctx is done.
+	Watch(ctx context.Context, handler func(machineID string)) error
+}
@@ -5,6 +5,7 @@ package ceph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -16,6 +17,7 @@ import (
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
 )
 
@@ -45,8 +47,15 @@ type validatedVolume struct {
 }
 
 type Provider interface {
+	// Mount exports the volume and returns its socket. If the volume is exported already and its changed
+	// connection cannot be swapped in, it returns the socket and an error wrapping volume.ErrConnectionNotUpdated.
 	Mount(ctx context.Context, machineID string, volume *validatedVolume) (string, error)
 	Unmount(ctx context.Context, machineID string, volumeID string) error
+	Stats(ctx context.Context, machineID string, volumeName string) (*api.VolumeStats, error)
+	Probe(ctx context.Context, machineID string, volumeName string, socketPath string) (api.VolumeHealthState, string)
+	CollectGarbage(ctx context.Context, volumeNames sets.Set[string]) error
+	// Close disconnects from the storage daemon, once the volumes are not used anymore.
+	Close() error
 }
 
 func QMPProvider(ctx context.Context, log logr.Logger, paths host.Paths, socket string) (Provider, error) {
@@ -55,15 +64,16 @@ func QMPProvider(ctx context.Context, log logr.Logger, paths host.Paths, socket
 		return nil, fmt.Errorf("failed to connect to qmp monitor: %w", err)
 	}
 
+	if err := monitor.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to qmp monitor: %w", err)
+	}
+
 	go func() {
-		// TODO
-		_ = monitor.Connect()
-		defer func() {
-			// TODO
-			_ = monitor.Disconnect()
-		}()
-
-		stream, _ := monitor.Events(ctx)
+		stream, err := monitor.Events(ctx)
+		if err != nil {
+			log.Error(err, "Failed to stream qmp events")
+			return
+		}
 		for e := range stream {
 			log.V(1).Info(fmt.Sprintf("EVENT: %s", e.Event))
 		}
@@ -79,6 +89,9 @@ func QMPProvider(ctx context.Context, log logr.Logger, paths host.Paths, socket
 type plugin struct {
 	provider Provider
 	host     volume.Host
+
+	// unusedDirs are the volume directories found unused by the last garbage collection.
+	unusedDirs sets.Set[string]
 }
 
 func NewPlugin(provider Provider) volume.Plugin {
@@ -182,7 +195,7 @@ func (p *plugin) Apply(ctx context.Context, spec *api.VolumeSpec, machineID stri
 	}
 
 	path, err := p.provider.Mount(ctx, machineID, volumeData)
-	if err != nil {
+	if err != nil && !errors.Is(err, volume.ErrConnectionNotUpdated) {
 		return nil, fmt.Errorf("failed to mount volume: %w", err)
 	}
 
@@ -192,7 +205,7 @@ func (p *plugin) Apply(ctx context.Context, spec *api.VolumeSpec, machineID stri
 		Path:   path,
 		Handle: volumeData.handle,
 		State:  api.VolumeStatePrepared,
-	}, nil
+	}, err
 }
 
 func (p *plugin) validateVolume(spec *api.VolumeSpec) (vData *validatedVolume, err error) {
@@ -237,6 +250,19 @@ func (p *plugin) validateVolume(spec *api.VolumeSpec) (vData *validatedVolume, e
 	return vData, nil
 }
 
+// Stats returns the IO of the volume counted by the qemu-storage-daemon serving it.
+func (p *plugin) Stats(ctx context.Context, computeVolumeName string, machineID string) (*api.VolumeStats, error) {
+	return p.provider.Stats(ctx, machineID, computeVolumeName)
+}
+
+func (p *plugin) Probe(
+	ctx context.Context,
+	status *api.VolumeStatus,
+	machineID string,
+) (api.VolumeHealthState, string) {
+	return p.provider.Probe(ctx, machineID, status.Name, status.Path)
+}
+
 func (p *plugin) Delete(ctx context.Context, computeVolumeName string, machineID string) error {
 	if err := p.provider.Unmount(ctx, machineID, computeVolumeName); err != nil {
 		return fmt.Errorf("failed to unmount volume %q: %w", computeVolumeName, err)
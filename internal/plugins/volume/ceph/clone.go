@@ -0,0 +1,366 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ceph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/raw"
+	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+)
+
+const (
+	clonePluginName = "cloud-hypervisor-provider.ironcore.dev/ceph-clone"
+
+	// baseImagePrefix prefixes the images a rootfs is imported into, baseSnapshot is the snapshot the disks
+	// are cloned from.
+	baseImagePrefix  = "rootfs-"
+	baseSnapshot     = "base"
+	cloneImagePrefix = "chp-"
+
+	// imageFilename records the image a disk was provisioned from.
+	imageFilename = "image"
+)
+
+// CloneOptions configure the pool disks provisioned from an image are cloned in.
+type CloneOptions struct {
+	Pool     string
+	Monitors []string
+	UserID   string
+	KeyFile  string
+}
+
+// clonePlugin provisions the local disks stored on ceph. The rootfs of their image is imported into the pool
+// once, each disk is a clone of a snapshot of it served by the qemu-storage-daemon.
+type clonePlugin struct {
+	provider Provider
+	host     volume.Host
+
+	imageCache ociutils.Cache
+	opts       CloneOptions
+	rbd        *rbdCLI
+
+	importsMu sync.Mutex
+	imports   map[string]*sync.Mutex
+}
+
+func NewClonePlugin(provider Provider, osImages ociutils.Cache, opts CloneOptions) volume.Plugin {
+	return &clonePlugin{
+		provider:   provider,
+		imageCache: osImages,
+		opts:       opts,
+		rbd: &rbdCLI{
+			pool:     opts.Pool,
+			monitors: opts.Monitors,
+			userID:   opts.UserID,
+			keyFile:  opts.KeyFile,
+		},
+		imports: make(map[string]*sync.Mutex),
+	}
+}
+
+func (p *clonePlugin) Init(host volume.Host) error {
+	p.host = host
+	if p.opts.Pool == "" {
+		return nil
+	}
+	if len(p.opts.Monitors) == 0 || p.opts.UserID == "" || p.opts.KeyFile == "" {
+		return fmt.Errorf("ceph clone pool %s requires monitors, user and key file", p.opts.Pool)
+	}
+	return nil
+}
+
+func (p *clonePlugin) Name() string {
+	return clonePluginName
+}
+
+func (p *clonePlugin) GetBackingVolumeID(spec *api.VolumeSpec) (string, error) {
+	if spec.LocalDisk == nil {
+		return "", fmt.Errorf("volume does not specify an LocalDisk")
+	}
+	return fmt.Sprintf("%s^%s", clonePluginName, spec.Name), nil
+}
+
+func (p *clonePlugin) CanSupport(spec *api.VolumeSpec) bool {
+	return spec.LocalDisk != nil && spec.LocalDisk.Medium == api.StorageMediumCeph
+}
+
+// cloneImageName returns the name of the image of a disk in the pool, unique across the hosts sharing it.
+func cloneImageName(machineID, computeVolumeName string) string {
+	sum := sha256.Sum256([]byte(machineID + "/" + computeVolumeName))
+	return cloneImagePrefix + hex.EncodeToString(sum[:16])
+}
+
+func (p *clonePlugin) Apply(ctx context.Context, spec *api.VolumeSpec, machineID string) (*api.VolumeStatus, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if p.opts.Pool == "" {
+		return nil, fmt.Errorf("ceph disks are not supported")
+	}
+	if spec.LocalDisk.Image == nil {
+		return nil, fmt.Errorf("ceph disks must be provisioned from an image")
+	}
+	key, err := os.ReadFile(p.opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ceph key: %w", err)
+	}
+
+	cloneImage := cloneImageName(machineID, spec.Name)
+	volumeDir := p.host.MachineVolumeDir(machineID, cephDriverName, cloneImage)
+	if err := os.MkdirAll(volumeDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	size, exists, err := p.rbd.size(log, cloneImage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting disk image: %w", err)
+	}
+	if !exists {
+		img, err := p.imageCache.Get(ctx, *spec.LocalDisk.Image)
+		if err != nil {
+			return nil, err
+		}
+		if img.RootFS == nil {
+			return nil, fmt.Errorf("image %s has no rootfs", *spec.LocalDisk.Image)
+		}
+
+		baseImage, err := p.importRootFS(log, img.RootFS)
+		if err != nil {
+			return nil, fmt.Errorf("error importing rootfs: %w", err)
+		}
+
+		log.V(1).Info("Cloning disk", "image", cloneImage, "base", baseImage)
+		if err := p.rbd.clone(log, baseImage, baseSnapshot, cloneImage); err != nil {
+			return nil, fmt.Errorf("error cloning disk: %w", err)
+		}
+		if size, _, err = p.rbd.size(log, cloneImage); err != nil {
+			return nil, fmt.Errorf("error getting disk image: %w", err)
+		}
+	}
+
+	// The disk keeps the size of the rootfs unless it is to be grown.
+	if spec.LocalDisk.Size > size {
+		log.V(1).Info("Growing disk", "image", cloneImage, "size", spec.LocalDisk.Size)
+		if err := p.rbd.resize(log, cloneImage, spec.LocalDisk.Size); err != nil {
+			return nil, fmt.Errorf("error growing disk: %w", err)
+		}
+		if size, _, err = p.rbd.size(log, cloneImage); err != nil {
+			return nil, fmt.Errorf("error getting disk image: %w", err)
+		}
+	}
+
+	image, err := readImage(volumeDir, *spec.LocalDisk.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := p.provider.Mount(ctx, machineID, &validatedVolume{
+		name:     spec.Name,
+		monitors: p.opts.Monitors,
+		pool:     p.opts.Pool,
+		image:    cloneImage,
+		handle:   cloneImage,
+		userID:   p.opts.UserID,
+		userKey:  strings.TrimSpace(string(key)),
+	})
+	if err != nil && !errors.Is(err, volume.ErrConnectionNotUpdated) {
+		return nil, fmt.Errorf("failed to mount volume: %w", err)
+	}
+
+	return &api.VolumeStatus{
+		Name:   spec.Name,
+		Type:   api.VolumeSocketType,
+		Path:   path,
+		Handle: cloneImage,
+		State:  api.VolumeStatePrepared,
+		Size:   size,
+		Image:  image,
+	}, err
+}
+
+// Clone provisions the disk from the disk of the source machine. It is cloned from a snapshot and flattened, so
+// that both disks can be deleted independently.
+func (p *clonePlugin) Clone(ctx context.Context, spec *api.VolumeSpec, sourceMachineID string, machineID string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if p.opts.Pool == "" {
+		return fmt.Errorf("ceph disks are not supported")
+	}
+
+	sourceImage := cloneImageName(sourceMachineID, spec.Name)
+	cloneImage := cloneImageName(machineID, spec.Name)
+	if _, exists, err := p.rbd.size(log, cloneImage); err != nil || exists {
+		return err
+	}
+	if _, exists, err := p.rbd.size(log, sourceImage); err != nil {
+		return fmt.Errorf("error getting source disk image: %w", err)
+	} else if !exists {
+		return fmt.Errorf("disk %s of machine %s not found", spec.Name, sourceMachineID)
+	}
+
+	// The disk is cloned under a name of its own first, so that an interrupted clone is not taken for the disk.
+	cloningImage := cloneImage + ".cloning"
+	snapshot := "clone-" + machineID
+	if err := p.rbd.remove(log, cloningImage); err != nil {
+		return fmt.Errorf("error removing earlier clone: %w", err)
+	}
+	if err := p.rbd.removeSnapshot(log, sourceImage, snapshot); err != nil {
+		return fmt.Errorf("error removing earlier clone snapshot: %w", err)
+	}
+
+	log.V(1).Info("Cloning disk of machine", "image", cloneImage, "source", sourceImage)
+	if err := p.rbd.createProtectedSnapshot(log, sourceImage, snapshot); err != nil {
+		return fmt.Errorf("error creating clone snapshot: %w", err)
+	}
+	if err := p.rbd.clone(log, sourceImage, snapshot, cloningImage); err != nil {
+		return fmt.Errorf("error cloning disk: %w", err)
+	}
+	if err := p.rbd.flatten(log, cloningImage); err != nil {
+		return fmt.Errorf("error flattening disk: %w", err)
+	}
+	if err := p.rbd.removeSnapshot(log, sourceImage, snapshot); err != nil {
+		return fmt.Errorf("error removing clone snapshot: %w", err)
+	}
+	if err := p.rbd.rename(log, cloningImage, cloneImage); err != nil {
+		return fmt.Errorf("error renaming disk: %w", err)
+	}
+
+	// The disk was provisioned from the image of the source disk, which may differ from the one of the spec.
+	image, err := os.ReadFile(filepath.Join(p.host.MachineVolumeDir(sourceMachineID, cephDriverName, sourceImage),
+		imageFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error reading source disk image: %w", err)
+	}
+	volumeDir := p.host.MachineVolumeDir(machineID, cephDriverName, cloneImage)
+	if err := os.MkdirAll(volumeDir, os.ModePerm); err != nil {
+		return err
+	}
+	_, err = readImage(volumeDir, string(image))
+	return err
+}
+
+// readImage returns the image the disk was cloned from, which is recorded in the volume directory the first
+// time the disk is applied.
+func readImage(volumeDir string, image string) (string, error) {
+	filename := filepath.Join(volumeDir, imageFilename)
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("error reading disk image: %w", err)
+	}
+	if err := os.WriteFile(filename, []byte(image), 0644); err != nil {
+		return "", fmt.Errorf("error recording disk image: %w", err)
+	}
+	return image, nil
+}
+
+// importRootFS imports the rootfs into the pool unless it was imported before, by this or another host. It
+// returns the image the disks are cloned from.
+func (p *clonePlugin) importRootFS(log logr.Logger, rootFS *ociutils.FileLayer) (string, error) {
+	baseImage := baseImagePrefix + rootFS.Descriptor.Digest.Encoded()[:32]
+
+	p.importsMu.Lock()
+	mu, ok := p.imports[baseImage]
+	if !ok {
+		mu = &sync.Mutex{}
+		p.imports[baseImage] = mu
+	}
+	p.importsMu.Unlock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists, err := p.rbd.size(log, baseImage+"@"+baseSnapshot); err != nil || exists {
+		return baseImage, err
+	}
+
+	// The rootfs is imported under a name of its own first, so that no host clones a partial import.
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	importImage := fmt.Sprintf("%s.importing.%s", baseImage, hostname)
+	if err := p.rbd.remove(log, importImage); err != nil {
+		return "", fmt.Errorf("error removing earlier import: %w", err)
+	}
+
+	log.V(1).Info("Importing rootfs", "image", baseImage, "file", rootFS.Path)
+	data, err := raw.Open(rootFS.Path)
+	if err != nil {
+		return "", fmt.Errorf("error opening rootfs: %w", err)
+	}
+	defer func() {
+		_ = data.Close()
+	}()
+	if err := p.rbd.importImage(log, data, importImage); err != nil {
+		return "", err
+	}
+	if err := p.rbd.createProtectedSnapshot(log, importImage, baseSnapshot); err != nil {
+		return "", err
+	}
+
+	if err := p.rbd.rename(log, importImage, baseImage); err != nil {
+		// Another host may have imported the rootfs meanwhile.
+		if _, exists, sizeErr := p.rbd.size(log, baseImage+"@"+baseSnapshot); sizeErr == nil && exists {
+			return baseImage, p.rbd.remove(log, importImage)
+		}
+		return "", err
+	}
+	return baseImage, nil
+}
+
+// Stats returns the IO of the disk counted by the qemu-storage-daemon serving it.
+func (p *clonePlugin) Stats(ctx context.Context, computeVolumeName string, machineID string) (*api.VolumeStats, error) {
+	return p.provider.Stats(ctx, machineID, computeVolumeName)
+}
+
+func (p *clonePlugin) Probe(
+	ctx context.Context,
+	status *api.VolumeStatus,
+	machineID string,
+) (api.VolumeHealthState, string) {
+	return p.provider.Probe(ctx, machineID, status.Name, status.Path)
+}
+
+func (p *clonePlugin) Delete(ctx context.Context, computeVolumeName string, machineID string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if err := p.provider.Unmount(ctx, machineID, computeVolumeName); err != nil {
+		return fmt.Errorf("failed to unmount volume %q: %w", computeVolumeName, err)
+	}
+
+	cloneImage := cloneImageName(machineID, computeVolumeName)
+	if p.opts.Pool != "" {
+		if err := p.rbd.remove(log, cloneImage); err != nil {
+			return fmt.Errorf("error removing disk image: %w", err)
+		}
+	}
+
+	return os.RemoveAll(p.host.MachineVolumeDir(machineID, cephDriverName, cloneImage))
+}
+
+// Open streams the content of the disk with rbd export.
+func (p *clonePlugin) Open(ctx context.Context, spec *api.VolumeSpec, machineID string) (io.ReadCloser, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if p.opts.Pool == "" {
+		return nil, fmt.Errorf("ceph disks are not supported")
+	}
+	return p.rbd.export(log, cloneImageName(machineID, spec.Name))
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ceph
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+)
+
+// ProviderType is the way the provider gets to the qemu-storage-daemon exporting the volumes.
+type ProviderType string
+
+const (
+	// ProviderTypeQMP connects to a qemu-storage-daemon managed outside the provider, e.g. by a systemd unit.
+	ProviderTypeQMP ProviderType = "qmp"
+	// ProviderTypeQemuStorage starts the qemu-storage-daemon unless it is already running.
+	ProviderTypeQemuStorage ProviderType = "qemu-storage"
+)
+
+const (
+	storageDaemonName = "qemu-storage-daemon"
+
+	storageDaemonStartTimeout = 10 * time.Second
+)
+
+func ParseProviderType(s string) (ProviderType, error) {
+	switch providerType := ProviderType(s); providerType {
+	case ProviderTypeQMP, ProviderTypeQemuStorage:
+		return providerType, nil
+	default:
+		return "", fmt.Errorf("unknown ceph provider %q, must be one of %s, %s", s,
+			ProviderTypeQMP, ProviderTypeQemuStorage)
+	}
+}
+
+type StorageDaemonOptions struct {
+	// BinaryPath is the path of the qemu-storage-daemon binary.
+	BinaryPath string
+	// QMPSocketPath is the path of the qmp socket the daemon serves.
+	QMPSocketPath string
+	// LogFile is the file the output of the daemon is written to. Defaults to a file next to the qmp socket.
+	LogFile string
+}
+
+// CheckStorageDaemon verifies that the binary at binPath is a qemu-storage-daemon and returns its version.
+func CheckStorageDaemon(ctx context.Context, binPath string) (string, error) {
+	info, err := os.Stat(binPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", binPath, err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("%s is not an executable", binPath)
+	}
+
+	out, err := exec.CommandContext(ctx, binPath, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w: %s", binPath, err, strings.TrimSpace(string(out)))
+	}
+	version := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	if !strings.HasPrefix(version, storageDaemonName) {
+		return "", fmt.Errorf("%s is not a qemu-storage-daemon: %q", binPath, version)
+	}
+	return version, nil
+}
+
+// QemuStorageProvider starts the qemu-storage-daemon, unless one already serves the qmp socket, and connects to
+// it. The daemon is started in its own session, so it and the volumes it exports survive provider restarts.
+func QemuStorageProvider(
+	ctx context.Context,
+	log logr.Logger,
+	paths host.Paths,
+	opts StorageDaemonOptions,
+) (Provider, error) {
+	if err := startStorageDaemon(ctx, log, opts); err != nil {
+		return nil, err
+	}
+	return QMPProvider(ctx, log, paths, opts.QMPSocketPath)
+}
+
+func startStorageDaemon(ctx context.Context, log logr.Logger, opts StorageDaemonOptions) error {
+	if storageDaemonRunning(opts.QMPSocketPath) {
+		log.V(1).Info("Using running qemu-storage-daemon", "socket", opts.QMPSocketPath)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.QMPSocketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create qmp socket directory: %w", err)
+	}
+	// A socket left behind by a daemon that is gone would make the new one fail to listen.
+	if err := os.Remove(opts.QMPSocketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale qmp socket: %w", err)
+	}
+
+	logFile := opts.LogFile
+	if logFile == "" {
+		logFile = filepath.Join(filepath.Dir(opts.QMPSocketPath), storageDaemonName+".log")
+	}
+	out, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open qemu-storage-daemon log file: %w", err)
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	cmd := exec.Command(opts.BinaryPath,
+		"--chardev", fmt.Sprintf("socket,id=qmp0,path=%s,server=on,wait=off", opts.QMPSocketPath),
+		"--monitor", "chardev=qmp0",
+	)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start qemu-storage-daemon: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	log.Info("Started qemu-storage-daemon", "pid", cmd.Process.Pid, "socket", opts.QMPSocketPath, "log", logFile)
+
+	timeout := time.After(storageDaemonStartTimeout)
+	for !storageDaemonRunning(opts.QMPSocketPath) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-exited:
+			return fmt.Errorf("qemu-storage-daemon exited, see %s: %w", logFile, err)
+		case <-timeout:
+			return fmt.Errorf("qemu-storage-daemon did not serve %s within %s", opts.QMPSocketPath,
+				storageDaemonStartTimeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+func storageDaemonRunning(socket string) bool {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ceph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const nodeNamePrefix = "ceph-"
+
+// CollectGarbage removes the exports and block nodes of the qemu-storage-daemon and the volume directories of
+// the ceph volumes and ceph root disks that are not in use. Everything is only removed if it was unused in the
+// previous run as well, so that volumes of machines created meanwhile are kept.
+func (p *plugin) CollectGarbage(ctx context.Context, volumes map[string][]*api.VolumeSpec) error {
+	names := sets.New[string]()
+	dirs := sets.New[string]()
+	for machineID, specs := range volumes {
+		for _, spec := range specs {
+			switch {
+			case spec.Connection != nil && spec.Connection.Driver == cephDriverName:
+				names.Insert(spec.Name)
+				dirs.Insert(p.host.MachineVolumeDir(machineID, cephDriverName, spec.Connection.Handle))
+			case spec.LocalDisk != nil && spec.LocalDisk.Medium == api.StorageMediumCeph:
+				names.Insert(spec.Name)
+				dirs.Insert(p.host.MachineVolumeDir(machineID, cephDriverName, cloneImageName(machineID, spec.Name)))
+			}
+		}
+	}
+
+	var errs []error
+	if err := p.provider.CollectGarbage(ctx, names); err != nil {
+		errs = append(errs, fmt.Errorf("error collecting storage daemon garbage: %w", err))
+	}
+	if err := p.collectVolumeDirs(dirs); err != nil {
+		errs = append(errs, fmt.Errorf("error collecting volume directories: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// collectVolumeDirs removes the volume directories, with their sockets, ceph confs and keys, of volumes that
+// do not exist anymore.
+func (p *plugin) collectVolumeDirs(dirs sets.Set[string]) error {
+	machineEntries, err := os.ReadDir(p.host.MachinesDir())
+	if err != nil {
+		return err
+	}
+
+	unused := sets.New[string]()
+	var errs []error
+	for _, machineEntry := range machineEntries {
+		if !machineEntry.IsDir() {
+			continue
+		}
+		pluginDir := p.host.MachineVolumesPluginDir(machineEntry.Name(), cephDriverName)
+		entries, err := os.ReadDir(pluginDir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			dir := filepath.Join(pluginDir, entry.Name())
+			if dirs.Has(dir) {
+				continue
+			}
+			unused.Insert(dir)
+			if !p.unusedDirs.Has(dir) {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	p.unusedDirs = unused
+	return errors.Join(errs...)
+}
+
+// CollectGarbage deletes the exports and block nodes of the volumes other than the given ones. Exports and
+// nodes are only deleted if they were unused in the previous run as well.
+func (q *QMP) CollectGarbage(_ context.Context, volumeNames sets.Set[string]) error {
+	inUse := sets.New[string]()
+	for name := range volumeNames {
+		handle := nodeNamePrefix + name
+		inUse.Insert(handle)
+		for slot := range rbdSlots {
+			inUse.Insert(rbdNodeName(handle, slot))
+		}
+	}
+
+	exports, err := q.listBlockExports()
+	if err != nil {
+		return err
+	}
+	nodes, err := q.listBlockNodes()
+	if err != nil {
+		return err
+	}
+	// The raw nodes are deleted before the rbd nodes below them.
+	slices.SortStableFunc(nodes, func(a, b BlockDevice) int {
+		switch {
+		case a.Drv != rbdDriver && b.Drv == rbdDriver:
+			return -1
+		case a.Drv == rbdDriver && b.Drv != rbdDriver:
+			return 1
+		}
+		return 0
+	})
+
+	unused := sets.New[string]()
+	var errs []error
+	for _, export := range exports {
+		if !strings.HasPrefix(export.ID, nodeNamePrefix) || inUse.Has(export.ID) {
+			continue
+		}
+		key := "export/" + export.ID
+		unused.Insert(key)
+		if !q.unused.Has(key) || export.ShuttingDown {
+			continue
+		}
+		q.log.Info("Deleting unused export", "id", export.ID)
+		if err := q.deleteExportBlockDev(export.ID); err != nil {
+			errs = append(errs, fmt.Errorf("error deleting export %s: %w", export.ID, err))
+		}
+	}
+	for _, node := range nodes {
+		if !strings.HasPrefix(node.NodeName, nodeNamePrefix) || inUse.Has(node.NodeName) {
+			continue
+		}
+		key := "node/" + node.NodeName
+		unused.Insert(key)
+		if !q.unused.Has(key) {
+			continue
+		}
+		q.log.Info("Deleting unused block node", "node", node.NodeName)
+		if err := q.deleteBlockDev(node.NodeName); err != nil {
+			errs = append(errs, fmt.Errorf("error deleting block node %s: %w", node.NodeName, err))
+		}
+	}
+	q.unused = unused
+	return errors.Join(errs...)
+}
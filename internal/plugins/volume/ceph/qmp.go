@@ -10,17 +10,41 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/digitalocean/go-qemu/qmp"
 	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	volumeplugin "github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	rbdDriver = "rbd"
+
+	// rbdSlots is the number of rbd nodes of a volume, the current one and the one replacing it.
+	rbdSlots    = 2
+	rbdSlotFile = "rbd-slot"
 )
 
 type QMP struct {
 	log     logr.Logger
 	paths   host.Paths
 	monitor *qmp.SocketMonitor
+
+	// failedOps are the failed IO operations of the volumes at their last probe.
+	failedOpsMu sync.Mutex
+	failedOps   map[string]int64
+
+	// unused are the exports and block nodes found unused by the last garbage collection.
+	unused sets.Set[string]
+}
+
+func (q *QMP) Close() error {
+	return q.monitor.Disconnect()
 }
 
 func (q *QMP) Mount(_ context.Context, machineID string, volume *validatedVolume) (string, error) {
@@ -32,22 +56,33 @@ func (q *QMP) Mount(_ context.Context, machineID string, volume *validatedVolume
 	log := q.log.WithValues("machineID", machineID, "volumeID", volume.handle)
 	socketPath := filepath.Join(volumeDir, "socket")
 
-	log.V(2).Info("Checking ceph conf")
-	confPath, err := q.createCephConf(log, machineID, volume)
-	if err != nil {
-		return "", fmt.Errorf("error creating ceph conf: %w", err)
-	}
-
 	handle := fmt.Sprintf("ceph-%s", volume.name)
 
-	if _, err := q.queryBlockNode(handle); err != nil {
-		if !errors.Is(err, ErrNotFound) {
-			return "", fmt.Errorf("error querying block device: %w", err)
+	var reconnectErr error
+	node, err := q.queryBlockNode(handle)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		log.V(2).Info("Creating ceph conf")
+		confPath, err := q.createCephConf(log, machineID, volume, 0)
+		if err != nil {
+			return "", fmt.Errorf("error creating ceph conf: %w", err)
 		}
 
-		if err := q.addBlockDev(volume, confPath); err != nil {
+		if err := q.addRBDBlockDev(rbdNodeName(handle, 0), volume, confPath); err != nil {
 			return "", fmt.Errorf("error adding block device: %w", err)
 		}
+		if err := q.addRawBlockDev(handle, rbdNodeName(handle, 0)); err != nil {
+			return "", fmt.Errorf("error adding block device: %w", err)
+		}
+	case err != nil:
+		return "", fmt.Errorf("error querying block device: %w", err)
+	case node.Drv == rbdDriver:
+		// Volumes mounted by earlier versions export the rbd node itself, their connection cannot be changed
+		// until they are mounted again.
+		log.V(2).Info("Block device exports rbd node, skipping reconnect")
+	default:
+		// The guest keeps using the previous connection if the changed one fails, e.g. due to invalid credentials.
+		reconnectErr = q.reconnect(log, machineID, volume, handle)
 	}
 
 	if _, err := q.queryBlockExports(handle); err != nil {
@@ -60,9 +95,64 @@ func (q *QMP) Mount(_ context.Context, machineID string, volume *validatedVolume
 		}
 	}
 
+	if reconnectErr != nil {
+		return socketPath, fmt.Errorf("%w: error reconnecting block device: %w",
+			volumeplugin.ErrConnectionNotUpdated, reconnectErr)
+	}
 	return socketPath, nil
 }
 
+// reconnect opens the rbd image again if the monitors or the credentials of the volume changed, and swaps it in
+// below the exported raw node. The export and thereby the disk of the guest are kept.
+func (q *QMP) reconnect(log logr.Logger, machineID string, volume *validatedVolume, handle string) error {
+	slot, err := q.readRBDSlot(machineID, volume)
+	if err != nil {
+		return err
+	}
+	changed, err := q.cephConfChanged(machineID, volume, slot)
+	if err != nil || !changed {
+		return err
+	}
+
+	next := (slot + 1) % rbdSlots
+	log.V(1).Info("Ceph connection changed, reconnecting", "slot", next)
+	if _, err := q.queryBlockNode(rbdNodeName(handle, next)); err == nil {
+		if err := q.deleteBlockDev(rbdNodeName(handle, next)); err != nil {
+			return fmt.Errorf("error deleting stale block device: %w", err)
+		}
+	}
+
+	confPath, err := q.createCephConf(log, machineID, volume, next)
+	if err != nil {
+		return fmt.Errorf("error creating ceph conf: %w", err)
+	}
+	if err := q.addRBDBlockDev(rbdNodeName(handle, next), volume, confPath); err != nil {
+		return fmt.Errorf("error adding block device: %w", err)
+	}
+	if err := q.reopenRawBlockDev(handle, rbdNodeName(handle, next)); err != nil {
+		if deleteErr := q.deleteBlockDev(rbdNodeName(handle, next)); deleteErr != nil {
+			log.Error(deleteErr, "error deleting block device after failed reopen")
+		}
+		return fmt.Errorf("error reopening block device: %w", err)
+	}
+	if err := q.writeRBDSlot(machineID, volume, next); err != nil {
+		return err
+	}
+
+	if err := q.deleteBlockDev(rbdNodeName(handle, slot)); err != nil {
+		log.Error(err, "error deleting replaced block device")
+	}
+	// The replaced key may have been revoked, it is not kept around.
+	confPath, keyPath := q.cephConfPaths(machineID, volume, slot)
+	for _, path := range []string{confPath, keyPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error(err, "error removing replaced ceph conf", "path", path)
+		}
+	}
+	log.Info("Ceph connection updated", "slot", next)
+	return nil
+}
+
 func (q *QMP) Unmount(_ context.Context, machineID string, volumeName string) error {
 
 	handle := fmt.Sprintf("ceph-%s", volumeName)
@@ -77,64 +167,199 @@ func (q *QMP) Unmount(_ context.Context, machineID string, volumeName string) er
 		}
 	}
 
-	if _, err := q.queryBlockNode(handle); err != nil {
-		if !errors.Is(err, ErrNotFound) {
-			return fmt.Errorf("error querying block device: %w", err)
+	for _, nodeName := range []string{handle, rbdNodeName(handle, 0), rbdNodeName(handle, 1)} {
+		if _, err := q.queryBlockNode(nodeName); err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("error querying block device: %w", err)
+			}
+			continue
 		}
-	} else {
-		if err := q.deleteBlockDev(handle); err != nil {
+		if err := q.deleteBlockDev(nodeName); err != nil {
 			return fmt.Errorf("error deleting block device: %w", err)
 		}
 	}
 
+	q.failedOpsMu.Lock()
+	delete(q.failedOps, handle)
+	q.failedOpsMu.Unlock()
+
 	return nil
 
 }
 
-func (q *QMP) volumeDir(machineID string, volumeHandle string) string {
-	return q.paths.MachineVolumeDir(machineID, cephDriverName, volumeHandle)
+func (q *QMP) Stats(_ context.Context, _ string, volumeName string) (*api.VolumeStats, error) {
+	stats, err := q.queryBlockStats(fmt.Sprintf("ceph-%s", volumeName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.VolumeStats{
+		ReadBytes:          stats.RdBytes,
+		ReadOps:            stats.RdOperations,
+		ReadLatencyMicros:  averageMicros(stats.RdTotalTimeNs, stats.RdOperations),
+		WriteBytes:         stats.WrBytes,
+		WriteOps:           stats.WrOperations,
+		WriteLatencyMicros: averageMicros(stats.WrTotalTimeNs, stats.WrOperations),
+	}, nil
 }
 
-func (q *QMP) createCephConf(log logr.Logger, machineID string, volume *validatedVolume) (string, error) {
-	confPath := filepath.Join(
-		q.volumeDir(machineID, volume.handle),
-		"ceph.conf",
-	)
-	keyPath := filepath.Join(
-		q.volumeDir(machineID, volume.handle),
-		"ceph.key",
-	)
+// Probe checks that the volume is exported on its socket. Volumes whose IO failed since the last probe are
+// degraded.
+func (q *QMP) Probe(_ context.Context, _ string, volumeName string, socketPath string) (api.VolumeHealthState, string) {
+	handle := fmt.Sprintf("ceph-%s", volumeName)
 
-	log.V(2).Info("Creating ceph conf", "confPath", confPath)
-	confFile, err := os.OpenFile(confPath, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	export, err := q.queryBlockExports(handle)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return api.VolumeHealthStateUnavailable, "volume is not exported by the qemu-storage-daemon"
+	case err != nil:
+		return api.VolumeHealthStateUnavailable, fmt.Sprintf("qemu-storage-daemon is not reachable: %v", err)
+	case export.ShuttingDown:
+		return api.VolumeHealthStateUnavailable, "volume export is shutting down"
+	}
+
+	if stat, err := os.Stat(socketPath); err != nil || stat.Mode()&os.ModeSocket == 0 {
+		return api.VolumeHealthStateUnavailable, fmt.Sprintf("socket %s is missing", socketPath)
+	}
+
+	stats, err := q.queryBlockStats(handle)
+	if err != nil {
+		return api.VolumeHealthStateUnavailable, fmt.Sprintf("error querying volume stats: %v", err)
+	}
+	failedOps := stats.FailedRdOperations + stats.FailedWrOperations
+
+	q.failedOpsMu.Lock()
+	defer q.failedOpsMu.Unlock()
+	if q.failedOps == nil {
+		q.failedOps = map[string]int64{}
+	}
+	previous, ok := q.failedOps[handle]
+	q.failedOps[handle] = failedOps
+	// The counters start over when the volume is exported again.
+	if ok && failedOps > previous {
+		return api.VolumeHealthStateDegraded, fmt.Sprintf("%d IO operations failed", failedOps-previous)
+	}
+	return api.VolumeHealthStateHealthy, ""
+}
+
+func (q *QMP) queryBlockStats(nodeName string) (*BlockStats, error) {
+	cmd, err := json.Marshal(QMPRequest[BlockStatsArguments]{
+		Execute:   "query-blockstats",
+		Arguments: BlockStatsArguments{QueryNodes: true},
+	})
 	if err != nil {
-		return "", fmt.Errorf("error opening conf file %s: %w", confPath, err)
+		return nil, fmt.Errorf("error marshalling cmd: %w", err)
+	}
+
+	res, err := q.monitor.Run(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("error executing cmd: %w", err)
+	}
+
+	var stats BlockStatsResponse
+	if err := json.Unmarshal(res, &stats); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+
+	for _, node := range stats.Data {
+		if node.NodeName == nodeName {
+			return &node.Stats, nil
+		}
 	}
+	return nil, ErrNotFound
+}
+
+func averageMicros(totalNs, ops int64) int64 {
+	if ops == 0 {
+		return 0
+	}
+	return totalNs / ops / 1000
+}
+
+func (q *QMP) volumeDir(machineID string, volumeHandle string) string {
+	return q.paths.MachineVolumeDir(machineID, cephDriverName, volumeHandle)
+}
 
+// rbdNodeName returns the name of the rbd node in the slot. The exported node switches between the slots when
+// the image is reopened with a changed connection.
+func rbdNodeName(handle string, slot int) string {
+	return fmt.Sprintf("%s-rbd%d", handle, slot)
+}
+
+func (q *QMP) cephConfPaths(machineID string, volume *validatedVolume, slot int) (string, string) {
+	volumeDir := q.volumeDir(machineID, volume.handle)
+	return filepath.Join(volumeDir, fmt.Sprintf("ceph-%d.conf", slot)),
+		filepath.Join(volumeDir, fmt.Sprintf("ceph-%d.key", slot))
+}
+
+func cephConfData(volume *validatedVolume, keyPath string) (string, string) {
 	confData := fmt.Sprintf(
 		"[global]\nmon_host = %s \n\n[client.%s]\nkeyring = %s\n",
 		strings.Join(volume.monitors, ","),
 		volume.userID,
 		keyPath,
 	)
-	_, err = confFile.WriteString(confData)
-	if err != nil {
-		return "", fmt.Errorf("error writing to conf file %s: %w", confPath, err)
+	keyData := fmt.Sprintf("[client.%s]\nkey = %s\n", volume.userID, volume.userKey)
+	return confData, keyData
+}
+
+func (q *QMP) createCephConf(log logr.Logger, machineID string, volume *validatedVolume, slot int) (string, error) {
+	confPath, keyPath := q.cephConfPaths(machineID, volume, slot)
+	confData, keyData := cephConfData(volume, keyPath)
+
+	log.V(2).Info("Creating ceph conf", "confPath", confPath)
+	if err := os.WriteFile(confPath, []byte(confData), os.ModePerm); err != nil {
+		return "", fmt.Errorf("error writing conf file %s: %w", confPath, err)
 	}
 
 	log.V(1).Info("Creating ceph key", "keyPath", keyPath)
-	keyFile, err := os.OpenFile(keyPath, os.O_CREATE|os.O_WRONLY, os.ModePerm)
-	if err != nil {
-		return "", fmt.Errorf("error opening key file %s: %w", keyPath, err)
+	if err := os.WriteFile(keyPath, []byte(keyData), os.ModePerm); err != nil {
+		return "", fmt.Errorf("error writing key file %s: %w", keyPath, err)
 	}
 
-	keyData := fmt.Sprintf("[client.%s]\nkey = %s\n", volume.userID, volume.userKey)
-	_, err = keyFile.WriteString(keyData)
+	return confPath, nil
+}
+
+// cephConfChanged returns whether the ceph conf or key of the volume differ from the ones in the slot.
+func (q *QMP) cephConfChanged(machineID string, volume *validatedVolume, slot int) (bool, error) {
+	confPath, keyPath := q.cephConfPaths(machineID, volume, slot)
+	confData, keyData := cephConfData(volume, keyPath)
+	for path, data := range map[string]string{confPath: confData, keyPath: keyData} {
+		current, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return true, nil
+			}
+			return false, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		if string(current) != data {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (q *QMP) readRBDSlot(machineID string, volume *validatedVolume) (int, error) {
+	data, err := os.ReadFile(filepath.Join(q.volumeDir(machineID, volume.handle), rbdSlotFile))
 	if err != nil {
-		return "", fmt.Errorf("error writing to key file %s: %w", keyPath, err)
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error reading rbd slot: %w", err)
+	}
+	slot, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || slot < 0 || slot >= rbdSlots {
+		return 0, fmt.Errorf("invalid rbd slot %q", data)
 	}
+	return slot, nil
+}
 
-	return confPath, nil
+func (q *QMP) writeRBDSlot(machineID string, volume *validatedVolume, slot int) error {
+	path := filepath.Join(q.volumeDir(machineID, volume.handle), rbdSlotFile)
+	if err := os.WriteFile(path, []byte(strconv.Itoa(slot)), 0644); err != nil {
+		return fmt.Errorf("error writing rbd slot: %w", err)
+	}
+	return nil
 }
 
 type BlockdevAddArguments struct {
@@ -150,6 +375,17 @@ type BlockdevAddArguments struct {
 	} `json:"cache"`
 }
 
+type RawBlockdevArguments struct {
+	NodeName string `json:"node-name"`
+	Driver   string `json:"driver"`
+	File     string `json:"file"`
+	Discard  string `json:"discard"`
+}
+
+type BlockdevReopenArguments struct {
+	Options []RawBlockdevArguments `json:"options"`
+}
+
 type BlockExportAddArguments struct {
 	ID       string `json:"id"`
 	NodeName string `json:"node-name"`
@@ -165,6 +401,10 @@ type DeleteExportBlockDevArguments struct {
 	ID string `json:"id"`
 }
 
+type BlockStatsArguments struct {
+	QueryNodes bool `json:"query-nodes"`
+}
+
 type DeleteBlockDevArguments struct {
 	Node string `json:"node-name"`
 }
@@ -180,6 +420,20 @@ var (
 
 // nolint: unparam
 func (q *QMP) queryBlockNode(nodeName string) (*BlockDevice, error) {
+	devs, err := q.listBlockNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devs {
+		if dev.NodeName == nodeName {
+			return &dev, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (q *QMP) listBlockNodes() ([]BlockDevice, error) {
 	cmd, err := json.Marshal(QMPRequest[any]{
 		Execute: "query-named-block-nodes",
 	})
@@ -196,17 +450,25 @@ func (q *QMP) queryBlockNode(nodeName string) (*BlockDevice, error) {
 	if err := json.Unmarshal(res, &devs); err != nil {
 		return nil, fmt.Errorf("error unmarshalling response: %w", err)
 	}
+	return devs.Data, nil
+}
 
-	for _, dev := range devs.Data {
-		if dev.NodeName == nodeName {
+// nolint: unparam
+func (q *QMP) queryBlockExports(nodeName string) (*BlockExportNode, error) {
+	devs, err := q.listBlockExports()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dev := range devs {
+		if dev.ID == nodeName {
 			return &dev, nil
 		}
 	}
 	return nil, ErrNotFound
 }
 
-// nolint: unparam
-func (q *QMP) queryBlockExports(nodeName string) (*BlockExportNode, error) {
+func (q *QMP) listBlockExports() ([]BlockExportNode, error) {
 	cmd, err := json.Marshal(QMPRequest[any]{
 		Execute: "query-block-exports",
 	})
@@ -223,21 +485,15 @@ func (q *QMP) queryBlockExports(nodeName string) (*BlockExportNode, error) {
 	if err := json.Unmarshal(res, &devs); err != nil {
 		return nil, fmt.Errorf("error unmarshalling response: %w", err)
 	}
-
-	for _, dev := range devs.Data {
-		if dev.ID == nodeName {
-			return &dev, nil
-		}
-	}
-	return nil, ErrNotFound
+	return devs.Data, nil
 }
 
-func (q *QMP) addBlockDev(volume *validatedVolume, confPath string) error {
+func (q *QMP) addRBDBlockDev(nodeName string, volume *validatedVolume, confPath string) error {
 	cmd, err := json.Marshal(QMPRequest[BlockdevAddArguments]{
 		Execute: "blockdev-add",
 		Arguments: BlockdevAddArguments{
-			NodeName: fmt.Sprintf("ceph-%s", volume.name),
-			Driver:   "rbd",
+			NodeName: nodeName,
+			Driver:   rbdDriver,
 			Pool:     volume.pool,
 			Image:    volume.image,
 			User:     volume.userID,
@@ -259,6 +515,50 @@ func (q *QMP) addBlockDev(volume *validatedVolume, confPath string) error {
 	return nil
 }
 
+// addRawBlockDev adds the exported node on top of the rbd node, so that the rbd node can be replaced.
+func (q *QMP) addRawBlockDev(nodeName string, file string) error {
+	cmd, err := json.Marshal(QMPRequest[RawBlockdevArguments]{
+		Execute:   "blockdev-add",
+		Arguments: rawBlockdevArguments(nodeName, file),
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling cmd: %w", err)
+	}
+
+	if _, err := q.monitor.Run(cmd); err != nil {
+		return fmt.Errorf("error executing cmd: %w", err)
+	}
+
+	return nil
+}
+
+func (q *QMP) reopenRawBlockDev(nodeName string, file string) error {
+	cmd, err := json.Marshal(QMPRequest[BlockdevReopenArguments]{
+		Execute: "blockdev-reopen",
+		Arguments: BlockdevReopenArguments{
+			Options: []RawBlockdevArguments{rawBlockdevArguments(nodeName, file)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling cmd: %w", err)
+	}
+
+	if _, err := q.monitor.Run(cmd); err != nil {
+		return fmt.Errorf("error executing cmd: %w", err)
+	}
+
+	return nil
+}
+
+func rawBlockdevArguments(nodeName string, file string) RawBlockdevArguments {
+	return RawBlockdevArguments{
+		NodeName: nodeName,
+		Driver:   "raw",
+		File:     file,
+		Discard:  "unmap",
+	}
+}
+
 func (q *QMP) deleteBlockDev(handle string) error {
 	cmd, err := json.Marshal(QMPRequest[DeleteBlockDevArguments]{
 		Execute: "blockdev-del",
@@ -323,6 +623,27 @@ func (q *QMP) deleteExportBlockDev(nodeName string) error {
 	return nil
 }
 
+type BlockStatsResponse struct {
+	Data []BlockStatsNode `json:"return"`
+}
+
+type BlockStatsNode struct {
+	NodeName string     `json:"node-name"`
+	Stats    BlockStats `json:"stats"`
+}
+
+type BlockStats struct {
+	RdBytes       int64 `json:"rd_bytes"`
+	WrBytes       int64 `json:"wr_bytes"`
+	RdOperations  int64 `json:"rd_operations"`
+	WrOperations  int64 `json:"wr_operations"`
+	RdTotalTimeNs int64 `json:"rd_total_time_ns"`
+	WrTotalTimeNs int64 `json:"wr_total_time_ns"`
+
+	FailedRdOperations int64 `json:"failed_rd_operations"`
+	FailedWrOperations int64 `json:"failed_wr_operations"`
+}
+
 type BlockExportResponse struct {
 	Data []BlockExportNode `json:"return"`
 }
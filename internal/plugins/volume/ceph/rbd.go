@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ceph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// rbdNotFoundExitCode is the exit code of the rbd CLI if an image or snapshot does not exist (ENOENT).
+const rbdNotFoundExitCode = 2
+
+// rbdCLI manages the images of a pool with the rbd CLI.
+type rbdCLI struct {
+	pool     string
+	monitors []string
+	userID   string
+	keyFile  string
+}
+
+// spec returns the spec of an image of the pool.
+func (c *rbdCLI) spec(image string) string {
+	return c.pool + "/" + image
+}
+
+// command returns the rbd command with the args, authenticated against the cluster.
+func (c *rbdCLI) command(args ...string) *exec.Cmd {
+	return exec.Command("rbd", append([]string{
+		"--id", c.userID,
+		"--keyfile", c.keyFile,
+		"-m", strings.Join(c.monitors, ","),
+	}, args...)...)
+}
+
+func (c *rbdCLI) run(log logr.Logger, stdin io.Reader, args ...string) ([]byte, error) {
+	log.V(2).Info("Running rbd", "args", args)
+
+	var stdout, stderr bytes.Buffer
+	cmd := c.command(args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &rbdError{args: args, err: err, stderr: string(bytes.TrimSpace(stderr.Bytes()))}
+	}
+	return stdout.Bytes(), nil
+}
+
+type rbdError struct {
+	args   []string
+	err    error
+	stderr string
+}
+
+func (e *rbdError) Error() string {
+	return fmt.Sprintf("rbd %s failed: %v: %s", strings.Join(e.args, " "), e.err, e.stderr)
+}
+
+func (e *rbdError) Unwrap() error {
+	return e.err
+}
+
+func isRBDNotFound(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == rbdNotFoundExitCode
+}
+
+// size returns the size of the image or snapshot in bytes. It reports false if it does not exist.
+func (c *rbdCLI) size(log logr.Logger, image string) (int64, bool, error) {
+	out, err := c.run(log, nil, "info", "--format", "json", c.spec(image))
+	if err != nil {
+		if isRBDNotFound(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	info := struct {
+		Size int64 `json:"size"`
+	}{}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return 0, false, fmt.Errorf("error decoding rbd info of %s: %w", image, err)
+	}
+	return info.Size, true, nil
+}
+
+func (c *rbdCLI) importImage(log logr.Logger, data io.Reader, image string) error {
+	_, err := c.run(log, data, "import", "-", c.spec(image))
+	return err
+}
+
+// createProtectedSnapshot creates a snapshot of the image that can be cloned.
+func (c *rbdCLI) createProtectedSnapshot(log logr.Logger, image, snapshot string) error {
+	if _, err := c.run(log, nil, "snap", "create", c.spec(image)+"@"+snapshot); err != nil {
+		return err
+	}
+	_, err := c.run(log, nil, "snap", "protect", c.spec(image)+"@"+snapshot)
+	return err
+}
+
+func (c *rbdCLI) rename(log logr.Logger, image, newImage string) error {
+	_, err := c.run(log, nil, "rename", c.spec(image), c.spec(newImage))
+	return err
+}
+
+func (c *rbdCLI) clone(log logr.Logger, image, snapshot, clone string) error {
+	_, err := c.run(log, nil, "clone", c.spec(image)+"@"+snapshot, c.spec(clone))
+	return err
+}
+
+// resize grows the image to at least size bytes, rounded up to full MiB.
+func (c *rbdCLI) resize(log logr.Logger, image string, size int64) error {
+	const mib = 1024 * 1024
+	_, err := c.run(log, nil, "resize", "--size", fmt.Sprintf("%dM", (size+mib-1)/mib), c.spec(image))
+	return err
+}
+
+// remove removes the image if it exists, along with its snapshots.
+func (c *rbdCLI) remove(log logr.Logger, image string) error {
+	if _, exists, err := c.size(log, image); err != nil || !exists {
+		return err
+	}
+
+	snapshots, err := c.snapshots(log, image)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Protected == "true" {
+			if _, err := c.run(log, nil, "snap", "unprotect", c.spec(image)+"@"+snapshot.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if len(snapshots) > 0 {
+		if _, err := c.run(log, nil, "snap", "purge", c.spec(image)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.run(log, nil, "rm", c.spec(image)); err != nil && !isRBDNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+type rbdSnapshot struct {
+	Name      string `json:"name"`
+	Protected string `json:"protected"`
+}
+
+func (c *rbdCLI) snapshots(log logr.Logger, image string) ([]rbdSnapshot, error) {
+	out, err := c.run(log, nil, "snap", "ls", "--format", "json", c.spec(image))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []rbdSnapshot
+	if err := json.Unmarshal(out, &snapshots); err != nil {
+		return nil, fmt.Errorf("error decoding rbd snapshots of %s: %w", image, err)
+	}
+	return snapshots, nil
+}
+
+// removeSnapshot removes the snapshot of the image if it exists, unprotecting it first.
+func (c *rbdCLI) removeSnapshot(log logr.Logger, image, snapshot string) error {
+	snapshots, err := c.snapshots(log, image)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		if s.Name != snapshot {
+			continue
+		}
+		if s.Protected == "true" {
+			if _, err := c.run(log, nil, "snap", "unprotect", c.spec(image)+"@"+snapshot); err != nil {
+				return err
+			}
+		}
+		_, err := c.run(log, nil, "snap", "rm", c.spec(image)+"@"+snapshot)
+		return err
+	}
+	return nil
+}
+
+// flatten copies the data of the parent snapshot into the clone, which makes it independent of the parent.
+func (c *rbdCLI) flatten(log logr.Logger, image string) error {
+	_, err := c.run(log, nil, "flatten", c.spec(image))
+	return err
+}
+
+// export streams the content of the image. A failure of rbd is returned by Read at the end of the stream.
+func (c *rbdCLI) export(log logr.Logger, image string) (io.ReadCloser, error) {
+	args := []string{"export", c.spec(image), "-"}
+	log.V(2).Info("Running rbd", "args", args)
+
+	cmd := c.command(args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, &rbdError{args: args, err: err}
+	}
+	return &rbdExport{cmd: cmd, args: args, stdout: stdout, stderr: stderr}, nil
+}
+
+type rbdExport struct {
+	cmd    *exec.Cmd
+	args   []string
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+	done   bool
+	err    error
+}
+
+func (e *rbdExport) Read(p []byte) (int, error) {
+	n, err := e.stdout.Read(p)
+	if errors.Is(err, io.EOF) {
+		if waitErr := e.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (e *rbdExport) Close() error {
+	if e.done {
+		return nil
+	}
+	// Closing the pipe stops an export that was not read to the end.
+	_ = e.stdout.Close()
+	_ = e.cmd.Process.Kill()
+	_ = e.wait()
+	return nil
+}
+
+func (e *rbdExport) wait() error {
+	if !e.done {
+		e.done = true
+		if err := e.cmd.Wait(); err != nil {
+			e.err = &rbdError{args: e.args, err: err, stderr: string(bytes.TrimSpace(e.stderr.Bytes()))}
+		}
+	}
+	return e.err
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package volume
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+)
+
+const (
+	DefaultGarbageCollectionInterval = 10 * time.Minute
+
+	// startupGarbageCollectionDelay is the delay of the second run. Plugins only remove what was unused in two
+	// runs, this removes the leftovers of a crash soon after the start.
+	startupGarbageCollectionDelay = time.Minute
+)
+
+// GarbageCollector releases the resources of volumes whose machine or volume does not exist anymore, on start
+// and then periodically.
+type GarbageCollector struct {
+	log      logr.Logger
+	machines store.Store[*api.Machine]
+	plugins  *PluginManager
+	interval time.Duration
+}
+
+func NewGarbageCollector(
+	log logr.Logger,
+	machines store.Store[*api.Machine],
+	plugins *PluginManager,
+	interval time.Duration,
+) (*GarbageCollector, error) {
+	if machines == nil {
+		return nil, fmt.Errorf("must specify machine store")
+	}
+	if plugins == nil {
+		return nil, fmt.Errorf("must specify plugin manager")
+	}
+	if interval == 0 {
+		interval = DefaultGarbageCollectionInterval
+	}
+
+	return &GarbageCollector{
+		log:      log,
+		machines: machines,
+		plugins:  plugins,
+		interval: interval,
+	}, nil
+}
+
+func (c *GarbageCollector) Start(ctx context.Context) error {
+	delay := min(c.interval, startupGarbageCollectionDelay)
+	for {
+		c.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = c.interval
+	}
+}
+
+func (c *GarbageCollector) collect(ctx context.Context) {
+	machines, err := c.machines.List(ctx)
+	if err != nil {
+		c.log.Error(err, "Failed to list machines")
+		return
+	}
+
+	// Volumes marked as deleted are still in use until the machine controller deleted them.
+	volumes := map[string][]*api.VolumeSpec{}
+	for _, machine := range machines {
+		volumes[machine.ID] = machine.Spec.Volumes
+	}
+
+	c.plugins.mu.RLock()
+	defer c.plugins.mu.RUnlock()
+	for name, plugin := range c.plugins.plugins {
+		gcPlugin, ok := plugin.(GarbageCollectPlugin)
+		if !ok {
+			continue
+		}
+		if err := gcPlugin.CollectGarbage(ctx, volumes); err != nil {
+			c.log.Error(err, "Failed to collect garbage", "plugin", name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package localdisk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"k8s.io/utils/ptr"
+)
+
+// cloneBlockSize is the size of the blocks a disk is copied in, blocks of zeros are left as holes.
+const cloneBlockSize = 64 * 1024
+
+// Clone provisions the disk with a copy of the disk of the source machine.
+func (p *plugin) Clone(ctx context.Context, spec *api.VolumeSpec, sourceMachineID string, machineID string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	volumeDir := p.volumeDir(spec.Name, machineID)
+	sourceDir := p.volumeDir(spec.Name, sourceMachineID)
+	if spec.LocalDisk.Medium == api.StorageMediumMemory {
+		if p.memoryDir == "" {
+			return fmt.Errorf("memory disks are not supported")
+		}
+		volumeDir = p.memoryVolumeDir(spec.Name, machineID)
+		sourceDir = p.memoryVolumeDir(spec.Name, sourceMachineID)
+	}
+
+	diskFilename := filepath.Join(volumeDir, "disk.raw")
+	if _, err := os.Stat(diskFilename); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(volumeDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// The disk is copied to a file of its own first, so that an interrupted copy is not taken for the disk.
+	cloningFilename := diskFilename + ".cloning"
+	log.V(1).Info("Copying disk of machine", "file", diskFilename, "source", sourceDir)
+	if err := copySparse(filepath.Join(sourceDir, "disk.raw"), cloningFilename); err != nil {
+		return fmt.Errorf("error copying disk: %w", err)
+	}
+	if err := os.Chmod(cloningFilename, os.FileMode(0666)); err != nil {
+		return fmt.Errorf("error changing disk file mode: %w", err)
+	}
+
+	// The disk was provisioned from the image of the source disk, which may differ from the one of the spec.
+	image, err := os.ReadFile(filepath.Join(sourceDir, imageFilename))
+	switch {
+	case err == nil:
+		if err := p.writeImage(volumeDir, ptr.To(string(image))); err != nil {
+			return err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("error reading source disk image: %w", err)
+	}
+
+	return os.Rename(cloningFilename, diskFilename)
+}
+
+// copySparse copies the file, leaving holes for the blocks of zeros.
+func copySparse(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = srcFile.Close()
+	}()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dstFile.Close()
+	}()
+
+	var (
+		block = make([]byte, cloneBlockSize)
+		zeros = make([]byte, cloneBlockSize)
+		size  int64
+	)
+	for {
+		n, err := io.ReadFull(srcFile, block)
+		if n > 0 {
+			if bytes.Equal(block[:n], zeros[:n]) {
+				if _, err := dstFile.Seek(int64(n), io.SeekCurrent); err != nil {
+					return err
+				}
+			} else if _, err := dstFile.Write(block[:n]); err != nil {
+				return err
+			}
+			size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// A trailing hole is only part of the file once it is truncated to its size.
+	if err := dstFile.Truncate(size); err != nil {
+		return err
+	}
+	return dstFile.Close()
+}
@@ -9,15 +9,18 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/go-logr/logr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/raw"
 	ociutils "github.com/ironcore-dev/provider-utils/ociutils/oci"
+	"k8s.io/utils/ptr"
 	utilstrings "k8s.io/utils/strings"
 )
 
@@ -25,6 +28,9 @@ const (
 	pluginName = "cloud-hypervisor-provider.ironcore.dev/empty-disk"
 
 	defaultSize = 500 * 1024 * 1024 // 500Mi by default
+
+	// imageFilename records the image a disk was provisioned from.
+	imageFilename = "image"
 )
 
 type plugin struct {
@@ -32,12 +38,16 @@ type plugin struct {
 	raw  raw.Raw
 
 	imageCache ociutils.Cache
+
+	// memoryDir is the tmpfs directory memory disks are stored in, memory disks are not supported if empty.
+	memoryDir string
 }
 
-func NewPlugin(raw raw.Raw, osImages ociutils.Cache) volume.Plugin {
+func NewPlugin(raw raw.Raw, osImages ociutils.Cache, memoryDir string) volume.Plugin {
 	return &plugin{
 		raw:        raw,
 		imageCache: osImages,
+		memoryDir:  memoryDir,
 	}
 }
 
@@ -58,17 +68,29 @@ func (p *plugin) GetBackingVolumeID(volume *api.VolumeSpec) (string, error) {
 }
 
 func (p *plugin) CanSupport(volume *api.VolumeSpec) bool {
-	return volume.LocalDisk != nil
+	return volume.LocalDisk != nil && volume.LocalDisk.Medium != api.StorageMediumCeph
 }
 
-func (p *plugin) diskFilename(computeVolumeName string, machineID string) string {
-	return filepath.Join(p.host.MachineVolumeDir(machineID, utilstrings.EscapeQualifiedName(pluginName), computeVolumeName), "disk.raw")
+func (p *plugin) volumeDir(computeVolumeName string, machineID string) string {
+	return p.host.MachineVolumeDir(machineID, utilstrings.EscapeQualifiedName(pluginName), computeVolumeName)
+}
+
+// memoryVolumeDir is the directory of a memory disk. It is outside the machine directory, which is not on a
+// tmpfs.
+func (p *plugin) memoryVolumeDir(computeVolumeName string, machineID string) string {
+	return filepath.Join(p.memoryDir, machineID, utilstrings.EscapeQualifiedName(computeVolumeName))
 }
 
 func (p *plugin) Apply(ctx context.Context, spec *api.VolumeSpec, machineID string) (*api.VolumeStatus, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
-	volumeDir := p.host.MachineVolumeDir(machineID, utilstrings.EscapeQualifiedName(pluginName), spec.Name)
+	volumeDir := p.volumeDir(spec.Name, machineID)
+	if spec.LocalDisk.Medium == api.StorageMediumMemory {
+		if p.memoryDir == "" {
+			return nil, fmt.Errorf("memory disks are not supported")
+		}
+		volumeDir = p.memoryVolumeDir(spec.Name, machineID)
+	}
 
 	log.V(2).Info("Creating volume directory", "directory", volumeDir)
 	if err := os.MkdirAll(volumeDir, os.ModePerm); err != nil {
@@ -80,38 +102,62 @@ func (p *plugin) Apply(ctx context.Context, spec *api.VolumeSpec, machineID stri
 		size = defaultSize
 	}
 
-	diskFilename := p.diskFilename(spec.Name, machineID)
+	diskFilename := filepath.Join(volumeDir, "disk.raw")
 	if _, err := os.Stat(diskFilename); err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			return nil, fmt.Errorf("error stat-ing disk: %w", err)
 		}
 
-		var createOption raw.CreateOption
+		var createOptions []raw.CreateOption
 		if imgRef := spec.LocalDisk.Image; imgRef != nil {
 			img, err := p.imageCache.Get(ctx, *imgRef)
 			if err != nil {
 				return nil, err
 			}
 
-			log.V(2).Info("Create disk with rootfs from img", "file", img.RootFS.Path)
-			createOption = raw.WithSourceFile(img.RootFS.Path)
-		} else {
-			size := spec.LocalDisk.Size
-			if size == 0 {
-				size = defaultSize
+			log.V(2).Info("Create disk with rootfs from img", "file", img.RootFS.Path, "size", spec.LocalDisk.Size)
+			createOptions = append(createOptions, raw.WithSourceFile(img.RootFS.Path))
+			// The disk keeps the size of the rootfs unless it is to be grown.
+			if spec.LocalDisk.Size != 0 {
+				createOptions = append(createOptions, raw.WithSize(spec.LocalDisk.Size))
 			}
-
+		} else {
 			log.V(2).Info("Create disk", "size", size)
-			createOption = raw.WithSize(size)
+			createOptions = append(createOptions, raw.WithSize(size))
+			if fs := spec.LocalDisk.Filesystem; fs != nil {
+				createOptions = append(createOptions, raw.WithFilesystem{
+					Type:    string(fs.Type),
+					Label:   fs.Label,
+					Options: fs.Options,
+				})
+			}
 		}
 
-		if err := p.raw.Create(diskFilename, createOption); err != nil {
+		if err := p.raw.Create(diskFilename, createOptions...); err != nil {
 			return nil, fmt.Errorf("error creating disk %w", err)
 		}
 		if err := os.Chmod(diskFilename, os.FileMode(0666)); err != nil {
 			return nil, fmt.Errorf("error changing disk file mode: %w", err)
 		}
+		if err := p.writeImage(volumeDir, spec.LocalDisk.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	image, err := p.readImage(volumeDir, spec.LocalDisk.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disks provisioned from an image are as large as the image if they were not grown.
+	if image != "" {
+		stat, err := os.Stat(diskFilename)
+		if err != nil {
+			return nil, fmt.Errorf("error stat-ing disk: %w", err)
+		}
+		size = stat.Size()
 	}
+
 	return &api.VolumeStatus{
 		Name:   spec.Name,
 		Type:   api.VolumeFileType,
@@ -119,11 +165,48 @@ func (p *plugin) Apply(ctx context.Context, spec *api.VolumeSpec, machineID stri
 		Handle: generateWWN(machineID, spec.Name),
 		State:  api.VolumeStatePrepared,
 		Size:   size,
+		Image:  image,
 	}, nil
 }
 
+func (p *plugin) writeImage(volumeDir string, image *string) error {
+	if image == nil {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(volumeDir, imageFilename), []byte(*image), 0644); err != nil {
+		return fmt.Errorf("error recording disk image: %w", err)
+	}
+	return nil
+}
+
+// readImage returns the image the disk was provisioned from. Disks provisioned before the image was recorded
+// are assumed to be provisioned from the current image, which is recorded for them.
+func (p *plugin) readImage(volumeDir string, image *string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(volumeDir, imageFilename))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("error reading disk image: %w", err)
+		}
+		if err := p.writeImage(volumeDir, image); err != nil {
+			return "", err
+		}
+		return ptr.Deref(image, ""), nil
+	}
+	return string(data), nil
+}
+
 func (p *plugin) Delete(_ context.Context, computeVolumeName string, machineID string) error {
-	return os.RemoveAll(p.host.MachineVolumeDir(machineID, utilstrings.EscapeQualifiedName(pluginName), computeVolumeName))
+	if p.memoryDir != "" {
+		if err := os.RemoveAll(p.memoryVolumeDir(computeVolumeName, machineID)); err != nil {
+			return fmt.Errorf("error removing memory disk: %w", err)
+		}
+		// The machine directory is left once its last memory disk is removed.
+		if err := os.Remove(filepath.Join(p.memoryDir, machineID)); err != nil && !errors.Is(err, os.ErrNotExist) &&
+			!errors.Is(err, syscall.ENOTEMPTY) {
+			return fmt.Errorf("error removing memory disk directory: %w", err)
+		}
+	}
+	return os.RemoveAll(p.volumeDir(computeVolumeName, machineID))
 }
 
 func generateWWN(machineID, diskName string) string {
@@ -135,3 +218,12 @@ func generateWWN(machineID, diskName string) string {
 
 	return strings.ToUpper(hex.EncodeToString(wwnBytes))
 }
+
+// Open returns a reader of the disk file.
+func (p *plugin) Open(_ context.Context, spec *api.VolumeSpec, machineID string) (io.ReadCloser, error) {
+	volumeDir := p.volumeDir(spec.Name, machineID)
+	if spec.LocalDisk.Medium == api.StorageMediumMemory {
+		volumeDir = p.memoryVolumeDir(spec.Name, machineID)
+	}
+	return os.Open(filepath.Join(volumeDir, "disk.raw"))
+}
@@ -5,7 +5,9 @@ package volume
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
@@ -14,10 +16,16 @@ import (
 
 type Host interface {
 	PluginDir(pluginName string) string
+	MachinesDir() string
 	MachinePluginDir(machineID string, pluginName string) string
+	MachineVolumesPluginDir(machineID string, pluginName string) string
 	MachineVolumeDir(machineID string, pluginName, volumeName string) string
 }
 
+// ErrConnectionNotUpdated is returned by Apply along with the status of a volume in use whose changed connection,
+// e.g. rotated credentials, could not be swapped in. The volume stays usable with its previous connection.
+var ErrConnectionNotUpdated = errors.New("changed connection of the volume was not applied")
+
 type Plugin interface {
 	Init(host Host) error
 	Name() string
@@ -28,6 +36,38 @@ type Plugin interface {
 	Delete(ctx context.Context, computeVolumeName string, machineID string) error
 }
 
+// StatsPlugin is implemented by plugins whose volumes are served outside of cloud-hypervisor, which has no
+// counters for them.
+type StatsPlugin interface {
+	Stats(ctx context.Context, computeVolumeName string, machineID string) (*api.VolumeStats, error)
+}
+
+// HealthPlugin is implemented by plugins whose volumes are served by a daemon, which may fail while the volume is
+// attached. Probe returns the state of the attached volume and, unless it is healthy, the reason.
+type HealthPlugin interface {
+	Probe(ctx context.Context, status *api.VolumeStatus, machineID string) (api.VolumeHealthState, string)
+}
+
+// GarbageCollectPlugin is implemented by plugins whose volumes leave resources behind if the provider crashes
+// while deleting them. CollectGarbage releases the resources of all volumes but the given volumes of the
+// machines, keyed by machine ID.
+type GarbageCollectPlugin interface {
+	CollectGarbage(ctx context.Context, volumes map[string][]*api.VolumeSpec) error
+}
+
+// ClonePlugin is implemented by plugins whose volumes can be copied to another machine. Clone provisions the
+// volume of the machine with the content of the volume of the same name of the source machine, whose VM must
+// not write to it meanwhile. Volumes that were provisioned already are left unchanged.
+type ClonePlugin interface {
+	Clone(ctx context.Context, spec *api.VolumeSpec, sourceMachineID string, machineID string) error
+}
+
+// ExportPlugin is implemented by plugins whose volumes can be read as raw disk, e.g. to export them as image. Open
+// returns a reader of the content of the volume, whose VM must not write to it meanwhile.
+type ExportPlugin interface {
+	Open(ctx context.Context, spec *api.VolumeSpec, machineID string) (io.ReadCloser, error)
+}
+
 type PluginManager struct {
 	mu      sync.RWMutex
 	plugins map[string]Plugin
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package quota
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Default is the name of the quota of the label values without a quota of their own.
+const Default = "*"
+
+// Quota limits the machines whose quota label has the name of the quota as value. Zero limits are unlimited.
+type Quota struct {
+	Name        string
+	Machines    int64
+	Cpu         int64
+	MemoryBytes int64
+}
+
+// Registry holds the quotas of a label, they can be replaced at runtime.
+type Registry struct {
+	label string
+
+	mu     sync.RWMutex
+	quotas map[string]Quota
+}
+
+func NewRegistry(label string, quotas []Quota) (*Registry, error) {
+	registry := &Registry{label: label}
+	if err := registry.Set(quotas); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Label returns the IRI label of the machines whose value selects their quota.
+func (r *Registry) Label() string {
+	return r.label
+}
+
+// Set replaces the quotas of the registry.
+func (r *Registry) Set(quotas []Quota) error {
+	byName := map[string]Quota{}
+	for _, quota := range quotas {
+		if _, ok := byName[quota.Name]; ok {
+			return fmt.Errorf("multiple quotas with same name (%s) found", quota.Name)
+		}
+		byName[quota.Name] = quota
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quotas = byName
+	return nil
+}
+
+// Get returns the quota of the label value, the Default quota if it has none.
+func (r *Registry) Get(value string) (Quota, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if quota, found := r.quotas[value]; found {
+		return quota, true
+	}
+	quota, found := r.quotas[Default]
+	return quota, found
+}
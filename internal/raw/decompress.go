@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package raw
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+const (
+	compressionNone = ""
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// compression returns the compression of the file, detected by its magic number.
+func compression(file *os.File) (string, error) {
+	magic := make([]byte, len(zstdMagic))
+	n, err := file.ReadAt(magic, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed reading magic number: %w", err)
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		return compressionZstd, nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		return compressionGzip, nil
+	default:
+		return compressionNone, nil
+	}
+}
+
+// decompressingReader returns a reader of the decompressed content of the file if it is gzip or zstd compressed.
+// Other files are returned as they are, so that copying them stays a file to file copy.
+func decompressingReader(file *os.File) (io.ReadCloser, error) {
+	fileCompression, err := compression(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fileCompression {
+	case compressionZstd:
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating zstd reader: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	case compressionGzip:
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating gzip reader: %w", err)
+		}
+		return reader, nil
+	default:
+		return file, nil
+	}
+}
+
+// Open returns a reader of the decompressed content of the file, see decompressingReader.
+func Open(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := decompressingReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if reader == io.ReadCloser(file) {
+		return file, nil
+	}
+	return &fileReader{ReadCloser: reader, file: file}, nil
+}
+
+// fileReader closes the file along with the reader of its content.
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	return errors.Join(r.ReadCloser.Close(), r.file.Close())
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package raw
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/go-logr/logr"
+)
+
+// Filesystem is created in a file created without source file, with mkfs.<Type>.
+type Filesystem struct {
+	Type    string
+	Label   string
+	Options []string
+}
+
+type WithFilesystem Filesystem
+
+func (f WithFilesystem) ApplyToCreate(o *CreateOptions) {
+	o.Filesystem = (*Filesystem)(&f)
+}
+
+// formatFile creates the filesystem in the file. The file is removed if that fails, so that it is created
+// again.
+func formatFile(log logr.Logger, filename string, fs *Filesystem) error {
+	var args []string
+	if fs.Label != "" {
+		args = append(args, "-L", fs.Label)
+	}
+	args = append(args, fs.Options...)
+	args = append(args, filename)
+
+	log.V(2).Info("Creating filesystem", "type", fs.Type, "args", args)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("mkfs."+fs.Type, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if removeErr := os.Remove(filename); removeErr != nil {
+			log.Error(removeErr, "error removing file after failed mkfs")
+		}
+		return fmt.Errorf("mkfs.%s failed: %w: %s", fs.Type, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
@@ -12,6 +12,11 @@ type Raw interface {
 	Create(filename string, opts ...CreateOption) error
 }
 
+// Checker is implemented by implementations that depend on the host, e.g. on tools being installed.
+type Checker interface {
+	Check() error
+}
+
 type CreateOption interface {
 	ApplyToCreate(o *CreateOptions)
 }
@@ -28,9 +33,12 @@ func (s WithSourceFile) ApplyToCreate(o *CreateOptions) {
 	o.SourceFile = string(s)
 }
 
+// CreateOptions configure the created file. With a source file, Size grows the copy to the size if it is
+// smaller. Filesystem is only supported without source file.
 type CreateOptions struct {
 	Size       *int64
 	SourceFile string
+	Filesystem *Filesystem
 }
 
 func (o *CreateOptions) ApplyToCreate(o2 *CreateOptions) {
@@ -40,6 +48,9 @@ func (o *CreateOptions) ApplyToCreate(o2 *CreateOptions) {
 	if o.SourceFile != "" {
 		o2.SourceFile = o.SourceFile
 	}
+	if o.Filesystem != nil {
+		o2.Filesystem = o.Filesystem
+	}
 }
 
 func (o *CreateOptions) ApplyOptions(opts []CreateOption) {
@@ -32,10 +32,23 @@ func (Exec) Create(filename string, opts ...CreateOption) error {
 		if err := createEmptyFileWithSeek(log, filename, seek-1); err != nil {
 			return fmt.Errorf("failed creating the empty ephemeral disk at %s: %w", filename, err)
 		}
+		if o.Filesystem != nil {
+			if err := formatFile(log, filename, o.Filesystem); err != nil {
+				return fmt.Errorf("failed creating filesystem on the ephemeral disk at %s: %w", filename, err)
+			}
+		}
 	} else {
+		if o.Filesystem != nil {
+			return fmt.Errorf("must not specify Filesystem when creating with source file")
+		}
 		if err := copyFile(log, o.SourceFile, filename); err != nil {
 			return fmt.Errorf("failed creating virtual disk image, source: %s, destination: %s: %w", o.SourceFile, filename, err)
 		}
+		if o.Size != nil {
+			if err := growFile(filename, *o.Size); err != nil {
+				return fmt.Errorf("failed growing virtual disk image %s: %w", filename, err)
+			}
+		}
 	}
 
 	return nil
@@ -64,6 +77,18 @@ func createEmptyFileWithSeek(log logr.Logger, filename string, seek int64) error
 	return nil
 }
 
+// growFile extends the file with a hole to size, files of at least the size are left unchanged.
+func growFile(filename string, size int64) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if stat.Size() >= size {
+		return nil
+	}
+	return os.Truncate(filename, size)
+}
+
 func copyFile(log logr.Logger, src, dst string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -86,7 +111,20 @@ func copyFile(log logr.Logger, src, dst string) error {
 		}
 	}()
 
-	if _, err = io.Copy(dstFile, srcFile); err != nil {
+	// Compressed sources are decompressed while they are copied, without an intermediate file.
+	reader, err := decompressingReader(srcFile)
+	if err != nil {
+		return fmt.Errorf("failed reading source file: %w", err)
+	}
+	if reader != srcFile {
+		defer func() {
+			if err := reader.Close(); err != nil {
+				log.Error(err, "error closing decompressing reader in copyFile", "path", src)
+			}
+		}()
+	}
+
+	if _, err = io.Copy(dstFile, reader); err != nil {
 		return fmt.Errorf("failed to copy data from source file to destination file: %w", err)
 	}
 
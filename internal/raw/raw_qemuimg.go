@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package raw
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const qemuImgBinary = "qemu-img"
+
+// QemuImg creates raw files with qemu-img. Source files may be in any format qemu-img reads, e.g. qcow2 or
+// vmdk, and may have backing files. They are converted to a flat raw file. Compressed source files are
+// decompressed while copying, like by Exec.
+type QemuImg struct{}
+
+func (QemuImg) Check() error {
+	if _, err := exec.LookPath(qemuImgBinary); err != nil {
+		return fmt.Errorf("%s not found: %w", qemuImgBinary, err)
+	}
+	return nil
+}
+
+func (QemuImg) Create(filename string, opts ...CreateOption) error {
+	o := &CreateOptions{}
+	o.ApplyOptions(opts)
+	log := ctrl.Log.WithName("raw-disk").WithValues("filename", filename)
+
+	if o.SourceFile == "" {
+		if o.Size == nil {
+			return fmt.Errorf("must specify Size when creating without source file")
+		}
+		if err := qemuImg(log, "create", "-f", "raw", filename, strconv.FormatInt(*o.Size, 10)); err != nil {
+			return fmt.Errorf("failed creating the empty ephemeral disk at %s: %w", filename, err)
+		}
+		if o.Filesystem != nil {
+			if err := formatFile(log, filename, o.Filesystem); err != nil {
+				return fmt.Errorf("failed creating filesystem on the ephemeral disk at %s: %w", filename, err)
+			}
+		}
+		return nil
+	}
+	if o.Filesystem != nil {
+		return fmt.Errorf("must not specify Filesystem when creating with source file")
+	}
+
+	if err := convertFile(log, o.SourceFile, filename); err != nil {
+		return fmt.Errorf("failed creating virtual disk image, source: %s, destination: %s: %w", o.SourceFile, filename, err)
+	}
+	if o.Size != nil {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return fmt.Errorf("failed growing virtual disk image %s: %w", filename, err)
+		}
+		if stat.Size() < *o.Size {
+			if err := qemuImg(log, "resize", "-f", "raw", filename, strconv.FormatInt(*o.Size, 10)); err != nil {
+				return fmt.Errorf("failed growing virtual disk image %s: %w", filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+// convertFile converts the source file to a raw file. Compressed files are no disk images qemu-img reads, they
+// are decompressed instead.
+func convertFile(log logr.Logger, src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed opening source file: %w", err)
+	}
+	srcCompression, err := compression(srcFile)
+	if closeErr := srcFile.Close(); closeErr != nil {
+		log.Error(closeErr, "error closing source file in convertFile", "path", src)
+	}
+	if err != nil {
+		return err
+	}
+
+	if srcCompression != compressionNone {
+		return copyFile(log, src, dst)
+	}
+	return qemuImg(log, "convert", "-O", "raw", src, dst)
+}
+
+func qemuImg(log logr.Logger, args ...string) error {
+	log.V(2).Info("Running qemu-img", "args", args)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(qemuImgBinary, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", qemuImgBinary, args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+func init() {
+	utilruntime.Must(impls.Add("qemu-img", 1, QemuImg{}))
+}
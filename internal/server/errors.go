@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// storeError converts an error of the machine store into a grpc status, so that callers can tell missing and
+// conflicting machines apart from failures. Other errors are wrapped with msg.
+func storeError(err error, machineID, msg string) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return status.Errorf(codes.NotFound, "machine %s not found", machineID)
+	case errors.Is(err, store.ErrAlreadyExists):
+		return status.Errorf(codes.AlreadyExists, "machine %s already exists", machineID)
+	case errors.Is(err, store.ErrResourceVersionNotLatest):
+		return status.Errorf(codes.Aborted, "machine %s was modified concurrently", machineID)
+	default:
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+}
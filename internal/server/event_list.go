@@ -6,14 +6,23 @@ package server
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	irievent "github.com/ironcore-dev/ironcore/iri/apis/event/v1alpha1"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
+// eventsWaitTimeout is how long ListEvents waits for newer events than the resource version of the request.
+const eventsWaitTimeout = 30 * time.Second
+
 func (s *Server) filterEvents(events []*recorder.Event, filter *iri.EventFilter) []*recorder.Event {
 	if filter == nil {
 		return events
@@ -62,7 +71,38 @@ func (s *Server) convertEventToIRIEvent(events []*recorder.Event) ([]*irievent.E
 	return res, nil
 }
 
+// awaitEvents waits for events newer than the resource version in the metadata of the request, if any, and
+// reports the resource version of the listed events in the response header.
+func (s *Server) awaitEvents(ctx context.Context) error {
+	if s.eventWatcher == nil {
+		return nil
+	}
+
+	version := s.eventWatcher.ResourceVersion()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(api.EventsResourceVersionKey); len(values) > 0 {
+			resourceVersion, err := strconv.ParseUint(values[0], 10, 64)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid events resource version %q", values[0])
+			}
+
+			waitCtx, cancel := context.WithTimeout(ctx, eventsWaitTimeout)
+			version = s.eventWatcher.WaitForEvents(waitCtx, resourceVersion)
+			cancel()
+			if err := ctx.Err(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+		}
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(api.EventsResourceVersionKey, strconv.FormatUint(version, 10)))
+}
+
 func (s *Server) ListEvents(ctx context.Context, req *iri.ListEventsRequest) (*iri.ListEventsResponse, error) {
+	if err := s.awaitEvents(ctx); err != nil {
+		return nil, err
+	}
+
 	events := s.filterEvents(s.eventStore.ListEvents(), req.Filter)
 
 	iriEvents, err := s.convertEventToIRIEvent(events)
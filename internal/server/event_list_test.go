@@ -4,11 +4,21 @@
 package server_test
 
 import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 )
 
 var _ = Describe("ListEvents", func() {
@@ -30,4 +40,58 @@ var _ = Describe("ListEvents", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(createResp).NotTo(BeNil())
 	})
+
+	It("should wait for events newer than the resource version", func(ctx SpecContext) {
+		By("creating a machine")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec:     &iri.MachineSpec{Power: iri.Power_POWER_ON, Class: machineClassName},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+
+		listEvents := func(resourceVersion string) (*iri.ListEventsResponse, string, error) {
+			var reqCtx context.Context = ctx
+			if resourceVersion != "" {
+				reqCtx = metadata.AppendToOutgoingContext(reqCtx, api.EventsResourceVersionKey, resourceVersion)
+			}
+			var header metadata.MD
+			resp, err := machineClient.ListEvents(reqCtx, &iri.ListEventsRequest{}, grpc.Header(&header))
+			return resp, strings.Join(header.Get(api.EventsResourceVersionKey), ","), err
+		}
+
+		By("getting the resource version")
+		resp, version, err := listEvents("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Events).To(BeEmpty())
+		Expect(version).To(Equal("0"))
+
+		By("waiting for an event")
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(200 * time.Millisecond)
+			eventRecorder.Eventf(machine.Metadata, corev1.EventTypeNormal, "Tested", "Recorded by the test")
+		}()
+		start := time.Now()
+		resp, version, err = listEvents(version)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(version).To(Equal("1"))
+		Expect(resp.Events).To(ConsistOf(HaveField("Spec", SatisfyAll(
+			HaveField("InvolvedObjectMeta.Id", machine.ID),
+			HaveField("Reason", "Tested"),
+		))))
+
+		By("returning immediately for a resource version of another run")
+		_, version, err = listEvents("7")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1"))
+
+		By("rejecting an invalid resource version")
+		_, _, err = listEvents("latest")
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
 })
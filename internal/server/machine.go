@@ -4,10 +4,15 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/utils/ptr"
 )
 
@@ -16,6 +21,9 @@ func (s *Server) convertMachineToIRIMachine(machine *api.Machine) (*iri.Machine,
 	if err != nil {
 		return nil, fmt.Errorf("error getting iri metadata: %w", err)
 	}
+	if err := s.setIRINICIPs(metadata, machine); err != nil {
+		return nil, fmt.Errorf("error getting network interface ips: %w", err)
+	}
 
 	spec, err := s.getIRIMachineSpec(machine)
 	if err != nil {
@@ -146,6 +154,36 @@ func (s *Server) getIRINICStatus(machine *api.Machine) ([]*iri.NetworkInterfaceS
 	return nics, nil
 }
 
+// setIRINICIPs reports the addresses of the network interfaces in an annotation, as their IRI status has no
+// field for them.
+func (s *Server) setIRINICIPs(metadata *irimeta.ObjectMetadata, machine *api.Machine) error {
+	delete(metadata.Annotations, api.NetworkInterfaceIPsAnnotation)
+
+	ips := map[string]api.NetworkInterfaceIPs{}
+	for _, nic := range machine.Status.NetworkInterfaceStatus {
+		if nic.Name == "" || len(nic.IPs) == 0 && len(nic.PublicIPs) == 0 {
+			continue
+		}
+		ips[nic.Name] = api.NetworkInterfaceIPs{
+			IPs:       nic.IPs,
+			PublicIPs: nic.PublicIPs,
+		}
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(ips)
+	if err != nil {
+		return err
+	}
+	if metadata.Annotations == nil {
+		metadata.Annotations = map[string]string{}
+	}
+	metadata.Annotations[api.NetworkInterfaceIPsAnnotation] = string(data)
+	return nil
+}
+
 func (s *Server) getIRIMachineStatus(machine *api.Machine) (*iri.MachineStatus, error) {
 	state, err := s.getIRIState(machine.Status.State)
 	if err != nil {
@@ -168,9 +206,26 @@ func (s *Server) getIRIMachineStatus(machine *api.Machine) (*iri.MachineStatus,
 		ImageRef:           machine.Status.ImageRef,
 		Volumes:            volumes,
 		NetworkInterfaces:  nics,
+		MachineConditions:  s.getIRIMachineConditions(machine),
 	}, nil
 }
 
+// getIRIMachineConditions returns the conditions of the machine, all of which are true since conditions that
+// do not apply are removed.
+func (s *Server) getIRIMachineConditions(machine *api.Machine) []*iri.Conditions {
+	var conditions []*iri.Conditions
+	for _, condition := range machine.Status.Conditions {
+		conditions = append(conditions, &iri.Conditions{
+			Type:               string(condition.Type),
+			Status:             string(corev1.ConditionTrue),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.UnixNano(),
+		})
+	}
+	return conditions
+}
+
 func (s *Server) getIRINICState(state api.NetworkInterfaceState) (iri.NetworkInterfaceState, error) {
 	switch state {
 	case api.NetworkInterfaceStateAttached:
@@ -232,13 +287,13 @@ func (s *Server) getPowerStateFromIRI(power iri.Power) (api.PowerState, error) {
 	case iri.Power_POWER_OFF:
 		return api.PowerStatePowerOff, nil
 	default:
-		return 0, fmt.Errorf("unknown iri power state '%q'", power)
+		return 0, status.Errorf(codes.InvalidArgument, "unknown iri power state %q", power)
 	}
 }
 
 func (s *Server) getVolumeFromIRIVolume(iriVolume *iri.Volume) (*api.VolumeSpec, error) {
 	if iriVolume == nil {
-		return nil, fmt.Errorf("original volume is nil")
+		return nil, status.Error(codes.InvalidArgument, "volume is nil")
 	}
 
 	var localDiskSpec *api.LocalDiskSpec
@@ -272,9 +327,16 @@ func (s *Server) getVolumeFromIRIVolume(iriVolume *iri.Volume) (*api.VolumeSpec,
 	return volumeSpec, nil
 }
 
+func validateSharedMemory(sharedMemory *bool, volume *api.VolumeSpec) error {
+	if volume.RequiresSharedMemory() && sharedMemory != nil && !*sharedMemory {
+		return fmt.Errorf("volume %s requires shared memory, which is disabled for the machine", volume.Name)
+	}
+	return nil
+}
+
 func (s *Server) getNICFromIRINIC(iriNIC *iri.NetworkInterface) (*api.NetworkInterfaceSpec, error) {
 	if iriNIC == nil {
-		return nil, fmt.Errorf("networkInterface is nil")
+		return nil, status.Error(codes.InvalidArgument, "network interface is nil")
 	}
 
 	return &api.NetworkInterfaceSpec{
@@ -5,23 +5,28 @@ package server
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
-	"github.com/ironcore-dev/provider-utils/storeutils/store"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (s *Server) updateAnnotations(ctx context.Context, machine *api.Machine, annotations map[string]string) error {
+	// A new value of the reboot annotation requests a reboot, the old one may be kept by the caller.
+	previous, _ := api.GetAnnotationsAnnotation(machine.Metadata)
+	if reboot := annotations[api.RebootRequestedAnnotation]; reboot != "" && reboot != previous[api.RebootRequestedAnnotation] {
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[api.RebootRequestedAnnotation] = reboot
+	}
+
 	if err := api.SetAnnotationsAnnotation(machine, annotations); err != nil {
 		return fmt.Errorf("failed to set machine annotations: %w", err)
 	}
 
 	if _, err := s.machineStore.Update(ctx, machine); err != nil {
-		return fmt.Errorf("failed to update machine: %w", err)
+		return storeError(err, machine.ID, "failed to update machine")
 	}
 
 	return nil
@@ -33,16 +38,13 @@ func (s *Server) UpdateMachineAnnotations(
 	log := s.loggerFrom(ctx)
 
 	log.V(1).Info("Getting machine")
-	machine, err := s.machineStore.Get(ctx, req.MachineId)
+	machine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
 	if err != nil {
-		if !errors.Is(err, store.ErrNotFound) {
-			return nil, fmt.Errorf("error getting machine: %w", err)
-		}
-		return nil, status.Errorf(codes.NotFound, "machine %s not found", req.MachineId)
+		return nil, err
 	}
 
 	if err := s.updateAnnotations(ctx, machine, req.Annotations); err != nil {
-		return nil, fmt.Errorf("failed to update machine annotations: %w", err)
+		return nil, err
 	}
 
 	return &iri.UpdateMachineAnnotationsResponse{}, nil
@@ -4,6 +4,7 @@
 package server_test
 
 import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
@@ -46,4 +47,48 @@ var _ = Describe("UpdateMachineAnnotations", func() {
 		Expect(updatedMachine.Machines).To(HaveLen(1))
 		Expect(updatedMachine.Machines[0].Metadata.Annotations).To(HaveKeyWithValue("foo", "bar"))
 	})
+
+	It("should request a reboot if the reboot annotation changes", func(ctx SpecContext) {
+		By("creating a machine")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Labels: map[string]string{
+						machinepoolletv1alpha1.MachineUIDLabel: "foobar",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(createResp).NotTo(BeNil())
+
+		By("setting the reboot annotation")
+		machineID := createResp.Machine.Metadata.Id
+		Expect(machineClient.UpdateMachineAnnotations(ctx, &iri.UpdateMachineAnnotationsRequest{
+			MachineId:   machineID,
+			Annotations: map[string]string{api.RebootRequestedAnnotation: "1"},
+		})).Error().NotTo(HaveOccurred())
+
+		machine, err := machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Annotations).To(HaveKeyWithValue(api.RebootRequestedAnnotation, "1"))
+
+		By("keeping the reboot annotation after the reboot")
+		delete(machine.Annotations, api.RebootRequestedAnnotation)
+		_, err = machineStore.Update(ctx, machine)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(machineClient.UpdateMachineAnnotations(ctx, &iri.UpdateMachineAnnotationsRequest{
+			MachineId:   machineID,
+			Annotations: map[string]string{api.RebootRequestedAnnotation: "1", "foo": "bar"},
+		})).Error().NotTo(HaveOccurred())
+
+		machine, err = machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Annotations).NotTo(HaveKey(api.RebootRequestedAnnotation))
+	})
 })
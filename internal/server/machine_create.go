@@ -5,13 +5,28 @@ package server
 
 import (
 	"context"
+	b64 "encoding/base64"
 	"fmt"
+	"maps"
 	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/ignition"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/strategy"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	apiutils "github.com/ironcore-dev/provider-utils/apiutils/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
 )
 
 func (s *Server) createMachineFromIRIMachine(
@@ -23,55 +38,78 @@ func (s *Server) createMachineFromIRIMachine(
 
 	switch {
 	case iriMachine == nil:
-		return nil, fmt.Errorf("iri machine is nil")
+		return nil, status.Error(codes.InvalidArgument, "iri machine is nil")
 	case iriMachine.Spec == nil:
-		return nil, fmt.Errorf("iri machine spec is nil")
+		return nil, status.Error(codes.InvalidArgument, "iri machine spec is nil")
 	case iriMachine.Metadata == nil:
-		return nil, fmt.Errorf("iri machine metadata is nil")
+		return nil, status.Error(codes.InvalidArgument, "iri machine metadata is nil")
 	}
 
-	class, found := s.machineClassRegistry.Get(iriMachine.Spec.Class)
-	if !found {
-		return nil, fmt.Errorf("machine class %s not supported", iriMachine.Spec.Class)
+	class, err := s.getMachineClass(iriMachine.Spec.Class)
+	if err != nil {
+		return nil, err
 	}
 
 	power, err := s.getPowerStateFromIRI(iriMachine.Spec.Power)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get power state: %w", err)
+		return nil, err
 	}
 
-	var volumes []*api.VolumeSpec
-	for _, iriVolume := range iriMachine.Spec.Volumes {
-		volumeSpec, err := s.getVolumeFromIRIVolume(iriVolume)
-		if err != nil {
-			return nil, fmt.Errorf("error converting volume: %w", err)
-		}
+	id := s.idGen.Generate()
 
-		volumes = append(volumes, volumeSpec)
+	annotations := iriMachine.Metadata.Annotations
+	dryRun, err := getDryRun(annotations)
+	if err != nil {
+		return nil, err
 	}
 
-	var networkInterfaces []*api.NetworkInterfaceSpec
-	for _, iriNetworkInterface := range iriMachine.Spec.NetworkInterfaces {
-		networkInterfaceSpec := &api.NetworkInterfaceSpec{
-			Name:       iriNetworkInterface.Name,
-			NetworkId:  iriNetworkInterface.NetworkId,
-			Ips:        iriNetworkInterface.Ips,
-			Attributes: iriNetworkInterface.Attributes,
-		}
-		networkInterfaces = append(networkInterfaces, networkInterfaceSpec)
+	guest, err := s.getGuestData(id, iriMachine)
+	if err != nil {
+		return nil, err
+	}
+
+	serialMode, consoleMode, err := getConsoleModes(annotations, class)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := s.getVolumesFromIRIMachine(iriMachine, class)
+	if err != nil {
+		return nil, err
+	}
+
+	networkInterfaces, err := getNetworkInterfacesFromIRIMachine(iriMachine)
+	if err != nil {
+		return nil, err
 	}
 
 	machine := &api.Machine{
 		Metadata: apiutils.Metadata{
-			ID: s.idGen.Generate(),
+			ID: id,
 		},
 		Spec: api.MachineSpec{
 			Power:             power,
 			Cpu:               int64(math.Max(float64(class.Cpu), 1)),
 			MemoryBytes:       class.MemoryBytes,
 			Volumes:           volumes,
-			Ignition:          iriMachine.Spec.IgnitionData,
+			Ignition:          guest.ignition,
+			IgnitionTransport: guest.ignitionTransport,
+			CloudInit:         guest.cloudInit,
+			OpaqueUserData:    guest.opaqueUserData,
+			Hostname:          guest.hostname,
+			DNS:               guest.dns,
 			NetworkInterfaces: networkInterfaces,
+			Landlock:          ptr.Deref(class.Landlock, s.landlock),
+			CpuTopology:       class.CpuTopology,
+			CpuFeatures:       class.CpuFeatures,
+			Clock:             class.Clock,
+			FreePageReporting: class.FreePageReporting,
+			SharedMemory:      class.SharedMemory,
+			MemoryBacking:     class.MemoryBacking,
+			VMConfigOverrides: maps.Clone(class.VMConfigOverrides),
+			SerialMode:        serialMode,
+			ConsoleMode:       consoleMode,
+			VMMRequirements:   class.VMMRequirements,
 		},
 	}
 
@@ -81,20 +119,471 @@ func (s *Server) createMachineFromIRIMachine(
 	api.SetClassLabel(machine, iriMachine.Spec.Class)
 	api.SetManagerLabel(machine, api.MachineManager)
 
+	s.admissionMu.Lock()
+	defer s.admissionMu.Unlock()
+	if err := s.admit(ctx, machine); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		// The machine is returned as the store would create it.
+		log.V(1).Info("Dry run, not storing machine")
+		strategy.MachineStrategy.PrepareForCreate(machine)
+		machine.SetCreatedAt(time.Now())
+		return machine, nil
+	}
+
 	apiMachine, err := s.machineStore.Create(ctx, machine)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create machine: %w", err)
+		return nil, storeError(err, machine.ID, "failed to create machine")
 	}
 
 	return apiMachine, nil
 }
 
+// getMachineClass returns the class, unless it is unknown or deprecated.
+func (s *Server) getMachineClass(name string) (mcr.MachineClass, error) {
+	class, found := s.machineClassRegistry.Get(name)
+	if !found {
+		return mcr.MachineClass{}, status.Errorf(codes.InvalidArgument, "machine class %s not supported", name)
+	}
+	if class.Deprecated {
+		return mcr.MachineClass{}, status.Errorf(codes.FailedPrecondition,
+			"machine class %s is deprecated and admits no new machines", name)
+	}
+	return class, nil
+}
+
+// guestData is the data the guest of a machine is provisioned with.
+type guestData struct {
+	ignition          []byte
+	ignitionTransport api.IgnitionTransport
+	cloudInit         *api.CloudInitSpec
+	opaqueUserData    []byte
+	hostname          string
+	dns               *api.DNSSpec
+}
+
+// getGuestData returns the ignition, cloud-init and opaque user data of the machine with the ssh keys and the
+// network identity of its annotations added.
+func (s *Server) getGuestData(id string, iriMachine *iri.Machine) (guestData, error) {
+	annotations := iriMachine.Metadata.Annotations
+	ignitionTransport, err := getIgnitionTransport(annotations)
+	if err != nil {
+		return guestData{}, err
+	}
+
+	cloudInit := getCloudInit(annotations)
+	if cloudInit != nil && iriMachine.Spec.IgnitionData != nil &&
+		ignitionTransport == api.IgnitionTransportConfigDrive {
+		return guestData{}, status.Errorf(codes.InvalidArgument,
+			"cloud-init data cannot be combined with ignition delivered via config drive")
+	}
+
+	ignitionData, err := addSSHAuthorizedKeys(id, iriMachine.Spec.IgnitionData, cloudInit, annotations)
+	if err != nil {
+		return guestData{}, err
+	}
+
+	hostname := getHostname(id, iriMachine.Metadata.Labels)
+	dns, err := s.getDNS(annotations)
+	if err != nil {
+		return guestData{}, err
+	}
+	if ignitionData, err = addNetworkIdentity(id, hostname, dns, ignitionData, cloudInit); err != nil {
+		return guestData{}, err
+	}
+
+	if err := s.validateIgnition(ignitionData, ignitionTransport); err != nil {
+		return guestData{}, err
+	}
+
+	opaqueUserData, err := s.getOpaqueUserData(annotations, ignitionData, ignitionTransport, cloudInit)
+	if err != nil {
+		return guestData{}, err
+	}
+
+	return guestData{
+		ignition:          ignitionData,
+		ignitionTransport: ignitionTransport,
+		cloudInit:         cloudInit,
+		opaqueUserData:    opaqueUserData,
+		hostname:          hostname,
+		dns:               dns,
+	}, nil
+}
+
+// getConsoleModes returns the serial and console mode of the annotations, defaulted by the ones of the class.
+func getConsoleModes(annotations map[string]string, class mcr.MachineClass) (api.ConsoleMode, api.ConsoleMode, error) {
+	serialMode, err := getConsoleMode(annotations, api.SerialModeAnnotation, class.SerialMode)
+	if err != nil {
+		return "", "", err
+	}
+	consoleMode, err := getConsoleMode(annotations, api.ConsoleModeAnnotation, class.ConsoleMode)
+	if err != nil {
+		return "", "", err
+	}
+	return serialMode, consoleMode, nil
+}
+
+func (s *Server) getVolumesFromIRIMachine(
+	iriMachine *iri.Machine,
+	class mcr.MachineClass,
+) ([]*api.VolumeSpec, error) {
+	annotations := iriMachine.Metadata.Annotations
+	filesystems, err := getVolumeFilesystems(annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []*api.VolumeSpec
+	for _, iriVolume := range iriMachine.Spec.Volumes {
+		volumeSpec, err := s.getVolumeFromIRIVolume(iriVolume)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := setVolumeFilesystem(filesystems, volumeSpec); err != nil {
+			return nil, err
+		}
+		if err := setVolumeMedium(annotations, volumeSpec); err != nil {
+			return nil, err
+		}
+
+		setRootDiskMedium(class, volumeSpec)
+
+		if err := validateSharedMemory(class.SharedMemory, volumeSpec); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if localDisk := volumeSpec.LocalDisk; localDisk != nil && localDisk.Image != nil && localDisk.Size == 0 {
+			localDisk.Size = class.RootDiskBytes
+		}
+
+		volumes = append(volumes, volumeSpec)
+	}
+	if err := validateMemoryDisks(class, volumes); err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+func getNetworkInterfacesFromIRIMachine(iriMachine *iri.Machine) ([]*api.NetworkInterfaceSpec, error) {
+	var networkInterfaces []*api.NetworkInterfaceSpec
+	for _, iriNetworkInterface := range iriMachine.Spec.NetworkInterfaces {
+		networkInterfaceSpec := &api.NetworkInterfaceSpec{
+			Name:       iriNetworkInterface.Name,
+			NetworkId:  iriNetworkInterface.NetworkId,
+			Ips:        iriNetworkInterface.Ips,
+			Attributes: iriNetworkInterface.Attributes,
+		}
+		if err := setNICFirewall(networkInterfaceSpec); err != nil {
+			return nil, err
+		}
+		networkInterfaces = append(networkInterfaces, networkInterfaceSpec)
+	}
+	return networkInterfaces, nil
+}
+
+func getDryRun(annotations map[string]string) (bool, error) {
+	value, ok := annotations[api.DryRunAnnotation]
+	if !ok {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "invalid %s annotation %q", api.DryRunAnnotation, value)
+	}
+	return dryRun, nil
+}
+
+func getIgnitionTransport(annotations map[string]string) (api.IgnitionTransport, error) {
+	transport, ok := annotations[api.IgnitionTransportAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	parsed, err := api.ParseIgnitionTransport(transport)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid %s annotation: %v", api.IgnitionTransportAnnotation, err)
+	}
+	return parsed, nil
+}
+
+// getConsoleMode returns the console mode of the annotation, defaulted by the one of the machine class.
+func getConsoleMode(annotations map[string]string, key string, classMode api.ConsoleMode) (api.ConsoleMode, error) {
+	value, ok := annotations[key]
+	if !ok {
+		return classMode, nil
+	}
+	mode, err := api.ParseConsoleMode(value)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid %s annotation: %v", key, err)
+	}
+	return mode, nil
+}
+
+func (s *Server) validateIgnition(data []byte, transport api.IgnitionTransport) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if transport == "" {
+		transport = s.ignitionTransport
+	}
+
+	if err := ignition.Validate(data); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	compress := s.ignitionCompression && transport == api.IgnitionTransportOEMStrings
+	encoded, err := ignition.Encode(data, transport, compress)
+	if err != nil {
+		return fmt.Errorf("failed to encode ignition: %w", err)
+	}
+	if err := ignition.CheckSize(encoded, transport); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func getCloudInit(annotations map[string]string) *api.CloudInitSpec {
+	userData, hasUserData := annotations[api.CloudInitUserDataAnnotation]
+	metaData, hasMetaData := annotations[api.CloudInitMetaDataAnnotation]
+	if !hasUserData && !hasMetaData {
+		return nil
+	}
+
+	return &api.CloudInitSpec{
+		UserData: []byte(userData),
+		MetaData: []byte(metaData),
+	}
+}
+
+// getOpaqueUserData returns the opaque user data of the annotations if it fits into the transport it is
+// delivered with. It shares the OEM strings with the ignition data if the machine gets no config drive.
+func (s *Server) getOpaqueUserData(
+	annotations map[string]string,
+	ignitionData []byte,
+	transport api.IgnitionTransport,
+	cloudInit *api.CloudInitSpec,
+) ([]byte, error) {
+	data := annotations[api.OpaqueUserDataAnnotation]
+	if data == "" {
+		return nil, nil
+	}
+	if transport == "" {
+		transport = s.ignitionTransport
+	}
+
+	size, limit := len(data), ignition.MaxConfigDriveSize
+	if transport == api.IgnitionTransportOEMStrings && cloudInit == nil {
+		encoded, err := ignition.Encode(ignitionData, transport, s.ignitionCompression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode ignition: %w", err)
+		}
+		size, limit = b64.StdEncoding.EncodedLen(len(data)), ignition.MaxOEMStringsSize-len(encoded)
+	}
+	if size > limit {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"encoded opaque user data of %d bytes exceeds the remaining limit of %d bytes", size, limit)
+	}
+	return []byte(data), nil
+}
+
+// addSSHAuthorizedKeys merges the SSH keys of the annotations into the cloud-init meta-data and returns the
+// ignition data with the keys merged. Ignition is generated if the machine has neither ignition nor cloud-init.
+func addSSHAuthorizedKeys(
+	machineID string,
+	ignitionData []byte,
+	cloudInit *api.CloudInitSpec,
+	annotations map[string]string,
+) ([]byte, error) {
+	keys, err := getSSHAuthorizedKeys(annotations)
+	if err != nil || len(keys) == 0 {
+		return ignitionData, err
+	}
+
+	if cloudInit != nil {
+		if cloudInit.MetaData, err = addPublicKeys(cloudInit.MetaData, machineID, keys); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cloud-init meta-data: %v", err)
+		}
+		if len(ignitionData) == 0 {
+			return nil, nil
+		}
+	}
+
+	user := annotations[api.SSHUserAnnotation]
+	if user == "" {
+		user = ignition.DefaultSSHUser
+	}
+	if ignitionData, err = ignition.AddSSHAuthorizedKeys(ignitionData, user, keys); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ignitionData, nil
+}
+
+func getSSHAuthorizedKeys(annotations map[string]string) ([]string, error) {
+	var keys []string
+	for _, line := range strings.Split(annotations[api.SSHAuthorizedKeysAnnotation], "\n") {
+		key := strings.TrimSpace(line)
+		if key == "" || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if fields := strings.Fields(key); len(fields) < 2 || !isSSHKeyType(fields[0]) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid ssh authorized key %q", key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func isSSHKeyType(keyType string) bool {
+	return strings.HasPrefix(keyType, "ssh-") || strings.HasPrefix(keyType, "ecdsa-") ||
+		strings.HasPrefix(keyType, "sk-")
+}
+
+// addPublicKeys adds keys to the public-keys of the cloud-init meta-data, which cloud-init authorizes for the
+// default user of the image.
+func addPublicKeys(metaData []byte, machineID string, keys []string) ([]byte, error) {
+	return updateMetaData(metaData, machineID, func(data map[string]any) error {
+		var publicKeys []any
+		switch existing := data["public-keys"].(type) {
+		case nil:
+		case string:
+			publicKeys = append(publicKeys, existing)
+		case []any:
+			publicKeys = existing
+		default:
+			return fmt.Errorf("public-keys must be a string or a list")
+		}
+		for _, key := range keys {
+			publicKeys = append(publicKeys, key)
+		}
+		data["public-keys"] = publicKeys
+		return nil
+	})
+}
+
+// updateMetaData applies update to the cloud-init meta-data. The machine ID is set as instance-id if the
+// meta-data has none.
+func updateMetaData(metaData []byte, machineID string, update func(data map[string]any) error) ([]byte, error) {
+	data := map[string]any{}
+	if err := yaml.Unmarshal(metaData, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]any{}
+	}
+	if _, ok := data["instance-id"]; !ok {
+		data["instance-id"] = machineID
+	}
+
+	if err := update(data); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(data)
+}
+
+// getHostname derives the hostname of the guest from the name of the ironcore machine. Machines without a
+// name, or with one that is no valid hostname, get their ID.
+func getHostname(machineID string, labels map[string]string) string {
+	name := strings.ToLower(labels[machinepoolletv1alpha1.MachineNameLabel])
+	if len(validation.IsDNS1123Label(name)) > 0 {
+		return machineID
+	}
+	return name
+}
+
+// getDNS returns the DNS configuration of the annotations, defaulted by the one of the provider.
+func (s *Server) getDNS(annotations map[string]string) (*api.DNSSpec, error) {
+	dns := &api.DNSSpec{
+		Servers: s.dns.Servers,
+		Search:  s.dns.Search,
+	}
+	if servers, ok := annotations[api.DNSServersAnnotation]; ok {
+		dns.Servers = splitList(servers)
+	}
+	if search, ok := annotations[api.DNSSearchAnnotation]; ok {
+		dns.Search = splitList(search)
+	}
+
+	for _, server := range dns.Servers {
+		if net.ParseIP(server) == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid dns server %q", server)
+		}
+	}
+	for _, domain := range dns.Search {
+		if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid dns search domain %q: %s", domain,
+				strings.Join(errs, ", "))
+		}
+	}
+
+	if len(dns.Servers) == 0 && len(dns.Search) == 0 {
+		return nil, nil
+	}
+	return dns, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// addNetworkIdentity sets the hostname in the cloud-init meta-data and returns the ignition data with files
+// setting the hostname and DNS configuration added.
+func addNetworkIdentity(
+	machineID, hostname string,
+	dns *api.DNSSpec,
+	ignitionData []byte,
+	cloudInit *api.CloudInitSpec,
+) ([]byte, error) {
+	if cloudInit != nil {
+		var err error
+		cloudInit.MetaData, err = updateMetaData(cloudInit.MetaData, machineID, func(data map[string]any) error {
+			if _, ok := data["local-hostname"]; !ok {
+				data["local-hostname"] = hostname
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cloud-init meta-data: %v", err)
+		}
+	}
+
+	files := []ignition.File{{Path: "/etc/hostname", Contents: hostname + "\n"}}
+	if dns != nil {
+		conf := "[Resolve]\n"
+		if len(dns.Servers) > 0 {
+			conf += fmt.Sprintf("DNS=%s\n", strings.Join(dns.Servers, " "))
+		}
+		if len(dns.Search) > 0 {
+			conf += fmt.Sprintf("Domains=%s\n", strings.Join(dns.Search, " "))
+		}
+		files = append(files, ignition.File{Path: "/etc/systemd/resolved.conf.d/ironcore.conf", Contents: conf})
+	}
+
+	ignitionData, err := ignition.AddFiles(ignitionData, files)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ignitionData, nil
+}
+
 func (s *Server) CreateMachine(
 	ctx context.Context,
 	req *iri.CreateMachineRequest,
 ) (res *iri.CreateMachineResponse, retErr error) {
 	log := s.loggerFrom(ctx)
 
+	if s.maintenance.Enabled() {
+		return nil, status.Error(codes.Unavailable, "host is in maintenance")
+	}
+
 	log.V(1).Info("Creating machine from iri machine")
 	machine, err := s.createMachineFromIRIMachine(ctx, log, req.Machine)
 	if err != nil {
@@ -4,11 +4,19 @@
 package server_test
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/ignition"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ = Describe("CreateMachine", func() {
@@ -45,4 +53,681 @@ var _ = Describe("CreateMachine", func() {
 		))
 	})
 
+	It("should reject an unsupported ignition transport", func(ctx SpecContext) {
+		By("creating a machine with an unknown ignition transport annotation")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.IgnitionTransportAnnotation: "floppy",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte(`{"ignition":{"version":"3.4.0"}}`),
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should store the requested ignition transport", func(ctx SpecContext) {
+		By("creating a machine with the config drive ignition transport")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.IgnitionTransportAnnotation: string(api.IgnitionTransportConfigDrive),
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte(`{"ignition":{"version":"3.4.0"}}`),
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the transport is set on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.IgnitionTransport).To(Equal(api.IgnitionTransportConfigDrive))
+	})
+	It("should enable landlock if the machine class requires it", func(ctx SpecContext) {
+		By("creating a machine of a class with landlock enabled")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: landlockMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring landlock is enabled on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Landlock).To(BeTrue())
+	})
+
+	It("should store the cpu topology of the machine class", func(ctx SpecContext) {
+		By("creating a machine of a class with a cpu topology")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: topologyMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the topology is set on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Cpu).To(Equal(int64(4)))
+		Expect(machine.Spec.CpuTopology).To(Equal(&api.CpuTopology{Sockets: 1, CoresPerSocket: 2, ThreadsPerCore: 2}))
+	})
+
+	It("should store the cpu features and clock of the machine class", func(ctx SpecContext) {
+		By("creating a machine of a class with cpu features")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: featuresMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the features and clock are set on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.CpuFeatures).To(Equal(&api.CpuFeatures{KvmHyperV: true, MaxPhysBits: 40}))
+		Expect(machine.Spec.Clock).To(Equal(api.GuestClockPTP))
+		Expect(machine.Spec.FreePageReporting).To(BeTrue())
+		Expect(machine.Spec.VMConfigOverrides).To(Equal(map[string]string{"memory.thp": "false"}))
+		Expect(machine.Spec.VMMRequirements).To(Equal(&api.VMMRequirements{
+			MinVersion: "v49.0",
+			Features:   []string{"kvm"},
+		}))
+	})
+
+	It("should store the memory settings of the machine class", func(ctx SpecContext) {
+		By("creating a machine of a class with private file backed memory")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: privateMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the memory settings are set on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.SharedMemory).To(HaveValue(BeFalse()))
+		Expect(machine.Spec.MemoryBacking).To(Equal(&api.MemoryBacking{File: "/dev/hugepages"}))
+	})
+
+	It("should size disks provisioned from an image by the machine class", func(ctx SpecContext) {
+		By("creating a machine with a root disk without size and one with size")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: rootDiskMachineClassName,
+					Volumes: []*iri.Volume{
+						{
+							Name: "root",
+							LocalDisk: &iri.LocalDisk{
+								Image: &iri.ImageSpec{Image: "example.org/os:1.0"},
+							},
+							Device: "oda",
+						},
+						{
+							Name: "sized",
+							LocalDisk: &iri.LocalDisk{
+								SizeBytes: emptyDiskSize,
+								Image:     &iri.ImageSpec{Image: "example.org/os:1.0"},
+							},
+							Device: "odb",
+						},
+					},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring only the disk without size is sized by the class")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes[0].LocalDisk.Size).To(BeEquivalentTo(rootDiskSize))
+		Expect(machine.Spec.Volumes[1].LocalDisk.Size).To(BeEquivalentTo(emptyDiskSize))
+	})
+
+	It("should store the disks provisioned from an image on the root disk medium of the class", func(ctx SpecContext) {
+		By("creating a machine with a disk provisioned from an image and an empty disk")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: cephRootDiskClassName,
+					Volumes: []*iri.Volume{
+						{
+							Name: "root",
+							LocalDisk: &iri.LocalDisk{
+								Image: &iri.ImageSpec{Image: "example.org/os:1.0"},
+							},
+							Device: "oda",
+						},
+						{
+							Name:      "data",
+							LocalDisk: &iri.LocalDisk{SizeBytes: emptyDiskSize},
+							Device:    "odb",
+						},
+					},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring only the disk provisioned from an image is stored on ceph")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes[0].LocalDisk.Medium).To(Equal(api.StorageMediumCeph))
+		Expect(machine.Spec.Volumes[0].RequiresSharedMemory()).To(BeTrue())
+		Expect(machine.Spec.Volumes[1].LocalDisk.Medium).To(BeEmpty())
+	})
+
+	It("should reject invalid ignition data", func(ctx SpecContext) {
+		By("creating a machine with ignition data that is not JSON")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte("not-json"),
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		By("creating a machine with ignition data without a version")
+		_, err = machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte("{}"),
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should reject ignition data exceeding the transport limit", func(ctx SpecContext) {
+		By("creating a machine with an oversized ignition")
+		padding := strings.Repeat("a", ignition.MaxOEMStringsSize)
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte(`{"ignition":{"version":"3.4.0"},"padding":"` + padding + `"}`),
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should store cloud-init data from annotations", func(ctx SpecContext) {
+		By("creating a machine with cloud-init user-data")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.CloudInitUserDataAnnotation: "#cloud-config\n",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the cloud-init data is set on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.CloudInit).To(SatisfyAll(
+			HaveField("UserData", Equal([]byte("#cloud-config\n"))),
+			HaveField("MetaData", MatchYAML(fmt.Sprintf("instance-id: %[1]s\nlocal-hostname: %[1]s\n", machine.ID))),
+		))
+	})
+
+	It("should store opaque user data from annotations", func(ctx SpecContext) {
+		By("creating a machine with opaque user data")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.OpaqueUserDataAnnotation: "agent-token: foo",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the opaque user data is set on the stored machine")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.OpaqueUserData).To(Equal([]byte("agent-token: foo")))
+	})
+
+	It("should reject opaque user data exceeding the oem strings limit", func(ctx SpecContext) {
+		By("creating a machine with oversized opaque user data")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.OpaqueUserDataAnnotation: strings.Repeat("a", ignition.MaxOEMStringsSize),
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should authorize ssh keys via ignition", func(ctx SpecContext) {
+		By("creating a machine with ssh keys and ignition data")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Labels: map[string]string{
+						machinepoolletv1alpha1.MachineNameLabel: "ssh",
+					},
+					Annotations: map[string]string{
+						api.SSHAuthorizedKeysAnnotation: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample user@host\n",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte(`{"ignition":{"version":"3.4.0"},"storage":{"files":[]}}`),
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the keys are merged into the stored ignition")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Ignition).To(MatchJSON(`{
+			"ignition": {"version": "3.4.0"},
+			"storage": {"files": [{
+				"path": "/etc/hostname",
+				"mode": 420,
+				"overwrite": true,
+				"contents": {"source": "data:,ssh%0A"}
+			}]},
+			"passwd": {"users": [{
+				"name": "core",
+				"sshAuthorizedKeys": ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample user@host"]
+			}]}
+		}`))
+	})
+
+	It("should authorize ssh keys via cloud-init meta-data", func(ctx SpecContext) {
+		By("creating a machine with ssh keys and cloud-init user-data")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.CloudInitUserDataAnnotation: "#cloud-config\n",
+						api.SSHAuthorizedKeysAnnotation: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the keys are added to the stored meta-data")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Ignition).To(BeEmpty())
+		Expect(machine.Spec.CloudInit.MetaData).To(MatchYAML(fmt.Sprintf(
+			"instance-id: %[1]s\nlocal-hostname: %[1]s\npublic-keys:\n- ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExample\n",
+			machine.ID)))
+	})
+
+	It("should derive the hostname and dns configuration of the guest", func(ctx SpecContext) {
+		By("creating a machine with a name and dns annotations")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Labels: map[string]string{
+						machinepoolletv1alpha1.MachineNameLabel: "Web-1",
+					},
+					Annotations: map[string]string{
+						api.DNSServersAnnotation: "10.0.0.53, 10.0.1.53",
+						api.DNSSearchAnnotation:  "example.org",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power:        iri.Power_POWER_ON,
+					Class:        machineClassName,
+					IgnitionData: []byte(`{"ignition":{"version":"3.4.0"}}`),
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the hostname and dns configuration are stored and written by ignition")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Hostname).To(Equal("web-1"))
+		Expect(machine.Spec.DNS).To(Equal(&api.DNSSpec{
+			Servers: []string{"10.0.0.53", "10.0.1.53"},
+			Search:  []string{"example.org"},
+		}))
+		Expect(string(machine.Spec.Ignition)).To(SatisfyAll(
+			ContainSubstring(`"source":"data:,web-1%0A"`),
+			ContainSubstring(`"path":"/etc/systemd/resolved.conf.d/ironcore.conf"`),
+			ContainSubstring(`DNS=10.0.0.53%2010.0.1.53%0ADomains=example.org%0A`),
+		))
+	})
+
+	It("should reject invalid dns servers", func(ctx SpecContext) {
+		By("creating a machine with an invalid dns server")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.DNSServersAnnotation: "dns.example.org",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should store the console modes of the machine class overridden by annotations", func(ctx SpecContext) {
+		By("creating a machine of a class with console modes and a serial mode annotation")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.SerialModeAnnotation: "Pty",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: featuresMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the serial mode of the annotation and the console mode of the class are stored")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.SerialMode).To(Equal(api.ConsoleModePty))
+		Expect(machine.Spec.ConsoleMode).To(Equal(api.ConsoleModeFile))
+
+		By("creating a machine with an invalid console mode")
+		_, err = machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.ConsoleModeAnnotation: "socket",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should store the filesystems of empty disks", func(ctx SpecContext) {
+		By("creating a machine with a filesystem for an empty disk")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.VolumeFilesystemsAnnotation: `{"scratch":{"type":"ext4","label":"scratch","options":["-E","lazy_itable_init=1","-q"]}}`,
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+					Volumes: []*iri.Volume{
+						{
+							Name:      "scratch",
+							LocalDisk: &iri.LocalDisk{SizeBytes: emptyDiskSize},
+							Device:    "oda",
+						},
+					},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring the filesystem is stored with the disk")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes[0].LocalDisk.Filesystem).To(Equal(&api.FilesystemSpec{
+			Type:    api.FilesystemTypeExt4,
+			Label:   "scratch",
+			Options: []string{"-E", "lazy_itable_init=1", "-q"},
+		}))
+	})
+
+	It("should reject filesystems reading from the host or on image disks", func(ctx SpecContext) {
+		for _, tc := range []struct {
+			filesystems string
+			volume      *iri.LocalDisk
+		}{
+			{`{"scratch":{"type":"ext4","options":["-d","/etc"]}}`, &iri.LocalDisk{SizeBytes: emptyDiskSize}},
+			{`{"scratch":{"type":"btrfs"}}`, &iri.LocalDisk{SizeBytes: emptyDiskSize}},
+			{`{"scratch":{"type":"xfs","label":"longer-than-12"}}`, &iri.LocalDisk{SizeBytes: emptyDiskSize}},
+			{`{"scratch":{"type":"ext4"}}`, &iri.LocalDisk{Image: &iri.ImageSpec{Image: "example.org/os:1.0"}}},
+		} {
+			By(fmt.Sprintf("creating a machine with the filesystems %s", tc.filesystems))
+			_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+				Machine: &iri.Machine{
+					Metadata: &irimeta.ObjectMetadata{
+						Annotations: map[string]string{
+							api.VolumeFilesystemsAnnotation: tc.filesystems,
+						},
+					},
+					Spec: &iri.MachineSpec{
+						Power:   iri.Power_POWER_ON,
+						Class:   machineClassName,
+						Volumes: []*iri.Volume{{Name: "scratch", LocalDisk: tc.volume, Device: "oda"}},
+					},
+				},
+			})
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		}
+	})
+
+	It("should store memory volumes in host memory within the class", func(ctx SpecContext) {
+		newMachine := func(class string, size int64) *iri.Machine {
+			return &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.MemoryVolumesAnnotation: "scratch",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: class,
+					Volumes: []*iri.Volume{
+						{Name: "scratch", LocalDisk: &iri.LocalDisk{SizeBytes: size}, Device: "oda"},
+						{Name: "data", LocalDisk: &iri.LocalDisk{SizeBytes: emptyDiskSize}, Device: "odb"},
+					},
+				},
+			}
+		}
+
+		By("creating a machine with a memory volume")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: newMachine(memoryDiskMachineClassName, memoryDiskSize),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("ensuring only the named volume is stored in memory")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes[0].LocalDisk.Medium).To(Equal(api.StorageMediumMemory))
+		Expect(machine.Spec.Volumes[1].LocalDisk.Medium).To(BeEmpty())
+
+		By("creating machines exceeding the memory disk size of their class")
+		_, err = machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: newMachine(memoryDiskMachineClassName, 2*memoryDiskSize),
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		_, err = machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: newMachine(machineClassName, emptyDiskSize),
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should reject invalid ssh keys", func(ctx SpecContext) {
+		By("creating a machine with an invalid ssh key")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.SSHAuthorizedKeysAnnotation: "not-a-key",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should return the machine of a dry run without storing it", func(ctx SpecContext) {
+		By("creating a machine in a dry run")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.DryRunAnnotation: "true",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: topologyMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(createResp.Machine.Spec.Class).To(Equal(topologyMachineClassName))
+
+		By("ensuring the machine is not stored")
+		machines, err := machineStore.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machines).To(BeEmpty())
+
+		By("validating an invalid machine in a dry run")
+		_, err = machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Annotations: map[string]string{
+						api.DryRunAnnotation:     "true",
+						api.DNSServersAnnotation: "dns.example.org",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should reject machines of a deprecated class", func(ctx SpecContext) {
+		By("creating a machine")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: deprecatedMachineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		By("not advertising the class")
+		statusResp, err := machineClient.Status(ctx, &iri.StatusRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusResp.MachineClassStatus).NotTo(ContainElement(
+			HaveField("MachineClass.Name", deprecatedMachineClassName)))
+		Expect(statusResp.MachineClassStatus).To(ContainElement(HaveField("MachineClass.Name", machineClassName)))
+	})
+
+	It("should reject machines while the host is in maintenance", func(ctx SpecContext) {
+		By("enabling the maintenance mode")
+		maintenanceMode.Set(true, maintenance.EvacuationNone)
+
+		By("creating a machine")
+		_, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+		By("reporting no capacity")
+		statusResp, err := machineClient.Status(ctx, &iri.StatusRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusResp.MachineClassStatus).To(HaveEach(HaveField("Quantity", BeZero())))
+	})
 })
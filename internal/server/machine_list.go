@@ -5,25 +5,21 @@ package server
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/go-logr/logr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
-	"github.com/ironcore-dev/provider-utils/storeutils/store"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 )
 
 func (s *Server) getCloudHypervisorMachine(ctx context.Context, id string) (*api.Machine, error) {
 	machine, err := s.machineStore.Get(ctx, id)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return nil, status.Errorf(codes.NotFound, "machine %s not found", id)
-		}
-		return nil, fmt.Errorf("failed to get machine: %w", err)
+		return nil, storeError(err, id, "failed to get machine")
 	}
 
 	if !api.IsManagedBy(machine, api.MachineManager) {
@@ -56,29 +52,79 @@ func (s *Server) listMachines(ctx context.Context, log logr.Logger) ([]*iri.Mach
 	return res, nil
 }
 
-func (s *Server) filterMachines(machines []*iri.Machine, filter *iri.MachineFilter) []*iri.Machine {
+// machineFilter is the label selector of a machine filter split into the keys matched against the class, power and
+// state of the machines and the selector of their IRI labels.
+type machineFilter struct {
+	class  *string
+	power  *iri.Power
+	state  *iri.MachineState
+	labels labels.Selector
+}
+
+func newMachineFilter(filter *iri.MachineFilter) (*machineFilter, error) {
+	res := &machineFilter{}
+	set := labels.Set{}
+	for key, value := range filter.LabelSelector {
+		switch key {
+		case api.ClassLabel:
+			res.class = &value
+		case api.PowerFilterKey:
+			power, ok := iri.Power_value[value]
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown power %q in filter", value)
+			}
+			res.power = ptr.To(iri.Power(power))
+		case api.StateFilterKey:
+			state, ok := iri.MachineState_value[value]
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "unknown state %q in filter", value)
+			}
+			res.state = ptr.To(iri.MachineState(state))
+		default:
+			set[key] = value
+		}
+	}
+	res.labels = labels.SelectorFromSet(set)
+	return res, nil
+}
+
+func (f *machineFilter) matches(iriMachine *iri.Machine) bool {
+	switch {
+	case f.class != nil && iriMachine.Spec.Class != *f.class:
+		return false
+	case f.power != nil && iriMachine.Spec.Power != *f.power:
+		return false
+	case f.state != nil && iriMachine.Status.State != *f.state:
+		return false
+	}
+	return f.labels.Matches(labels.Set(iriMachine.Metadata.Labels))
+}
+
+func (s *Server) filterMachines(machines []*iri.Machine, filter *iri.MachineFilter) ([]*iri.Machine, error) {
 	if filter == nil {
-		return machines
+		return machines, nil
+	}
+
+	f, err := newMachineFilter(filter)
+	if err != nil {
+		return nil, err
 	}
 
-	var (
-		res []*iri.Machine
-		sel = labels.SelectorFromSet(filter.LabelSelector)
-	)
+	var res []*iri.Machine
 	for _, iriMachine := range machines {
-		if !sel.Matches(labels.Set(iriMachine.Metadata.Labels)) {
+		if !f.matches(iriMachine) {
 			continue
 		}
 
 		res = append(res, iriMachine)
 	}
-	return res
+	return res, nil
 }
 
 func (s *Server) getMachine(ctx context.Context, id string) (*iri.Machine, error) {
 	machine, err := s.getCloudHypervisorMachine(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get machine: %w", err)
+		return nil, err
 	}
 
 	return s.convertMachineToIRIMachine(machine)
@@ -108,7 +154,10 @@ func (s *Server) ListMachines(ctx context.Context, req *iri.ListMachinesRequest)
 		return nil, err
 	}
 
-	machines = s.filterMachines(machines, req.Filter)
+	machines, err = s.filterMachines(machines, req.Filter)
+	if err != nil {
+		return nil, err
+	}
 
 	return &iri.ListMachinesResponse{
 		Machines: machines,
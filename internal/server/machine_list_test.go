@@ -4,11 +4,14 @@
 package server_test
 
 import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ = Describe("ListMachine", func() {
@@ -58,4 +61,156 @@ var _ = Describe("ListMachine", func() {
 		By("listing the machines")
 		Expect(machineClient.ListMachines(ctx, &iri.ListMachinesRequest{})).To(HaveField("Machines", ConsistOf(machines...)))
 	})
+
+	It("should report the addresses of the network interfaces", func(ctx SpecContext) {
+		By("creating a machine")
+		res, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+					NetworkInterfaces: []*iri.NetworkInterface{
+						{Name: "primary-nic", NetworkId: "network-id", Ips: []string{"10.0.0.1"}},
+						{Name: "pending-nic", NetworkId: "network-id"},
+					},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machineID := res.Machine.Metadata.Id
+		Expect(res.Machine.Metadata.Annotations).NotTo(HaveKey(api.NetworkInterfaceIPsAnnotation))
+
+		By("preparing the network interfaces")
+		machine, err := machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		machine.Status.NetworkInterfaceStatus = []api.NetworkInterfaceStatus{
+			{
+				Name:      "primary-nic",
+				State:     api.NetworkInterfaceStateAttached,
+				IPs:       []string{"10.0.0.1"},
+				PublicIPs: []string{"203.0.113.1"},
+			},
+			{Name: "pending-nic", State: api.NetworkInterfaceStatePending},
+		}
+		_, err = machineStore.Update(ctx, machine)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("listing the machine")
+		list, err := machineClient.ListMachines(ctx, &iri.ListMachinesRequest{
+			Filter: &iri.MachineFilter{Id: machineID},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list.Machines).To(HaveLen(1))
+		Expect(list.Machines[0].Metadata.Annotations).To(HaveKeyWithValue(api.NetworkInterfaceIPsAnnotation,
+			MatchJSON(`{"primary-nic":{"ips":["10.0.0.1"],"publicIPs":["203.0.113.1"]}}`)))
+	})
+
+	It("should report the states of the volumes and network interfaces", func(ctx SpecContext) {
+		By("creating a machine")
+		res, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+					NetworkInterfaces: []*iri.NetworkInterface{
+						{Name: "primary-nic", NetworkId: "network-id"},
+					},
+					Volumes: []*iri.Volume{
+						{Name: "primary-volume", Device: "oda", LocalDisk: &iri.LocalDisk{SizeBytes: emptyDiskSize}},
+						{Name: "secondary-volume", Device: "odb", LocalDisk: &iri.LocalDisk{SizeBytes: emptyDiskSize}},
+					},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machineID := res.Machine.Metadata.Id
+
+		By("attaching the volumes and network interfaces")
+		machine, err := machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		machine.Status.VolumeStatus = []api.VolumeStatus{
+			{Name: "primary-volume", Handle: "primary-handle", State: api.VolumeStateAttached},
+			{Name: "secondary-volume", State: api.VolumeStatePrepared},
+		}
+		machine.Status.NetworkInterfaceStatus = []api.NetworkInterfaceStatus{
+			{Name: "primary-nic", Handle: "nic-handle", State: api.NetworkInterfaceStateAttached},
+		}
+		_, err = machineStore.Update(ctx, machine)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("listing the machine")
+		list, err := machineClient.ListMachines(ctx, &iri.ListMachinesRequest{
+			Filter: &iri.MachineFilter{Id: machineID},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list.Machines).To(HaveLen(1))
+		Expect(list.Machines[0].Status.Volumes).To(ConsistOf(
+			&iri.VolumeStatus{Name: "primary-volume", Handle: "primary-handle", State: iri.VolumeState_VOLUME_ATTACHED},
+			&iri.VolumeStatus{Name: "secondary-volume", State: iri.VolumeState_VOLUME_PENDING},
+		))
+		Expect(list.Machines[0].Status.NetworkInterfaces).To(ConsistOf(&iri.NetworkInterfaceStatus{
+			Name:   "primary-nic",
+			Handle: "nic-handle",
+			State:  iri.NetworkInterfaceState_NETWORK_INTERFACE_ATTACHED,
+		}))
+	})
+
+	It("should filter machines by class, power and state", func(ctx SpecContext) {
+		By("creating machines of different classes and power")
+		const scopeLabel = "test.ironcore.dev/filter"
+		create := func(class string, power iri.Power) string {
+			res, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+				Machine: &iri.Machine{
+					Metadata: &irimeta.ObjectMetadata{Labels: map[string]string{scopeLabel: "true"}},
+					Spec:     &iri.MachineSpec{Power: power, Class: class},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return res.Machine.Metadata.Id
+		}
+		runningID := create(machineClassName, iri.Power_POWER_ON)
+		pendingID := create(machineClassName, iri.Power_POWER_ON)
+		offID := create(landlockMachineClassName, iri.Power_POWER_OFF)
+
+		By("marking a machine as running")
+		machine, err := machineStore.Get(ctx, runningID)
+		Expect(err).NotTo(HaveOccurred())
+		machine.Status.State = api.MachineStateRunning
+		_, err = machineStore.Update(ctx, machine)
+		Expect(err).NotTo(HaveOccurred())
+
+		listIDs := func(selector map[string]string) []string {
+			selector[scopeLabel] = "true"
+			list, err := machineClient.ListMachines(ctx, &iri.ListMachinesRequest{
+				Filter: &iri.MachineFilter{LabelSelector: selector},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			var ids []string
+			for _, machine := range list.Machines {
+				ids = append(ids, machine.Metadata.Id)
+			}
+			return ids
+		}
+
+		By("filtering by class")
+		Expect(listIDs(map[string]string{api.ClassLabel: machineClassName})).To(ConsistOf(runningID, pendingID))
+
+		By("filtering by power")
+		Expect(listIDs(map[string]string{api.PowerFilterKey: "POWER_OFF"})).To(ConsistOf(offID))
+
+		By("filtering by state")
+		Expect(listIDs(map[string]string{api.StateFilterKey: "MACHINE_RUNNING"})).To(ConsistOf(runningID))
+		Expect(listIDs(map[string]string{
+			api.ClassLabel:     machineClassName,
+			api.StateFilterKey: "MACHINE_PENDING",
+		})).To(ConsistOf(pendingID))
+
+		By("rejecting an unknown state")
+		_, err = machineClient.ListMachines(ctx, &iri.ListMachinesRequest{
+			Filter: &iri.MachineFilter{LabelSelector: map[string]string{api.StateFilterKey: "running"}},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
 })
@@ -5,9 +5,10 @@ package server
 
 import (
 	"context"
-	"fmt"
 
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *Server) AttachNetworkInterface(
@@ -19,23 +20,36 @@ func (s *Server) AttachNetworkInterface(
 	log.V(1).Info("Attaching NIC to machine")
 
 	if req == nil {
-		return nil, fmt.Errorf("AttachNetworkInterfaceRequest is nil")
+		return nil, status.Error(codes.InvalidArgument, "AttachNetworkInterfaceRequest is nil")
 	}
 
-	apiMachine, err := s.machineStore.Get(ctx, req.MachineId)
+	apiMachine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get machine: %w", err)
+		return nil, err
 	}
 
 	nicSpec, err := s.getNICFromIRINIC(req.NetworkInterface)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nic from iri nic: %w", err)
+		return nil, err
+	}
+	for _, nic := range apiMachine.Spec.NetworkInterfaces {
+		if nic.Name != nicSpec.Name {
+			continue
+		}
+		if nic.DeletedAt != nil {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"nic %s of machine %s is still being detached", nic.Name, req.MachineId)
+		}
+		return nil, status.Errorf(codes.AlreadyExists, "nic %s already exists in machine %s", nic.Name, req.MachineId)
+	}
+	if err := setNICFirewall(nicSpec); err != nil {
+		return nil, err
 	}
 
 	apiMachine.Spec.NetworkInterfaces = append(apiMachine.Spec.NetworkInterfaces, nicSpec)
 
 	if _, err := s.machineStore.Update(ctx, apiMachine); err != nil {
-		return nil, fmt.Errorf("failed to update machine: %w", err)
+		return nil, storeError(err, req.MachineId, "failed to update machine")
 	}
 
 	return &iri.AttachNetworkInterfaceResponse{}, nil
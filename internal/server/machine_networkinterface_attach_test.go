@@ -4,11 +4,14 @@
 package server_test
 
 import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ = Describe("AttachNetworkInterface", func() {
@@ -50,4 +53,98 @@ var _ = Describe("AttachNetworkInterface", func() {
 		Expect(updatedMachine.Machines).To(HaveLen(1))
 		Expect(updatedMachine.Machines[0].Spec.NetworkInterfaces).To(HaveLen(1))
 	})
+
+	It("should store the firewall of a network interface", func(ctx SpecContext) {
+		By("creating a machine")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machineID := createResp.Machine.Metadata.Id
+
+		By("attaching a network interface with an invalid firewall")
+		_, err = machineClient.AttachNetworkInterface(ctx, &iri.AttachNetworkInterfaceRequest{
+			MachineId: machineID,
+			NetworkInterface: &iri.NetworkInterface{
+				Name:      "my-nic",
+				NetworkId: "network-id",
+				Attributes: map[string]string{
+					api.NetworkInterfaceFirewallAttribute: `{"ingress":[{"protocol":"ICMP","port":22}]}`,
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		By("attaching a network interface with a firewall")
+		Expect(machineClient.AttachNetworkInterface(ctx, &iri.AttachNetworkInterfaceRequest{
+			MachineId: machineID,
+			NetworkInterface: &iri.NetworkInterface{
+				Name:      "my-nic",
+				NetworkId: "network-id",
+				Attributes: map[string]string{
+					api.NetworkInterfaceFirewallAttribute: `{"ingress":[{"protocol":"TCP","port":22,"cidrs":["10.0.0.0/8"]}]}`,
+				},
+			},
+		})).Error().NotTo(HaveOccurred())
+
+		By("ensuring the firewall is stored with the network interface")
+		machine, err := machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.NetworkInterfaces).To(HaveLen(1))
+		firewall := machine.Spec.NetworkInterfaces[0].Firewall
+		Expect(firewall).To(Equal(&api.FirewallSpec{
+			Ingress: []api.FirewallRule{{Protocol: api.FirewallProtocolTCP, Port: 22, CIDRs: []string{"10.0.0.0/8"}}},
+		}))
+		Expect(firewall.Restricts(api.FirewallPolicyTypeIngress)).To(BeTrue())
+		Expect(firewall.Restricts(api.FirewallPolicyTypeEgress)).To(BeFalse())
+	})
+
+	It("should reject network interfaces of unknown machines or with existing names", func(ctx SpecContext) {
+		nic := &iri.NetworkInterface{Name: "my-nic", NetworkId: "network-id"}
+
+		By("attaching a network interface to an unknown machine")
+		_, err := machineClient.AttachNetworkInterface(ctx, &iri.AttachNetworkInterfaceRequest{
+			MachineId:        "unknown",
+			NetworkInterface: nic,
+		})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		By("creating a machine with a network interface")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power:             iri.Power_POWER_ON,
+					Class:             machineClassName,
+					NetworkInterfaces: []*iri.NetworkInterface{nic},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machineID := createResp.Machine.Metadata.Id
+
+		By("attaching a network interface with the same name")
+		_, err = machineClient.AttachNetworkInterface(ctx, &iri.AttachNetworkInterfaceRequest{
+			MachineId:        machineID,
+			NetworkInterface: nic,
+		})
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+
+		By("attaching the network interface again while it is detached")
+		Expect(machineClient.DetachNetworkInterface(ctx, &iri.DetachNetworkInterfaceRequest{
+			MachineId: machineID,
+			Name:      nic.Name,
+		})).Error().NotTo(HaveOccurred())
+		_, err = machineClient.AttachNetworkInterface(ctx, &iri.AttachNetworkInterfaceRequest{
+			MachineId:        machineID,
+			NetworkInterface: nic,
+		})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
 })
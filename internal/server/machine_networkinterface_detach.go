@@ -5,11 +5,12 @@ package server
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/utils/ptr"
 )
 
@@ -21,12 +22,12 @@ func (s *Server) DetachNetworkInterface(
 	log.V(1).Info("Detaching nic from machine")
 
 	if req == nil {
-		return nil, fmt.Errorf("DetachNetworkInterface is nil")
+		return nil, status.Error(codes.InvalidArgument, "DetachNetworkInterfaceRequest is nil")
 	}
 
-	apiMachine, err := s.machineStore.Get(ctx, req.MachineId)
+	apiMachine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get machine: %w", err)
+		return nil, err
 	}
 
 	var updatedNICS []*api.NetworkInterfaceSpec
@@ -40,13 +41,13 @@ func (s *Server) DetachNetworkInterface(
 	}
 
 	if !found {
-		return nil, fmt.Errorf("nic '%s' not found in machine '%s'", req.Name, req.MachineId)
+		return nil, status.Errorf(codes.NotFound, "nic %s not found in machine %s", req.Name, req.MachineId)
 	}
 
 	apiMachine.Spec.NetworkInterfaces = updatedNICS
 
 	if _, err := s.machineStore.Update(ctx, apiMachine); err != nil {
-		return nil, fmt.Errorf("failed to update machine: %w", err)
+		return nil, storeError(err, req.MachineId, "failed to update machine")
 	}
 
 	return &iri.DetachNetworkInterfaceResponse{}, nil
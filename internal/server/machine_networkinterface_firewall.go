@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxPort = 65535
+
+// setNICFirewall sets the firewall the attributes of the network interface define.
+func setNICFirewall(nic *api.NetworkInterfaceSpec) error {
+	data, ok := nic.Attributes[api.NetworkInterfaceFirewallAttribute]
+	if !ok {
+		return nil
+	}
+
+	firewall := &api.FirewallSpec{}
+	if err := json.Unmarshal([]byte(data), firewall); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid firewall of network interface %s: %v", nic.Name, err)
+	}
+	if err := validateFirewall(firewall); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid firewall of network interface %s: %v", nic.Name, err)
+	}
+	nic.Firewall = firewall
+	return nil
+}
+
+func validateFirewall(firewall *api.FirewallSpec) error {
+	for _, policyType := range firewall.PolicyTypes {
+		switch policyType {
+		case api.FirewallPolicyTypeIngress, api.FirewallPolicyTypeEgress:
+		default:
+			return fmt.Errorf("unsupported policy type %q", policyType)
+		}
+	}
+	for i, rule := range firewall.Ingress {
+		if err := validateFirewallRule(rule); err != nil {
+			return fmt.Errorf("ingress rule %d: %w", i, err)
+		}
+	}
+	for i, rule := range firewall.Egress {
+		if err := validateFirewallRule(rule); err != nil {
+			return fmt.Errorf("egress rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateFirewallRule(rule api.FirewallRule) error {
+	switch rule.Protocol {
+	case api.FirewallProtocolTCP, api.FirewallProtocolUDP, api.FirewallProtocolSCTP:
+	case "", api.FirewallProtocolICMP:
+		if rule.Port != 0 || rule.EndPort != 0 {
+			return fmt.Errorf("ports require protocol TCP, UDP or SCTP")
+		}
+	default:
+		return fmt.Errorf("unsupported protocol %q", rule.Protocol)
+	}
+
+	if rule.Port < 0 || rule.Port > maxPort {
+		return fmt.Errorf("invalid port %d", rule.Port)
+	}
+	if rule.EndPort != 0 && (rule.Port == 0 || rule.EndPort < rule.Port || rule.EndPort > maxPort) {
+		return fmt.Errorf("invalid port range %d-%d", rule.Port, rule.EndPort)
+	}
+
+	for _, cidr := range rule.CIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR: %w", err)
+		}
+	}
+	return nil
+}
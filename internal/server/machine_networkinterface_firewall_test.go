@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("NetworkInterfaceFirewall", func() {
+	DescribeTable("validating the firewall of a network interface",
+		func(ctx SpecContext, firewall string, code codes.Code) {
+			createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+				Machine: &iri.Machine{
+					Metadata: &irimeta.ObjectMetadata{},
+					Spec: &iri.MachineSpec{
+						Power: iri.Power_POWER_ON,
+						Class: machineClassName,
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = machineClient.AttachNetworkInterface(ctx, &iri.AttachNetworkInterfaceRequest{
+				MachineId: createResp.Machine.Metadata.Id,
+				NetworkInterface: &iri.NetworkInterface{
+					Name:       "my-nic",
+					NetworkId:  "network-id",
+					Attributes: map[string]string{api.NetworkInterfaceFirewallAttribute: firewall},
+				},
+			})
+			Expect(status.Code(err)).To(Equal(code))
+		},
+		Entry("no rules", `{}`, codes.OK),
+		Entry("egress only", `{"policyTypes":["Egress"]}`, codes.OK),
+		Entry("tcp port", `{"ingress":[{"protocol":"TCP","port":22}]}`, codes.OK),
+		Entry("udp port range", `{"egress":[{"protocol":"UDP","port":8000,"endPort":8999}]}`, codes.OK),
+		Entry("sctp without port", `{"ingress":[{"protocol":"SCTP"}]}`, codes.OK),
+		Entry("icmp", `{"ingress":[{"protocol":"ICMP","cidrs":["10.0.0.0/8","fd00::/8"]}]}`, codes.OK),
+		Entry("any protocol", `{"egress":[{"cidrs":["0.0.0.0/0"]}]}`, codes.OK),
+		Entry("invalid json", `{"ingress":`, codes.InvalidArgument),
+		Entry("unsupported policy type", `{"policyTypes":["Forward"]}`, codes.InvalidArgument),
+		Entry("unsupported protocol", `{"ingress":[{"protocol":"GRE"}]}`, codes.InvalidArgument),
+		Entry("icmp with port", `{"ingress":[{"protocol":"ICMP","port":22}]}`, codes.InvalidArgument),
+		Entry("port without protocol", `{"egress":[{"port":53}]}`, codes.InvalidArgument),
+		Entry("port out of range", `{"ingress":[{"protocol":"TCP","port":70000}]}`, codes.InvalidArgument),
+		Entry("negative port", `{"ingress":[{"protocol":"TCP","port":-1}]}`, codes.InvalidArgument),
+		Entry("end port without port", `{"ingress":[{"protocol":"TCP","endPort":80}]}`, codes.InvalidArgument),
+		Entry("end port below port", `{"ingress":[{"protocol":"TCP","port":80,"endPort":79}]}`, codes.InvalidArgument),
+		Entry("end port out of range", `{"ingress":[{"protocol":"TCP","port":80,"endPort":65536}]}`,
+			codes.InvalidArgument),
+		Entry("invalid cidr", `{"egress":[{"protocol":"UDP","port":53,"cidrs":["10.0.0.0/33"]}]}`,
+			codes.InvalidArgument),
+	)
+})
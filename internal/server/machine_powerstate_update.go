@@ -5,26 +5,21 @@ package server
 
 import (
 	"context"
-	"errors"
-	"fmt"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
-	"github.com/ironcore-dev/provider-utils/storeutils/store"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (s *Server) updatePowerState(ctx context.Context, machine *api.Machine, iriPower iri.Power) error {
 	power, err := s.getPowerStateFromIRI(iriPower)
 	if err != nil {
-		return fmt.Errorf("failed to get power state: %w", err)
+		return err
 	}
 
 	machine.Spec.Power = power
 
 	if _, err = s.machineStore.Update(ctx, machine); err != nil {
-		return fmt.Errorf("failed to update machine: %w", err)
+		return storeError(err, machine.ID, "failed to update machine")
 	}
 
 	return nil
@@ -37,16 +32,13 @@ func (s *Server) UpdateMachinePower(
 	log := s.loggerFrom(ctx)
 
 	log.V(1).Info("Getting machine")
-	machine, err := s.machineStore.Get(ctx, req.MachineId)
+	machine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
 	if err != nil {
-		if !errors.Is(err, store.ErrNotFound) {
-			return nil, fmt.Errorf("error getting machine: %w", err)
-		}
-		return nil, status.Errorf(codes.NotFound, "machine %s not found", req.MachineId)
+		return nil, err
 	}
 
 	if err := s.updatePowerState(ctx, machine, req.Power); err != nil {
-		return nil, fmt.Errorf("failed to update power state: %w", err)
+		return nil, err
 	}
 
 	return &iri.UpdateMachinePowerResponse{}, nil
@@ -6,8 +6,12 @@ package server
 import (
 	"context"
 	"fmt"
+	"slices"
 
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *Server) AttachVolume(ctx context.Context, req *iri.AttachVolumeRequest) (*iri.AttachVolumeResponse, error) {
@@ -15,23 +19,73 @@ func (s *Server) AttachVolume(ctx context.Context, req *iri.AttachVolumeRequest)
 	log.V(1).Info("Attaching volume to machine")
 
 	if req == nil || req.MachineId == "" || req.Volume == nil {
-		return nil, fmt.Errorf("invalid request")
+		return nil, status.Error(codes.InvalidArgument, "invalid request")
 	}
 
-	apiMachine, err := s.machineStore.Get(ctx, req.MachineId)
+	apiMachine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get machine: %w", err)
+		return nil, err
 	}
 
 	volumeSpec, err := s.getVolumeFromIRIVolume(req.Volume)
 	if err != nil {
-		return nil, fmt.Errorf("error converting volume: %w", err)
+		return nil, err
+	}
+	for _, volume := range apiMachine.Spec.Volumes {
+		if volume.Name != volumeSpec.Name {
+			continue
+		}
+		if volume.DeletedAt != nil {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"volume %s of machine %s is still being detached", volume.Name, req.MachineId)
+		}
+		return nil, status.Errorf(codes.AlreadyExists,
+			"volume %s already exists in machine %s", volume.Name, req.MachineId)
+	}
+
+	if localDisk := volumeSpec.LocalDisk; localDisk != nil && localDisk.Image != nil {
+		className, _ := api.GetClassLabel(apiMachine)
+		class, found := s.machineClassRegistry.Get(className)
+		if !found {
+			return nil, status.Errorf(codes.FailedPrecondition, "machine class %s not supported", className)
+		}
+		setRootDiskMedium(class, volumeSpec)
+	}
+
+	if err := validateSharedMemory(apiMachine.Spec.SharedMemory, volumeSpec); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	annotations, err := api.GetAnnotationsAnnotation(apiMachine.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+	filesystems, err := getVolumeFilesystems(annotations)
+	if err != nil {
+		return nil, err
+	}
+	if err := setVolumeFilesystem(filesystems, volumeSpec); err != nil {
+		return nil, err
+	}
+	if err := setVolumeMedium(annotations, volumeSpec); err != nil {
+		return nil, err
+	}
+
+	if volumeSpec.LocalDisk != nil && volumeSpec.LocalDisk.Medium == api.StorageMediumMemory {
+		className, _ := api.GetClassLabel(apiMachine)
+		class, found := s.machineClassRegistry.Get(className)
+		if !found {
+			return nil, status.Errorf(codes.FailedPrecondition, "machine class %s not supported", className)
+		}
+		if err := validateMemoryDisks(class, append(slices.Clone(apiMachine.Spec.Volumes), volumeSpec)); err != nil {
+			return nil, err
+		}
 	}
 
 	apiMachine.Spec.Volumes = append(apiMachine.Spec.Volumes, volumeSpec)
 
 	if _, err := s.machineStore.Update(ctx, apiMachine); err != nil {
-		return nil, fmt.Errorf("failed to update machine with new volume: %w", err)
+		return nil, storeError(err, req.MachineId, "failed to update machine with new volume")
 	}
 
 	return &iri.AttachVolumeResponse{}, nil
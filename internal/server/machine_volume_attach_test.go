@@ -11,6 +11,8 @@ import (
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ = Describe("AttachVolume", func() {
@@ -61,4 +63,37 @@ var _ = Describe("AttachVolume", func() {
 			}, Equal(fmt.Sprintf("%s-%s-%d", volume.Name, volume.Device, volume.LocalDisk.SizeBytes))),
 		))
 	})
+
+	It("should reject a vhost-user volume for a machine without shared memory", func(ctx SpecContext) {
+		By("creating a machine of a class without shared memory")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: privateMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("attaching a ceph volume")
+		_, err = machineClient.AttachVolume(ctx, &iri.AttachVolumeRequest{
+			MachineId: createResp.Machine.Metadata.Id,
+			Volume: &iri.Volume{
+				Name:   "ceph-1",
+				Device: "odb",
+				Connection: &iri.VolumeConnection{
+					Driver: "ceph",
+					Handle: "pool/image",
+				},
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		By("ensuring the volume was not added")
+		machine, err := machineStore.Get(ctx, createResp.Machine.Metadata.Id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes).To(BeEmpty())
+	})
 })
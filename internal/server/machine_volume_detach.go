@@ -5,11 +5,12 @@ package server
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/utils/ptr"
 )
 
@@ -18,12 +19,12 @@ func (s *Server) DetachVolume(ctx context.Context, req *iri.DetachVolumeRequest)
 	log.V(1).Info("Detaching volume from machine")
 
 	if req == nil || req.MachineId == "" || req.Name == "" {
-		return nil, fmt.Errorf("invalid request")
+		return nil, status.Error(codes.InvalidArgument, "invalid request")
 	}
 
-	apiMachine, err := s.machineStore.Get(ctx, req.MachineId)
+	apiMachine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get machine: %w", err)
+		return nil, err
 	}
 
 	var updatedVolumes []*api.VolumeSpec
@@ -37,13 +38,13 @@ func (s *Server) DetachVolume(ctx context.Context, req *iri.DetachVolumeRequest)
 	}
 
 	if !found {
-		return nil, fmt.Errorf("volume '%s' not found in machine '%s'", req.Name, req.MachineId)
+		return nil, status.Errorf(codes.NotFound, "volume %s not found in machine %s", req.Name, req.MachineId)
 	}
 
 	apiMachine.Spec.Volumes = updatedVolumes
 
 	if _, err := s.machineStore.Update(ctx, apiMachine); err != nil {
-		return nil, fmt.Errorf("failed to update machine after detaching volume: %w", err)
+		return nil, storeError(err, req.MachineId, "failed to update machine after detaching volume")
 	}
 
 	return &iri.DetachVolumeResponse{}, nil
@@ -10,6 +10,8 @@ import (
 	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ = Describe("DetachVolume", func() {
@@ -61,4 +63,33 @@ var _ = Describe("DetachVolume", func() {
 			return v.DeletedAt != nil && v.Name == diskName
 		})))
 	})
+
+	It("should reject detaching volumes that do not exist", func(ctx SpecContext) {
+		By("detaching a volume of an unknown machine")
+		_, err := machineClient.DetachVolume(ctx, &iri.DetachVolumeRequest{MachineId: "unknown", Name: "disk-1"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		By("creating a machine")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("detaching a volume the machine does not have")
+		_, err = machineClient.DetachVolume(ctx, &iri.DetachVolumeRequest{
+			MachineId: createResp.Machine.Metadata.Id,
+			Name:      "disk-1",
+		})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		By("detaching without a volume name")
+		_, err = machineClient.DetachVolume(ctx, &iri.DetachVolumeRequest{MachineId: createResp.Machine.Metadata.Id})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
 })
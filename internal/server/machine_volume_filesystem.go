@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type filesystemRules struct {
+	maxLabelLength int
+	// flags are the allowed mkfs flags and whether they take a value. Flags that read from the host, e.g.
+	// mkfs.ext4 -d or mkfs.xfs -p, or that refer to host devices, e.g. external journals, are not allowed.
+	flags map[string]bool
+}
+
+var supportedFilesystems = map[api.FilesystemType]filesystemRules{
+	api.FilesystemTypeExt4: {
+		maxLabelLength: 16,
+		flags: map[string]bool{
+			"-b": true, "-E": true, "-g": true, "-G": true, "-i": true, "-I": true, "-m": true, "-N": true,
+			"-O": true, "-T": true, "-U": true, "-q": false,
+		},
+	},
+	api.FilesystemTypeXFS: {
+		maxLabelLength: 12,
+		flags: map[string]bool{
+			"-b": true, "-i": true, "-m": true, "-n": true, "-s": true, "-f": false, "-K": false, "-q": false,
+		},
+	},
+}
+
+// getVolumeFilesystems returns the filesystems of the volumes in the annotations.
+func getVolumeFilesystems(annotations map[string]string) (map[string]*api.FilesystemSpec, error) {
+	data, ok := annotations[api.VolumeFilesystemsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	var filesystems map[string]*api.FilesystemSpec
+	if err := json.Unmarshal([]byte(data), &filesystems); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid volume filesystems: %v", err)
+	}
+	for name, fs := range filesystems {
+		if err := validateFilesystem(fs); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid filesystem of volume %s: %v", name, err)
+		}
+	}
+	return filesystems, nil
+}
+
+func validateFilesystem(fs *api.FilesystemSpec) error {
+	if fs == nil {
+		return fmt.Errorf("filesystem must not be empty")
+	}
+	rules, ok := supportedFilesystems[fs.Type]
+	if !ok {
+		return fmt.Errorf("unsupported filesystem type %q", fs.Type)
+	}
+	if len(fs.Label) > rules.maxLabelLength {
+		return fmt.Errorf("label %q is longer than %d characters", fs.Label, rules.maxLabelLength)
+	}
+
+	for i := 0; i < len(fs.Options); i++ {
+		takesValue, ok := rules.flags[fs.Options[i]]
+		if !ok {
+			return fmt.Errorf("unsupported mkfs.%s option %q", fs.Type, fs.Options[i])
+		}
+		if !takesValue {
+			continue
+		}
+		i++
+		if i == len(fs.Options) || strings.HasPrefix(fs.Options[i], "-") {
+			return fmt.Errorf("mkfs.%s option %s requires a value", fs.Type, fs.Options[i-1])
+		}
+	}
+	return nil
+}
+
+// setVolumeFilesystem sets the filesystem the annotations define for the volume, which has to be an empty
+// local disk.
+func setVolumeFilesystem(filesystems map[string]*api.FilesystemSpec, volume *api.VolumeSpec) error {
+	fs, ok := filesystems[volume.Name]
+	if !ok {
+		return nil
+	}
+	if volume.LocalDisk == nil || volume.LocalDisk.Image != nil {
+		return status.Errorf(codes.InvalidArgument, "filesystem of volume %s requires an empty local disk", volume.Name)
+	}
+	volume.LocalDisk.Filesystem = fs
+	return nil
+}
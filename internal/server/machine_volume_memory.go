@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"slices"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// setVolumeMedium stores the volume in host memory if the annotations name it. Only empty local disks with a
+// size can be stored in memory.
+func setVolumeMedium(annotations map[string]string, volume *api.VolumeSpec) error {
+	if !slices.Contains(splitList(annotations[api.MemoryVolumesAnnotation]), volume.Name) {
+		return nil
+	}
+	if volume.LocalDisk == nil || volume.LocalDisk.Image != nil {
+		return status.Errorf(codes.InvalidArgument, "memory volume %s must be an empty local disk", volume.Name)
+	}
+	if volume.LocalDisk.Size == 0 {
+		return status.Errorf(codes.InvalidArgument, "memory volume %s must specify a size", volume.Name)
+	}
+	volume.LocalDisk.Medium = api.StorageMediumMemory
+	return nil
+}
+
+// setRootDiskMedium stores a disk provisioned from an image on the root disk medium of the class.
+func setRootDiskMedium(class mcr.MachineClass, volume *api.VolumeSpec) {
+	if volume.LocalDisk != nil && volume.LocalDisk.Image != nil && class.RootDiskMedium != "" {
+		volume.LocalDisk.Medium = class.RootDiskMedium
+	}
+}
+
+// validateMemoryDisks checks that the memory disks of a machine fit into the memory disk size of its class.
+func validateMemoryDisks(class mcr.MachineClass, volumes []*api.VolumeSpec) error {
+	var total int64
+	for _, volume := range volumes {
+		if volume.DeletedAt == nil && volume.LocalDisk != nil && volume.LocalDisk.Medium == api.StorageMediumMemory {
+			total += volume.LocalDisk.Size
+		}
+	}
+	if total > class.MemoryDiskBytes {
+		return status.Errorf(codes.InvalidArgument, "memory volumes of %s exceed the memory disk size %s of class %s",
+			resource.NewQuantity(total, resource.BinarySI), resource.NewQuantity(class.MemoryDiskBytes, resource.BinarySI),
+			class.Name)
+	}
+	return nil
+}
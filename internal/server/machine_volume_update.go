@@ -4,12 +4,99 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"maps"
 
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/utils/ptr"
 )
 
-func (s *Server) UpdateVolume(ctx context.Context, request *iri.UpdateVolumeRequest) (*iri.UpdateVolumeResponse, error) {
-	//TODO implement me
-	return &iri.UpdateVolumeResponse{}, nil
+// UpdateVolume changes the image of a local disk or the connection of a network volume. The disk is provisioned
+// from the new image when the VM is powered off the next time, network volumes are reconnected right away by
+// their plugin. Other changes of volumes are not supported and ignored.
+func (s *Server) UpdateVolume(ctx context.Context, req *iri.UpdateVolumeRequest) (*iri.UpdateVolumeResponse, error) {
+	log := s.loggerFrom(ctx)
+	log.V(1).Info("Updating volume of machine")
+
+	if req == nil || req.MachineId == "" || req.Volume == nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid request")
+	}
+
+	apiMachine, err := s.getCloudHypervisorMachine(ctx, req.MachineId)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeSpec, err := s.getVolumeFromIRIVolume(req.Volume)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, volume := range apiMachine.Spec.Volumes {
+		if volume.Name != volumeSpec.Name || volume.DeletedAt != nil {
+			continue
+		}
+
+		if volume.Connection != nil && volumeSpec.Connection != nil {
+			if err := s.updateVolumeConnection(ctx, apiMachine, volume, volumeSpec.Connection); err != nil {
+				return nil, err
+			}
+			return &iri.UpdateVolumeResponse{}, nil
+		}
+
+		if volume.LocalDisk == nil || volumeSpec.LocalDisk == nil {
+			return &iri.UpdateVolumeResponse{}, nil
+		}
+
+		image, updatedImage := ptr.Deref(volume.LocalDisk.Image, ""), ptr.Deref(volumeSpec.LocalDisk.Image, "")
+		if image == updatedImage {
+			return &iri.UpdateVolumeResponse{}, nil
+		}
+		if image == "" || updatedImage == "" {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"image of volume %s cannot be added or removed, only changed", volume.Name)
+		}
+
+		log.V(1).Info("Changing image of volume", "volume", volume.Name, "image", updatedImage, "previous", image)
+		volume.LocalDisk.Image = ptr.To(updatedImage)
+		if _, err := s.machineStore.Update(ctx, apiMachine); err != nil {
+			return nil, storeError(err, req.MachineId, "failed to update machine")
+		}
+		return &iri.UpdateVolumeResponse{}, nil
+	}
+
+	return nil, status.Errorf(codes.NotFound, "volume %s not found in machine %s", volumeSpec.Name, req.MachineId)
+}
+
+// updateVolumeConnection changes the attributes and secrets of the connection of a network volume, e.g. its
+// monitors or credentials. The driver and handle identify the volume and cannot be changed.
+func (s *Server) updateVolumeConnection(
+	ctx context.Context,
+	apiMachine *api.Machine,
+	volume *api.VolumeSpec,
+	connection *api.VolumeConnection,
+) error {
+	log := s.loggerFrom(ctx)
+
+	if connection.Driver != volume.Connection.Driver || connection.Handle != volume.Connection.Handle {
+		return status.Errorf(codes.InvalidArgument, "driver and handle of volume %s cannot be changed", volume.Name)
+	}
+	if maps.Equal(connection.Attributes, volume.Connection.Attributes) &&
+		maps.EqualFunc(connection.SecretData, volume.Connection.SecretData, bytes.Equal) &&
+		maps.EqualFunc(connection.EncryptionData, volume.Connection.EncryptionData, bytes.Equal) {
+		return nil
+	}
+
+	log.V(1).Info("Changing connection of volume", "volume", volume.Name)
+	volume.Connection.Attributes = connection.Attributes
+	volume.Connection.SecretData = connection.SecretData
+	volume.Connection.EncryptionData = connection.EncryptionData
+	if _, err := s.machineStore.Update(ctx, apiMachine); err != nil {
+		return storeError(err, apiMachine.ID, "failed to update machine")
+	}
+	return nil
 }
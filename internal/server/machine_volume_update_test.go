@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
+	machinepoolletv1alpha1 "github.com/ironcore-dev/ironcore/poollet/machinepoollet/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("UpdateVolume", func() {
+	It("should change the image of a local disk", func(ctx SpecContext) {
+		By("creating a machine with a root disk")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{
+					Labels: map[string]string{
+						machinepoolletv1alpha1.MachineUIDLabel: "foobar",
+					},
+				},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_ON,
+					Class: machineClassName,
+					Volumes: []*iri.Volume{
+						{
+							Name: "root",
+							LocalDisk: &iri.LocalDisk{
+								Image: &iri.ImageSpec{Image: "example.org/os:1.0"},
+							},
+							Device: "oda",
+						},
+						{
+							Name: "data",
+							LocalDisk: &iri.LocalDisk{
+								SizeBytes: emptyDiskSize,
+							},
+							Device: "odb",
+						},
+					},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machineID := createResp.Machine.Metadata.Id
+
+		By("changing the image of the root disk")
+		Expect(machineClient.UpdateVolume(ctx, &iri.UpdateVolumeRequest{
+			MachineId: machineID,
+			Volume: &iri.Volume{
+				Name: "root",
+				LocalDisk: &iri.LocalDisk{
+					Image: &iri.ImageSpec{Image: "example.org/os:2.0"},
+				},
+				Device: "oda",
+			},
+		})).Error().NotTo(HaveOccurred())
+
+		machine, err := machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes[0].LocalDisk.Image).To(Equal(ptr.To("example.org/os:2.0")))
+
+		By("adding an image to an empty disk")
+		_, err = machineClient.UpdateVolume(ctx, &iri.UpdateVolumeRequest{
+			MachineId: machineID,
+			Volume: &iri.Volume{
+				Name: "data",
+				LocalDisk: &iri.LocalDisk{
+					Image: &iri.ImageSpec{Image: "example.org/os:2.0"},
+				},
+				Device: "odb",
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		By("updating an unknown volume")
+		_, err = machineClient.UpdateVolume(ctx, &iri.UpdateVolumeRequest{
+			MachineId: machineID,
+			Volume: &iri.Volume{
+				Name:   "unknown",
+				Device: "odc",
+			},
+		})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should change the connection of a ceph volume", func(ctx SpecContext) {
+		connection := func(monitors string) *iri.VolumeConnection {
+			return &iri.VolumeConnection{
+				Driver:     "ceph",
+				Handle:     "ceph-volume",
+				Attributes: map[string]string{"image": "pool/image", "monitors": monitors},
+				SecretData: map[string][]byte{"userID": []byte("admin"), "userKey": []byte("key")},
+			}
+		}
+
+		By("creating a machine with a ceph volume")
+		createResp, err := machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power:   iri.Power_POWER_ON,
+					Class:   machineClassName,
+					Volumes: []*iri.Volume{{Name: "data", Device: "oda", Connection: connection("10.0.0.1:6789")}},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		machineID := createResp.Machine.Metadata.Id
+
+		By("changing the monitors of the volume")
+		Expect(machineClient.UpdateVolume(ctx, &iri.UpdateVolumeRequest{
+			MachineId: machineID,
+			Volume:    &iri.Volume{Name: "data", Device: "oda", Connection: connection("10.0.0.2:6789,10.0.0.3:6789")},
+		})).Error().NotTo(HaveOccurred())
+
+		machine, err := machineStore.Get(ctx, machineID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machine.Spec.Volumes[0].Connection.Attributes).To(HaveKeyWithValue("monitors", "10.0.0.2:6789,10.0.0.3:6789"))
+
+		By("changing the handle of the volume")
+		changedHandle := connection("10.0.0.2:6789")
+		changedHandle.Handle = "other-volume"
+		_, err = machineClient.UpdateVolume(ctx, &iri.UpdateVolumeRequest{
+			MachineId: machineID,
+			Volume:    &iri.Volume{Name: "data", Device: "oda", Connection: changedHandle},
+		})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	hostCapacity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "host_capacity",
+			Help:      "Capacity of the host by resource, cpu in cores, memory and disk in bytes.",
+		},
+		[]string{"resource"},
+	)
+
+	hostAllocatable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "host_allocatable",
+			Help:      "Capacity of the host less the system reserved resources, available to machines.",
+		},
+		[]string{"resource"},
+	)
+
+	hostAllocated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "host_allocated",
+			Help:      "Resources allocated by the machines of the host, as of the last status request.",
+		},
+		[]string{"resource"},
+	)
+
+	quotaLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "quota_limit",
+			Help:      "Limit of the quota of a value of the quota label by resource, 0 if unlimited.",
+		},
+		[]string{"quota", "resource"},
+	)
+
+	quotaUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "quota_used",
+			Help:      "Resources used by the machines of a value of the quota label, as of the last status request.",
+		},
+		[]string{"quota", "resource"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(hostCapacity, hostAllocatable, hostAllocated, quotaLimit, quotaUsed)
+}
+
+func setResourceMetrics(gauge *prometheus.GaugeVec, resources Resources) {
+	gauge.WithLabelValues("cpu").Set(float64(resources.CPUMillis) / 1000)
+	gauge.WithLabelValues("memory").Set(float64(resources.MemoryBytes))
+	gauge.WithLabelValues("disk").Set(float64(resources.DiskBytes))
+}
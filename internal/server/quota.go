@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// quotaUsage are the machines of a value of the quota label and the cpus and memory of their classes.
+type quotaUsage struct {
+	machines    int64
+	cpu         int64
+	memoryBytes int64
+}
+
+// quotaValue returns the value of the quota label of the machine, false if it has none.
+func (s *Server) quotaValue(machine *api.Machine) (string, bool) {
+	labels, err := api.GetLabelsAnnotation(machine.Metadata)
+	if err != nil {
+		return "", false
+	}
+	value, ok := labels[s.quotas.Label()]
+	return value, ok
+}
+
+// quotaUsage returns the usage of the machines by value of the quota label and updates the quota metrics.
+func (s *Server) quotaUsage(machines []*api.Machine) map[string]quotaUsage {
+	if s.quotas == nil {
+		return nil
+	}
+
+	usages := map[string]quotaUsage{}
+	for _, machine := range machines {
+		value, ok := s.quotaValue(machine)
+		if !ok {
+			continue
+		}
+		usage := usages[value]
+		usage.machines++
+		usage.cpu += machine.Spec.Cpu
+		usage.memoryBytes += machine.Spec.MemoryBytes
+		usages[value] = usage
+	}
+
+	quotaUsed.Reset()
+	quotaLimit.Reset()
+	for value, usage := range usages {
+		quota, found := s.quotas.Get(value)
+		if !found {
+			continue
+		}
+		quotaUsed.WithLabelValues(value, "machines").Set(float64(usage.machines))
+		quotaUsed.WithLabelValues(value, "cpu").Set(float64(usage.cpu))
+		quotaUsed.WithLabelValues(value, "memory").Set(float64(usage.memoryBytes))
+		quotaLimit.WithLabelValues(value, "machines").Set(float64(quota.Machines))
+		quotaLimit.WithLabelValues(value, "cpu").Set(float64(quota.Cpu))
+		quotaLimit.WithLabelValues(value, "memory").Set(float64(quota.MemoryBytes))
+	}
+	return usages
+}
+
+// admitQuota rejects the machine with ResourceExhausted if it exceeds the quota of the value of its quota label.
+func (s *Server) admitQuota(machines []*api.Machine, machine *api.Machine) error {
+	if s.quotas == nil {
+		return nil
+	}
+	value, ok := s.quotaValue(machine)
+	if !ok {
+		return nil
+	}
+	quota, found := s.quotas.Get(value)
+	if !found {
+		return nil
+	}
+
+	usage := s.quotaUsage(machines)[value]
+	for _, r := range []struct {
+		name                   string
+		used, requested, limit *resource.Quantity
+	}{
+		{
+			"machines",
+			resource.NewQuantity(usage.machines, resource.DecimalSI),
+			resource.NewQuantity(1, resource.DecimalSI),
+			resource.NewQuantity(quota.Machines, resource.DecimalSI),
+		},
+		{
+			"cpu",
+			resource.NewQuantity(usage.cpu, resource.DecimalSI),
+			resource.NewQuantity(machine.Spec.Cpu, resource.DecimalSI),
+			resource.NewQuantity(quota.Cpu, resource.DecimalSI),
+		},
+		{
+			"memory",
+			resource.NewQuantity(usage.memoryBytes, resource.BinarySI),
+			resource.NewQuantity(machine.Spec.MemoryBytes, resource.BinarySI),
+			resource.NewQuantity(quota.MemoryBytes, resource.BinarySI),
+		},
+	} {
+		if r.limit.IsZero() {
+			continue
+		}
+		total := r.used.DeepCopy()
+		total.Add(*r.requested)
+		if total.Cmp(*r.limit) > 0 {
+			return status.Errorf(codes.ResourceExhausted,
+				"quota of %s=%s exceeded: machine requests %s %s, %s of %s are used",
+				s.quotas.Label(), value, r.requested, r.name, r.used, r.limit)
+		}
+	}
+	return nil
+}
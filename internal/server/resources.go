@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// machineResources returns the resources of the host the machine allocates. Machines that are powered off keep
+// their resources, they may be powered on anytime. Disks stored in host memory allocate memory, disks of ceph
+// and disks provisioned from an image without size allocate no disk space of the host.
+func machineResources(machine *api.Machine) Resources {
+	resources := Resources{
+		CPUMillis:   machine.Spec.Cpu * 1000,
+		MemoryBytes: machine.Spec.MemoryBytes,
+	}
+	for _, vol := range machine.Spec.Volumes {
+		if vol.LocalDisk == nil || vol.DeletedAt != nil {
+			continue
+		}
+		switch vol.LocalDisk.Medium {
+		case api.StorageMediumMemory:
+			resources.MemoryBytes += vol.LocalDisk.Size
+		case api.StorageMediumCeph:
+		default:
+			resources.DiskBytes += vol.LocalDisk.Size
+		}
+	}
+	return resources
+}
+
+// freeResources returns the allocatable resources of the host not allocated by the machines, nil if the host
+// capacity is not known.
+func (s *Server) freeResources(machines []*api.Machine) *Resources {
+	if s.capacity == nil {
+		return nil
+	}
+
+	var allocated Resources
+	for _, machine := range machines {
+		resources := machineResources(machine)
+		allocated.CPUMillis += resources.CPUMillis
+		allocated.MemoryBytes += resources.MemoryBytes
+		allocated.DiskBytes += resources.DiskBytes
+	}
+	setResourceMetrics(hostAllocated, allocated)
+
+	return &Resources{
+		CPUMillis:   max(s.allocatable.CPUMillis-allocated.CPUMillis, 0),
+		MemoryBytes: max(s.allocatable.MemoryBytes-allocated.MemoryBytes, 0),
+		DiskBytes:   max(s.allocatable.DiskBytes-allocated.DiskBytes, 0),
+	}
+}
+
+// listAccountedMachines returns the machines for the accounting of the host resources and quotas, nil if neither
+// is enabled.
+func (s *Server) listAccountedMachines(ctx context.Context) ([]*api.Machine, error) {
+	if s.capacity == nil && s.quotas == nil {
+		return nil, nil
+	}
+	machines, err := s.machineStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+	return machines, nil
+}
+
+// admit rejects the machine with ResourceExhausted if its resources exceed the free allocatable resources of the
+// host or its quota. The caller holds the admissionMu until the machine is stored.
+func (s *Server) admit(ctx context.Context, machine *api.Machine) error {
+	machines, err := s.listAccountedMachines(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.admitQuota(machines, machine); err != nil {
+		return err
+	}
+
+	free := s.freeResources(machines)
+	if free == nil {
+		return nil
+	}
+
+	requested := machineResources(machine)
+	for _, r := range []struct {
+		name            string
+		requested, free *resource.Quantity
+	}{
+		{
+			"cpu",
+			resource.NewMilliQuantity(requested.CPUMillis, resource.DecimalSI),
+			resource.NewMilliQuantity(free.CPUMillis, resource.DecimalSI),
+		},
+		{
+			"memory",
+			resource.NewQuantity(requested.MemoryBytes, resource.BinarySI),
+			resource.NewQuantity(free.MemoryBytes, resource.BinarySI),
+		},
+		{
+			"disk",
+			resource.NewQuantity(requested.DiskBytes, resource.BinarySI),
+			resource.NewQuantity(free.DiskBytes, resource.BinarySI),
+		},
+	} {
+		if r.requested.Cmp(*r.free) > 0 {
+			return status.Errorf(codes.ResourceExhausted, "machine requests %s %s, only %s are free on the host",
+				r.requested, r.name, r.free)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/quota"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/server"
+	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
+	irimeta "github.com/ironcore-dev/ironcore/iri/apis/meta/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Resources", func() {
+	newServer := func(capacity *server.Resources, reserved server.Resources) (*server.Server, error) {
+		classRegistry, err := mcr.NewMachineClassRegistry([]mcr.MachineClass{
+			{Name: machineClassName, Cpu: 1000, MemoryBytes: 2147483648},
+			{Name: topologyMachineClassName, Cpu: 4, MemoryBytes: 2147483648},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return server.New(machineStore, server.Options{
+			MachineClassRegistry: classRegistry,
+			Maintenance:          maintenanceMode,
+			Capacity:             capacity,
+			SystemReserved:       reserved,
+		})
+	}
+
+	It("should report the machine classes fitting into the allocatable resources", func(ctx SpecContext) {
+		srv, err := newServer(
+			&server.Resources{CPUMillis: 16000, MemoryBytes: 16 * 1024 * 1024 * 1024, DiskBytes: 100 * 1024 * 1024 * 1024},
+			server.Resources{CPUMillis: 4000, MemoryBytes: 2 * 1024 * 1024 * 1024},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("reporting the quantities of the empty host")
+		statusResp, err := srv.Status(ctx, &iri.StatusRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusResp.MachineClassStatus).To(ConsistOf(
+			HaveField("Quantity", BeNumerically("==", 0)),
+			HaveField("Quantity", BeNumerically("==", 3)),
+		))
+
+		By("creating a machine")
+		_, err = machineClient.CreateMachine(ctx, &iri.CreateMachineRequest{
+			Machine: &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_OFF,
+					Class: topologyMachineClassName,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("reporting the quantities less the resources of the machine")
+		statusResp, err = srv.Status(ctx, &iri.StatusRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusResp.MachineClassStatus).To(ContainElement(SatisfyAll(
+			HaveField("MachineClass.Name", topologyMachineClassName),
+			HaveField("Quantity", BeNumerically("==", 2)),
+		)))
+	})
+
+	It("should reject system reserved resources exceeding the capacity", func() {
+		_, err := newServer(
+			&server.Resources{CPUMillis: 4000, MemoryBytes: 1024 * 1024 * 1024, DiskBytes: 1024 * 1024 * 1024},
+			server.Resources{CPUMillis: 4000},
+		)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject machines exceeding the free allocatable resources", func(ctx SpecContext) {
+		srv, err := newServer(
+			&server.Resources{CPUMillis: 8000, MemoryBytes: 16 * 1024 * 1024 * 1024, DiskBytes: 2 * emptyDiskSize},
+			server.Resources{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		newMachine := func(diskSize int64) *iri.Machine {
+			return &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_OFF,
+					Class: topologyMachineClassName,
+					Volumes: []*iri.Volume{{
+						Name:      "scratch",
+						LocalDisk: &iri.LocalDisk{SizeBytes: diskSize},
+						Device:    "oda",
+					}},
+				},
+			}
+		}
+
+		By("creating a machine with a disk exceeding the disk space of the host")
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine(3 * emptyDiskSize)})
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+
+		By("creating machines until the cpus of the host are allocated")
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine(emptyDiskSize)})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine(emptyDiskSize)})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("creating a machine exceeding the cpus of the host")
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine(0)})
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		Expect(err).To(MatchError(ContainSubstring("machine requests 4 cpu, only 0 are free on the host")))
+	})
+
+	It("should report the machine classes backed by hugepages fitting into the free hugepages", func(ctx SpecContext) {
+		const hugepageSize = 2 * 1024 * 1024
+		classRegistry, err := mcr.NewMachineClassRegistry([]mcr.MachineClass{
+			{Name: machineClassName, Cpu: 1, MemoryBytes: 2147483648},
+			{
+				Name:          topologyMachineClassName,
+				Cpu:           1,
+				MemoryBytes:   2147483648,
+				MemoryBacking: &api.MemoryBacking{HugepageSizeBytes: hugepageSize},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		freeHugepages := int64(5 * 1024 * 1024 * 1024)
+		srv, err := server.New(machineStore, server.Options{
+			MachineClassRegistry: classRegistry,
+			Maintenance:          maintenanceMode,
+			FreeHugepages: func(sizeBytes int64) (int64, error) {
+				Expect(sizeBytes).To(BeNumerically("==", hugepageSize))
+				return freeHugepages, nil
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("reporting the quantity of the class backed by hugepages")
+		statusResp, err := srv.Status(ctx, &iri.StatusRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusResp.MachineClassStatus).To(ConsistOf(
+			SatisfyAll(HaveField("MachineClass.Name", machineClassName), HaveField("Quantity", BeNumerically("==", 1000))),
+			SatisfyAll(HaveField("MachineClass.Name", topologyMachineClassName), HaveField("Quantity", BeNumerically("==", 2))),
+		))
+
+		By("reporting the class unavailable once the hugepages are used up")
+		freeHugepages = 1024 * 1024 * 1024
+		statusResp, err = srv.Status(ctx, &iri.StatusRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusResp.MachineClassStatus).To(ContainElement(SatisfyAll(
+			HaveField("MachineClass.Name", topologyMachineClassName),
+			HaveField("Quantity", BeNumerically("==", 0)),
+		)))
+	})
+
+	It("should reject machines exceeding the quota of their label value", func(ctx SpecContext) {
+		classRegistry, err := mcr.NewMachineClassRegistry([]mcr.MachineClass{
+			{Name: topologyMachineClassName, Cpu: 4, MemoryBytes: 2147483648},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		quotas, err := quota.NewRegistry("tenant", []quota.Quota{
+			{Name: "a", Machines: 1},
+			{Name: quota.Default, Cpu: 8},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		srv, err := server.New(machineStore, server.Options{
+			MachineClassRegistry: classRegistry,
+			Maintenance:          maintenanceMode,
+			Quotas:               quotas,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		newMachine := func(tenant string) *iri.Machine {
+			return &iri.Machine{
+				Metadata: &irimeta.ObjectMetadata{Labels: map[string]string{"tenant": tenant}},
+				Spec: &iri.MachineSpec{
+					Power: iri.Power_POWER_OFF,
+					Class: topologyMachineClassName,
+				},
+			}
+		}
+
+		By("creating machines up to the machine quota of a tenant")
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine("a")})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine("a")})
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		Expect(err).To(MatchError(ContainSubstring("quota of tenant=a exceeded")))
+
+		By("creating machines up to the default cpu quota of another tenant")
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine("b")})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine("b")})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: newMachine("b")})
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		Expect(err).To(MatchError(ContainSubstring("machine requests 4 cpu, 8 of 8 are used")))
+
+		By("creating a machine without the quota label")
+		_, err = srv.CreateMachine(ctx, &iri.CreateMachineRequest{Machine: &iri.Machine{
+			Metadata: &irimeta.ObjectMetadata{},
+			Spec:     &iri.MachineSpec{Power: iri.Power_POWER_OFF, Class: topologyMachineClassName},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
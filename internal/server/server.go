@@ -6,11 +6,14 @@ package server
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/quota"
 	"github.com/ironcore-dev/ironcore/broker/common/idgen"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
@@ -30,6 +33,33 @@ type Server struct {
 
 	machineStore store.Store[*api.Machine]
 	eventStore   recorder.EventStore
+	// eventWatcher is the event store if clients can wait for its events, nil otherwise.
+	eventWatcher EventWatcher
+
+	ignitionTransport   api.IgnitionTransport
+	ignitionCompression bool
+
+	landlock bool
+
+	maintenance *maintenance.Mode
+
+	dns api.DNSSpec
+
+	capacity    *Resources
+	allocatable Resources
+	quotas      *quota.Registry
+	// freeHugepages returns the free hugepages of a size in bytes, nil if they are not considered.
+	freeHugepages func(sizeBytes int64) (int64, error)
+	// admissionMu serializes the admission and creation of machines, so that they do not overcommit the host.
+	admissionMu sync.Mutex
+}
+
+// Resources are the cpu, memory and disk space of the host machines are placed on.
+type Resources struct {
+	CPUMillis   int64
+	MemoryBytes int64
+	// DiskBytes is the space of the filesystem the local disks are stored on.
+	DiskBytes int64
 }
 
 type Options struct {
@@ -38,6 +68,38 @@ type Options struct {
 	EventStore recorder.EventStore
 
 	MachineClassRegistry mcr.MachineClassRegistry
+
+	// IgnitionTransport is the default transport of the ignition payload, used to validate its size.
+	IgnitionTransport   api.IgnitionTransport
+	IgnitionCompression bool
+
+	// Landlock enables landlock for machines whose class does not configure it.
+	Landlock bool
+
+	// Maintenance rejects new machines while the host is in maintenance.
+	Maintenance *maintenance.Mode
+
+	// DNS is the DNS configuration of guests whose machine does not configure it.
+	DNS api.DNSSpec
+
+	// Capacity is the capacity of the host. The machine classes are reported available until the machines
+	// allocate it less SystemReserved, without limit if nil.
+	Capacity *Resources
+	// SystemReserved is the part of the capacity reserved for the host and its daemons.
+	SystemReserved Resources
+
+	// Quotas limit the machines by the value of their quota label. Machines are not limited if nil.
+	Quotas *quota.Registry
+
+	// FreeHugepages returns the bytes of the free hugepages of a size on the host. The machine classes backed by
+	// hugepages are reported available until they are used up, they are not considered if nil.
+	FreeHugepages func(sizeBytes int64) (int64, error)
+}
+
+// EventWatcher is implemented by event stores whose clients can wait for new events by resource version.
+type EventWatcher interface {
+	ResourceVersion() uint64
+	WaitForEvents(ctx context.Context, resourceVersion uint64) uint64
 }
 
 type nilEventStore struct{}
@@ -53,6 +115,9 @@ func setOptionsDefaults(o *Options) {
 	if o.EventStore == nil {
 		o.EventStore = &nilEventStore{}
 	}
+	if o.IgnitionTransport == "" {
+		o.IgnitionTransport = api.IgnitionTransportOEMStrings
+	}
 }
 
 func New(store store.Store[*api.Machine], opts Options) (*Server, error) {
@@ -62,11 +127,37 @@ func New(store store.Store[*api.Machine], opts Options) (*Server, error) {
 		return nil, fmt.Errorf("MachineClassRegistry option is required")
 	}
 
+	var allocatable Resources
+	if opts.Capacity != nil {
+		allocatable = Resources{
+			CPUMillis:   opts.Capacity.CPUMillis - opts.SystemReserved.CPUMillis,
+			MemoryBytes: opts.Capacity.MemoryBytes - opts.SystemReserved.MemoryBytes,
+			DiskBytes:   opts.Capacity.DiskBytes - opts.SystemReserved.DiskBytes,
+		}
+		if allocatable.CPUMillis <= 0 || allocatable.MemoryBytes <= 0 || allocatable.DiskBytes <= 0 {
+			return nil, fmt.Errorf("system reserved resources exceed the capacity of the host")
+		}
+		setResourceMetrics(hostCapacity, *opts.Capacity)
+		setResourceMetrics(hostAllocatable, allocatable)
+	}
+
+	eventWatcher, _ := opts.EventStore.(EventWatcher)
+
 	return &Server{
 		idGen:                opts.IDGen,
+		eventWatcher:         eventWatcher,
 		machineStore:         store,
 		eventStore:           opts.EventStore,
 		machineClassRegistry: opts.MachineClassRegistry,
+		ignitionTransport:    opts.IgnitionTransport,
+		ignitionCompression:  opts.IgnitionCompression,
+		landlock:             opts.Landlock,
+		maintenance:          opts.Maintenance,
+		dns:                  opts.DNS,
+		capacity:             opts.Capacity,
+		allocatable:          allocatable,
+		quotas:               opts.Quotas,
+		freeHugepages:        opts.FreeHugepages,
 	}, nil
 }
 
@@ -13,18 +13,22 @@ import (
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/cmd/cloud-hypervisor-provider/app"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/events"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/maintenance"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/server"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/strategy"
 	iriv1alpha1 "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 	"github.com/ironcore-dev/ironcore/iri/remote/machine"
 	"github.com/ironcore-dev/provider-utils/eventutils/event"
+	"github.com/ironcore-dev/provider-utils/eventutils/recorder"
 	hostutils "github.com/ironcore-dev/provider-utils/storeutils/host"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/utils/ptr"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -34,14 +38,26 @@ const (
 	pollingInterval      = 50 * time.Millisecond
 	consistentlyDuration = 1 * time.Second
 
-	machineClassName = "sample-machine-class"
-	emptyDiskSize    = 1024 * 1024 * 1024
+	machineClassName           = "sample-machine-class"
+	landlockMachineClassName   = "landlock-machine-class"
+	topologyMachineClassName   = "topology-machine-class"
+	featuresMachineClassName   = "features-machine-class"
+	privateMachineClassName    = "private-memory-machine-class"
+	rootDiskMachineClassName   = "root-disk-machine-class"
+	rootDiskSize               = 10 * 1024 * 1024 * 1024
+	cephRootDiskClassName      = "ceph-root-disk-machine-class"
+	memoryDiskMachineClassName = "memory-disk-machine-class"
+	deprecatedMachineClassName = "deprecated-machine-class"
+	memoryDiskSize             = 1024 * 1024 * 1024
+	emptyDiskSize              = 1024 * 1024 * 1024
 )
 
 var (
-	machineClient iriv1alpha1.MachineRuntimeClient
-	machineEvents *event.ListWatchSource[*api.Machine]
-	machineStore  *hostutils.Store[*api.Machine]
+	machineClient   iriv1alpha1.MachineRuntimeClient
+	machineEvents   *event.ListWatchSource[*api.Machine]
+	machineStore    *hostutils.Store[*api.Machine]
+	maintenanceMode *maintenance.Mode
+	eventRecorder   *events.Recorder
 
 	tempDir string
 )
@@ -89,11 +105,75 @@ var _ = BeforeEach(func() {
 			Cpu:         1000,
 			MemoryBytes: 2147483648,
 		},
+		{
+			Name:        landlockMachineClassName,
+			Cpu:         1000,
+			MemoryBytes: 2147483648,
+			Landlock:    ptr.To(true),
+		},
+		{
+			Name:        topologyMachineClassName,
+			Cpu:         4,
+			MemoryBytes: 2147483648,
+			CpuTopology: &api.CpuTopology{Sockets: 1, CoresPerSocket: 2, ThreadsPerCore: 2},
+		},
+		{
+			Name:        featuresMachineClassName,
+			Cpu:         2,
+			MemoryBytes: 2147483648,
+			CpuFeatures: &api.CpuFeatures{KvmHyperV: true, MaxPhysBits: 40},
+			Clock:       api.GuestClockPTP,
+
+			FreePageReporting: true,
+			VMConfigOverrides: map[string]string{"memory.thp": "false"},
+			SerialMode:        api.ConsoleModeTty,
+			ConsoleMode:       api.ConsoleModeFile,
+			VMMRequirements:   &api.VMMRequirements{MinVersion: "v49.0", Features: []string{"kvm"}},
+		},
+		{
+			Name:         privateMachineClassName,
+			Cpu:          1000,
+			MemoryBytes:  2147483648,
+			SharedMemory: ptr.To(false),
+			MemoryBacking: &api.MemoryBacking{
+				File: "/dev/hugepages",
+			},
+		},
+		{
+			Name:          rootDiskMachineClassName,
+			Cpu:           2,
+			MemoryBytes:   2147483648,
+			RootDiskBytes: rootDiskSize,
+		},
+		{
+			Name:           cephRootDiskClassName,
+			Cpu:            2,
+			MemoryBytes:    2147483648,
+			RootDiskMedium: api.StorageMediumCeph,
+		},
+		{
+			Name:            memoryDiskMachineClassName,
+			Cpu:             2,
+			MemoryBytes:     2147483648,
+			MemoryDiskBytes: memoryDiskSize,
+		},
+		{
+			Name:        deprecatedMachineClassName,
+			Cpu:         1000,
+			MemoryBytes: 2147483648,
+			Deprecated:  true,
+		},
 	})
 	Expect(err).NotTo(HaveOccurred())
 
+	maintenanceMode = maintenance.NewMode(false, maintenance.EvacuationNone)
+
+	eventRecorder = events.NewRecorder(log, recorder.NewEventStore(log, recorder.EventStoreOptions{}))
+
 	srv, err := server.New(machineStore, server.Options{
+		EventStore:           eventRecorder,
 		MachineClassRegistry: classRegistry,
+		Maintenance:          maintenanceMode,
 	})
 	Expect(err).NotTo(HaveOccurred())
 
@@ -102,7 +182,7 @@ var _ = BeforeEach(func() {
 
 	go func() {
 		defer GinkgoRecover()
-		Expect(app.RunGRPCServer(cancelCtx, log, log, srv, filepath.Join(tempDir, "test.sock"))).To(Succeed())
+		Expect(app.RunGRPCServer(cancelCtx, log, log, srv, filepath.Join(tempDir, "test.sock"), nil)).To(Succeed())
 	}()
 
 	go func() {
@@ -6,14 +6,29 @@ package server
 import (
 	"context"
 
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/mcr"
 	iri "github.com/ironcore-dev/ironcore/iri/apis/machine/v1alpha1"
 )
 
+// unlimitedQuantity is the quantity of the machine classes reported without a known host capacity.
+const unlimitedQuantity = int64(1000)
+
 func (s *Server) Status(ctx context.Context, _ *iri.StatusRequest) (*iri.StatusResponse, error) {
 	log := s.loggerFrom(ctx)
 
+	machines, err := s.listAccountedMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	free := s.freeResources(machines)
+	s.quotaUsage(machines)
+
 	var classes []*iri.MachineClassStatus
 	for _, class := range s.machineClassRegistry.List() {
+		if class.Deprecated {
+			continue
+		}
 		classes = append(classes, &iri.MachineClassStatus{
 			MachineClass: &iri.MachineClass{
 				Name: class.Name,
@@ -25,7 +40,7 @@ func (s *Server) Status(ctx context.Context, _ *iri.StatusRequest) (*iri.StatusR
 				},
 			},
 			//TODO will be deprecated soon
-			Quantity: 1000,
+			Quantity: s.quantity(log, class, free),
 		})
 	}
 
@@ -34,3 +49,35 @@ func (s *Server) Status(ctx context.Context, _ *iri.StatusRequest) (*iri.StatusR
 		MachineClassStatus: classes,
 	}, nil
 }
+
+// quantity returns the number of machines of the class that fit into the free resources of the host.
+func (s *Server) quantity(log logr.Logger, class mcr.MachineClass, free *Resources) int64 {
+	// No new machines are admitted during maintenance.
+	if s.maintenance.Enabled() {
+		return 0
+	}
+
+	quantity := unlimitedQuantity
+	if backing := class.MemoryBacking; backing != nil && backing.HugepageSizeBytes != 0 && s.freeHugepages != nil {
+		// The hugepages of machines that are not running yet are not counted, they are only used up on start.
+		freeHugepages, err := s.freeHugepages(backing.HugepageSizeBytes)
+		if err != nil {
+			log.Error(err, "Failed to get free hugepages, reporting machine class unavailable", "class", class.Name)
+			return 0
+		}
+		if class.MemoryBytes > 0 {
+			quantity = min(quantity, freeHugepages/class.MemoryBytes)
+		}
+	}
+	if free == nil {
+		return quantity
+	}
+
+	if class.Cpu > 0 {
+		quantity = min(quantity, free.CPUMillis/(class.Cpu*1000))
+	}
+	if class.MemoryBytes > 0 {
+		quantity = min(quantity, free.MemoryBytes/class.MemoryBytes)
+	}
+	return quantity
+}
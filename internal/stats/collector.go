@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package stats samples the resource usage of the VMs and publishes it in the machine status and as metrics.
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+	"github.com/ironcore-dev/provider-utils/storeutils/store"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+const DefaultInterval = 30 * time.Second
+
+type Options struct {
+	// Interval between two samples. Defaults to DefaultInterval.
+	Interval time.Duration
+	// ProcDir is the mount point of procfs. Defaults to /proc.
+	ProcDir string
+	// SysDir is the mount point of sysfs. Defaults to /sys.
+	SysDir string
+	// VolumePlugins provide the stats of the volumes cloud-hypervisor has no counters for.
+	VolumePlugins *volume.PluginManager
+}
+
+type sample struct {
+	pid     int
+	cpuTime time.Duration
+	at      time.Time
+}
+
+// Collector periodically samples the usage of the VMs of the running machines.
+type Collector struct {
+	log      logr.Logger
+	machines store.Store[*api.Machine]
+	vmm      vmm.VirtualMachineManager
+
+	interval time.Duration
+	procDir  string
+	sysDir   string
+
+	volumePlugins *volume.PluginManager
+
+	samples map[string]sample
+}
+
+func NewCollector(
+	log logr.Logger,
+	machines store.Store[*api.Machine],
+	vmm vmm.VirtualMachineManager,
+	opts Options,
+) (*Collector, error) {
+	if machines == nil {
+		return nil, fmt.Errorf("must specify machine store")
+	}
+	if vmm == nil {
+		return nil, fmt.Errorf("must specify virtual machine manager")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.ProcDir == "" {
+		opts.ProcDir = "/proc"
+	}
+	if opts.SysDir == "" {
+		opts.SysDir = "/sys"
+	}
+
+	return &Collector{
+		log:      log,
+		machines: machines,
+		vmm:      vmm,
+		interval: opts.Interval,
+		procDir:  opts.ProcDir,
+		sysDir:   opts.SysDir,
+
+		volumePlugins: opts.VolumePlugins,
+		samples:       map[string]sample{},
+	}, nil
+}
+
+func (c *Collector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Collector) collect(ctx context.Context) {
+	machines, err := c.machines.List(ctx)
+	if err != nil {
+		c.log.Error(err, "Failed to list machines")
+		return
+	}
+
+	seen := map[string]struct{}{}
+	var reclaimed int64
+	for _, machine := range machines {
+		if machine.DeletedAt != nil {
+			continue
+		}
+		if machine.Status.State != api.MachineStateRunning {
+			if clearStats(machine) {
+				if err := c.updateStatus(ctx, machine.ID, func(machine *api.Machine) {
+					clearStats(machine)
+				}); err != nil {
+					c.log.V(1).Info("Failed to clear machine stats", "machineID", machine.ID, "error", err.Error())
+				}
+			}
+			continue
+		}
+		seen[machine.ID] = struct{}{}
+
+		utilization, err := c.collectMachine(ctx, machine)
+		if err != nil {
+			c.log.V(1).Info("Failed to collect machine stats", "machineID", machine.ID, "error", err.Error())
+		}
+		if utilization != nil {
+			reclaimed += utilization.ReclaimedMemoryBytes
+		}
+	}
+	memoryReclaimed.Set(float64(reclaimed))
+
+	for machineID := range c.samples {
+		if _, ok := seen[machineID]; !ok {
+			delete(c.samples, machineID)
+			deleteMachineMetrics(machineID)
+		}
+	}
+}
+
+func (c *Collector) collectMachine(ctx context.Context, machine *api.Machine) (*api.UtilizationStatus, error) {
+	apiSocket := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	counters, err := c.vmm.Counters(ctx, apiSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vm counters: %w", err)
+	}
+
+	nics := map[string]*api.NetworkInterfaceStats{}
+	for _, nic := range machine.Status.NetworkInterfaceStatus {
+		if nic.State != api.NetworkInterfaceStateAttached {
+			continue
+		}
+		stats, err := c.networkInterfaceStats(counters, nic)
+		if err != nil {
+			c.log.V(1).Info("Failed to get network interface stats", "machineID", machine.ID, "nic", nic.Name,
+				"error", err.Error())
+		}
+		nics[nic.Name] = stats
+	}
+
+	volumes := map[string]*api.VolumeStats{}
+	for _, status := range machine.Status.VolumeStatus {
+		if status.State != api.VolumeStateAttached {
+			continue
+		}
+		stats, err := c.volumeStats(ctx, machine, counters, status)
+		if err != nil {
+			c.log.V(1).Info("Failed to get volume stats", "machineID", machine.ID, "volume", status.Name,
+				"error", err.Error())
+		}
+		volumes[status.Name] = stats
+	}
+
+	pid, err := c.vmm.Pid(ctx, apiSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vmm pid: %w", err)
+	}
+
+	usage, err := readProcessUsage(c.procDir, pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process usage: %w", err)
+	}
+
+	now := time.Now()
+	previous, ok := c.samples[machine.ID]
+	c.samples[machine.ID] = sample{pid: pid, cpuTime: usage.cpuTime, at: now}
+
+	// The cpu usage is averaged between two samples of the same process.
+	var utilization *api.UtilizationStatus
+	if ok && previous.pid == pid && usage.cpuTime >= previous.cpuTime {
+		utilization = &api.UtilizationStatus{
+			CpuMillis:   int64(usage.cpuTime-previous.cpuTime) * 1000 / int64(now.Sub(previous.at)),
+			MemoryBytes: usage.rssBytes,
+			SampledAt:   now.UTC().Truncate(time.Second),
+		}
+		machineCPUUsage.WithLabelValues(machine.ID).Set(float64(utilization.CpuMillis))
+		machineMemoryUsage.WithLabelValues(machine.ID).Set(float64(utilization.MemoryBytes))
+
+		// Pages reported free are discarded by cloud-hypervisor and drop out of its resident memory.
+		if machine.Spec.FreePageReporting {
+			utilization.ReclaimedMemoryBytes = max(machine.Spec.MemoryBytes-usage.rssBytes, 0)
+			machineMemoryReclaimed.WithLabelValues(machine.ID).Set(float64(utilization.ReclaimedMemoryBytes))
+		}
+	}
+
+	return utilization, c.updateStatus(ctx, machine.ID, func(machine *api.Machine) {
+		if utilization != nil {
+			machine.Status.Utilization = utilization
+		}
+		for i := range machine.Status.NetworkInterfaceStatus {
+			nic := &machine.Status.NetworkInterfaceStatus[i]
+			nic.Stats = nics[nic.Name]
+		}
+		for i := range machine.Status.VolumeStatus {
+			status := &machine.Status.VolumeStatus[i]
+			status.Stats = volumes[status.Name]
+		}
+		devices.set(machine.ID, machine.Status.NetworkInterfaceStatus, machine.Status.VolumeStatus)
+	})
+}
+
+// clearStats removes the stats of a machine without VM and reports whether it had any.
+func clearStats(machine *api.Machine) bool {
+	cleared := machine.Status.Utilization != nil
+	machine.Status.Utilization = nil
+	for i := range machine.Status.NetworkInterfaceStatus {
+		cleared = cleared || machine.Status.NetworkInterfaceStatus[i].Stats != nil
+		machine.Status.NetworkInterfaceStatus[i].Stats = nil
+	}
+	for i := range machine.Status.VolumeStatus {
+		cleared = cleared || machine.Status.VolumeStatus[i].Stats != nil
+		machine.Status.VolumeStatus[i].Stats = nil
+	}
+	return cleared
+}
+
+func (c *Collector) updateStatus(ctx context.Context, machineID string, update func(machine *api.Machine)) error {
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.Is(err, store.ErrResourceVersionNotLatest)
+	}, func() error {
+		machine, err := c.machines.Get(ctx, machineID)
+		if err != nil {
+			return err
+		}
+		update(machine)
+		_, err = c.machines.Update(ctx, machine)
+		return err
+	})
+	return store.IgnoreErrNotFound(err)
+}
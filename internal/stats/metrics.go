@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"sync"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	machineCPUUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "machine_cpu_usage_millicores",
+			Help:      "CPU used by the VM of a machine, averaged over the sampling interval.",
+		},
+		[]string{"machine"},
+	)
+
+	machineMemoryUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "machine_memory_usage_bytes",
+			Help:      "Resident memory of the VM of a machine.",
+		},
+		[]string{"machine"},
+	)
+
+	machineMemoryReclaimed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "machine_memory_reclaimed_bytes",
+			Help:      "Memory of a machine returned to the host by free page reporting.",
+		},
+		[]string{"machine"},
+	)
+
+	memoryReclaimed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "cloud_hypervisor_provider",
+			Name:      "memory_reclaimed_bytes",
+			Help:      "Memory of all machines returned to the host by free page reporting.",
+		},
+	)
+
+	devices = &deviceMetrics{
+		nics:    map[string][]api.NetworkInterfaceStatus{},
+		volumes: map[string][]api.VolumeStatus{},
+	}
+)
+
+func init() {
+	metrics.Registry.MustRegister(machineCPUUsage, machineMemoryUsage, machineMemoryReclaimed, memoryReclaimed, devices)
+}
+
+func deleteMachineMetrics(machineID string) {
+	labels := prometheus.Labels{"machine": machineID}
+	machineCPUUsage.DeletePartialMatch(labels)
+	machineMemoryUsage.DeletePartialMatch(labels)
+	machineMemoryReclaimed.DeletePartialMatch(labels)
+	devices.delete(machineID)
+}
+
+var (
+	nicReceiveBytesDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_network_receive_bytes_total",
+		"Bytes received by a network interface of a machine.",
+		[]string{"machine", "interface"}, nil,
+	)
+	nicReceivePacketsDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_network_receive_packets_total",
+		"Packets received by a network interface of a machine.",
+		[]string{"machine", "interface"}, nil,
+	)
+	nicTransmitBytesDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_network_transmit_bytes_total",
+		"Bytes transmitted by a network interface of a machine.",
+		[]string{"machine", "interface"}, nil,
+	)
+	nicTransmitPacketsDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_network_transmit_packets_total",
+		"Packets transmitted by a network interface of a machine.",
+		[]string{"machine", "interface"}, nil,
+	)
+
+	volumeReadBytesDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_volume_read_bytes_total",
+		"Bytes read from a volume of a machine.",
+		[]string{"machine", "volume"}, nil,
+	)
+	volumeReadOpsDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_volume_read_ops_total",
+		"Read operations on a volume of a machine.",
+		[]string{"machine", "volume"}, nil,
+	)
+	volumeWriteBytesDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_volume_write_bytes_total",
+		"Bytes written to a volume of a machine.",
+		[]string{"machine", "volume"}, nil,
+	)
+	volumeWriteOpsDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_volume_write_ops_total",
+		"Write operations on a volume of a machine.",
+		[]string{"machine", "volume"}, nil,
+	)
+	volumeReadLatencyDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_volume_read_latency_microseconds",
+		"Average latency of the reads from a volume of a machine.",
+		[]string{"machine", "volume"}, nil,
+	)
+	volumeWriteLatencyDesc = prometheus.NewDesc(
+		"cloud_hypervisor_provider_machine_volume_write_latency_microseconds",
+		"Average latency of the writes to a volume of a machine.",
+		[]string{"machine", "volume"}, nil,
+	)
+)
+
+// deviceMetrics exposes the counters of the devices of the last sample.
+type deviceMetrics struct {
+	mu      sync.Mutex
+	nics    map[string][]api.NetworkInterfaceStatus
+	volumes map[string][]api.VolumeStatus
+}
+
+func (m *deviceMetrics) set(machineID string, nics []api.NetworkInterfaceStatus, volumes []api.VolumeStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nics[machineID] = nics
+	m.volumes[machineID] = volumes
+}
+
+func (m *deviceMetrics) delete(machineID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.nics, machineID)
+	delete(m.volumes, machineID)
+}
+
+func (m *deviceMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nicReceiveBytesDesc
+	ch <- nicReceivePacketsDesc
+	ch <- nicTransmitBytesDesc
+	ch <- nicTransmitPacketsDesc
+	ch <- volumeReadBytesDesc
+	ch <- volumeReadOpsDesc
+	ch <- volumeWriteBytesDesc
+	ch <- volumeWriteOpsDesc
+	ch <- volumeReadLatencyDesc
+	ch <- volumeWriteLatencyDesc
+}
+
+func (m *deviceMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for machineID, nics := range m.nics {
+		for _, nic := range nics {
+			if nic.Stats == nil {
+				continue
+			}
+			for desc, value := range map[*prometheus.Desc]int64{
+				nicReceiveBytesDesc:    nic.Stats.RxBytes,
+				nicReceivePacketsDesc:  nic.Stats.RxPackets,
+				nicTransmitBytesDesc:   nic.Stats.TxBytes,
+				nicTransmitPacketsDesc: nic.Stats.TxPackets,
+			} {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), machineID, nic.Name)
+			}
+		}
+	}
+
+	for machineID, volumes := range m.volumes {
+		for _, volume := range volumes {
+			if volume.Stats == nil {
+				continue
+			}
+			for desc, value := range map[*prometheus.Desc]int64{
+				volumeReadBytesDesc:  volume.Stats.ReadBytes,
+				volumeReadOpsDesc:    volume.Stats.ReadOps,
+				volumeWriteBytesDesc: volume.Stats.WriteBytes,
+				volumeWriteOpsDesc:   volume.Stats.WriteOps,
+			} {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), machineID, volume.Name)
+			}
+			for desc, value := range map[*prometheus.Desc]int64{
+				volumeReadLatencyDesc:  volume.Stats.ReadLatencyMicros,
+				volumeWriteLatencyDesc: volume.Stats.WriteLatencyMicros,
+			} {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), machineID, volume.Name)
+			}
+		}
+	}
+}
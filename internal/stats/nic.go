@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/vmm"
+)
+
+// networkInterfaceStats returns the traffic of an attached network interface. Virtio network devices are
+// counted by cloud-hypervisor, tap devices by the host. Passthrough devices have no counters.
+func (c *Collector) networkInterfaceStats(
+	counters client.VmCounters,
+	nic api.NetworkInterfaceStatus,
+) (*api.NetworkInterfaceStats, error) {
+	if deviceCounters, ok := counters[vmm.NicID(nic.Name)]; ok {
+		return &api.NetworkInterfaceStats{
+			RxBytes:   deviceCounters["rx_bytes"],
+			RxPackets: deviceCounters["rx_frames"],
+			TxBytes:   deviceCounters["tx_bytes"],
+			TxPackets: deviceCounters["tx_frames"],
+		}, nil
+	}
+
+	if nic.Type != api.NetworkInterfaceTAPType || nic.Path == "" {
+		return nil, nil
+	}
+
+	// The host receives what the guest transmits and vice versa.
+	statsDir := filepath.Join(c.sysDir, "class", "net", nic.Path, "statistics")
+	stats := &api.NetworkInterfaceStats{}
+	for name, value := range map[string]*int64{
+		"tx_bytes":   &stats.RxBytes,
+		"tx_packets": &stats.RxPackets,
+		"rx_bytes":   &stats.TxBytes,
+		"rx_packets": &stats.TxPackets,
+	} {
+		var err error
+		if *value, err = readCounter(filepath.Join(statsDir, name)); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+func readCounter(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid counter %s: %w", path, err)
+	}
+	return value, nil
+}
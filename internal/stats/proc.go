@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the cpu times in /proc/<pid>/stat. It is 100 on all supported
+// architectures.
+const clockTicks = 100
+
+// processUsage is the resource usage of a cloud-hypervisor process including its vcpu threads.
+type processUsage struct {
+	cpuTime  time.Duration
+	rssBytes int64
+}
+
+func readProcessUsage(procDir string, pid int) (*processUsage, error) {
+	cpuTime, err := readCPUTime(fmt.Sprintf("%s/%d/stat", procDir, pid))
+	if err != nil {
+		return nil, err
+	}
+
+	rss, err := readRSS(fmt.Sprintf("%s/%d/status", procDir, pid))
+	if err != nil {
+		return nil, err
+	}
+
+	return &processUsage{cpuTime: cpuTime, rssBytes: rss}, nil
+}
+
+func readCPUTime(path string) (time.Duration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	// The command in the second field may contain spaces, the fields after it are separated by single spaces.
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("invalid %s", path)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	// utime and stime are the fields 14 and 15, the fields after the command start at field 3.
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("invalid %s: too few fields", path)
+	}
+
+	var ticks int64
+	for _, field := range fields[11:13] {
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", path, err)
+		}
+		ticks += value
+	}
+	return time.Duration(ticks) * time.Second / clockTicks, nil
+}
+
+func readRSS(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:")
+		if !ok {
+			continue
+		}
+
+		kib, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid VmRSS in %s: %w", path, err)
+		}
+		return kib * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no VmRSS in %s", path)
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/plugins/volume"
+)
+
+// volumeStats returns the IO of an attached volume. Disks are counted by cloud-hypervisor, vhost-user disks by
+// the plugin serving them.
+func (c *Collector) volumeStats(
+	ctx context.Context,
+	machine *api.Machine,
+	counters client.VmCounters,
+	status api.VolumeStatus,
+) (*api.VolumeStats, error) {
+	if deviceCounters, ok := counters[status.Handle]; ok {
+		return &api.VolumeStats{
+			ReadBytes:          deviceCounters["read_bytes"],
+			ReadOps:            deviceCounters["read_ops"],
+			ReadLatencyMicros:  deviceCounters["read_latency_avg"],
+			WriteBytes:         deviceCounters["write_bytes"],
+			WriteOps:           deviceCounters["write_ops"],
+			WriteLatencyMicros: deviceCounters["write_latency_avg"],
+		}, nil
+	}
+
+	if c.volumePlugins == nil {
+		return nil, nil
+	}
+
+	for _, spec := range machine.Spec.Volumes {
+		if spec.Name != status.Name {
+			continue
+		}
+		if spec.Imported {
+			return nil, nil
+		}
+
+		plugin, err := c.volumePlugins.FindPluginBySpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find plugin: %w", err)
+		}
+		statsPlugin, ok := plugin.(volume.StatsPlugin)
+		if !ok {
+			return nil, nil
+		}
+		return statsPlugin.Stats(ctx, status.Name, machine.ID)
+	}
+	return nil, nil
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+)
+
+// DefaultVMInfoCacheTTL is short enough for a guest shutdown to be noticed by the next poll of the shutdown.
+const DefaultVMInfoCacheTTL = 2 * time.Second
+
+// vmInfoCache keeps the last VM info of every instance for the ttl. The entry of an instance is invalidated by
+// every call changing its VM and by the events of its event monitor, the ttl bounds how long changes made by the
+// guest itself, e.g. a shutdown, go unnoticed without event monitor.
+type vmInfoCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedVMInfo
+	// generations counts the invalidations of every instance, so that an info fetched before an invalidation is
+	// not cached after it. The calls changing the VM hold the lock of the instance like GetVM, but the events are
+	// handled without it.
+	generations map[string]uint64
+}
+
+type cachedVMInfo struct {
+	data      []byte
+	fetchedAt time.Time
+}
+
+func newVMInfoCache(ttl time.Duration) *vmInfoCache {
+	return &vmInfoCache{
+		ttl:         ttl,
+		entries:     make(map[string]cachedVMInfo),
+		generations: make(map[string]uint64),
+	}
+}
+
+// get returns a copy of the cached VM info of the instance, so that callers cannot change the cache.
+func (c *vmInfoCache) get(instanceID string) (*client.VmInfo, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[instanceID]
+	c.mu.Unlock()
+	if !ok || time.Since(entry.fetchedAt) > c.ttl {
+		vmInfoCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	vm := &client.VmInfo{}
+	if err := json.Unmarshal(entry.data, vm); err != nil {
+		vmInfoCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+	vmInfoCacheRequests.WithLabelValues("hit").Inc()
+	return vm, true
+}
+
+// generation returns the generation of the instance to pass to set with the VM info fetched afterwards.
+func (c *vmInfoCache) generation(instanceID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[instanceID]
+}
+
+// set caches the VM info of the instance unless the instance was invalidated since the generation was taken.
+func (c *vmInfoCache) set(instanceID string, generation uint64, vm *client.VmInfo) {
+	if c.ttl <= 0 || vm == nil {
+		return
+	}
+
+	data, err := json.Marshal(vm)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[instanceID] != generation {
+		return
+	}
+	c.entries[instanceID] = cachedVMInfo{data: data, fetchedAt: time.Now()}
+}
+
+func (c *vmInfoCache) invalidate(instanceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, instanceID)
+	c.generations[instanceID]++
+}
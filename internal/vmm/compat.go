@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+// DefaultMinVersion is the oldest cloud-hypervisor release the provider is tested against.
+const DefaultMinVersion = "v48.0"
+
+var ErrIncompatible = errors.New("incompatible cloud-hypervisor")
+
+type compatibility struct {
+	minVersion       semver.Version
+	requiredFeatures []string
+}
+
+func newCompatibility(minVersion string, requiredFeatures []string) (*compatibility, error) {
+	if minVersion == "" {
+		minVersion = DefaultMinVersion
+	}
+
+	v, err := semver.ParseTolerant(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minimum cloud-hypervisor version %q: %w", minVersion, err)
+	}
+
+	return &compatibility{
+		minVersion:       v,
+		requiredFeatures: requiredFeatures,
+	}, nil
+}
+
+// check returns an ErrIncompatible error if the pinged cloud-hypervisor is older than the minimum
+// version or lacks a required feature.
+func (c *compatibility) check(ping *client.VmmPingResponse) error {
+	capabilities, err := capabilitiesOf(ping)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrIncompatible, err)
+	}
+	if err := capabilities.satisfies(c.minVersion, c.requiredFeatures); err != nil {
+		return fmt.Errorf("%w: %w", ErrIncompatible, err)
+	}
+	return nil
+}
+
+// instanceCapabilities are the version and the build features a cloud-hypervisor instance reported on ping.
+type instanceCapabilities struct {
+	version  semver.Version
+	features sets.Set[string]
+}
+
+func capabilitiesOf(ping *client.VmmPingResponse) (instanceCapabilities, error) {
+	if ping == nil {
+		return instanceCapabilities{}, errors.New("empty ping response")
+	}
+
+	v, err := semver.ParseTolerant(ping.Version)
+	if err != nil {
+		return instanceCapabilities{}, fmt.Errorf("unparsable version %q: %w", ping.Version, err)
+	}
+
+	return instanceCapabilities{
+		version:  v,
+		features: sets.New(ptr.Deref(ping.Features, nil)...),
+	}, nil
+}
+
+func (c instanceCapabilities) satisfies(minVersion semver.Version, features []string) error {
+	if c.version.LT(minVersion) {
+		return fmt.Errorf("version %s is older than %s", c.version, minVersion)
+	}
+	if missing := sets.New(features...).Difference(c.features); missing.Len() > 0 {
+		return fmt.Errorf("missing features %v", sets.List(missing))
+	}
+	return nil
+}
+
+// satisfiesRequirements returns why the instance cannot run a machine with the requirements, nil if it can.
+func (c instanceCapabilities) satisfiesRequirements(requirements *api.VMMRequirements) error {
+	if requirements == nil {
+		return nil
+	}
+
+	var minVersion semver.Version
+	if requirements.MinVersion != "" {
+		v, err := semver.ParseTolerant(requirements.MinVersion)
+		if err != nil {
+			return fmt.Errorf("invalid minimum version %q: %w", requirements.MinVersion, err)
+		}
+		minVersion = v
+	}
+	return c.satisfies(minVersion, requirements.Features)
+}
@@ -0,0 +1,419 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/ignition"
+	"k8s.io/utils/ptr"
+)
+
+// vmConfigBuilder builds the cloud-hypervisor config of a machine's VM.
+type vmConfigBuilder struct {
+	paths               host.Paths
+	firmwarePath        string
+	enableVsock         bool
+	ignitionCompression bool
+	nicPCISegments      int16
+	landlockRules       []client.LandlockConfig
+
+	overrides         map[string]string
+	overrideAllowlist []string
+
+	oemStringLabels      []string
+	oemStringAnnotations []string
+}
+
+func newVMConfigBuilder(paths host.Paths, opts ManagerOptions) *vmConfigBuilder {
+	if opts.VMConfigOverrideAllowlist == nil {
+		opts.VMConfigOverrideAllowlist = DefaultVMConfigOverrideAllowlist
+	}
+	return &vmConfigBuilder{
+		paths:               paths,
+		firmwarePath:        opts.FirmwarePath,
+		enableVsock:         opts.EnableVsock,
+		ignitionCompression: opts.IgnitionCompression,
+		nicPCISegments:      opts.NICPCISegments,
+		landlockRules:       opts.LandlockRules,
+
+		overrides:         opts.VMConfigOverrides,
+		overrideAllowlist: opts.VMConfigOverrideAllowlist,
+
+		oemStringLabels:      opts.OEMStringLabels,
+		oemStringAnnotations: opts.OEMStringAnnotations,
+	}
+}
+
+func (b *vmConfigBuilder) build(machine *api.Machine) (*client.VmConfig, error) {
+	payload := client.PayloadConfig{
+		Cmdline:   nil,
+		Firmware:  ptr.To(b.firmwarePath),
+		HostData:  nil,
+		Igvm:      nil,
+		Initramfs: nil,
+		Kernel:    nil,
+	}
+
+	platform := &client.PlatformConfig{
+		Uuid: ptr.To(machine.ID),
+	}
+	if b.nicPCISegments > 0 {
+		platform.NumPciSegments = ptr.To(1 + b.nicPCISegments)
+	}
+
+	if machine.Spec.Ignition != nil && !hasIgnitionConfigDrive(machine) {
+		data, err := ignition.Encode(machine.Spec.Ignition, api.IgnitionTransportOEMStrings, b.ignitionCompression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode ignition: %w", err)
+		}
+		platform.OemStrings = ptr.To([]string{string(data)})
+	}
+
+	if len(machine.Spec.OpaqueUserData) > 0 && machine.Status.ConfigDrive == nil {
+		oemStrings := append(ptr.Deref(platform.OemStrings, nil),
+			opaqueUserDataOEMStringPrefix+b64.StdEncoding.EncodeToString(machine.Spec.OpaqueUserData))
+		platform.OemStrings = &oemStrings
+	}
+
+	if metadataOEMStrings := b.metadataOEMStrings(machine); len(metadataOEMStrings) > 0 {
+		oemStrings := append(ptr.Deref(platform.OemStrings, nil), metadataOEMStrings...)
+		platform.OemStrings = &oemStrings
+	}
+
+	// kvmclock and the KVM PTP hypercall need no device, the guest only has to know which one to use.
+	if machine.Spec.Clock != "" {
+		oemStrings := append(ptr.Deref(platform.OemStrings, nil), clockOEMStringPrefix+string(machine.Spec.Clock))
+		platform.OemStrings = &oemStrings
+	}
+
+	var disks []client.DiskConfig
+	for _, vol := range orderedVolumes(machine) {
+		if vol.State != api.VolumeStatePrepared {
+			continue
+		}
+
+		disks = append(disks, diskConfig(&vol))
+	}
+
+	if configDrive := machine.Status.ConfigDrive; configDrive != nil {
+		disks = append(disks, client.DiskConfig{
+			Id:       ptr.To(configDriveID),
+			Path:     ptr.To(configDrive.Path),
+			Readonly: ptr.To(true),
+		})
+	}
+
+	var (
+		dev  []client.DeviceConfig
+		nets []client.NetConfig
+	)
+	for _, nic := range machine.Status.NetworkInterfaceStatus {
+		if nic.State != api.NetworkInterfaceStatePrepared {
+			return nil, fmt.Errorf("nic %s is not attached", nic.Name)
+		}
+
+		if nic.Type == api.NetworkInterfaceTAPType {
+			nets = append(nets, netConfig(&nic))
+			continue
+		}
+		dev = append(dev, nicConfig(&nic))
+	}
+
+	var vsock *client.VsockConfig
+	if b.enableVsock {
+		vsock = &client.VsockConfig{
+			Cid:    guestCID,
+			Socket: b.paths.MachineVsockFile(machine.ID),
+		}
+	}
+
+	cpus := &client.CpusConfig{
+		BootVcpus: int(machine.Spec.Cpu),
+		MaxVcpus:  int(machine.Spec.Cpu),
+		Topology:  cpuTopology(machine.Spec.CpuTopology),
+	}
+
+	var sgxEpc *[]client.SgxEpcConfig
+	if features := machine.Spec.CpuFeatures; features != nil {
+		if features.AMX {
+			cpus.Features = &client.CpuFeatures{Amx: ptr.To(true)}
+		}
+		if features.KvmHyperV {
+			cpus.KvmHyperv = ptr.To(true)
+		}
+		if features.MaxPhysBits != 0 {
+			cpus.MaxPhysBits = ptr.To(features.MaxPhysBits)
+		}
+		if features.SgxEpcBytes != 0 {
+			sgxEpc = &[]client.SgxEpcConfig{{
+				Id:       "epc0",
+				Size:     features.SgxEpcBytes,
+				Prefault: ptr.To(true),
+			}}
+		}
+	}
+
+	// The balloon is never inflated, it only carries the free pages reported by the guest.
+	var balloon *client.BalloonConfig
+	if machine.Spec.FreePageReporting {
+		balloon = &client.BalloonConfig{
+			Size:              0,
+			FreePageReporting: ptr.To(true),
+			DeflateOnOom:      ptr.To(true),
+		}
+	}
+
+	console := consoleConfig(machine.Spec.ConsoleMode, api.ConsoleModeOff, b.paths.MachineConsoleLogFile(machine.ID))
+	serial := consoleConfig(machine.Spec.SerialMode, api.ConsoleModeFile, b.paths.MachineSerialLogFile(machine.ID))
+
+	config := &client.VmConfig{
+		Cpus:     cpus,
+		Balloon:  balloon,
+		SgxEpc:   sgxEpc,
+		Devices:  &dev,
+		Net:      &nets,
+		Disks:    &disks,
+		Memory:   memoryConfig(machine),
+		Console:  console,
+		Serial:   serial,
+		Payload:  payload,
+		Platform: platform,
+		Vsock:    vsock,
+
+		LandlockEnable: ptr.To(machine.Spec.Landlock),
+		LandlockRules:  b.getLandlockRules(machine),
+	}
+
+	overrides := maps.Clone(b.overrides)
+	if overrides == nil {
+		overrides = map[string]string{}
+	}
+	maps.Copy(overrides, machine.Spec.VMConfigOverrides)
+	if err := ValidateVMConfigOverrides(overrides, b.overrideAllowlist); err != nil {
+		return nil, err
+	}
+	if err := applyVMConfigOverrides(config, overrides); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// consoleConfig returns the config of a serial port or virtio console in the mode, defaultMode if empty. In file
+// mode, the output is written to file.
+func consoleConfig(mode, defaultMode api.ConsoleMode, file string) *client.ConsoleConfig {
+	if mode == "" {
+		mode = defaultMode
+	}
+	switch mode {
+	case api.ConsoleModeTty:
+		return &client.ConsoleConfig{Mode: client.ConsoleConfigModeTty}
+	case api.ConsoleModeFile:
+		return &client.ConsoleConfig{Mode: client.ConsoleConfigModeFile, File: ptr.To(file)}
+	case api.ConsoleModePty:
+		return &client.ConsoleConfig{Mode: client.ConsoleConfigModePty}
+	default:
+		return &client.ConsoleConfig{Mode: client.ConsoleConfigModeOff}
+	}
+}
+
+// metadataOEMStrings returns the OEM strings of the selected IRI labels and annotations of the machine, sorted by
+// key. Strings exceeding maxMetadataOEMStringsSize are left out.
+func (b *vmConfigBuilder) metadataOEMStrings(machine *api.Machine) []string {
+	var oemStrings []string
+	if len(b.oemStringLabels) > 0 {
+		labels, _ := api.GetLabelsAnnotation(machine.Metadata)
+		oemStrings = append(oemStrings, selectOEMStrings(labelOEMStringPrefix, labels, b.oemStringLabels)...)
+	}
+	if len(b.oemStringAnnotations) > 0 {
+		annotations, _ := api.GetAnnotationsAnnotation(machine.Metadata)
+		oemStrings = append(oemStrings,
+			selectOEMStrings(annotationOEMStringPrefix, annotations, b.oemStringAnnotations)...)
+	}
+
+	var size int
+	var limited []string
+	for _, oemString := range oemStrings {
+		if size+len(oemString) > maxMetadataOEMStringsSize {
+			continue
+		}
+		size += len(oemString)
+		limited = append(limited, oemString)
+	}
+	return limited
+}
+
+func selectOEMStrings(prefix string, values map[string]string, keys []string) []string {
+	var oemStrings []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if !slices.ContainsFunc(keys, func(pattern string) bool {
+			if keyPrefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				return strings.HasPrefix(key, keyPrefix)
+			}
+			return key == pattern
+		}) {
+			continue
+		}
+		oemStrings = append(oemStrings, fmt.Sprintf("%s%s=%s", prefix, key, values[key]))
+	}
+	return oemStrings
+}
+
+// getLandlockRules returns the paths a landlocked VM may access in addition to the ones in its config.
+// The machine dir is included since disks and sockets are hot-plugged from there.
+func (b *vmConfigBuilder) getLandlockRules(machine *api.Machine) *[]client.LandlockConfig {
+	if !machine.Spec.Landlock {
+		return nil
+	}
+
+	rules := append([]client.LandlockConfig{{
+		Path:   b.paths.MachineDir(machine.ID),
+		Access: "rw",
+	}}, b.landlockRules...)
+	if backing := machine.Spec.MemoryBacking; backing != nil && backing.File != "" {
+		rules = append(rules, client.LandlockConfig{Path: backing.File, Access: "rw"})
+	}
+	return &rules
+}
+
+func cpuTopology(topology *api.CpuTopology) *client.CpuTopology {
+	if topology == nil {
+		return nil
+	}
+
+	return &client.CpuTopology{
+		Packages:       ptr.To(topology.Sockets),
+		DiesPerPackage: ptr.To(1),
+		CoresPerDie:    ptr.To(topology.CoresPerSocket),
+		ThreadsPerCore: ptr.To(topology.ThreadsPerCore),
+	}
+}
+
+func memoryConfig(machine *api.Machine) *client.MemoryConfig {
+	shared := sharedMemory(machine)
+
+	backing := machine.Spec.MemoryBacking
+	if backing == nil {
+		return &client.MemoryConfig{
+			Size:   machine.Spec.MemoryBytes,
+			Shared: ptr.To(shared),
+		}
+	}
+
+	// Only memory zones can be backed by a file, so all memory is put into a single zone.
+	zone := client.MemoryZoneConfig{
+		Id:     "mem0",
+		Size:   machine.Spec.MemoryBytes,
+		Shared: ptr.To(shared),
+	}
+	if backing.HugepageSizeBytes != 0 {
+		zone.Hugepages = ptr.To(true)
+		zone.HugepageSize = ptr.To(backing.HugepageSizeBytes)
+	}
+	if backing.File != "" {
+		zone.File = ptr.To(backing.File)
+	}
+	return &client.MemoryConfig{
+		Size:  0,
+		Zones: &[]client.MemoryZoneConfig{zone},
+	}
+}
+
+// MemoryShared reports whether the memory of a created VM is shared.
+func MemoryShared(memory *client.MemoryConfig) bool {
+	if memory == nil {
+		return false
+	}
+	if zones := ptr.Deref(memory.Zones, nil); len(zones) > 0 {
+		return ptr.Deref(zones[0].Shared, false)
+	}
+	return ptr.Deref(memory.Shared, false)
+}
+
+// sharedMemory reports whether the guest memory is mapped shared. Shared memory cannot be enabled once the VM is
+// created, so it is enabled if any volume may be connected via vhost-user.
+func sharedMemory(machine *api.Machine) bool {
+	if machine.Spec.SharedMemory != nil {
+		return *machine.Spec.SharedMemory
+	}
+	for _, volume := range machine.Spec.Volumes {
+		if volume.DeletedAt == nil && volume.RequiresSharedMemory() {
+			return true
+		}
+	}
+	for _, status := range machine.Status.VolumeStatus {
+		if status.Type == api.VolumeSocketType {
+			return true
+		}
+	}
+	return false
+}
+
+// orderedVolumes returns the volumes of the machine in its disk order, so that the guest enumerates the disks of a
+// recreated VM as before. Volumes not in the order follow in the order of the status.
+func orderedVolumes(machine *api.Machine) []api.VolumeStatus {
+	volumes := slices.Clone(machine.Status.VolumeStatus)
+	position := func(name string) int {
+		if i := slices.Index(machine.Status.DiskOrder, name); i >= 0 {
+			return i
+		}
+		return len(machine.Status.DiskOrder)
+	}
+	slices.SortStableFunc(volumes, func(a, b api.VolumeStatus) int {
+		return position(a.Name) - position(b.Name)
+	})
+	return volumes
+}
+
+func diskConfig(volume *api.VolumeStatus) client.DiskConfig {
+	disk := client.DiskConfig{
+		Id: ptr.To(volume.Handle),
+	}
+
+	switch volume.Type {
+	case api.VolumeSocketType:
+		disk.VhostUser = ptr.To(true)
+		disk.VhostSocket = ptr.To(volume.Path)
+		disk.Readonly = ptr.To(false)
+	case api.VolumeFileType:
+		disk.Path = ptr.To(volume.Path)
+	}
+	return disk
+}
+
+func nicConfig(nic *api.NetworkInterfaceStatus) client.DeviceConfig {
+	return client.DeviceConfig{
+		Id:         ptr.To(NicID(nic.Name)),
+		Path:       nic.Path,
+		PciSegment: pciSegment(nic.PCISegment),
+	}
+}
+
+func netConfig(nic *api.NetworkInterfaceStatus) client.NetConfig {
+	var mac *string
+	if nic.MAC != "" {
+		mac = ptr.To(nic.MAC)
+	}
+	return client.NetConfig{
+		Id:         ptr.To(NicID(nic.Name)),
+		Tap:        ptr.To(nic.Path),
+		Mac:        mac,
+		PciSegment: pciSegment(nic.PCISegment),
+	}
+}
+
+// pciSegment leaves the default segment unset, which keeps the configs of VMs without NIC segments unchanged.
+func pciSegment(segment int16) *int16 {
+	if segment == 0 {
+		return nil
+	}
+	return ptr.To(segment)
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"slices"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/ptr"
+)
+
+// defaultMaxPhysBits is the physical address width cloud-hypervisor reports if none is configured.
+const defaultMaxPhysBits = 46
+
+// Drift is the difference between the config of a VM and the config computed from its machine.
+type Drift struct {
+	// Vcpus and MemoryBytes are set if the VM has to be resized to them. The VM can be resized without restart.
+	Vcpus       *int
+	MemoryBytes *int64
+	// Fields are the differing fields that only take effect when the VM is created again.
+	Fields []string
+}
+
+// ConfigDrift compares the config of a created VM with the desired one. Disks and devices are hot-plugged and
+// not compared, neither is the uuid, which identifies the machine of the VM.
+func ConfigDrift(live, desired *client.VmConfig) Drift {
+	var drift Drift
+
+	liveCpus := ptr.Deref(live.Cpus, client.CpusConfig{})
+	desiredCpus := ptr.Deref(desired.Cpus, client.CpusConfig{})
+	if liveCpus.BootVcpus != desiredCpus.BootVcpus {
+		if desiredCpus.BootVcpus <= liveCpus.MaxVcpus {
+			drift.Vcpus = ptr.To(desiredCpus.BootVcpus)
+		} else {
+			drift.Fields = append(drift.Fields, "cpus.boot_vcpus")
+		}
+	}
+	if desiredCpus.Topology != nil && !equality.Semantic.DeepEqual(liveCpus.Topology, desiredCpus.Topology) {
+		drift.Fields = append(drift.Fields, "cpus.topology")
+	}
+	if ptr.Deref(ptr.Deref(liveCpus.Features, client.CpuFeatures{}).Amx, false) !=
+		ptr.Deref(ptr.Deref(desiredCpus.Features, client.CpuFeatures{}).Amx, false) {
+		drift.Fields = append(drift.Fields, "cpus.features.amx")
+	}
+	if ptr.Deref(liveCpus.KvmHyperv, false) != ptr.Deref(desiredCpus.KvmHyperv, false) {
+		drift.Fields = append(drift.Fields, "cpus.kvm_hyperv")
+	}
+	if ptr.Deref(liveCpus.MaxPhysBits, defaultMaxPhysBits) != ptr.Deref(desiredCpus.MaxPhysBits, defaultMaxPhysBits) {
+		drift.Fields = append(drift.Fields, "cpus.max_phys_bits")
+	}
+	if !slices.Equal(sgxEpcSizes(live.SgxEpc), sgxEpcSizes(desired.SgxEpc)) {
+		drift.Fields = append(drift.Fields, "sgx_epc")
+	}
+
+	if liveSize, desiredSize := memorySize(live.Memory), memorySize(desired.Memory); liveSize != desiredSize {
+		if memory := ptr.Deref(live.Memory, client.MemoryConfig{}); len(ptr.Deref(memory.Zones, nil)) == 0 &&
+			desiredSize >= memory.Size && desiredSize <= memory.Size+ptr.Deref(memory.HotplugSize, 0) {
+			drift.MemoryBytes = ptr.To(desiredSize)
+		} else {
+			drift.Fields = append(drift.Fields, "memory.size")
+		}
+	}
+	if MemoryShared(live.Memory) != MemoryShared(desired.Memory) {
+		drift.Fields = append(drift.Fields, "memory.shared")
+	}
+	if !equality.Semantic.DeepEqual(memoryBacking(live.Memory), memoryBacking(desired.Memory)) {
+		drift.Fields = append(drift.Fields, "memory.zones")
+	}
+	if balloonFreePageReporting(live.Balloon) != balloonFreePageReporting(desired.Balloon) {
+		drift.Fields = append(drift.Fields, "balloon.free_page_reporting")
+	}
+
+	if ptr.Deref(live.Payload.Firmware, "") != ptr.Deref(desired.Payload.Firmware, "") {
+		drift.Fields = append(drift.Fields, "payload.firmware")
+	}
+	if !slices.Equal(oemStrings(live.Platform), oemStrings(desired.Platform)) {
+		drift.Fields = append(drift.Fields, "platform.oem_strings")
+	}
+	if NumPCISegments(live.Platform) != NumPCISegments(desired.Platform) {
+		drift.Fields = append(drift.Fields, "platform.num_pci_segments")
+	}
+	if consoleDrifted(live.Serial, desired.Serial) {
+		drift.Fields = append(drift.Fields, "serial")
+	}
+	if consoleDrifted(live.Console, desired.Console) {
+		drift.Fields = append(drift.Fields, "console")
+	}
+	if (live.Vsock == nil) != (desired.Vsock == nil) {
+		drift.Fields = append(drift.Fields, "vsock")
+	}
+	if ptr.Deref(live.LandlockEnable, false) != ptr.Deref(desired.LandlockEnable, false) {
+		drift.Fields = append(drift.Fields, "landlock_enable")
+	}
+
+	return drift
+}
+
+// consoleDrifted reports whether the mode of a serial port or virtio console differs, or the file it is written to.
+// The file of a pty is the terminal allocated by cloud-hypervisor.
+func consoleDrifted(live, desired *client.ConsoleConfig) bool {
+	liveConfig, desiredConfig := ptr.Deref(live, client.ConsoleConfig{}), ptr.Deref(desired, client.ConsoleConfig{})
+	if liveConfig.Mode != desiredConfig.Mode {
+		return true
+	}
+	return desiredConfig.Mode == client.ConsoleConfigModeFile &&
+		ptr.Deref(liveConfig.File, "") != ptr.Deref(desiredConfig.File, "")
+}
+
+// memorySize returns the memory of the VM including the hot-plugged memory.
+func memorySize(memory *client.MemoryConfig) int64 {
+	if memory == nil {
+		return 0
+	}
+	size := memory.Size + ptr.Deref(memory.HotpluggedSize, 0)
+	for _, zone := range ptr.Deref(memory.Zones, nil) {
+		size += zone.Size + ptr.Deref(zone.HotpluggedSize, 0)
+	}
+	return size
+}
+
+// memoryBacking returns the zones of the memory without their size and sharing, which are compared separately.
+func memoryBacking(memory *client.MemoryConfig) []client.MemoryZoneConfig {
+	if memory == nil {
+		return nil
+	}
+	var zones []client.MemoryZoneConfig
+	for _, zone := range ptr.Deref(memory.Zones, nil) {
+		zones = append(zones, client.MemoryZoneConfig{
+			Id:           zone.Id,
+			File:         zone.File,
+			Hugepages:    ptr.To(ptr.Deref(zone.Hugepages, false)),
+			HugepageSize: zone.HugepageSize,
+		})
+	}
+	return zones
+}
+
+func balloonFreePageReporting(balloon *client.BalloonConfig) bool {
+	return balloon != nil && ptr.Deref(balloon.FreePageReporting, false)
+}
+
+func sgxEpcSizes(sgxEpc *[]client.SgxEpcConfig) []int64 {
+	var sizes []int64
+	for _, epc := range ptr.Deref(sgxEpc, nil) {
+		sizes = append(sizes, epc.Size)
+	}
+	return sizes
+}
+
+// NumPCISegments returns the number of PCI segments of the VM, including the default segment.
+func NumPCISegments(platform *client.PlatformConfig) int16 {
+	if platform == nil {
+		return 1
+	}
+	return max(ptr.Deref(platform.NumPciSegments, 1), 1)
+}
+
+func oemStrings(platform *client.PlatformConfig) []string {
+	if platform == nil {
+		return nil
+	}
+	return ptr.Deref(platform.OemStrings, nil)
+}
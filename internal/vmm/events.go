@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// eventsPollInterval is how often the event monitor files of the instances are checked for new events. It is
+// well below DefaultVMInfoCacheTTL, so that a guest shutdown is noticed before the cached VM info expires.
+const eventsPollInterval = 250 * time.Millisecond
+
+// EventsFile returns the file the cloud-hypervisor instance of the api socket writes its events to, as passed
+// via --event-monitor path=<file>.
+func EventsFile(socketPath string) string {
+	return strings.TrimSuffix(socketPath, filepath.Ext(socketPath)) + ".events"
+}
+
+// Start invalidates the cached VM info of an instance whenever its event monitor reports an event, e.g. a
+// shutdown or reboot by the guest, until the context is done. Instances without event monitor file rely on the
+// ttl of the cache alone.
+func (m *Manager) Start(ctx context.Context) error {
+	if m.vmInfo.ttl <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+
+	// sizes are the sizes of the event monitor files when they were last checked, events are only appended.
+	sizes := make(map[string]int64, len(m.instances))
+	for instanceID := range m.instances {
+		sizes[instanceID] = eventsFileSize(instanceID)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for instanceID, last := range sizes {
+			// The file is recreated if the instance restarted, then it shrinks.
+			if size := eventsFileSize(instanceID); size != last {
+				sizes[instanceID] = size
+				m.vmInfo.invalidate(instanceID)
+			}
+		}
+	}
+}
+
+func eventsFileSize(instanceID string) int64 {
+	info, err := os.Stat(EventsFile(instanceID))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
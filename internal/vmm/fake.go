@@ -0,0 +1,647 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	DefaultFakeInstances = 16
+
+	fakeSnapshotFile = "config.json"
+)
+
+// FakeManager simulates cloud-hypervisor instances in memory. It allows running the provider
+// on hosts without KVM, e.g. for development and e2e tests.
+type FakeManager struct {
+	log    logr.Logger
+	config *vmConfigBuilder
+
+	mu        sync.Mutex
+	instances map[string]*client.VmInfo
+	free      sets.Set[string]
+
+	dirs      []string
+	selection SocketSelection
+	numaNodes map[string]int
+}
+
+// NewFakeManager creates a FakeManager with the given number of instances. The instance ids are
+// socket paths spread over CHSocketsPaths, although no sockets are created.
+func NewFakeManager(log logr.Logger, paths host.Paths, opts ManagerOptions, instances int) (*FakeManager, error) {
+	if instances <= 0 {
+		return nil, fmt.Errorf("number of fake instances must be positive")
+	}
+	if len(opts.CHSocketsPaths) == 0 {
+		return nil, fmt.Errorf("must specify cloud-hypervisor sockets dir")
+	}
+
+	m := &FakeManager{
+		log:       log,
+		config:    newVMConfigBuilder(paths, opts),
+		instances: make(map[string]*client.VmInfo),
+		free:      sets.New[string](),
+		dirs:      opts.CHSocketsPaths,
+		selection: opts.SocketSelection,
+		numaNodes: cleanNUMANodes(opts.NUMANodes),
+	}
+
+	reserved := sets.New(opts.ReservedInstances...)
+	for i := range instances {
+		dir := opts.CHSocketsPaths[i%len(opts.CHSocketsPaths)]
+		socketPath := filepath.Join(dir, fmt.Sprintf("fake-%d.sock", i))
+		m.instances[socketPath] = nil
+		if !reserved.Has(socketPath) {
+			m.free.Insert(socketPath)
+		}
+		recordInstance(socketPath, "fake", true)
+	}
+
+	log.WithName("init").V(1).Info("Initialized fake instances", "num", instances)
+	return m, nil
+}
+
+func (m *FakeManager) Close() {}
+
+// Start returns once the context is done, fake instances report no events.
+func (m *FakeManager) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (m *FakeManager) Ping(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.instances[instanceID]; !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Pid returns the pid of the provider itself, since fake instances have no process of their own.
+func (m *FakeManager) Pid(_ context.Context, instanceID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.instances[instanceID]; !found {
+		return 0, ErrNotFound
+	}
+	return os.Getpid(), nil
+}
+
+// VMMInfo reports the pid of the provider itself, like Pid.
+func (m *FakeManager) VMMInfo(_ context.Context, instanceID string) (*client.VmmPingResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.instances[instanceID]; !found {
+		return nil, ErrNotFound
+	}
+	return &client.VmmPingResponse{Version: "fake", Pid: ptr.To(int64(os.Getpid()))}, nil
+}
+
+func (m *FakeManager) NUMANode(instanceID string) (int, bool) {
+	return numaNodeOf(m.numaNodes, instanceID)
+}
+
+// GetFreeApiSocket takes any free socket, fake instances satisfy all requirements.
+func (m *FakeManager) GetFreeApiSocket(_ *api.VMMRequirements) (*string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	socket, found := selectSocket(m.free, m.dirs, m.selection, m.numaNodes)
+	if !found {
+		return nil, ErrNoFreeSocket
+	}
+
+	return ptr.To(socket), nil
+}
+
+func (m *FakeManager) FreeApiSocket(_ context.Context, socket string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.instances[socket]; !found {
+		m.log.Info("Failed to ping socket: discard socket", "socket", socket)
+		return
+	}
+	m.free.Insert(socket)
+}
+
+// GetUpgradeApiSocket takes any free socket, since fake instances have no versions.
+func (m *FakeManager) GetUpgradeApiSocket(
+	_ context.Context,
+	instanceID string,
+	_ *api.VMMRequirements,
+) (*string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.instances[instanceID]; !found {
+		return nil, ErrNotFound
+	}
+
+	socket, found := selectSocket(m.free, m.dirs, m.selection, m.numaNodes)
+	if !found {
+		return nil, ErrNoFreeSocket
+	}
+
+	return ptr.To(socket), nil
+}
+
+// Upgrade moves the VM to the other fake instance in memory, regardless of the method.
+func (m *FakeManager) Upgrade(_ context.Context, machine *api.Machine, to string, method api.UpgradeMethod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	vm, err := m.vm(from)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	target, found := m.instances[to]
+	if !found {
+		return ErrNotFound
+	}
+	if target != nil {
+		return fmt.Errorf("vm is already created")
+	}
+
+	m.instances[to] = vm
+	m.instances[from] = nil
+	m.log.V(1).Info("Upgraded machine", "instanceID", from, "to", to, "method", method)
+
+	return nil
+}
+
+// vm returns the VM of an instance. The caller has to hold m.mu.
+func (m *FakeManager) vm(instanceID string) (*client.VmInfo, error) {
+	vm, found := m.instances[instanceID]
+	if !found {
+		return nil, ErrNotFound
+	}
+	if vm == nil {
+		return nil, ErrVmNotCreated
+	}
+	return vm, nil
+}
+
+func (m *FakeManager) GetVM(_ context.Context, instanceID string) (*client.VmInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := *vm
+	info.Config.Disks = ptr.To(slices.Clone(ptr.Deref(vm.Config.Disks, nil)))
+	info.Config.Devices = ptr.To(slices.Clone(ptr.Deref(vm.Config.Devices, nil)))
+	info.Config.Net = ptr.To(slices.Clone(ptr.Deref(vm.Config.Net, nil)))
+	return &info, nil
+}
+
+// Counters returns zero counters for the disks of the VM.
+func (m *FakeManager) Counters(_ context.Context, instanceID string) (client.VmCounters, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := client.VmCounters{}
+	for _, disk := range ptr.Deref(vm.Config.Disks, nil) {
+		counters[ptr.Deref(disk.Id, "")] = map[string]int64{
+			"read_bytes":  0,
+			"write_bytes": 0,
+			"read_ops":    0,
+			"write_ops":   0,
+		}
+	}
+	return counters, nil
+}
+
+func (m *FakeManager) VMs(ctx context.Context) (map[string]*client.VmInfo, error) {
+	m.mu.Lock()
+	var instanceIDs []string
+	for instanceID, vm := range m.instances {
+		if vm != nil {
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+	}
+	m.mu.Unlock()
+
+	vms := make(map[string]*client.VmInfo)
+	for _, instanceID := range instanceIDs {
+		vm, err := m.GetVM(ctx, instanceID)
+		if err != nil {
+			continue
+		}
+		vms[instanceID] = vm
+	}
+	return vms, nil
+}
+
+func (m *FakeManager) Config(machine *api.Machine) (*client.VmConfig, error) {
+	return m.config.build(machine)
+}
+
+func (m *FakeManager) CreateVM(_ context.Context, machine *api.Machine) error {
+	instanceID := ptr.Deref(machine.Spec.ApiSocketPath, "")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+	if vm != nil {
+		return fmt.Errorf("vm is already created")
+	}
+
+	vmConfig, err := m.config.build(machine)
+	if err != nil {
+		return err
+	}
+
+	m.instances[instanceID] = &client.VmInfo{
+		Config:           *vmConfig,
+		MemoryActualSize: ptr.To(vmConfig.Memory.Size),
+		State:            client.Created,
+	}
+	m.log.V(1).Info("Created vm", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) PowerOn(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State == client.Running {
+		return fmt.Errorf("vm is already running")
+	}
+
+	vm.State = client.Running
+	m.log.V(1).Info("Powered on machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) PowerOff(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	vm.State = client.Shutdown
+	m.log.V(1).Info("Powered off machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) Reboot(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	m.log.V(1).Info("Rebooted machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) Pause(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	vm.State = client.Paused
+	m.log.V(1).Info("Paused machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) Resume(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Paused {
+		return fmt.Errorf("vm is not paused")
+	}
+
+	vm.State = client.Running
+	m.log.V(1).Info("Resumed machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) Resize(_ context.Context, instanceID string, vcpus *int, memoryBytes *int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	if vcpus != nil {
+		vm.Config.Cpus.BootVcpus = *vcpus
+	}
+	if memoryBytes != nil {
+		vm.Config.Memory.HotpluggedSize = ptr.To(*memoryBytes - vm.Config.Memory.Size)
+		vm.MemoryActualSize = ptr.To(*memoryBytes)
+	}
+	m.log.V(1).Info("Resized machine", "instanceID", instanceID)
+
+	return nil
+}
+
+// Snapshot writes the config of the VM to dir, the VM keeps running.
+func (m *FakeManager) Snapshot(_ context.Context, instanceID string, dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(vm.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vm config: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, fakeSnapshotFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	m.log.V(1).Info("Snapshotted machine", "instanceID", instanceID, "dir", dir)
+
+	return nil
+}
+
+// Restore creates a running VM with the config written by Snapshot.
+func (m *FakeManager) Restore(_ context.Context, instanceID string, dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+	if vm != nil {
+		return fmt.Errorf("vm is already created")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, fakeSnapshotFile))
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var config client.VmConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	m.instances[instanceID] = &client.VmInfo{
+		Config: config,
+		State:  client.Running,
+	}
+	m.log.V(1).Info("Restored machine", "instanceID", instanceID, "dir", dir)
+
+	return nil
+}
+
+// fakeReceivers are the fake instances receiving a migration, keyed by port or socket path. Migrations
+// between fake instances only work within one process.
+var fakeReceivers sync.Map
+
+func fakeMigrationKey(url string) string {
+	if address, ok := strings.CutPrefix(url, "tcp:"); ok {
+		if _, port, err := net.SplitHostPort(address); err == nil {
+			return "tcp:" + port
+		}
+	}
+	return url
+}
+
+// SendMigration hands the VM over to the fake instance receiving on destinationURL.
+func (m *FakeManager) SendMigration(ctx context.Context, instanceID string, destinationURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+
+	receiver, ok := fakeReceivers.LoadAndDelete(fakeMigrationKey(destinationURL))
+	if !ok {
+		return fmt.Errorf("no receiver listening on %s", destinationURL)
+	}
+
+	select {
+	case receiver.(chan client.VmInfo) <- *vm:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	m.instances[instanceID] = nil
+	m.log.V(1).Info("Sent migration", "instanceID", instanceID, "destination", destinationURL)
+
+	return nil
+}
+
+// ReceiveMigration waits for a fake instance to send its VM to receiverURL.
+func (m *FakeManager) ReceiveMigration(ctx context.Context, instanceID string, receiverURL string) error {
+	m.mu.Lock()
+	vm, found := m.instances[instanceID]
+	m.mu.Unlock()
+	if !found {
+		return ErrNotFound
+	}
+	if vm != nil {
+		return fmt.Errorf("vm is already created")
+	}
+
+	key := fakeMigrationKey(receiverURL)
+	receiver := make(chan client.VmInfo)
+	if _, loaded := fakeReceivers.LoadOrStore(key, receiver); loaded {
+		return fmt.Errorf("address %s is already in use", receiverURL)
+	}
+	defer fakeReceivers.CompareAndDelete(key, receiver)
+
+	select {
+	case info := <-receiver:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.instances[instanceID] = &info
+		m.log.V(1).Info("Received migration", "instanceID", instanceID, "receiver", receiverURL)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PowerButton shuts the VM down immediately, as if the guest handled the ACPI event.
+func (m *FakeManager) PowerButton(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	vm.State = client.Shutdown
+	m.log.V(1).Info("Pressed power button of machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) Delete(_ context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.instances[instanceID]; !found {
+		return ErrNotFound
+	}
+
+	m.instances[instanceID] = nil
+	m.log.V(1).Info("Deleted machine", "instanceID", instanceID)
+
+	return nil
+}
+
+func (m *FakeManager) RemoveDevice(_ context.Context, instanceID string, deviceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+
+	isDevice := func(id *string) bool { return ptr.Deref(id, "") == deviceID }
+	disks := ptr.Deref(vm.Config.Disks, nil)
+	devices := ptr.Deref(vm.Config.Devices, nil)
+	nets := ptr.Deref(vm.Config.Net, nil)
+	if !slices.ContainsFunc(disks, func(d client.DiskConfig) bool { return isDevice(d.Id) }) &&
+		!slices.ContainsFunc(devices, func(d client.DeviceConfig) bool { return isDevice(d.Id) }) &&
+		!slices.ContainsFunc(nets, func(n client.NetConfig) bool { return isDevice(n.Id) }) {
+		return fmt.Errorf("device %s not found", deviceID)
+	}
+
+	vm.Config.Disks = ptr.To(slices.DeleteFunc(disks, func(d client.DiskConfig) bool { return isDevice(d.Id) }))
+	vm.Config.Devices = ptr.To(slices.DeleteFunc(devices, func(d client.DeviceConfig) bool { return isDevice(d.Id) }))
+	vm.Config.Net = ptr.To(slices.DeleteFunc(nets, func(n client.NetConfig) bool { return isDevice(n.Id) }))
+	m.log.V(1).Info("Removed device from on machine", "instanceID", instanceID, "deviceID", deviceID)
+
+	return nil
+}
+
+func (m *FakeManager) AddNIC(_ context.Context, instanceID string, nic *api.NetworkInterfaceStatus) error {
+	if nic.State != api.NetworkInterfaceStatePrepared {
+		return fmt.Errorf("nic %s is not attached", nic.Name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+
+	if nic.Type == api.NetworkInterfaceTAPType {
+		vm.Config.Net = ptr.To(append(ptr.Deref(vm.Config.Net, nil), netConfig(nic)))
+	} else {
+		vm.Config.Devices = ptr.To(append(ptr.Deref(vm.Config.Devices, nil), nicConfig(nic)))
+	}
+	m.log.V(1).Info("Added device", "instanceID", instanceID, "name", nic.Name)
+
+	return nil
+}
+
+func (m *FakeManager) RemoveNIC(ctx context.Context, instanceID string, nicName string) error {
+	return m.RemoveDevice(ctx, instanceID, NicID(nicName))
+}
+
+func (m *FakeManager) AddDisk(_ context.Context, instanceID string, volume *api.VolumeStatus) error {
+	if volume.State != api.VolumeStatePrepared {
+		return fmt.Errorf("volume %s is not prepared", volume.Handle)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vm, err := m.vm(instanceID)
+	if err != nil {
+		return err
+	}
+
+	vm.Config.Disks = ptr.To(append(ptr.Deref(vm.Config.Disks, nil), diskConfig(volume)))
+	m.log.V(1).Info("Added device", "instanceID", instanceID, "diskName", volume.Handle)
+
+	return nil
+}
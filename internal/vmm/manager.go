@@ -5,47 +5,139 @@ package vmm
 
 import (
 	"context"
-	b64 "encoding/base64"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
 	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
 	utilssync "github.com/ironcore-dev/provider-utils/storeutils/sync"
+	"golang.org/x/time/rate"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/ptr"
 )
 
 type ManagerOptions struct {
-	CHSocketsPath     string
+	// CHSocketsPaths are the directories the api sockets of the instances are discovered in.
+	CHSocketsPaths []string
+	// SocketSelection chooses the socket of a new machine. Defaults to SocketSelectionRandom.
+	SocketSelection SocketSelection
+	// NUMANodes are the NUMA nodes the instances of the sockets directories are pinned to, by directory. The
+	// machine cgroups of the instances are confined to the cpus and memory of the node.
+	NUMANodes map[string]int
+
 	FirmwarePath      string
 	ReservedInstances []string
+
+	// EnableVsock adds a hybrid vsock device to every VM, proxied via a unix socket in the machine dir.
+	EnableVsock bool
+
+	// NICPCISegments are the PCI segments added to every VM for its network interfaces, one interface per
+	// segment. Disabled if 0.
+	NICPCISegments int16
+
+	// IgnitionCompression gzip compresses ignition payloads passed via OEM strings where the spec supports it.
+	IgnitionCompression bool
+
+	// MinVersion is the minimum cloud-hypervisor version of a usable instance. Defaults to DefaultMinVersion.
+	MinVersion string
+	// RequiredFeatures are the cloud-hypervisor features a usable instance has to report.
+	RequiredFeatures []string
+
+	// VMConfigOverrides are set in the config of every VM, by dot separated path of the field (e.g. memory.thp).
+	// The overrides of the machine class take precedence.
+	VMConfigOverrides map[string]string
+	// VMConfigOverrideAllowlist are the fields that may be overridden. Defaults to
+	// DefaultVMConfigOverrideAllowlist.
+	VMConfigOverrideAllowlist []string
+
+	// LandlockRules grant access to additional paths for VMs with landlock enabled.
+	LandlockRules []client.LandlockConfig
+
+	// OEMStringLabels and OEMStringAnnotations are the keys of the IRI labels and annotations passed to the
+	// guest as OEM strings. A key ending with * matches all keys with its prefix.
+	OEMStringLabels      []string
+	OEMStringAnnotations []string
+
+	// VMInfoCacheTTL is how long the VM info of an instance is reused until it is requested again. Calls changing
+	// the VM and the events of the event monitor of the instance invalidate it. Disabled if 0.
+	VMInfoCacheTTL time.Duration
+
+	// APIRateLimit is the rate of the api calls per second each instance is limited to, bursts of up to APIBurst
+	// calls are not delayed. Disabled if 0.
+	APIRateLimit float64
+	APIBurst     int
 }
 
 func NewManager(log logr.Logger, paths host.Paths, opts ManagerOptions) (*Manager, error) {
 	initLog := log.WithName("init")
 
-	entries, err := os.ReadDir(opts.CHSocketsPath)
+	if len(opts.CHSocketsPaths) == 0 {
+		return nil, fmt.Errorf("must specify cloud-hypervisor sockets dir")
+	}
+
+	compat, err := newCompatibility(opts.MinVersion, opts.RequiredFeatures)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read cloud-hypervisor sockets dir: %w", err)
+		return nil, err
 	}
 
 	m := &Manager{
 		idMu:         utilssync.NewMutexMap[string](),
 		instances:    make(map[string]*client.ClientWithResponses),
-		paths:        paths,
-		firmwarePath: opts.FirmwarePath,
+		config:       newVMConfigBuilder(paths, opts),
 		log:          log,
 		free:         sets.New[string](),
+		compat:       compat,
+		incompatible: make(map[string]string),
+		dirs:         opts.CHSocketsPaths,
+		selection:    opts.SocketSelection,
+		numaNodes:    cleanNUMANodes(opts.NUMANodes),
+		vmInfo:       newVMInfoCache(opts.VMInfoCacheTTL),
+		capabilities: make(map[string]instanceCapabilities),
+		apiRateLimit: rate.Limit(opts.APIRateLimit),
+		apiBurst:     opts.APIBurst,
 	}
 	reserved := sets.NewString(opts.ReservedInstances...)
+	for _, dir := range opts.CHSocketsPaths {
+		if err := m.discover(initLog, dir, reserved); err != nil {
+			return nil, err
+		}
+	}
+
+	initLog.V(1).Info("Successfully initialized clients", "num", len(m.instances), "incompatible", len(m.incompatible))
+	if len(m.instances) == 0 {
+		if len(m.incompatible) > 0 {
+			return nil, fmt.Errorf("no compatible instances found, %d incompatible", len(m.incompatible))
+		}
+		return nil, errors.New("no instances found")
+	}
+
+	return m, nil
+}
+
+// newLimiter returns the limiter of the api calls of an instance, nil if they are not limited.
+func (m *Manager) newLimiter() *rate.Limiter {
+	if m.apiRateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(m.apiRateLimit, max(m.apiBurst, 1))
+}
+
+// discover creates the clients of the compatible instances whose api sockets are in the directory.
+func (m *Manager) discover(initLog logr.Logger, dir string, reserved sets.String) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cloud-hypervisor sockets dir: %w", err)
+	}
+
 	for _, v := range entries {
 		if v.IsDir() {
 			continue
@@ -54,19 +146,29 @@ func NewManager(log logr.Logger, paths host.Paths, opts ManagerOptions) (*Manage
 			continue
 		}
 
-		socketPath := filepath.Join(opts.CHSocketsPath, v.Name())
+		socketPath := filepath.Join(dir, v.Name())
 
-		apiClient, err := NewUnixSocketClient(socketPath)
+		apiClient, err := newInstrumentedClient(socketPath, m.newLimiter())
 		if err != nil {
 			initLog.V(1).Info("Failed to init cloud-hypervisor client", "path", socketPath)
 			continue
 		}
 
-		if _, err := apiClient.GetVmmPing(context.TODO()); err != nil {
+		ping, err := apiClient.GetVmmPingWithResponse(context.TODO())
+		if err != nil {
 			initLog.V(1).Info("Failed to ping cloud-hypervisor socket", "path", socketPath)
 			continue
 		}
 
+		if err := m.compat.check(ping.JSON200); err != nil {
+			initLog.Info("Skipping incompatible cloud-hypervisor socket", "path", socketPath, "reason", err.Error())
+			m.incompatible[socketPath] = err.Error()
+			recordInstance(socketPath, pingVersion(ping.JSON200), false)
+			continue
+		}
+		recordInstance(socketPath, pingVersion(ping.JSON200), true)
+		m.setCapabilities(socketPath, ping.JSON200)
+
 		initLog.V(2).Info("Created cloud-hypervisor client", "socketPath", socketPath)
 		m.instances[socketPath] = apiClient
 
@@ -78,13 +180,7 @@ func NewManager(log logr.Logger, paths host.Paths, opts ManagerOptions) (*Manage
 			}
 		}
 	}
-
-	initLog.V(1).Info("Successfully initialized clients", "num", len(m.instances))
-	if len(m.instances) == 0 {
-		return nil, errors.New("no instances found")
-	}
-
-	return m, nil
+	return nil
 }
 
 type Manager struct {
@@ -92,22 +188,112 @@ type Manager struct {
 
 	idMu      *utilssync.MutexMap[string]
 	instances map[string]*client.ClientWithResponses
+	// migrating are the instances sending or receiving a VM, their api does not answer until the migration
+	// finished.
+	migrating sync.Map
 
 	free   sets.Set[string]
 	freeMu sync.Mutex
 
-	paths        host.Paths
-	firmwarePath string
+	config *vmConfigBuilder
+
+	compat       *compatibility
+	incompatible map[string]string
+
+	dirs      []string
+	selection SocketSelection
+	numaNodes map[string]int
+
+	vmInfo *vmInfoCache
+
+	apiRateLimit rate.Limit
+	apiBurst     int
+
+	// capabilities are the ones the compatible instances reported on their last ping, by instance.
+	capabilities   map[string]instanceCapabilities
+	capabilitiesMu sync.RWMutex
 }
 
+const (
+	configDriveID = "configdrive"
+
+	// clockOEMStringPrefix prefixes the OEM string telling the guest which clock to synchronize with.
+	clockOEMStringPrefix = "ironcore.dev/clock="
+	// opaqueUserDataOEMStringPrefix prefixes the OEM string with the base64 encoded opaque user data.
+	opaqueUserDataOEMStringPrefix = "ironcore.dev/opaque-user-data="
+	// labelOEMStringPrefix and annotationOEMStringPrefix prefix the OEM strings <key>=<value> of the labels and
+	// annotations passed to the guest.
+	labelOEMStringPrefix      = "ironcore.dev/label/"
+	annotationOEMStringPrefix = "ironcore.dev/annotation/"
+	// maxMetadataOEMStringsSize limits the OEM strings of labels and annotations, so that they cannot crowd out
+	// the ignition data.
+	maxMetadataOEMStringsSize = 4 * 1024
+
+	// guestCID is the vsock CID of every guest. It only has to be unique per VM, since each VM
+	// proxies vsock via its own unix socket.
+	guestCID = 3
+)
+
 var (
 	ErrBrokenSocket = errors.New("broken socket")
 	ErrNotFound     = errors.New("not found")
 	ErrVmNotCreated = errors.New("vm is not created")
+	ErrNoFreeSocket = errors.New("no free socket available")
+	// ErrMigrating is returned for instances sending or receiving a VM.
+	ErrMigrating = errors.New("instance is migrating a vm")
+	// ErrNoCompatibleSocket is returned if sockets are free, but none satisfies the requirements of the machine.
+	ErrNoCompatibleSocket = fmt.Errorf("%w: no free instance satisfies the requirements", ErrNoFreeSocket)
+	// ErrNoNewerSocket is returned if sockets are free, but none has a newer version than the instance of the
+	// machine and satisfies its requirements.
+	ErrNoNewerSocket = fmt.Errorf("%w: no free instance of a newer version satisfies the requirements", ErrNoFreeSocket)
 )
 
-func (m *Manager) Ping(ctx context.Context, instanceID string) error {
+func (m *Manager) Close() {
+	for instanceID, apiClient := range m.instances {
+		m.idMu.Lock(instanceID)
+		closeIdleConnections(apiClient)
+		m.idMu.Unlock(instanceID)
+	}
+}
+
+// lock locks the instance for a call of its api. Instances migrating a VM are not locked, the call would block
+// until the migration finished.
+func (m *Manager) lock(instanceID string) error {
 	m.idMu.Lock(instanceID)
+	if _, ok := m.migrating.Load(instanceID); ok {
+		m.idMu.Unlock(instanceID)
+		return ErrMigrating
+	}
+	return nil
+}
+
+// startMigration marks the instance as migrating, so that the lock of the instance is not held during the
+// migration.
+func (m *Manager) startMigration(instanceID string) (*client.ClientWithResponses, error) {
+	if err := m.lock(instanceID); err != nil {
+		return nil, err
+	}
+	defer m.idMu.Unlock(instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return nil, ErrNotFound
+	}
+	m.migrating.Store(instanceID, struct{}{})
+	return apiClient, nil
+}
+
+func (m *Manager) finishMigration(instanceID string) {
+	m.idMu.Lock(instanceID)
+	defer m.idMu.Unlock(instanceID)
+	m.migrating.Delete(instanceID)
+	m.vmInfo.invalidate(instanceID)
+}
+
+func (m *Manager) Ping(ctx context.Context, instanceID string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
 	return m.ping(ctx, instanceID)
 }
@@ -135,17 +321,132 @@ func (m *Manager) ping(ctx context.Context, instanceID string) error {
 		)
 	}
 
+	if err := m.compat.check(ping.JSON200); err != nil {
+		recordInstance(instanceID, pingVersion(ping.JSON200), false)
+		return err
+	}
+	// The instance may have been replaced by another version since it was discovered.
+	m.setCapabilities(instanceID, ping.JSON200)
+
 	return nil
 }
 
-func (m *Manager) GetFreeApiSocket() (*string, error) {
+func (m *Manager) setCapabilities(instanceID string, ping *client.VmmPingResponse) {
+	capabilities, err := capabilitiesOf(ping)
+	if err != nil {
+		return
+	}
+
+	m.capabilitiesMu.Lock()
+	defer m.capabilitiesMu.Unlock()
+	m.capabilities[instanceID] = capabilities
+}
+
+// Pid returns the process id of the cloud-hypervisor instance.
+func (m *Manager) Pid(ctx context.Context, instanceID string) (int, error) {
+	if err := m.lock(instanceID); err != nil {
+		return 0, err
+	}
+	defer m.idMu.Unlock(instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return 0, ErrNotFound
+	}
+
+	ping, err := apiClient.GetVmmPingWithResponse(ctx)
+	if err != nil {
+		return 0, wrapIfSocketClosed(fmt.Errorf("failed to ping vmm: %w", err))
+	}
+	if ping.JSON200 == nil || ping.JSON200.Pid == nil {
+		return 0, fmt.Errorf("vmm did not report its pid")
+	}
+
+	return int(*ping.JSON200.Pid), nil
+}
+
+// VMMInfo returns the version, pid and features the cloud-hypervisor instance reports on ping.
+func (m *Manager) VMMInfo(ctx context.Context, instanceID string) (*client.VmmPingResponse, error) {
+	if err := m.lock(instanceID); err != nil {
+		return nil, err
+	}
+	defer m.idMu.Unlock(instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	ping, err := apiClient.GetVmmPingWithResponse(ctx)
+	if err != nil {
+		return nil, wrapIfSocketClosed(fmt.Errorf("failed to ping vmm: %w", err))
+	}
+	if err := validateStatus(ping.StatusCode()); err != nil {
+		return nil, err
+	}
+	if ping.JSON200 == nil {
+		return nil, fmt.Errorf("vmm did not report its version")
+	}
+
+	return ping.JSON200, nil
+}
+
+// NUMANode returns the NUMA node the instance is pinned to, false if it is not pinned.
+func (m *Manager) NUMANode(instanceID string) (int, bool) {
+	return numaNodeOf(m.numaNodes, instanceID)
+}
+
+// IncompatibleInstances returns the sockets skipped during initialization and the reason why.
+func (m *Manager) IncompatibleInstances() map[string]string {
+	return maps.Clone(m.incompatible)
+}
+
+func pingVersion(ping *client.VmmPingResponse) string {
+	if ping == nil {
+		return ""
+	}
+	return ping.Version
+}
+
+// GetFreeApiSocket takes a free socket whose instance satisfies the requirements, any if they are nil.
+func (m *Manager) GetFreeApiSocket(requirements *api.VMMRequirements) (*string, error) {
 	m.freeMu.Lock()
 	defer m.freeMu.Unlock()
 
-	socket, found := m.free.PopAny()
+	if requirements == nil {
+		return m.takeFreeSocket(m.free.Clone(), ErrNoFreeSocket)
+	}
+	return m.takeFreeSocket(m.freeSatisfying(func(capabilities instanceCapabilities) bool {
+		return capabilities.satisfiesRequirements(requirements) == nil
+	}), ErrNoCompatibleSocket)
+}
+
+// freeSatisfying returns the free sockets whose instance capabilities satisfy accept. The caller has to hold
+// m.freeMu.
+func (m *Manager) freeSatisfying(accept func(capabilities instanceCapabilities) bool) sets.Set[string] {
+	m.capabilitiesMu.RLock()
+	defer m.capabilitiesMu.RUnlock()
+
+	candidates := sets.New[string]()
+	for socket := range m.free {
+		if capabilities, ok := m.capabilities[socket]; ok && accept(capabilities) {
+			candidates.Insert(socket)
+		}
+	}
+	return candidates
+}
+
+// takeFreeSocket selects one of the candidates and removes it from the free sockets. If none is left although
+// sockets are free, it returns unsatisfied. The caller has to hold m.freeMu.
+func (m *Manager) takeFreeSocket(candidates sets.Set[string], unsatisfied error) (*string, error) {
+	socket, found := selectSocket(candidates, m.dirs, m.selection, m.numaNodes)
 	if !found {
-		return nil, fmt.Errorf("no free socket available")
+		if m.free.Len() > 0 {
+			return nil, unsatisfied
+		}
+		return nil, ErrNoFreeSocket
 	}
+	m.free.Delete(socket)
 
 	return ptr.To(socket), nil
 }
@@ -169,7 +470,9 @@ func wrapIfSocketClosed(err error) error {
 }
 
 func (m *Manager) GetVM(ctx context.Context, instanceID string) (*client.VmInfo, error) {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return nil, err
+	}
 	defer m.idMu.Unlock(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
@@ -179,6 +482,12 @@ func (m *Manager) GetVM(ctx context.Context, instanceID string) (*client.VmInfo,
 		return nil, ErrNotFound
 	}
 
+	if vm, ok := m.vmInfo.get(instanceID); ok {
+		return vm, nil
+	}
+
+	// An event of the instance while the VM is fetched invalidates it, the fetched info is not cached then.
+	generation := m.vmInfo.generation(instanceID)
 	log.V(2).Info("Getting vm")
 	resp, err := apiClient.GetVmInfoWithResponse(ctx)
 	if err != nil {
@@ -193,95 +502,82 @@ func (m *Manager) GetVM(ctx context.Context, instanceID string) (*client.VmInfo,
 		return nil, err
 	}
 
+	m.vmInfo.set(instanceID, generation, resp.JSON200)
 	return resp.JSON200, nil
 }
 
-func (m *Manager) CreateVM(ctx context.Context, machine *api.Machine) error {
-	instanceID := ptr.Deref(machine.Spec.ApiSocketPath, "")
-	m.idMu.Lock(instanceID)
+// Counters returns the counters of the devices of the VM, keyed by device id.
+func (m *Manager) Counters(ctx context.Context, instanceID string) (client.VmCounters, error) {
+	if err := m.lock(instanceID); err != nil {
+		return nil, err
+	}
 	defer m.idMu.Unlock(instanceID)
 
-	log := m.log.WithValues("instanceID", instanceID)
-
 	apiClient, found := m.instances[instanceID]
 	if !found {
-		return ErrNotFound
+		return nil, ErrNotFound
 	}
 
-	payload := client.PayloadConfig{
-		Cmdline:   nil,
-		Firmware:  ptr.To(m.firmwarePath),
-		HostData:  nil,
-		Igvm:      nil,
-		Initramfs: nil,
-		Kernel:    nil,
+	resp, err := apiClient.GetVmCountersWithResponse(ctx)
+	if err != nil {
+		return nil, wrapIfSocketClosed(fmt.Errorf("failed to get vm counters: %w", err))
 	}
-
-	platform := &client.PlatformConfig{
-		Uuid: ptr.To(machine.ID),
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		if strings.Contains(string(resp.Body), "VM is not created") {
+			return nil, ErrVmNotCreated
+		}
+		return nil, err
 	}
-
-	if machine.Spec.Ignition != nil {
-		platform.OemStrings = ptr.To([]string{
-			b64.StdEncoding.EncodeToString(machine.Spec.Ignition),
-		})
+	if resp.JSON200 == nil {
+		return client.VmCounters{}, nil
 	}
 
-	var disks []client.DiskConfig
-	for _, vol := range machine.Status.VolumeStatus {
-		if vol.State != api.VolumeStatePrepared {
-			continue
-		}
+	return *resp.JSON200, nil
+}
 
-		disk := client.DiskConfig{
-			Id: ptr.To(vol.Handle),
+// VMs returns the VMs of all instances that have one, keyed by instance id.
+// Instances that cannot be reached are skipped.
+func (m *Manager) VMs(ctx context.Context) (map[string]*client.VmInfo, error) {
+	vms := make(map[string]*client.VmInfo)
+	for instanceID := range m.instances {
+		vm, err := m.GetVM(ctx, instanceID)
+		if err != nil {
+			if !errors.Is(err, ErrVmNotCreated) {
+				m.log.V(1).Info("Failed to get vm, skipping instance", "instanceID", instanceID, "error", err.Error())
+			}
+			continue
 		}
+		vms[instanceID] = vm
+	}
+	return vms, nil
+}
 
-		switch vol.Type {
-		case api.VolumeSocketType:
-			disk.VhostUser = ptr.To(true)
-			disk.VhostSocket = ptr.To(vol.Path)
-			disk.Readonly = ptr.To(false)
-		case api.VolumeFileType:
-			disk.Path = ptr.To(vol.Path)
-		}
+func (m *Manager) Config(machine *api.Machine) (*client.VmConfig, error) {
+	return m.config.build(machine)
+}
 
-		disks = append(disks, disk)
+func (m *Manager) CreateVM(ctx context.Context, machine *api.Machine) error {
+	instanceID := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	if err := m.lock(instanceID); err != nil {
+		return err
 	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
-	var dev []client.DeviceConfig
-	for _, nic := range machine.Status.NetworkInterfaceStatus {
-		if nic.State != api.NetworkInterfaceStatePrepared {
-			return fmt.Errorf("nic %s is not attached", nic.Name)
-		}
+	log := m.log.WithValues("instanceID", instanceID, "machineID", machine.ID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
 
-		dev = append(dev, client.DeviceConfig{
-			Id:   ptr.To(getNicID(nic.Name)),
-			Path: nic.Path,
-		})
+	vmConfig, err := m.config.build(machine)
+	if err != nil {
+		return err
 	}
 
 	log.V(2).Info("Creating vm")
-	resp, err := apiClient.CreateVMWithResponse(ctx, client.CreateVMJSONRequestBody{
-		Cpus: &client.CpusConfig{
-			BootVcpus: int(machine.Spec.Cpu),
-			MaxVcpus:  int(machine.Spec.Cpu),
-		},
-		Devices: &dev,
-		Disks:   &disks,
-		Memory: &client.MemoryConfig{
-			Size:   machine.Spec.MemoryBytes,
-			Shared: ptr.To(true),
-		},
-		Console: &client.ConsoleConfig{
-			Mode: "Off",
-		},
-		Serial: &client.ConsoleConfig{
-			Mode: "Tty",
-		},
-		Payload:  payload,
-		Platform: platform,
-	})
+	resp, err := apiClient.CreateVMWithResponse(ctx, *vmConfig)
 	if err != nil {
 		return wrapIfSocketClosed(fmt.Errorf("failed to get vm: %w", err))
 	}
@@ -295,8 +591,11 @@ func (m *Manager) CreateVM(ctx context.Context, machine *api.Machine) error {
 }
 
 func (m *Manager) RemoveDevice(ctx context.Context, instanceID string, deviceID string) error {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
 
@@ -322,8 +621,11 @@ func (m *Manager) RemoveDevice(ctx context.Context, instanceID string, deviceID
 }
 
 func (m *Manager) AddNIC(ctx context.Context, instanceID string, nic *api.NetworkInterfaceStatus) error {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
 
@@ -336,10 +638,22 @@ func (m *Manager) AddNIC(ctx context.Context, instanceID string, nic *api.Networ
 		return ErrNotFound
 	}
 
-	resp, err := apiClient.PutVmAddDeviceWithResponse(ctx, client.DeviceConfig{
-		Id:   ptr.To(getNicID(nic.Name)),
-		Path: nic.Path,
-	})
+	if nic.Type == api.NetworkInterfaceTAPType {
+		resp, err := apiClient.PutVmAddNetWithResponse(ctx, netConfig(nic))
+		if err != nil {
+			return wrapIfSocketClosed(fmt.Errorf("failed to add net: %w", err))
+		}
+
+		if err := validateStatus(resp.StatusCode()); err != nil {
+			log.V(1).Info("Failed to add nic", "error", string(resp.Body))
+			return err
+		}
+		log.V(1).Info("Added net", "name", nic.Name)
+
+		return nil
+	}
+
+	resp, err := apiClient.PutVmAddDeviceWithResponse(ctx, nicConfig(nic))
 	if err != nil {
 		return wrapIfSocketClosed(fmt.Errorf("failed to remove device: %w", err))
 	}
@@ -354,12 +668,15 @@ func (m *Manager) AddNIC(ctx context.Context, instanceID string, nic *api.Networ
 }
 
 func (m *Manager) RemoveNIC(ctx context.Context, instanceID string, nicName string) error {
-	return m.RemoveDevice(ctx, instanceID, getNicID(nicName))
+	return m.RemoveDevice(ctx, instanceID, NicID(nicName))
 }
 
 func (m *Manager) AddDisk(ctx context.Context, instanceID string, volume *api.VolumeStatus) error {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
 
@@ -372,20 +689,7 @@ func (m *Manager) AddDisk(ctx context.Context, instanceID string, volume *api.Vo
 		return ErrNotFound
 	}
 
-	disk := client.DiskConfig{
-		Id: ptr.To(volume.Handle),
-	}
-
-	switch volume.Type {
-	case api.VolumeSocketType:
-		disk.VhostUser = ptr.To(true)
-		disk.VhostSocket = ptr.To(volume.Path)
-		disk.Readonly = ptr.To(false)
-	case api.VolumeFileType:
-		disk.Path = ptr.To(volume.Path)
-	}
-
-	resp, err := apiClient.PutVmAddDiskWithResponse(ctx, disk)
+	resp, err := apiClient.PutVmAddDiskWithResponse(ctx, diskConfig(volume))
 	if err != nil {
 		return wrapIfSocketClosed(fmt.Errorf("failed to add device: %w", err))
 	}
@@ -400,8 +704,11 @@ func (m *Manager) AddDisk(ctx context.Context, instanceID string, volume *api.Vo
 }
 
 func (m *Manager) PowerOn(ctx context.Context, instanceID string) error {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
 
@@ -425,8 +732,11 @@ func (m *Manager) PowerOn(ctx context.Context, instanceID string) error {
 }
 
 func (m *Manager) PowerOff(ctx context.Context, instanceID string) error {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
 
@@ -449,9 +759,311 @@ func (m *Manager) PowerOff(ctx context.Context, instanceID string) error {
 	return nil
 }
 
+// Pause stops the vcpus of the running VM, e.g. while its disks are read.
+func (m *Manager) Pause(ctx context.Context, instanceID string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	resp, err := apiClient.PauseVMWithResponse(ctx)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to pause vm: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to pause vm", "error", string(resp.Body))
+		return err
+	}
+	log.V(1).Info("Paused machine")
+
+	return nil
+}
+
+// Resume continues the paused VM.
+func (m *Manager) Resume(ctx context.Context, instanceID string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	resp, err := apiClient.ResumeVMWithResponse(ctx)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to resume vm: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to resume vm", "error", string(resp.Body))
+		return err
+	}
+	log.V(1).Info("Resumed machine")
+
+	return nil
+}
+
+// Reboot reboots the VM. Hot-plugged devices are kept.
+func (m *Manager) Reboot(ctx context.Context, instanceID string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	resp, err := apiClient.RebootVMWithResponse(ctx)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to reboot vm: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to reboot vm", "error", string(resp.Body))
+		return err
+	}
+	log.V(1).Info("Rebooted machine")
+
+	return nil
+}
+
+// Resize hot-plugs or unplugs vcpus and memory of the running VM. Unset values are kept.
+func (m *Manager) Resize(ctx context.Context, instanceID string, vcpus *int, memoryBytes *int64) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	resp, err := apiClient.PutVmResizeWithResponse(ctx, client.VmResize{
+		DesiredVcpus: vcpus,
+		DesiredRam:   memoryBytes,
+	})
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to resize vm: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to resize vm", "error", string(resp.Body))
+		return err
+	}
+	log.V(1).Info("Resized machine", "vcpus", vcpus, "memoryBytes", memoryBytes)
+
+	return nil
+}
+
+// Snapshot pauses the VM and writes a snapshot of it to dir. The VM is resumed if the snapshot fails.
+func (m *Manager) Snapshot(ctx context.Context, instanceID string, dir string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	pauseResp, err := apiClient.PauseVMWithResponse(ctx)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to pause vm: %w", err))
+	}
+	if err := validateStatus(pauseResp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to pause vm", "error", string(pauseResp.Body))
+		return err
+	}
+
+	resp, err := apiClient.PutVmSnapshotWithResponse(ctx, client.VmSnapshotConfig{
+		DestinationUrl: ptr.To("file://" + dir),
+	})
+	if err == nil {
+		err = validateStatus(resp.StatusCode())
+		if err != nil {
+			log.V(1).Info("Failed to snapshot vm", "error", string(resp.Body))
+		}
+	}
+	if err != nil {
+		if resumeResp, resumeErr := apiClient.ResumeVMWithResponse(ctx); resumeErr != nil {
+			log.Error(resumeErr, "Failed to resume vm after failed snapshot")
+		} else if resumeErr := validateStatus(resumeResp.StatusCode()); resumeErr != nil {
+			log.Error(resumeErr, "Failed to resume vm after failed snapshot", "body", string(resumeResp.Body))
+		}
+		return wrapIfSocketClosed(fmt.Errorf("failed to snapshot vm: %w", err))
+	}
+	log.V(1).Info("Snapshotted machine", "dir", dir)
+
+	return nil
+}
+
+// Restore creates the VM from the snapshot in dir and resumes it.
+func (m *Manager) Restore(ctx context.Context, instanceID string, dir string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	resp, err := apiClient.PutVmRestoreWithResponse(ctx, client.RestoreConfig{
+		SourceUrl: "file://" + dir,
+	})
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to restore vm: %w", err))
+	}
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to restore vm", "error", string(resp.Body))
+		return err
+	}
+
+	resumeResp, err := apiClient.ResumeVMWithResponse(ctx)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to resume vm: %w", err))
+	}
+	if err := validateStatus(resumeResp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to resume vm", "error", string(resumeResp.Body))
+		return err
+	}
+	log.V(1).Info("Restored machine", "dir", dir)
+
+	return nil
+}
+
+// SendMigration migrates the VM to the cloud-hypervisor instance listening on destinationURL
+// (tcp:<host>:<port> or unix:<path>). It blocks until the migration finished.
+func (m *Manager) SendMigration(ctx context.Context, instanceID string, destinationURL string) error {
+	return m.sendMigration(ctx, instanceID, destinationURL, false)
+}
+
+// sendMigration migrates the VM, with local its shared memory is handed over to a receiver on the same host
+// instead of being copied.
+func (m *Manager) sendMigration(ctx context.Context, instanceID string, destinationURL string, local bool) error {
+	apiClient, err := m.startMigration(instanceID)
+	if err != nil {
+		return err
+	}
+	defer m.finishMigration(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	log.V(1).Info("Sending migration", "destination", destinationURL, "local", local)
+	data := client.SendMigrationData{
+		DestinationUrl: destinationURL,
+	}
+	if local {
+		data.Local = ptr.To(true)
+	}
+	resp, err := apiClient.PutVmSendMigrationWithResponse(ctx, data)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to send migration: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to send migration", "error", string(resp.Body))
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(resp.Body)))
+	}
+	log.V(1).Info("Sent migration")
+
+	return nil
+}
+
+// ReceiveMigration listens on receiverURL (tcp:<host>:<port> or unix:<path>) for a VM migrated by another
+// cloud-hypervisor instance. It blocks until the migration finished.
+func (m *Manager) ReceiveMigration(ctx context.Context, instanceID string, receiverURL string) error {
+	apiClient, err := m.startMigration(instanceID)
+	if err != nil {
+		return err
+	}
+	defer m.finishMigration(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	log.V(1).Info("Receiving migration", "receiver", receiverURL)
+	resp, err := apiClient.PutVmReceiveMigrationWithResponse(ctx, client.ReceiveMigrationData{
+		ReceiverUrl: receiverURL,
+	})
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to receive migration: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to receive migration", "error", string(resp.Body))
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(resp.Body)))
+	}
+	log.V(1).Info("Received migration")
+
+	return nil
+}
+
+// PowerButton presses the ACPI power button of the VM, asking the guest to shut down.
+func (m *Manager) PowerButton(ctx context.Context, instanceID string) error {
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
+	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
+
+	log := m.log.WithValues("instanceID", instanceID)
+
+	apiClient, found := m.instances[instanceID]
+	if !found {
+		return ErrNotFound
+	}
+
+	resp, err := apiClient.PowerButtonVMWithResponse(ctx)
+	if err != nil {
+		return wrapIfSocketClosed(fmt.Errorf("failed to press power button: %w", err))
+	}
+
+	if err := validateStatus(resp.StatusCode()); err != nil {
+		log.V(1).Info("Failed to press power button", "error", string(resp.Body))
+		return err
+	}
+	log.V(1).Info("Pressed power button of machine")
+
+	return nil
+}
+
 func (m *Manager) Delete(ctx context.Context, instanceID string) error {
-	m.idMu.Lock(instanceID)
+	if err := m.lock(instanceID); err != nil {
+		return err
+	}
 	defer m.idMu.Unlock(instanceID)
+	defer m.vmInfo.invalidate(instanceID)
 
 	log := m.log.WithValues("instanceID", instanceID)
 
@@ -474,6 +1086,12 @@ func (m *Manager) Delete(ctx context.Context, instanceID string) error {
 	return nil
 }
 
-func getNicID(nicName string) string {
+func hasIgnitionConfigDrive(machine *api.Machine) bool {
+	configDrive := machine.Status.ConfigDrive
+	return configDrive != nil && configDrive.Format == api.ConfigDriveFormatOpenStack
+}
+
+// NicID is the id of the device of a network interface in the VM config.
+func NicID(nicName string) string {
 	return fmt.Sprintf("%s//%s", "NIC", nicName)
 }
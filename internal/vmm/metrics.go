@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var instanceInfo = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "cloud_hypervisor_provider",
+		Name:      "vmm_instance_info",
+		Help:      "Cloud-hypervisor instances discovered by the provider and whether they are compatible.",
+	},
+	[]string{"socket", "version", "compatible"},
+)
+
+var vmInfoCacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cloud_hypervisor_provider",
+		Name:      "vm_info_cache_requests_total",
+		Help:      "Requests of the VM info of an instance by whether they were served from the cache.",
+	},
+	[]string{"result"},
+)
+
+var apiRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cloud_hypervisor_provider",
+		Name:      "vmm_api_requests_total",
+		Help: "Calls of the cloud-hypervisor api by socket, endpoint and http status code, error if none was " +
+			"returned, throttled if the call could not wait for the rate limit of the socket.",
+	},
+	[]string{"socket", "endpoint", "code"},
+)
+
+var apiRequestDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "cloud_hypervisor_provider",
+		Name:      "vmm_api_request_duration_seconds",
+		Help:      "Latency of the calls of the cloud-hypervisor api by endpoint.",
+		// Migrations and snapshots block for their duration, the other calls should return within milliseconds.
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 9),
+	},
+	[]string{"endpoint"},
+)
+
+var apiThrottledRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cloud_hypervisor_provider",
+		Name:      "vmm_api_throttled_requests_total",
+		Help:      "Calls of the cloud-hypervisor api that waited for the rate limit of their socket.",
+	},
+	[]string{"socket"},
+)
+
+var apiThrottleSeconds = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cloud_hypervisor_provider",
+		Name:      "vmm_api_throttled_seconds_total",
+		Help:      "Time the calls of the cloud-hypervisor api waited for the rate limit of their socket.",
+	},
+	[]string{"socket"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(instanceInfo, vmInfoCacheRequests, apiRequests, apiRequestDuration,
+		apiThrottledRequests, apiThrottleSeconds)
+}
+
+func recordInstance(socket, version string, compatible bool) {
+	instanceInfo.DeletePartialMatch(prometheus.Labels{"socket": socket})
+
+	compatibleLabel := "false"
+	if compatible {
+		compatibleLabel = "true"
+	}
+	instanceInfo.WithLabelValues(socket, version, compatibleLabel).Set(1)
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+)
+
+// DefaultVMConfigOverrideAllowlist are the fields of the VM config that may be overridden by default. They tune
+// the VM without touching what the provider manages, like disks, network interfaces or the payload.
+var DefaultVMConfigOverrideAllowlist = []string{
+	"iommu",
+	"memory.hotplug_method",
+	"memory.mergeable",
+	"memory.thp",
+	"pvpanic",
+	"rng",
+	"watchdog",
+}
+
+// ValidateVMConfigOverrides checks that the overrides only set fields of the allowlist, and that their values fit
+// the fields. An allowlist entry allows the field at its path and the fields below it.
+func ValidateVMConfigOverrides(overrides map[string]string, allowlist []string) error {
+	for _, path := range slices.Sorted(maps.Keys(overrides)) {
+		if !slices.ContainsFunc(allowlist, func(allowed string) bool {
+			return path == allowed || strings.HasPrefix(path, allowed+".")
+		}) {
+			return fmt.Errorf("vm config field %s is not in the override allowlist", path)
+		}
+	}
+	return applyVMConfigOverrides(&client.VmConfig{}, overrides)
+}
+
+// applyVMConfigOverrides sets the fields of the config at the dot separated paths of the overrides. Values that
+// are valid JSON are set as such, all others as string.
+func applyVMConfigOverrides(config *client.VmConfig, overrides map[string]string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vm config: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to unmarshal vm config: %w", err)
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(overrides)) {
+		var value any
+		if err := json.Unmarshal([]byte(overrides[path]), &value); err != nil {
+			value = overrides[path]
+		}
+		if err := setField(fields, strings.Split(path, "."), value); err != nil {
+			return fmt.Errorf("failed to override vm config field %s: %w", path, err)
+		}
+	}
+
+	if data, err = json.Marshal(fields); err != nil {
+		return fmt.Errorf("failed to marshal vm config: %w", err)
+	}
+	var overridden client.VmConfig
+	if err := json.Unmarshal(data, &overridden); err != nil {
+		return fmt.Errorf("invalid vm config overrides: %w", err)
+	}
+	*config = overridden
+	return nil
+}
+
+func setField(fields map[string]any, path []string, value any) error {
+	if len(path) == 1 {
+		fields[path[0]] = value
+		return nil
+	}
+
+	child, ok := fields[path[0]]
+	if !ok || child == nil {
+		child = map[string]any{}
+		fields[path[0]] = child
+	}
+	childFields, ok := child.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s is not an object", path[0])
+	}
+	return setField(childFields, path[1:], value)
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/internal/host"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// SocketSelection is the policy the api socket of a new machine is chosen from the sockets directories with.
+type SocketSelection string
+
+const (
+	// SocketSelectionRandom chooses any free socket.
+	SocketSelectionRandom SocketSelection = "random"
+	// SocketSelectionSpread chooses a socket of the directory with the most free sockets, spreading the machines
+	// evenly, e.g. over the instances pinned to different NUMA nodes.
+	SocketSelectionSpread SocketSelection = "spread"
+	// SocketSelectionFill chooses a socket of the first directory with a free socket, in the order the directories
+	// are configured, e.g. to prefer the instances of a newer version.
+	SocketSelectionFill SocketSelection = "fill"
+	// SocketSelectionNUMA chooses a socket of the directory whose NUMA node has the most free memory, see
+	// ManagerOptions.NUMANodes. Directories without NUMA node are only chosen if no other has a free socket.
+	SocketSelectionNUMA SocketSelection = "numa"
+)
+
+func ParseSocketSelection(s string) (SocketSelection, error) {
+	switch selection := SocketSelection(s); selection {
+	case SocketSelectionRandom, SocketSelectionSpread, SocketSelectionFill, SocketSelectionNUMA:
+		return selection, nil
+	default:
+		return "", fmt.Errorf("unknown socket selection %q, must be one of %s, %s, %s, %s", s,
+			SocketSelectionRandom, SocketSelectionSpread, SocketSelectionFill, SocketSelectionNUMA)
+	}
+}
+
+// selectSocket removes the socket chosen by the policy from the free sockets.
+func selectSocket(
+	free sets.Set[string],
+	dirs []string,
+	selection SocketSelection,
+	numaNodes map[string]int,
+) (string, bool) {
+	if free.Len() == 0 {
+		return "", false
+	}
+
+	byDir := map[string][]string{}
+	for _, socket := range sets.List(free) {
+		dir := filepath.Dir(socket)
+		byDir[dir] = append(byDir[dir], socket)
+	}
+
+	var sockets []string
+	switch selection {
+	case SocketSelectionSpread:
+		for _, dir := range dirs {
+			if len(byDir[filepath.Clean(dir)]) > len(sockets) {
+				sockets = byDir[filepath.Clean(dir)]
+			}
+		}
+	case SocketSelectionFill:
+		for _, dir := range dirs {
+			if sockets = byDir[filepath.Clean(dir)]; len(sockets) > 0 {
+				break
+			}
+		}
+	case SocketSelectionNUMA:
+		mostMemFree := int64(-1)
+		for _, dir := range dirs {
+			candidates := byDir[filepath.Clean(dir)]
+			node, ok := numaNodes[filepath.Clean(dir)]
+			if len(candidates) == 0 || !ok {
+				continue
+			}
+			// A node whose memory can't be read is only chosen if no other node has a free socket.
+			memFree, err := host.NUMANodeMemFree(node)
+			if err != nil {
+				memFree = 0
+			}
+			if memFree > mostMemFree || (memFree == mostMemFree && len(candidates) > len(sockets)) {
+				sockets, mostMemFree = candidates, memFree
+			}
+		}
+	}
+
+	if len(sockets) == 0 {
+		return free.PopAny()
+	}
+	free.Delete(sockets[0])
+	return sockets[0], true
+}
+
+// numaNodeOf returns the NUMA node the instance of the socket is pinned to.
+func numaNodeOf(numaNodes map[string]int, socket string) (int, bool) {
+	node, ok := numaNodes[filepath.Dir(socket)]
+	return node, ok
+}
+
+// cleanNUMANodes returns the NUMA nodes keyed by the cleaned socket directories.
+func cleanNUMANodes(numaNodes map[string]int) map[string]int {
+	cleaned := map[string]int{}
+	for dir, node := range numaNodes {
+		cleaned[filepath.Clean(dir)] = node
+	}
+	return cleaned
+}
@@ -5,22 +5,122 @@ package vmm
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"golang.org/x/time/rate"
 )
 
 func NewUnixSocketClient(socketPath string) (*client.ClientWithResponses, error) {
-	transport := &http.Transport{
+	return newClient(unixSocketTransport(socketPath))
+}
+
+// newInstrumentedClient returns a client whose calls are recorded in the api metrics of the socket. The calls wait
+// for the limiter, if any.
+func newInstrumentedClient(socketPath string, limiter *rate.Limiter) (*client.ClientWithResponses, error) {
+	return newClient(&instrumentedTransport{
+		socket:  socketPath,
+		limiter: limiter,
+		next:    unixSocketTransport(socketPath),
+	})
+}
+
+func unixSocketTransport(socketPath string) *http.Transport {
+	return &http.Transport{
 		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 			return net.Dial("unix", socketPath)
 		},
 	}
+}
 
+func newClient(transport http.RoundTripper) (*client.ClientWithResponses, error) {
 	httpClient := &http.Client{
 		Transport: transport,
 	}
 
-	return client.NewClientWithResponses("http://localhost/api/v1", client.WithHTTPClient(httpClient))
+	return client.NewClientWithResponses(apiServer+apiPrefix, client.WithHTTPClient(httpClient))
+}
+
+const (
+	apiServer = "http://localhost"
+	apiPrefix = "/api/v1"
+)
+
+const (
+	// DefaultAPIRateLimit and DefaultAPIBurst leave room for the few calls of a reconciliation, while the
+	// reconciliations of all machines after a restart of the provider are spread over the instances.
+	DefaultAPIRateLimit = 20
+	DefaultAPIBurst     = 40
+)
+
+// instrumentedTransport records the latency and the outcome of the api calls by endpoint, e.g. vm.info. The time
+// a call waits for the limiter is not part of its latency.
+type instrumentedTransport struct {
+	socket string
+	// limiter bounds the rate of the calls of the instance, unlimited if nil.
+	limiter *rate.Limiter
+	next    *http.Transport
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, apiPrefix), "/")
+
+	if t.limiter != nil {
+		waitStart := time.Now()
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			apiRequests.WithLabelValues(t.socket, endpoint, "throttled").Inc()
+			return nil, fmt.Errorf("rate limit of %s: %w", t.socket, err)
+		}
+		if waited := time.Since(waitStart); waited > time.Millisecond {
+			apiThrottledRequests.WithLabelValues(t.socket).Inc()
+			apiThrottleSeconds.WithLabelValues(t.socket).Add(waited.Seconds())
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	apiRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	apiRequests.WithLabelValues(t.socket, endpoint, code).Inc()
+	return resp, err
+}
+
+// CloseIdleConnections lets the http client close the connections of the wrapped transport.
+func (t *instrumentedTransport) CloseIdleConnections() {
+	t.next.CloseIdleConnections()
+}
+
+// closeIdleConnections closes the connections the client keeps open to its instance.
+func closeIdleConnections(apiClient *client.ClientWithResponses) {
+	c, ok := apiClient.ClientInterface.(*client.Client)
+	if !ok {
+		return
+	}
+	if httpClient, ok := c.Client.(*http.Client); ok {
+		httpClient.CloseIdleConnections()
+	}
+}
+
+// SocketOwner returns the owner of the api socket, which is the user the cloud-hypervisor instance runs as.
+func SocketOwner(socketPath string) (uid, gid int, err error) {
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("failed to get owner of %s", socketPath)
+	}
+	return int(stat.Uid), int(stat.Gid), nil
 }
@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// upgradeSocketFile is the unix socket in the machine directory the new instance receives the VM on.
+	upgradeSocketFile = "upgrade.sock"
+	// upgradeSnapshotDir is the directory in the machine directory the VM is snapshotted to. It differs from the
+	// snapshot dir of the maintenance, which is restored whenever the machine has no VM.
+	upgradeSnapshotDir = "upgrade-snapshot"
+
+	// upgradeReceiverTimeout is how long the new instance may take to listen for the VM.
+	upgradeReceiverTimeout = 10 * time.Second
+	// upgradeRestartTimeout is how long the old instance may take to answer again, cloud-hypervisor exits once it
+	// sent its VM and is restarted by its unit.
+	upgradeRestartTimeout = 30 * time.Second
+	upgradePollInterval   = 100 * time.Millisecond
+)
+
+// GetUpgradeApiSocket takes a free socket whose instance has a newer version than the instance and satisfies the
+// requirements. The free instances are pinged first, since they may have been restarted with a new binary.
+func (m *Manager) GetUpgradeApiSocket(
+	ctx context.Context,
+	instanceID string,
+	requirements *api.VMMRequirements,
+) (*string, error) {
+	m.capabilitiesMu.RLock()
+	current, ok := m.capabilities[instanceID]
+	m.capabilitiesMu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	m.freeMu.Lock()
+	defer m.freeMu.Unlock()
+
+	for socket := range m.free {
+		if err := m.ping(ctx, socket); err != nil {
+			m.log.V(1).Info("Failed to ping free socket, not upgrading to it", "socket", socket, "error", err.Error())
+			m.capabilitiesMu.Lock()
+			delete(m.capabilities, socket)
+			m.capabilitiesMu.Unlock()
+		}
+	}
+
+	return m.takeFreeSocket(m.freeSatisfying(func(capabilities instanceCapabilities) bool {
+		return capabilities.version.GT(current.version) && capabilities.satisfiesRequirements(requirements) == nil
+	}), ErrNoNewerSocket)
+}
+
+// Upgrade moves the running VM of the machine from its instance to the instance to, which must not have a VM.
+// Afterwards, the instance of the machine has no VM and can be freed. If the upgrade fails, the VM keeps running
+// on the instance of the machine.
+func (m *Manager) Upgrade(ctx context.Context, machine *api.Machine, to string, method api.UpgradeMethod) error {
+	from := ptr.Deref(machine.Spec.ApiSocketPath, "")
+	log := m.log.WithValues("instanceID", from, "to", to, "method", method)
+
+	vm, err := m.GetVM(ctx, from)
+	if err != nil {
+		return err
+	}
+	if vm.State != client.Running {
+		return fmt.Errorf("vm is not running")
+	}
+
+	dir := m.config.paths.MachineDir(machine.ID)
+	switch method {
+	case api.UpgradeMethodMigration:
+		shared := ptr.Deref(ptr.Deref(vm.Config.Memory, client.MemoryConfig{}).Shared, false)
+		err = m.upgradeByMigration(ctx, from, to, filepath.Join(dir, upgradeSocketFile), shared)
+	case api.UpgradeMethodSnapshot:
+		err = m.upgradeBySnapshot(ctx, from, to, filepath.Join(dir, upgradeSnapshotDir))
+	default:
+		return fmt.Errorf("unsupported upgrade method %q", method)
+	}
+	if err != nil {
+		// A partially received or restored VM is dropped, so that the instance can be freed again.
+		if deleteErr := m.Delete(ctx, to); deleteErr != nil {
+			log.V(1).Info("Failed to delete vm of failed upgrade", "error", deleteErr.Error())
+		}
+		return err
+	}
+	log.V(1).Info("Upgraded machine")
+
+	m.releaseUpgraded(ctx, from)
+	return nil
+}
+
+// upgradeByMigration lets the instance to receive the VM on a unix socket and sends it there. With shared
+// memory, the memory is handed over instead of copied.
+func (m *Manager) upgradeByMigration(ctx context.Context, from, to, socketPath string, shared bool) error {
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale upgrade socket: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(socketPath)
+	}()
+	url := "unix:" + socketPath
+
+	receiveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	received := make(chan error, 1)
+	go func() {
+		received <- m.ReceiveMigration(receiveCtx, to, url)
+	}()
+
+	if err := awaitReceiver(ctx, socketPath, received); err != nil {
+		return err
+	}
+
+	if err := m.sendMigration(ctx, from, url, shared); err != nil {
+		cancel()
+		<-received
+		return fmt.Errorf("failed to send vm: %w", err)
+	}
+	// The VM was handed over once it is sent, whatever the receiving call reports.
+	if err := <-received; err != nil {
+		m.log.V(1).Info("Receiving instance reported an error after the vm was sent", "instanceID", to,
+			"error", err.Error())
+	}
+	return nil
+}
+
+// awaitReceiver waits until cloud-hypervisor listens on the socket, or the receiving call returned early.
+func awaitReceiver(ctx context.Context, socketPath string, received <-chan error) error {
+	timeout := time.After(upgradeReceiverTimeout)
+	ticker := time.NewTicker(upgradePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := os.Stat(socketPath); err == nil {
+			return nil
+		}
+
+		select {
+		case err := <-received:
+			if err == nil {
+				err = errors.New("returned before the vm was sent")
+			}
+			return fmt.Errorf("failed to receive vm: %w", err)
+		case <-timeout:
+			return fmt.Errorf("instance did not listen on %s within %s", socketPath, upgradeReceiverTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// upgradeBySnapshot snapshots the VM to dir and restores it on the instance to. The paused VM is resumed if the
+// snapshot cannot be restored.
+func (m *Manager) upgradeBySnapshot(ctx context.Context, from, to, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove old upgrade snapshot: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	if err := m.Snapshot(ctx, from, dir); err != nil {
+		return err
+	}
+
+	if err := m.Restore(ctx, to, dir); err != nil {
+		if resumeErr := m.Resume(ctx, from); resumeErr != nil {
+			m.log.Error(resumeErr, "Failed to resume vm after failed restore", "instanceID", from)
+		}
+		return fmt.Errorf("failed to restore vm: %w", err)
+	}
+	return nil
+}
+
+// releaseUpgraded waits until the instance the VM was moved away from answers again and deletes the VM left on
+// it, so that it can be freed.
+func (m *Manager) releaseUpgraded(ctx context.Context, instanceID string) {
+	log := m.log.WithValues("instanceID", instanceID)
+
+	deadline := time.Now().Add(upgradeRestartTimeout)
+	for {
+		err := m.Ping(ctx, instanceID)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, ErrIncompatible) || time.Now().After(deadline) {
+			log.Info("Instance did not come back after the upgrade", "error", err.Error())
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(upgradePollInterval):
+		}
+	}
+
+	if err := m.Delete(ctx, instanceID); err != nil {
+		log.V(1).Info("Failed to delete vm left after the upgrade", "error", err.Error())
+	}
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package vmm
+
+import (
+	"context"
+
+	"github.com/ironcore-dev/cloud-hypervisor-provider/api"
+	"github.com/ironcore-dev/cloud-hypervisor-provider/cloud-hypervisor/client"
+)
+
+const (
+	ModeReal = "real"
+	ModeFake = "fake"
+)
+
+// BreakPoint is the state of a VM stopped at a breakpoint of the gdb stub of cloud-hypervisor, which the api
+// spec does not list.
+const BreakPoint client.VmInfoState = "BreakPoint"
+
+// VirtualMachineManager manages the VMs of a set of cloud-hypervisor instances.
+type VirtualMachineManager interface {
+	// Start watches the instances until the context is done.
+	Start(ctx context.Context) error
+
+	Ping(ctx context.Context, instanceID string) error
+	Pid(ctx context.Context, instanceID string) (int, error)
+	// VMMInfo returns the version, pid and features the cloud-hypervisor instance reports on ping.
+	VMMInfo(ctx context.Context, instanceID string) (*client.VmmPingResponse, error)
+	// NUMANode returns the NUMA node the instance is pinned to, false if it is not pinned.
+	NUMANode(instanceID string) (int, bool)
+
+	GetFreeApiSocket(requirements *api.VMMRequirements) (*string, error)
+	FreeApiSocket(ctx context.Context, socket string)
+	// GetUpgradeApiSocket takes a free socket whose instance has a newer version than the instance and satisfies
+	// the requirements.
+	GetUpgradeApiSocket(ctx context.Context, instanceID string, requirements *api.VMMRequirements) (*string, error)
+	// Upgrade moves the running VM of the machine from its instance to the instance to.
+	Upgrade(ctx context.Context, machine *api.Machine, to string, method api.UpgradeMethod) error
+
+	GetVM(ctx context.Context, instanceID string) (*client.VmInfo, error)
+	// Config returns the config the VM of the machine is created with.
+	Config(machine *api.Machine) (*client.VmConfig, error)
+	VMs(ctx context.Context) (map[string]*client.VmInfo, error)
+	Counters(ctx context.Context, instanceID string) (client.VmCounters, error)
+	CreateVM(ctx context.Context, machine *api.Machine) error
+	PowerOn(ctx context.Context, instanceID string) error
+	PowerOff(ctx context.Context, instanceID string) error
+	PowerButton(ctx context.Context, instanceID string) error
+	Reboot(ctx context.Context, instanceID string) error
+	Pause(ctx context.Context, instanceID string) error
+	Resume(ctx context.Context, instanceID string) error
+	Resize(ctx context.Context, instanceID string, vcpus *int, memoryBytes *int64) error
+	Snapshot(ctx context.Context, instanceID string, dir string) error
+	Restore(ctx context.Context, instanceID string, dir string) error
+	SendMigration(ctx context.Context, instanceID string, destinationURL string) error
+	ReceiveMigration(ctx context.Context, instanceID string, receiverURL string) error
+	Delete(ctx context.Context, instanceID string) error
+
+	RemoveDevice(ctx context.Context, instanceID string, deviceID string) error
+	AddNIC(ctx context.Context, instanceID string, nic *api.NetworkInterfaceStatus) error
+	RemoveNIC(ctx context.Context, instanceID string, nicName string) error
+	AddDisk(ctx context.Context, instanceID string, volume *api.VolumeStatus) error
+
+	// Close closes the connections to the instances, their VMs keep running.
+	Close()
+}
+
+var (
+	_ VirtualMachineManager = (*Manager)(nil)
+	_ VirtualMachineManager = (*FakeManager)(nil)
+)
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2026 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vsock connects to the guests via the hybrid vsock of cloud-hypervisor, which proxies the vsock of a VM
+// through a unix socket on the host.
+package vsock
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// handshakeTimeout bounds the wait for the guest to accept the connection.
+const handshakeTimeout = 5 * time.Second
+
+// Dial connects to the port of the guest whose hybrid vsock is proxied via socketPath. The guest has to listen on
+// the vsock port.
+func Dial(ctx context.Context, socketPath string, port uint32) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to vsock socket: %w", err)
+	}
+
+	if err := handshake(conn, port); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// handshake asks cloud-hypervisor to connect to the port of the guest. It reads the reply byte by byte, so that no
+// data of the guest following it is consumed.
+func handshake(conn net.Conn, port uint32) error {
+	if err := conn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %d\n", port); err != nil {
+		return fmt.Errorf("error sending vsock connect: %w", err)
+	}
+
+	var reply strings.Builder
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return fmt.Errorf("guest did not accept the connection to vsock port %d: %w", port, err)
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		if reply.Len() > 64 {
+			return fmt.Errorf("invalid vsock connect reply %q", reply.String())
+		}
+		reply.WriteByte(buf[0])
+	}
+	if !strings.HasPrefix(reply.String(), "OK ") {
+		return fmt.Errorf("invalid vsock connect reply %q", reply.String())
+	}
+
+	return conn.SetDeadline(time.Time{})
+}